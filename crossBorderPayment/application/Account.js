@@ -17,7 +17,7 @@ function generateAccountID() {
   return uuidv4();
 }
 
-async function createAccount(customerID, bankID, balance) {
+async function createAccount(customerID, bankID, balance, iban = "") {
   try {
     const ccp = buildCCPOrg1();
     const walletPath = path.join(__dirname, "wallet/org1");
@@ -39,14 +39,14 @@ async function createAccount(customerID, bankID, balance) {
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("CreateAccount");
+    let statefulTxn = contract.createTransaction("account:CreateAccount");
 
     console.log("\n--> Submit Transaction: Propose a new account");
-    await statefulTxn.submit(accountID, customerID, bankID, balance);
+    await statefulTxn.submit(accountID, customerID, bankID, balance, iban, "", "", "");
     console.log("* Result: committed");
 
     console.log("\n--> Evaluate Transaction: query the customer accounts");
-    let result = await contract.evaluateTransaction("QueryAccount", accountID);
+    let result = await contract.evaluateTransaction("account:QueryAccount", accountID);
     console.log(
       "* Result: Customer Accounts: " + prettyJSONString(result.toString())
     );
@@ -79,7 +79,7 @@ async function deleteAccount(customerID, accountID) {
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("DeleteAccount");
+    let statefulTxn = contract.createTransaction("account:DeleteAccount");
 
     console.log("\n--> Submit Transaction: Propose a new bank");
     await statefulTxn.submit(accountID);
@@ -111,7 +111,7 @@ async function searchCustomerbyAccount(customerID) {
 
     console.log("\n--> Evaluate Transaction: query the customer accounts");
     let result = await contract.evaluateTransaction(
-      "QueryCustomer",
+      "customer:QueryCustomer",
       customerID
     );
     console.log(
@@ -149,7 +149,7 @@ async function fetchCustomerAccounts(customerID) {
 
     console.log("\n--> Evaluate Transaction: query the customer accounts");
     let result = await contract.evaluateTransaction(
-      "QueryCustomerAccounts",
+      "customer:QueryCustomerAccounts",
       customerID
     );
     console.log(
@@ -186,7 +186,7 @@ async function fetchBankAccounts(bankID, bankadminID) {
 
     console.log("\n--> Evaluate Transaction: query the customer accounts");
     let result = await contract.evaluateTransaction(
-      "QueryBankAccounts",
+      "bank:QueryBankAccounts",
       bankID
     );
     console.log(
@@ -222,7 +222,7 @@ async function fetchBankCustomers(bankID, bankadminID) {
 
     console.log("\n--> Evaluate Transaction: query the bank customer");
     let result = await contract.evaluateTransaction(
-      "QueryCustomersByBank",
+      "bank:QueryCustomersByBank",
       bankID
     );
     console.log("* Result: Customers: " + prettyJSONString(result.toString()));
@@ -237,7 +237,10 @@ async function fetchBankCustomers(bankID, bankadminID) {
   }
 }
 
-async function updateBalance(customerID, accountID, amount) {
+// updateBalance proposes a balance change as the bank administrator. The
+// balance only changes once another administrator or an operator approves
+// the returned operation.
+async function updateBalance(bankadminID, accountID, amount) {
   try {
     const ccp = buildCCPOrg1();
     const walletPath = path.join(__dirname, "wallet/org1");
@@ -247,22 +250,25 @@ async function updateBalance(customerID, accountID, amount) {
 
     await gateway.connect(ccp, {
       wallet: wallet,
-      identity: customerID,
+      identity: bankadminID,
       discovery: { enabled: true, asLocalhost: true },
     });
 
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("UpdateBalance");
+    let statefulTxn = contract.createTransaction("account:UpdateBalance");
 
-    console.log("\n--> Submit Transaction: Propose a new bank");
-    await statefulTxn.submit(accountID, amount);
-    console.log("* Result: committed");
+    console.log("\n--> Submit Transaction: Propose a balance change");
+    const result = await statefulTxn.submit(accountID, amount);
+    console.log("* Result: Operation: " + prettyJSONString(result.toString()));
     gateway.disconnect();
+
+    const operation = JSON.parse(result.toString());
+    return operation;
   } catch (error) {
     console.error("Error:", error);
-    throw new Error("Failed to fetch customer accounts");
+    throw new Error("Failed to propose the balance change");
   }
 }
 
@@ -14,65 +14,6 @@ const {
 const myChannel = "bankschannel";
 const myChaincodeName = "bank";
 
-async function createBank(
-  bankID,
-  bankadminID,
-  name,
-  password,
-  country,
-  currency,
-  reserves
-) {
-  try {
-    const certFile = "/path/to/certificate.pem";
-    const agent = new https.Agent({
-      ca: fs.readFileSync(certFile),
-    });
-
-    const ccp = buildCCPOrg1();
-    const walletPath = path.join(__dirname, "wallet/org1");
-    const wallet = await buildWallet(Wallets, walletPath);
-
-    const gateway = new Gateway();
-
-    await gateway.connect(ccp, {
-      wallet: wallet,
-      identity: bankadminID,
-      discovery: { enabled: true, asLocalhost: true },
-    });
-
-    const network = await gateway.getNetwork(myChannel);
-    const contract = network.getContract(myChaincodeName);
-
-    let statefulTxn = contract.createTransaction("CreateBank");
-
-    console.log("\n--> Submit Transaction: Propose a new bank");
-    await statefulTxn.submit(
-      bankID,
-      bankadminID,
-      name,
-      password,
-      country,
-      currency,
-      reserves
-    );
-    console.log("* Result: committed");
-
-    console.log(
-      "\n--> Evaluate Transaction: query the bank that was just created"
-    );
-    let result = await contract.evaluateTransaction("QueryBank", bankID);
-    console.log("* Result: Bank: " + prettyJSONString(result.toString()));
-
-    gateway.disconnect();
-
-    return true; // İşlem başarılıysa true döndür
-  } catch (error) {
-    console.error(`**** FAILED to submit bank: ${error}`);
-    return false; // İşlem başarısızsa false döndür
-  }
-}
-
 async function createBank(
   bankID,
   bankadminID,
@@ -104,7 +45,7 @@ async function createBank(
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("CreateBank");
+    let statefulTxn = contract.createTransaction("bank:CreateBank");
 
     console.log("\n--> Submit Transaction: Propose a new bank");
     await statefulTxn.submit(
@@ -115,14 +56,15 @@ async function createBank(
       country,
       currency,
       reserves,
-      1
+      1,
+      ""
     );
-    console.log("* Result: committed");
+    console.log("* Result: committed, awaiting admission by the member banks");
 
     console.log(
       "\n--> Evaluate Transaction: query the bank that was just created"
     );
-    let result = await contract.evaluateTransaction("QueryBank", bankID);
+    let result = await contract.evaluateTransaction("bank:QueryBank", bankID);
     console.log("* Result: Bank: " + prettyJSONString(result.toString()));
 
     gateway.disconnect();
@@ -203,7 +145,7 @@ async function createBankWithExchangeRate(
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("CreateBank");
+    let statefulTxn = contract.createTransaction("bank:CreateBank");
 
     console.log("\n--> Submit Transaction: Propose a new bank");
     await statefulTxn.submit(
@@ -214,14 +156,15 @@ async function createBankWithExchangeRate(
       country,
       currency,
       reserves,
-      exchangeRate
+      exchangeRate,
+      ""
     );
-    console.log("* Result: committed");
+    console.log("* Result: committed, awaiting admission by the member banks");
 
     console.log(
       "\n--> Evaluate Transaction: query the bank that was just created"
     );
-    let result = await contract.evaluateTransaction("QueryBank", bankID);
+    let result = await contract.evaluateTransaction("bank:QueryBank", bankID);
     console.log("* Result: Bank: " + prettyJSONString(result.toString()));
 
     gateway.disconnect();
@@ -256,7 +199,7 @@ async function loginBank(bankadminID, bankID, password) {
       console.log(
         "\n--> Evaluate Transaction: query the bank that was just created"
       );
-      let result = await contract.evaluateTransaction("QueryBank", bankID);
+      let result = await contract.evaluateTransaction("bank:QueryBank", bankID);
       console.log("* Result: Bank: " + prettyJSONString(result.toString()));
       return { success: true };
     } else {
@@ -286,7 +229,7 @@ async function getPasswordFromBlockchain(UserID) {
 
     console.log("\n--> Evaluate Transaction: QueryCustomerPassword");
     const result = await contract.evaluateTransaction(
-      "QueryCustomerPassword",
+      "customer:QueryCustomerPassword",
       UserID
     );
     const password = result.toString();
@@ -313,16 +256,191 @@ async function updateBankProfile(bankID, bankadminID, name, reserves, country) {
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("UpdateBankProfile");
+    let statefulTxn = contract.createTransaction("bank:UpdateBankProfile");
+
+    console.log("\n--> Submit Transaction: Propose a bank profile change");
+    const result = await statefulTxn.submit(
+      bankID,
+      bankadminID,
+      name,
+      reserves,
+      country
+    );
+    console.log("* Result: Operation: " + prettyJSONString(result.toString()));
+
+    gateway.disconnect();
+    return { success: true, operation: JSON.parse(result.toString()) };
+  } catch (error) {
+    console.error(`**** FAILED to update profile: ${error}`);
+    return { success: false, error: error.message };
+  }
+}
+
+// Bank profile and balance changes wait for a second administrator or an
+// operator, who approves or rejects them here
+async function fetchPendingOperations(bankadminID) {
+  try {
+    const ccp = buildCCPOrg1();
+    const walletPath = path.join(__dirname, "wallet/org1");
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+      wallet: wallet,
+      identity: bankadminID,
+      discovery: { enabled: true, asLocalhost: true },
+    });
+
+    const network = await gateway.getNetwork(myChannel);
+    const contract = network.getContract(myChaincodeName);
+
+    console.log("\n--> Evaluate Transaction: query the pending operations");
+    let result = await contract.evaluateTransaction(
+      "admin:QueryPendingOperations"
+    );
+    console.log("* Result: Operations: " + prettyJSONString(result.toString()));
+
+    gateway.disconnect();
+
+    const operations = JSON.parse(result.toString());
+    return operations;
+  } catch (error) {
+    console.error("Error:", error);
+    throw new Error("Failed to fetch pending operations");
+  }
+}
+
+async function approveOperation(bankadminID, operationID) {
+  try {
+    const ccp = buildCCPOrg1();
+    const walletPath = path.join(__dirname, "wallet/org1");
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+      wallet: wallet,
+      identity: bankadminID,
+      discovery: { enabled: true, asLocalhost: true },
+    });
+
+    const network = await gateway.getNetwork(myChannel);
+    const contract = network.getContract(myChaincodeName);
+
+    let statefulTxn = contract.createTransaction("admin:ApproveOperation");
+
+    console.log("\n--> Submit Transaction: Approve operation " + operationID);
+    const result = await statefulTxn.submit(operationID);
+    console.log("* Result: Operation: " + prettyJSONString(result.toString()));
+
+    gateway.disconnect();
+    return { success: true, operation: JSON.parse(result.toString()) };
+  } catch (error) {
+    console.error(`**** FAILED to approve operation: ${error}`);
+    return { success: false, error: error.message };
+  }
+}
+
+async function rejectOperation(bankadminID, operationID, reason) {
+  try {
+    const ccp = buildCCPOrg1();
+    const walletPath = path.join(__dirname, "wallet/org1");
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+      wallet: wallet,
+      identity: bankadminID,
+      discovery: { enabled: true, asLocalhost: true },
+    });
+
+    const network = await gateway.getNetwork(myChannel);
+    const contract = network.getContract(myChaincodeName);
+
+    let statefulTxn = contract.createTransaction("admin:RejectOperation");
 
-    console.log("\n--> Submit Transaction: Update customer profile");
-    await statefulTxn.submit(bankID, bankadminID, name, reserves, country);
+    console.log("\n--> Submit Transaction: Reject operation " + operationID);
+    await statefulTxn.submit(operationID, reason);
     console.log("* Result: committed");
 
     gateway.disconnect();
     return { success: true };
   } catch (error) {
-    console.error(`**** FAILED to update profile: ${error}`);
+    console.error(`**** FAILED to reject operation: ${error}`);
+    return { success: false, error: error.message };
+  }
+}
+
+// A new bank is an application until the member banks vote it in. The first
+// member is admitted by an operator, as there is nobody to vote yet.
+async function fetchMembership(bankadminID, bankID) {
+  try {
+    const ccp = buildCCPOrg1();
+    const walletPath = path.join(__dirname, "wallet/org1");
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+      wallet: wallet,
+      identity: bankadminID,
+      discovery: { enabled: true, asLocalhost: true },
+    });
+
+    const network = await gateway.getNetwork(myChannel);
+    const contract = network.getContract(myChaincodeName);
+
+    console.log("\n--> Evaluate Transaction: query the bank's membership");
+    let result = await contract.evaluateTransaction("bank:QueryBank", bankID);
+    const bank = JSON.parse(result.toString());
+    result = await contract.evaluateTransaction(
+      "bank:QueryOpenMembershipProposals"
+    );
+    console.log("* Result: Proposals: " + prettyJSONString(result.toString()));
+
+    gateway.disconnect();
+
+    const proposals = JSON.parse(result.toString());
+    return { bank, proposals };
+  } catch (error) {
+    console.error("Error:", error);
+    throw new Error("Failed to fetch the bank's membership");
+  }
+}
+
+async function voteOnMembership(bankadminID, bankID, voterBankID, inFavor) {
+  try {
+    const ccp = buildCCPOrg1();
+    const walletPath = path.join(__dirname, "wallet/org1");
+    const wallet = await buildWallet(Wallets, walletPath);
+
+    const gateway = new Gateway();
+
+    await gateway.connect(ccp, {
+      wallet: wallet,
+      identity: bankadminID,
+      discovery: { enabled: true, asLocalhost: true },
+    });
+
+    const network = await gateway.getNetwork(myChannel);
+    const contract = network.getContract(myChaincodeName);
+
+    let statefulTxn = contract.createTransaction("bank:VoteOnMembership");
+
+    console.log("\n--> Submit Transaction: Vote on the membership of " + bankID);
+    const result = await statefulTxn.submit(
+      bankID,
+      voterBankID,
+      inFavor ? "true" : "false"
+    );
+    console.log("* Result: Proposal: " + prettyJSONString(result.toString()));
+
+    gateway.disconnect();
+    return { success: true, proposal: JSON.parse(result.toString()) };
+  } catch (error) {
+    console.error(`**** FAILED to vote: ${error}`);
     return { success: false, error: error.message };
   }
 }
@@ -345,7 +463,7 @@ async function fetchBanks(bankID, bankadminID) {
     const contract = network.getContract(myChaincodeName);
 
     console.log("\n--> Evaluate Transaction: query the customer accounts");
-    let result = await contract.evaluateTransaction("QueryBank", bankID);
+    let result = await contract.evaluateTransaction("bank:QueryBank", bankID);
     console.log("* Result: Banks: " + prettyJSONString(result.toString()));
 
     gateway.disconnect();
@@ -377,7 +495,7 @@ async function searchCustomer(bankadminID, customerID) {
 
     console.log("\n--> Evaluate Transaction: query the customer");
     let result = await contract.evaluateTransaction(
-      "QueryCustomer",
+      "customer:QueryCustomer",
       customerID
     );
     console.log("* Result: Customer: " + prettyJSONString(result.toString()));
@@ -406,25 +524,25 @@ async function updateReserve(bankadminID, bankID, reserves) {
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    const userPassword = await getPasswordFromBlockchain(bankadminID);
+    let result = await contract.evaluateTransaction("bank:QueryBank", bankID);
+    const bank = JSON.parse(result.toString());
 
-    if (password === userPassword) {
-      console.log("Şifre doğrulandı");
-      console.log(
-        "\n--> Evaluate Transaction: query the bank that was just created"
-      );
-      let statefulTxn = contract.createTransaction("UpdateReserve");
-      console.log("\n--> Submit Transaction: Propose a new bank");
-      await statefulTxn.submit(bankadminID, bankID, reserves);
-      console.log("* Result: committed");
-      gateway.disconnect();
-      return { success: true };
-    } else {
-      throw new Error("Şifre yanlış");
-    }
+    let statefulTxn = contract.createTransaction("bank:UpdateBankProfile");
+    console.log("\n--> Submit Transaction: Propose new bank reserves");
+    result = await statefulTxn.submit(
+      bankID,
+      bankadminID,
+      bank.name,
+      reserves,
+      bank.country
+    );
+    console.log("* Result: Operation: " + prettyJSONString(result.toString()));
+
+    gateway.disconnect();
+    return { success: true, operation: JSON.parse(result.toString()) };
   } catch (error) {
     console.error("Error:", error);
-    throw new Error("Failed to fetch customer accounts");
+    throw new Error("Failed to propose the bank reserves");
   }
 }
 
@@ -435,4 +553,9 @@ module.exports = {
   updateBankProfile,
   fetchBanks,
   updateReserve,
+  fetchPendingOperations,
+  approveOperation,
+  rejectOperation,
+  fetchMembership,
+  voteOnMembership,
 };
@@ -32,7 +32,7 @@ async function fetchCustomerPayments(customerID,accountID) {
 	  const contract = network.getContract(myChaincodeName);
   
 	  console.log('\n--> Evaluate Transaction: query the customer Payments');
-	  let result = await contract.evaluateTransaction('QueryPayments', accountID);
+	  let result = await contract.evaluateTransaction('payment:QueryPayments', accountID);
 	  console.log('* Result: Customer Payments: ' + prettyJSONString(result.toString()));
 
 	  gateway.disconnect();
@@ -72,7 +72,7 @@ async function createPayment(senderAccountID, receiverAccountID, senderCustomerI
 			let network = await gateway.getNetwork(myChannel);
 			let contract = network.getContract(myChaincodeName);
 		// Get accountSender information
-		const accountSenderBytes = await contract.evaluateTransaction('GetAccount', senderAccountID);
+		const accountSenderBytes = await contract.evaluateTransaction('account:GetAccount', senderAccountID);
 		const accountSender = JSON.parse(accountSenderBytes.toString());
 
 		await gateway.connect(ccp,
@@ -82,7 +82,7 @@ async function createPayment(senderAccountID, receiverAccountID, senderCustomerI
 		contract = network.getContract(myChaincodeName);
 
 		// Get accountReceiver information
-		const accountReceiverBytes = await contract.evaluateTransaction('GetAccount', receiverAccountID);
+		const accountReceiverBytes = await contract.evaluateTransaction('account:GetAccount', receiverAccountID);
 		const accountReceiver = JSON.parse(accountReceiverBytes.toString());
 	
 		// Get exchange rate using fromCurrency and toCurrency
@@ -102,8 +102,8 @@ async function createPayment(senderAccountID, receiverAccountID, senderCustomerI
 			date: date
 		};
 	
-		let statefulTxn = contract.createTransaction('CreatePayment');
-		await statefulTxn.submit(paymentID,senderAccountID,receiverAccountID,senderCustomerID,receiverCustomerID,amount,exchangeRate,date);
+		let statefulTxn = contract.createTransaction('payment:CreatePayment');
+		await statefulTxn.submit(paymentID,senderAccountID,receiverAccountID,senderCustomerID,receiverCustomerID,amount,exchangeRate,date,'','');
 		console.log(JSON.stringify(payment));
 
 		gateway.disconnect();
@@ -22,6 +22,11 @@ const {
   loginBank,
   createBankWithExchangeRate,
   fetchBanks,
+  fetchPendingOperations,
+  approveOperation,
+  rejectOperation,
+  fetchMembership,
+  voteOnMembership,
 } = require("./Bank.js");
 const { createPayment, fetchCustomerPayments } = require("./Payment.js");
 const {
@@ -143,9 +148,9 @@ app.post("/loginCustomer", async (req, res) => {
 
 // ============== HESAP OLUSTURMA================= //
 app.post("/createAccount", async (req, res) => {
-  const { customerID, bankID, balance } = req.body;
+  const { customerID, bankID, balance, iban } = req.body;
   try {
-    const result = await createAccount(customerID, bankID, balance);
+    const result = await createAccount(customerID, bankID, balance, iban);
     if (!result.success) {
       const errorMessage = "Your ID is incorrect.";
       res.render("createAccount", { errorMessage });
@@ -274,20 +279,23 @@ app.post("/deleteAccount", async (req, res) => {
   }
 });
 
+// Balance changes are proposed by the bank administrator and wait for a
+// second administrator or an operator to approve them
 app.post("/updateBalance", async (req, res) => {
-  const customerID = req.session.customerID;
+  const bankadminID = req.session.bankadminID;
+  const bankID = req.session.bankID;
   const accountID = req.body.accountID;
   const amount = req.body.amount;
 
   try {
-    await updateBalance(customerID, accountID, amount);
-    req.session.customerID = customerID;
+    const operation = await updateBalance(bankadminID, accountID, amount);
+    req.session.bankadminID = bankadminID;
+    req.session.bankID = bankID;
 
-    const message = "Account balance updated.";
-    res.render("customerHome", { message });
+    const message = `Balance change ${operation.operationID} is pending approval.`;
+    res.redirect("/pendingOperations?message=" + encodeURIComponent(message));
   } catch (error) {
     console.error("Error:", error);
-    req.session.customerID = customerID;
     res.status(500).json({ error: "Account balance update failed." });
   }
 });
@@ -361,7 +369,8 @@ app.post("/createBank", async (req, res) => {
       req.session.country = country;
       req.session.reserves = reserves;
       req.session.currency = currency;
-      res.redirect("/bankHome");
+      const message = `Bank ${bankID} is created and waits for the member banks to admit it.`;
+      res.redirect("/membership?message=" + encodeURIComponent(message));
     } else {
       res.redirect("/createBank?error=invalid_user");
     }
@@ -475,6 +484,82 @@ app.get("/customerDetails/:customerID", async (req, res) => {
   }
 });
 
+// ============== KONSORSIYUM UYELIGI==================== //
+app.get("/membership", async (req, res) => {
+  const bankadminID = req.session.bankadminID;
+  const bankID = req.session.bankID;
+  const message = req.query.message;
+  const error = req.query.error;
+  try {
+    const { bank, proposals } = await fetchMembership(bankadminID, bankID);
+    req.session.bankadminID = bankadminID;
+    req.session.bankID = bankID;
+    res.render("membership", { bank, proposals, message, error });
+  } catch (error) {
+    console.error("Error:", error);
+    res.status(500).json({ error: "Failed to fetch the bank's membership." });
+  }
+});
+
+app.post("/voteOnMembership", async (req, res) => {
+  const bankadminID = req.session.bankadminID;
+  const voterBankID = req.session.bankID;
+  const { bankID, inFavor } = req.body;
+  const result = await voteOnMembership(
+    bankadminID,
+    bankID,
+    voterBankID,
+    inFavor === "true"
+  );
+  if (result.success) {
+    const message = `Vote on ${bankID} is recorded; the proposal is ${result.proposal.status}.`;
+    res.redirect("/membership?message=" + encodeURIComponent(message));
+  } else {
+    res.redirect("/membership?error=" + encodeURIComponent(result.error));
+  }
+});
+
+// ============== BEKLEYEN ISLEMLER==================== //
+app.get("/pendingOperations", async (req, res) => {
+  const bankadminID = req.session.bankadminID;
+  const bankID = req.session.bankID;
+  const message = req.query.message;
+  const error = req.query.error;
+  try {
+    const operations = await fetchPendingOperations(bankadminID);
+    req.session.bankadminID = bankadminID;
+    req.session.bankID = bankID;
+    res.render("pendingOperations", { operations, message, error });
+  } catch (error) {
+    console.error("Error:", error);
+    res.status(500).json({ error: "Failed to fetch pending operations." });
+  }
+});
+
+app.post("/approveOperation", async (req, res) => {
+  const bankadminID = req.session.bankadminID;
+  const { operationID } = req.body;
+  const result = await approveOperation(bankadminID, operationID);
+  if (result.success) {
+    const message = `Operation ${operationID} is approved.`;
+    res.redirect("/pendingOperations?message=" + encodeURIComponent(message));
+  } else {
+    res.redirect("/pendingOperations?error=" + encodeURIComponent(result.error));
+  }
+});
+
+app.post("/rejectOperation", async (req, res) => {
+  const bankadminID = req.session.bankadminID;
+  const { operationID, reason } = req.body;
+  const result = await rejectOperation(bankadminID, operationID, reason);
+  if (result.success) {
+    const message = `Operation ${operationID} is rejected.`;
+    res.redirect("/pendingOperations?message=" + encodeURIComponent(message));
+  } else {
+    res.redirect("/pendingOperations?error=" + encodeURIComponent(result.error));
+  }
+});
+
 app.get("/k6-test", (req, res) => {
   res.send("K6 testi başarıyla tamamlandı");
 });
@@ -68,7 +68,7 @@ async function SetCustomer(ccp, wallet, UserID, password, name, surname) {
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("CreateCustomer");
+    let statefulTxn = contract.createTransaction("customer:CreateCustomer");
 
     console.log("\n--> Submit Transaction: Propose a new user");
     await statefulTxn.submit(UserID, password, name, surname);
@@ -77,7 +77,7 @@ async function SetCustomer(ccp, wallet, UserID, password, name, surname) {
     console.log(
       "\n--> Evaluate Transaction: query the user that was just created"
     );
-    let result = await contract.evaluateTransaction("QueryCustomer", UserID);
+    let result = await contract.evaluateTransaction("customer:QueryCustomer", UserID);
     console.log("* Result: User: " + prettyJSONString(result.toString()));
     gateway.disconnect();
     return { success: true };
@@ -121,7 +121,7 @@ async function getPasswordFromBlockchain(UserID) {
 
     console.log("\n--> Evaluate Transaction: QueryCustomerPassword");
     const result = await contract.evaluateTransaction(
-      "QueryCustomerPassword",
+      "customer:QueryCustomerPassword",
       UserID
     );
     const password = result.toString();
@@ -149,7 +149,7 @@ async function updateProfile(customerID, name, surname, password) {
     const network = await gateway.getNetwork(myChannel);
     const contract = network.getContract(myChaincodeName);
 
-    let statefulTxn = contract.createTransaction("UpdateProfile");
+    let statefulTxn = contract.createTransaction("customer:UpdateProfile");
 
     console.log("\n--> Submit Transaction: Update customer profile");
     await statefulTxn.submit(customerID, name, surname, password);
@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient
+
+import (
+	"strconv"
+
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+const accountPrefix = bank.AccountContractName + ":"
+
+// CreateAccountRequest holds the arguments of CreateAccount
+type CreateAccountRequest struct {
+	AccountID     string
+	CustomerID    string
+	BankID        string
+	Balance       float64
+	IBAN          string
+	RoutingScheme string
+	RoutingNumber string
+	AccountNumber string
+}
+
+// CreateAccount opens an account for a customer at a bank, identified by
+// either an IBAN or a local account number
+func (c *Client) CreateAccount(req CreateAccountRequest) error {
+	return c.submit(nil, accountPrefix+"CreateAccount", req.AccountID, req.CustomerID, req.BankID, formatFloat(req.Balance),
+		req.IBAN, req.RoutingScheme, req.RoutingNumber, req.AccountNumber)
+}
+
+// GetAccount returns an account
+func (c *Client) GetAccount(accountID string) (*bank.Account, error) {
+	return c.account(accountPrefix+"GetAccount", accountID)
+}
+
+// QueryAccount returns an account
+func (c *Client) QueryAccount(accountID string) (*bank.Account, error) {
+	return c.account(accountPrefix+"QueryAccount", accountID)
+}
+
+// QueryAccountByIBAN returns the account with an IBAN
+func (c *Client) QueryAccountByIBAN(iban string) (*bank.Account, error) {
+	return c.account(accountPrefix+"QueryAccountByIBAN", iban)
+}
+
+// QueryAccountByNumber returns the account with a local account number at the
+// branch a routing number names
+func (c *Client) QueryAccountByNumber(routingScheme string, routingNumber string, accountNumber string) (*bank.Account, error) {
+	return c.account(accountPrefix+"QueryAccountByNumber", routingScheme, routingNumber, accountNumber)
+}
+
+func (c *Client) account(name string, args ...string) (*bank.Account, error) {
+	result := new(bank.Account)
+	err := c.evaluate(result, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateBalance proposes to credit amount to an account, or debit it when
+// negative. The balance changes once a different client approves the returned
+// operation.
+func (c *Client) UpdateBalance(accountID string, amount float64) (*bank.PendingOperation, error) {
+	result := new(bank.PendingOperation)
+	err := c.submit(result, accountPrefix+"UpdateBalance", accountID, formatFloat(amount))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FreezeAccount blocks all payments to and from an account
+func (c *Client) FreezeAccount(accountID string, reason string) error {
+	return c.submit(nil, accountPrefix+"FreezeAccount", accountID, reason)
+}
+
+// UnfreezeAccount makes a frozen account active again
+func (c *Client) UnfreezeAccount(accountID string) error {
+	return c.submit(nil, accountPrefix+"UnfreezeAccount", accountID)
+}
+
+// MarkAccountDormant flags an unused account
+func (c *Client) MarkAccountDormant(accountID string) error {
+	return c.submit(nil, accountPrefix+"MarkAccountDormant", accountID)
+}
+
+// ReactivateAccount makes a dormant account active again
+func (c *Client) ReactivateAccount(accountID string) error {
+	return c.submit(nil, accountPrefix+"ReactivateAccount", accountID)
+}
+
+// AddAccountHolder makes a customer a joint holder of an account
+func (c *Client) AddAccountHolder(accountID string, customerID string) error {
+	return c.submit(nil, accountPrefix+"AddAccountHolder", accountID, customerID)
+}
+
+// RemoveAccountHolder takes a joint holder off an account
+func (c *Client) RemoveAccountHolder(accountID string, customerID string) error {
+	return c.submit(nil, accountPrefix+"RemoveAccountHolder", accountID, customerID)
+}
+
+// SetSigningRule sets how many holders must sign an account's payments. rule
+// is bank.SigningAnyOne, bank.SigningAll or bank.SigningNOfM, which alone
+// uses requiredSignatures.
+func (c *Client) SetSigningRule(accountID string, rule string, requiredSignatures int) error {
+	return c.submit(nil, accountPrefix+"SetSigningRule", accountID, rule, strconv.Itoa(requiredSignatures))
+}
+
+// CloseAccount closes an account for good, moving any remaining balance to
+// sweepAccountID when it is not empty
+func (c *Client) CloseAccount(accountID string, sweepAccountID string) error {
+	return c.submit(nil, accountPrefix+"CloseAccount", accountID, sweepAccountID)
+}
+
+// DeleteAccount closes an empty account
+func (c *Client) DeleteAccount(accountID string) error {
+	return c.submit(nil, accountPrefix+"DeleteAccount", accountID)
+}
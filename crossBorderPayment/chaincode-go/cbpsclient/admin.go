@@ -0,0 +1,197 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient
+
+import (
+	"strconv"
+
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+const adminPrefix = bank.AdminContractName + ":"
+
+// AddWatchlistEntry adds a name, customer ID or country code to the watchlist
+func (c *Client) AddWatchlistEntry(entryType string, value string, reason string) error {
+	return c.submit(nil, adminPrefix+"AddWatchlistEntry", entryType, value, reason)
+}
+
+// RemoveWatchlistEntry deletes an entry from the watchlist
+func (c *Client) RemoveWatchlistEntry(entryType string, value string) error {
+	return c.submit(nil, adminPrefix+"RemoveWatchlistEntry", entryType, value)
+}
+
+// QueryWatchlist returns every watchlist entry
+func (c *Client) QueryWatchlist() ([]*bank.WatchlistEntry, error) {
+	var result []*bank.WatchlistEntry
+	err := c.evaluate(&result, adminPrefix+"QueryWatchlist")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetTierLimit sets the outbound limits of a KYC tier in one currency
+func (c *Client) SetTierLimit(limit bank.TierLimit) error {
+	return c.submit(nil, adminPrefix+"SetTierLimit", limit.Tier, limit.Currency, formatFloat(limit.PerTransactionMax),
+		formatFloat(limit.DailyLimit), formatFloat(limit.MonthlyLimit), strconv.Itoa(limit.MaxDailyPayments), strconv.Itoa(limit.MaxMonthlyPayments))
+}
+
+// QueryTierLimits returns the limits of a tier in every configured currency
+func (c *Client) QueryTierLimits(tier string) ([]*bank.TierLimit, error) {
+	var result []*bank.TierLimit
+	err := c.evaluate(&result, adminPrefix+"QueryTierLimits", tier)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetCustomerTier moves a customer to another KYC tier
+func (c *Client) SetCustomerTier(customerID string, tier string) error {
+	return c.submit(nil, adminPrefix+"SetCustomerTier", customerID, tier)
+}
+
+// QueryLimitUsage returns a customer's current daily and monthly counters in
+// one currency
+func (c *Client) QueryLimitUsage(customerID string, currency string) ([]*bank.LimitCounter, error) {
+	var result []*bank.LimitCounter
+	err := c.evaluate(&result, adminPrefix+"QueryLimitUsage", customerID, currency)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AddPurposeCode adds a code to the purpose code list or changes its description
+func (c *Client) AddPurposeCode(code string, description string) error {
+	return c.submit(nil, adminPrefix+"AddPurposeCode", code, description)
+}
+
+// RemovePurposeCode takes a code off the purpose code list
+func (c *Client) RemovePurposeCode(code string) error {
+	return c.submit(nil, adminPrefix+"RemovePurposeCode", code)
+}
+
+// QueryPurposeCodes returns the purpose code list
+func (c *Client) QueryPurposeCodes() ([]*bank.PurposeCode, error) {
+	var result []*bank.PurposeCode
+	err := c.evaluate(&result, adminPrefix+"QueryPurposeCodes")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyInvariants checks the ledger's accounting invariants
+func (c *Client) VerifyInvariants() (*bank.InvariantReport, error) {
+	result := new(bank.InvariantReport)
+	err := c.evaluate(result, adminPrefix+"VerifyInvariants")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// InitLedger stores the first chaincode configuration
+func (c *Client) InitLedger(config bank.ChaincodeConfig) (*bank.ChaincodeConfig, error) {
+	return c.putConfig(adminPrefix+"InitLedger", config)
+}
+
+// Configure replaces the chaincode configuration. config.Version must be the
+// version currently stored.
+func (c *Client) Configure(config bank.ChaincodeConfig) (*bank.ChaincodeConfig, error) {
+	return c.putConfig(adminPrefix+"Configure", config)
+}
+
+func (c *Client) putConfig(name string, config bank.ChaincodeConfig) (*bank.ChaincodeConfig, error) {
+	configJSON, err := formatJSON(name, config)
+	if err != nil {
+		return nil, err
+	}
+	result := new(bank.ChaincodeConfig)
+	err = c.submit(result, name, configJSON)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetConfig returns the chaincode configuration
+func (c *Client) GetConfig() (*bank.ChaincodeConfig, error) {
+	result := new(bank.ChaincodeConfig)
+	err := c.evaluate(result, adminPrefix+"GetConfig")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MigrateState upgrades up to pageSize stored objects to the current schema
+// version. Call it until the returned progress is complete.
+func (c *Client) MigrateState(pageSize int) (*bank.MigrationProgress, error) {
+	result := new(bank.MigrationProgress)
+	err := c.submit(result, adminPrefix+"MigrateState", strconv.Itoa(pageSize))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetMigrationProgress returns the progress of the schema migration
+func (c *Client) GetMigrationProgress() (*bank.MigrationProgress, error) {
+	result := new(bank.MigrationProgress)
+	err := c.evaluate(result, adminPrefix+"GetMigrationProgress")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AdmitFoundingBank makes the first member of the consortium, which has no
+// members to vote on it yet
+func (c *Client) AdmitFoundingBank(bankID string) (*bank.MembershipProposal, error) {
+	result := new(bank.MembershipProposal)
+	err := c.submit(result, adminPrefix+"AdmitFoundingBank", bankID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ApproveOperation applies an operation proposed by a different client and
+// returns it
+func (c *Client) ApproveOperation(operationID string) (*bank.PendingOperation, error) {
+	result := new(bank.PendingOperation)
+	err := c.submit(result, adminPrefix+"ApproveOperation", operationID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RejectOperation closes a pending operation without applying it
+func (c *Client) RejectOperation(operationID string, reason string) error {
+	return c.submit(nil, adminPrefix+"RejectOperation", operationID, reason)
+}
+
+// QueryOperation returns an operation, pending or decided
+func (c *Client) QueryOperation(operationID string) (*bank.PendingOperation, error) {
+	result := new(bank.PendingOperation)
+	err := c.evaluate(result, adminPrefix+"QueryOperation", operationID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryPendingOperations returns the operations waiting for approval
+func (c *Client) QueryPendingOperations() ([]*bank.PendingOperation, error) {
+	var result []*bank.PendingOperation
+	err := c.evaluate(&result, adminPrefix+"QueryPendingOperations")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,190 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient
+
+import (
+	"strconv"
+
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+const bankPrefix = bank.BankContractName + ":"
+
+// CreateBankRequest holds the arguments of CreateBank
+type CreateBankRequest struct {
+	BankID       string
+	Name         string
+	Password     string
+	Country      string
+	Currency     string
+	Reserves     float64
+	ExchangeRate float64
+	BIC          string
+}
+
+// UpdateBankProfileRequest holds the arguments of UpdateBankProfile
+type UpdateBankProfileRequest struct {
+	BankID   string
+	Name     string
+	Reserves float64
+	Country  string
+}
+
+// CreateBank registers a bank. The caller becomes its administrator.
+func (c *Client) CreateBank(req CreateBankRequest) error {
+	return c.submit(nil, bankPrefix+"CreateBank", req.BankID, "", req.Name, req.Password, req.Country, req.Currency, formatFloat(req.Reserves), formatFloat(req.ExchangeRate), req.BIC)
+}
+
+// UpdateBankProfile proposes a new name, reserves and country for a bank. The
+// profile changes once a different client approves the returned operation.
+func (c *Client) UpdateBankProfile(req UpdateBankProfileRequest) (*bank.PendingOperation, error) {
+	result := new(bank.PendingOperation)
+	err := c.submit(result, bankPrefix+"UpdateBankProfile", req.BankID, "", req.Name, formatFloat(req.Reserves), req.Country)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateExchangeRate publishes a new exchange rate for a bank's currency
+func (c *Client) UpdateExchangeRate(bankID string, exchangeRate float64) error {
+	return c.submit(nil, bankPrefix+"UpdateExchangeRate", bankID, formatFloat(exchangeRate))
+}
+
+// QueryBank returns a bank
+func (c *Client) QueryBank(bankID string) (*bank.Bank, error) {
+	result := new(bank.Bank)
+	err := c.evaluate(result, bankPrefix+"QueryBank", bankID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryBankByBIC returns the bank with a BIC, or with the BIC of its head
+// office for a branch BIC
+func (c *Client) QueryBankByBIC(bic string) (*bank.Bank, error) {
+	result := new(bank.Bank)
+	err := c.evaluate(result, bankPrefix+"QueryBankByBIC", bic)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryBankAccounts returns the accounts held at a bank
+func (c *Client) QueryBankAccounts(bankID string) ([]*bank.Account, error) {
+	var result []*bank.Account
+	err := c.evaluate(&result, bankPrefix+"QueryBankAccounts", bankID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryCustomersByBank returns the customers holding an account at a bank
+func (c *Client) QueryCustomersByBank(bankID string) ([]*bank.Customer, error) {
+	var result []*bank.Customer
+	err := c.evaluate(&result, bankPrefix+"QueryCustomersByBank", bankID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetPurposeCodeRule makes a purpose code mandatory for a bank's payments with
+// banks in country. A non-empty purposeCodes restricts the accepted codes.
+func (c *Client) SetPurposeCodeRule(bankID string, country string, purposeCodes []string) error {
+	name := bankPrefix + "SetPurposeCodeRule"
+	if purposeCodes == nil {
+		purposeCodes = []string{}
+	}
+	codesJSON, err := formatJSON(name, purposeCodes)
+	if err != nil {
+		return err
+	}
+	return c.submit(nil, name, bankID, country, codesJSON)
+}
+
+// RemovePurposeCodeRule deletes a bank's purpose code rule for a country
+func (c *Client) RemovePurposeCodeRule(bankID string, country string) error {
+	return c.submit(nil, bankPrefix+"RemovePurposeCodeRule", bankID, country)
+}
+
+// QueryPurposeCodeRules returns the purpose code rules of a bank
+func (c *Client) QueryPurposeCodeRules(bankID string) ([]*bank.PurposeCodeRule, error) {
+	var result []*bank.PurposeCodeRule
+	err := c.evaluate(&result, bankPrefix+"QueryPurposeCodeRules", bankID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ProposeMembershipChange opens a vote among the member banks to suspend,
+// reinstate or expel a bank. proposerBankID must be administered by the caller,
+// and its vote in favor is counted.
+func (c *Client) ProposeMembershipChange(bankID string, proposerBankID string, action string, reason string) (*bank.MembershipProposal, error) {
+	result := new(bank.MembershipProposal)
+	err := c.submit(result, bankPrefix+"ProposeMembershipChange", bankID, proposerBankID, action, reason)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VoteOnMembership casts voterBankID's vote on the open membership proposal
+// for a bank and returns the proposal
+func (c *Client) VoteOnMembership(bankID string, voterBankID string, inFavor bool) (*bank.MembershipProposal, error) {
+	result := new(bank.MembershipProposal)
+	err := c.submit(result, bankPrefix+"VoteOnMembership", bankID, voterBankID, strconv.FormatBool(inFavor))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WithdrawMembershipProposal closes the open membership proposal for a bank
+// without a decision and returns it
+func (c *Client) WithdrawMembershipProposal(bankID string, reason string) (*bank.MembershipProposal, error) {
+	result := new(bank.MembershipProposal)
+	err := c.submit(result, bankPrefix+"WithdrawMembershipProposal", bankID, reason)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryMemberBanks returns the active member banks
+func (c *Client) QueryMemberBanks() ([]*bank.Bank, error) {
+	var result []*bank.Bank
+	err := c.evaluate(&result, bankPrefix+"QueryMemberBanks")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryMembershipProposals returns every membership proposal for a bank,
+// oldest first
+func (c *Client) QueryMembershipProposals(bankID string) ([]*bank.MembershipProposal, error) {
+	var result []*bank.MembershipProposal
+	err := c.evaluate(&result, bankPrefix+"QueryMembershipProposals", bankID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryOpenMembershipProposals returns the membership proposals still being
+// voted on
+func (c *Client) QueryOpenMembershipProposals() ([]*bank.MembershipProposal, error) {
+	var result []*bank.MembershipProposal
+	err := c.evaluate(&result, bankPrefix+"QueryOpenMembershipProposals")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
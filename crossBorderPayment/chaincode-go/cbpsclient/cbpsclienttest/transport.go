@@ -0,0 +1,114 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cbpsclienttest provides an in-memory cbpsclient.Transport for
+// testing code that uses the client without a Fabric network.
+package cbpsclienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Handler answers one transaction. It returns the transaction's payload, or
+// an error such as a *contracterrors.Error to fail it the way the contract
+// would.
+type Handler func(args []string) ([]byte, error)
+
+// Call is a transaction received by a Transport
+type Call struct {
+	Name   string
+	Args   []string
+	Submit bool
+}
+
+// Transport records every transaction and answers it with the handler
+// registered for its name. Transactions without a handler fail. It is safe
+// for concurrent use.
+type Transport struct {
+	mu       sync.Mutex
+	handlers map[string]Handler
+	calls    []Call
+}
+
+// NewTransport returns a Transport without handlers
+func NewTransport() *Transport {
+	return &Transport{handlers: make(map[string]Handler)}
+}
+
+// Handle registers handler for the namespaced transaction name, replacing
+// any previous one
+func (t *Transport) Handle(name string, handler Handler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[name] = handler
+}
+
+// Return makes the transaction name succeed with v encoded as JSON. A string
+// or []byte v is returned as it is, like the contract returns strings.
+func (t *Transport) Return(name string, v interface{}) {
+	var payload []byte
+	switch value := v.(type) {
+	case nil:
+	case string:
+		payload = []byte(value)
+	case []byte:
+		payload = value
+	default:
+		var err error
+		payload, err = json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("cbpsclienttest: failed to encode the result of %s: %v", name, err))
+		}
+	}
+	t.Handle(name, func([]string) ([]byte, error) {
+		return payload, nil
+	})
+}
+
+// Fail makes the transaction name fail with err
+func (t *Transport) Fail(name string, err error) {
+	t.Handle(name, func([]string) ([]byte, error) {
+		return nil, err
+	})
+}
+
+// Calls returns the transactions received so far, oldest first
+func (t *Transport) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Call(nil), t.calls...)
+}
+
+// LastCall returns the most recent transaction, or false if there was none
+func (t *Transport) LastCall() (Call, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.calls) == 0 {
+		return Call{}, false
+	}
+	return t.calls[len(t.calls)-1], true
+}
+
+// SubmitTransaction records and answers a submitted transaction
+func (t *Transport) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	return t.invoke(true, name, args)
+}
+
+// EvaluateTransaction records and answers an evaluated transaction
+func (t *Transport) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	return t.invoke(false, name, args)
+}
+
+func (t *Transport) invoke(submit bool, name string, args []string) ([]byte, error) {
+	t.mu.Lock()
+	t.calls = append(t.calls, Call{Name: name, Args: append([]string(nil), args...), Submit: submit})
+	handler, ok := t.handlers[name]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("cbpsclienttest: no handler for %s", name)
+	}
+	return handler(args)
+}
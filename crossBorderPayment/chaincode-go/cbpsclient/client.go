@@ -0,0 +1,94 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cbpsclient is a typed Go client for the bank chaincode. It wraps
+// every transaction of the bank, customer, account, payment and admin
+// contracts, encodes the arguments the way the contracts expect them and
+// decodes the results into the chaincode's own types.
+//
+// The client sends transactions through a Transport. A *client.Contract from
+// github.com/hyperledger/fabric-gateway/pkg/client satisfies Transport as it
+// is:
+//
+//	network := gateway.GetNetwork("bankschannel")
+//	banks := cbpsclient.New(network.GetContract("bank"))
+//	bank, err := banks.QueryBank("BANK1")
+//	if errors.Is(err, cbpsclient.ErrNotFound) {
+//		...
+//	}
+//
+// Package cbpsclienttest provides an in-memory Transport for tests.
+package cbpsclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Transport submits and evaluates chaincode transactions. name is the
+// namespaced transaction name, for example "payment:CreatePayment".
+type Transport interface {
+	// SubmitTransaction endorses and commits a transaction and returns its
+	// result
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+	// EvaluateTransaction runs a transaction on one peer without committing
+	// it and returns its result
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+}
+
+// Client calls the bank chaincode through a Transport. It is safe for
+// concurrent use if its Transport is.
+type Client struct {
+	transport Transport
+}
+
+// New returns a client that sends transactions through transport
+func New(transport Transport) *Client {
+	return &Client{transport: transport}
+}
+
+// submit commits a transaction and decodes its JSON result into result,
+// unless result is nil
+func (c *Client) submit(result interface{}, name string, args ...string) error {
+	payload, err := c.transport.SubmitTransaction(name, args...)
+	if err != nil {
+		return mapError(name, err)
+	}
+	return decode(name, payload, result)
+}
+
+// evaluate runs a query and decodes its JSON result into result
+func (c *Client) evaluate(result interface{}, name string, args ...string) error {
+	payload, err := c.transport.EvaluateTransaction(name, args...)
+	if err != nil {
+		return mapError(name, err)
+	}
+	return decode(name, payload, result)
+}
+
+func decode(name string, payload []byte, result interface{}) error {
+	if result == nil {
+		return nil
+	}
+	err := json.Unmarshal(payload, result)
+	if err != nil {
+		return fmt.Errorf("%s: failed to decode result: %v", name, err)
+	}
+	return nil
+}
+
+// formatFloat encodes an amount or rate argument without losing precision
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// formatJSON encodes a struct or slice argument
+func formatJSON(name string, v interface{}) (string, error) {
+	argJSON, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to encode argument: %v", name, err)
+	}
+	return string(argJSON), nil
+}
@@ -0,0 +1,238 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/cbpsclienttest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEveryTransactionIsWrapped(t *testing.T) {
+	embedded := reflect.TypeOf(&contractapi.Contract{})
+	client := reflect.TypeOf(&cbpsclient.Client{})
+	contracts := []interface{}{&bank.BankContract{}, &bank.CustomerContract{}, &bank.AccountContract{}, &bank.PaymentContract{}, &bank.AdminContract{}}
+	for _, contract := range contracts {
+		contractType := reflect.TypeOf(contract)
+		for i := 0; i < contractType.NumMethod(); i++ {
+			name := contractType.Method(i).Name
+			if _, ok := embedded.MethodByName(name); ok {
+				continue
+			}
+			if _, ok := client.MethodByName(name); !ok {
+				t.Errorf("%s.%s has no client method", contractType.Elem().Name(), name)
+			}
+		}
+	}
+}
+
+func TestArguments(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       func(c *cbpsclient.Client) error
+		result     string
+		wantName   string
+		wantArgs   []string
+		wantSubmit bool
+	}{
+		{
+			name: "CreateBank",
+			call: func(c *cbpsclient.Client) error {
+				return c.CreateBank(cbpsclient.CreateBankRequest{BankID: "BANK1", Name: "First", Password: "pw", Country: "DE", Currency: "EUR", Reserves: 1000000, ExchangeRate: 1.1})
+			},
+			wantName:   "bank:CreateBank",
+			wantArgs:   []string{"BANK1", "", "First", "pw", "DE", "EUR", "1e+06", "1.1", ""},
+			wantSubmit: true,
+		},
+		{
+			name: "CreatePayment",
+			call: func(c *cbpsclient.Client) error {
+				return c.CreatePayment(bank.PaymentInstruction{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 12.5, ExchangeRate: 0.85, Date: "2024-01-02"})
+			},
+			wantName:   "payment:CreatePayment",
+			wantArgs:   []string{"P1", "A1", "A2", "C1", "C2", "12.5", "0.85", "2024-01-02", "", ""},
+			wantSubmit: true,
+		},
+		{
+			name: "CreatePayment with remittance",
+			call: func(c *cbpsclient.Client) error {
+				return c.CreatePayment(bank.PaymentInstruction{PaymentID: "P1", SenderAccountID: "A1", BeneficiaryID: "B1", SenderCustomerID: "C1", Amount: 10, ExchangeRate: 1, PurposeCode: "SUPP", InvoiceNumbers: []string{"INV-1"}})
+			},
+			wantName:   "payment:CreatePayment",
+			wantArgs:   []string{"P1", "A1", "", "C1", "", "10", "1", "", "B1", `{"invoiceNumbers":["INV-1"],"purposeCode":"SUPP"}`},
+			wantSubmit: true,
+		},
+		{
+			name: "CreatePaymentBatch",
+			call: func(c *cbpsclient.Client) error {
+				_, err := c.CreatePaymentBatch("B1", nil, bank.BatchModeAtomic)
+				return err
+			},
+			wantName:   "payment:CreatePaymentBatch",
+			wantArgs:   []string{"B1", "[]", "atomic"},
+			wantSubmit: true,
+		},
+		{
+			name: "CreatePaymentFromPacs008",
+			call: func(c *cbpsclient.Client) error {
+				_, err := c.CreatePaymentFromPacs008([]byte("<Document/>"))
+				return err
+			},
+			wantName:   "payment:CreatePaymentFromPacs008",
+			wantArgs:   []string{"<Document/>"},
+			wantSubmit: true,
+		},
+		{
+			name: "SetTierLimit",
+			call: func(c *cbpsclient.Client) error {
+				return c.SetTierLimit(bank.TierLimit{Tier: "basic", Currency: "EUR", PerTransactionMax: 500, DailyLimit: 1000, MonthlyLimit: 5000, MaxDailyPayments: 3, MaxMonthlyPayments: 20})
+			},
+			wantName:   "admin:SetTierLimit",
+			wantArgs:   []string{"basic", "EUR", "500", "1000", "5000", "3", "20"},
+			wantSubmit: true,
+		},
+		{
+			name: "Configure",
+			call: func(c *cbpsclient.Client) error {
+				_, err := c.Configure(bank.ChaincodeConfig{Version: 2, SupportedCurrencies: []string{"EUR"}, AdminMSPs: []string{}})
+				return err
+			},
+			wantName:   "admin:Configure",
+			wantArgs:   []string{`{"version":2,"supportedCurrencies":["EUR"],"rateMaxAgeSeconds":0,"defaultFeeRate":0,"defaultFlatFee":0,"adminMSPs":[]}`},
+			wantSubmit: true,
+		},
+		{
+			name: "QueryPayments",
+			call: func(c *cbpsclient.Client) error {
+				_, err := c.QueryPayments("A1")
+				return err
+			},
+			result:   "[]",
+			wantName: "payment:QueryPayments",
+			wantArgs: []string{"A1"},
+		},
+		{
+			name: "MigrateState",
+			call: func(c *cbpsclient.Client) error {
+				_, err := c.MigrateState(100)
+				return err
+			},
+			wantName:   "admin:MigrateState",
+			wantArgs:   []string{"100"},
+			wantSubmit: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := cbpsclienttest.NewTransport()
+			result := tt.result
+			if result == "" {
+				result = "{}"
+			}
+			transport.Return(tt.wantName, result)
+			err := tt.call(cbpsclient.New(transport))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			call, ok := transport.LastCall()
+			if !ok {
+				t.Fatalf("no transaction was sent")
+			}
+			if call.Name != tt.wantName || call.Submit != tt.wantSubmit || !reflect.DeepEqual(call.Args, tt.wantArgs) {
+				t.Fatalf("sent %+v, want %s%v (submit %t)", call, tt.wantName, tt.wantArgs, tt.wantSubmit)
+			}
+		})
+	}
+}
+
+func TestResults(t *testing.T) {
+	transport := cbpsclienttest.NewTransport()
+	client := cbpsclient.New(transport)
+
+	transport.Return("bank:QueryBank", &bank.Bank{BankID: "BANK1", Currency: "EUR", Reserves: 1000, AccountIDs: []string{"A1"}})
+	got, err := client.QueryBank("BANK1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.BankID != "BANK1" || got.Currency != "EUR" || got.Reserves != 1000 || len(got.AccountIDs) != 1 {
+		t.Fatalf("unexpected bank %+v", got)
+	}
+
+	transport.Return("payment:QueryPayments", []*bank.Payment{{PaymentID: "P1", Amount: 10}, {PaymentID: "P2", Amount: 20}})
+	payments, err := client.QueryPayments("A1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payments) != 2 || payments[1].PaymentID != "P2" || payments[1].Amount != 20 {
+		t.Fatalf("unexpected payments %+v", payments)
+	}
+
+	transport.Return("customer:QueryCustomerPassword", "secret")
+	password, err := client.QueryCustomerPassword("C1")
+	if err != nil || password != "secret" {
+		t.Fatalf("QueryCustomerPassword = %q, %v", password, err)
+	}
+
+	transport.Return("bank:QueryBank", "not json")
+	if _, err := client.QueryBank("BANK1"); err == nil || !strings.Contains(err.Error(), "failed to decode result") {
+		t.Fatalf("error = %v, want a decoding error", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	breach := &bank.LimitBreach{PaymentID: "P1", CustomerID: "C1", Tier: "basic", Currency: "EUR", Amount: 600, Reason: "per-transaction"}
+	limitErr := contracterrors.New(contracterrors.LimitExceeded, "payment exceeds the limit").WithDetails(breach)
+	gatewayStatus, err := status.New(codes.Aborted, "failed to endorse transaction").WithDetails(&gateway.ErrorDetail{
+		Address: "peer0.org1.example.com:7051",
+		MspId:   "Org1MSP",
+		Message: "chaincode response 500, " + contracterrors.New(contracterrors.NotFound, "account A9 does not exist").Error(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := cbpsclienttest.NewTransport()
+	client := cbpsclient.New(transport)
+
+	transport.Fail("payment:CreatePayment", limitErr)
+	err = client.CreatePayment(bank.PaymentInstruction{PaymentID: "P1"})
+	if !errors.Is(err, cbpsclient.ErrLimitExceeded) || errors.Is(err, cbpsclient.ErrNotFound) {
+		t.Fatalf("error = %v, want a limit error", err)
+	}
+	var clientErr *cbpsclient.Error
+	if !errors.As(err, &clientErr) || clientErr.Transaction != "payment:CreatePayment" || clientErr.Message != "payment exceeds the limit" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	var gotBreach bank.LimitBreach
+	if err := clientErr.DecodeDetails(&gotBreach); err != nil || gotBreach != *breach {
+		t.Fatalf("details = %+v (%v), want %+v", gotBreach, err, breach)
+	}
+
+	transport.Fail("account:QueryAccount", gatewayStatus.Err())
+	_, err = client.QueryAccount("A9")
+	if !errors.Is(err, cbpsclient.ErrNotFound) {
+		t.Fatalf("error = %v, want the code from the gRPC status details", err)
+	}
+	if status.Code(errors.Unwrap(err)) != codes.Aborted {
+		t.Fatalf("the transport error was not kept: %v", errors.Unwrap(err))
+	}
+
+	transport.Fail("bank:QueryBank", errors.New("connection refused"))
+	_, err = client.QueryBank("BANK1")
+	if err == nil || errors.As(err, &clientErr) || !strings.Contains(err.Error(), "bank:QueryBank: connection refused") {
+		t.Fatalf("error = %v, want the transport error", err)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient
+
+import (
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+const customerPrefix = bank.CustomerContractName + ":"
+
+// CustomerRequest holds the arguments of CreateCustomer and UpdateProfile
+type CustomerRequest struct {
+	CustomerID string
+	Password   string
+	Name       string
+	Surname    string
+}
+
+// VerifyCustomerRequest holds the arguments of VerifyCustomer. ValidUntil is
+// a yyyy-mm-dd date.
+type VerifyCustomerRequest struct {
+	BankID     string
+	CustomerID string
+	RiskRating string
+	ValidUntil string
+}
+
+// BeneficiaryRequest holds the arguments of AddBeneficiary and
+// UpdateBeneficiary. Name and ReceiverAccountID are only used when adding.
+type BeneficiaryRequest struct {
+	CustomerID        string
+	BeneficiaryID     string
+	Nickname          string
+	ReceiverAccountID string
+	Name              string
+	PaymentLimit      float64
+	DailyLimit        float64
+}
+
+// CreateCustomer registers a customer
+func (c *Client) CreateCustomer(req CustomerRequest) error {
+	return c.submit(nil, customerPrefix+"CreateCustomer", req.CustomerID, req.Password, req.Name, req.Surname)
+}
+
+// UpdateProfile updates a customer's name, surname and password
+func (c *Client) UpdateProfile(req CustomerRequest) error {
+	return c.submit(nil, customerPrefix+"UpdateProfile", req.CustomerID, req.Name, req.Surname, req.Password)
+}
+
+// QueryCustomer returns a customer
+func (c *Client) QueryCustomer(customerID string) (*bank.Customer, error) {
+	result := new(bank.Customer)
+	err := c.evaluate(result, customerPrefix+"QueryCustomer", customerID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryCustomerPassword returns a customer's password
+func (c *Client) QueryCustomerPassword(customerID string) (string, error) {
+	// The contract returns strings as they are rather than as JSON
+	payload, err := c.transport.EvaluateTransaction(customerPrefix+"QueryCustomerPassword", customerID)
+	if err != nil {
+		return "", mapError(customerPrefix+"QueryCustomerPassword", err)
+	}
+	return string(payload), nil
+}
+
+// QueryCustomerAccounts returns a customer's accounts
+func (c *Client) QueryCustomerAccounts(customerID string) ([]*bank.Account, error) {
+	var result []*bank.Account
+	err := c.evaluate(&result, customerPrefix+"QueryCustomerAccounts", customerID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AddKYCDocument records the hash of a KYC document reviewed by a bank
+func (c *Client) AddKYCDocument(bankID string, customerID string, documentHash string) error {
+	return c.submit(nil, customerPrefix+"AddKYCDocument", bankID, customerID, documentHash)
+}
+
+// VerifyCustomer marks a customer's KYC as verified
+func (c *Client) VerifyCustomer(req VerifyCustomerRequest) error {
+	return c.submit(nil, customerPrefix+"VerifyCustomer", req.BankID, req.CustomerID, req.RiskRating, req.ValidUntil)
+}
+
+// EnrollCustomerClient records the client identity that acts as a customer
+func (c *Client) EnrollCustomerClient(bankID string, customerID string, clientID string) error {
+	return c.submit(nil, customerPrefix+"EnrollCustomerClient", bankID, customerID, clientID)
+}
+
+// RejectCustomer marks a customer's KYC as rejected
+func (c *Client) RejectCustomer(bankID string, customerID string) error {
+	return c.submit(nil, customerPrefix+"RejectCustomer", bankID, customerID)
+}
+
+// ExpireCustomerKYC marks a verified customer as expired ahead of their expiry
+// date
+func (c *Client) ExpireCustomerKYC(bankID string, customerID string) error {
+	return c.submit(nil, customerPrefix+"ExpireCustomerKYC", bankID, customerID)
+}
+
+// RequestKYCRenewal moves a rejected or expired customer back to pending
+func (c *Client) RequestKYCRenewal(bankID string, customerID string) error {
+	return c.submit(nil, customerPrefix+"RequestKYCRenewal", bankID, customerID)
+}
+
+// AddBeneficiary saves an account for a customer to pay by nickname. The
+// result's NameCheck tells whether Name matched the account holder exactly.
+func (c *Client) AddBeneficiary(req BeneficiaryRequest) (*bank.Beneficiary, error) {
+	result := new(bank.Beneficiary)
+	err := c.submit(result, customerPrefix+"AddBeneficiary", req.CustomerID, req.BeneficiaryID, req.Nickname, req.ReceiverAccountID, req.Name,
+		formatFloat(req.PaymentLimit), formatFloat(req.DailyLimit))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateBeneficiary changes the nickname and limits of a beneficiary
+func (c *Client) UpdateBeneficiary(req BeneficiaryRequest) error {
+	return c.submit(nil, customerPrefix+"UpdateBeneficiary", req.CustomerID, req.BeneficiaryID, req.Nickname,
+		formatFloat(req.PaymentLimit), formatFloat(req.DailyLimit))
+}
+
+// RemoveBeneficiary deletes a saved beneficiary
+func (c *Client) RemoveBeneficiary(customerID string, beneficiaryID string) error {
+	return c.submit(nil, customerPrefix+"RemoveBeneficiary", customerID, beneficiaryID)
+}
+
+// QueryBeneficiary returns one of a customer's beneficiaries
+func (c *Client) QueryBeneficiary(customerID string, beneficiaryID string) (*bank.Beneficiary, error) {
+	result := new(bank.Beneficiary)
+	err := c.evaluate(result, customerPrefix+"QueryBeneficiary", customerID, beneficiaryID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryBeneficiaries returns a customer's beneficiaries
+func (c *Client) QueryBeneficiaries(customerID string) ([]*bank.Beneficiary, error) {
+	var result []*bank.Beneficiary
+	err := c.evaluate(&result, customerPrefix+"QueryBeneficiaries", customerID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
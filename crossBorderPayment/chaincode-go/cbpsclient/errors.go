@@ -0,0 +1,107 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"google.golang.org/grpc/status"
+)
+
+// Error is an error returned by the contract. Match it by code with
+// errors.Is and the Err sentinels, or inspect it with errors.As.
+type Error struct {
+	// Transaction is the namespaced name of the failed transaction
+	Transaction string
+	Code        contracterrors.Code
+	Message     string
+	// Details holds the code's details as decoded from JSON, see
+	// DecodeDetails
+	Details interface{}
+
+	err error
+}
+
+// Sentinels for errors.Is, one per contract error code
+var (
+	ErrNotFound          = &Error{Code: contracterrors.NotFound}
+	ErrAlreadyExists     = &Error{Code: contracterrors.AlreadyExists}
+	ErrInsufficientFunds = &Error{Code: contracterrors.InsufficientFunds}
+	ErrForbidden         = &Error{Code: contracterrors.Forbidden}
+	ErrValidation        = &Error{Code: contracterrors.Validation}
+	ErrRateStale         = &Error{Code: contracterrors.RateStale}
+	ErrInvalidState      = &Error{Code: contracterrors.InvalidState}
+	ErrLimitExceeded     = &Error{Code: contracterrors.LimitExceeded}
+	ErrInternal          = &Error{Code: contracterrors.Internal}
+)
+
+func (e *Error) Error() string {
+	if e.Transaction == "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s failed with %s: %s", e.Transaction, e.Code, e.Message)
+}
+
+// Unwrap returns the error of the transport
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target is the sentinel of e's code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Transaction == "" && t.Message == "" && t.Code == e.Code
+}
+
+// DecodeDetails decodes the error's details into v, for example a
+// *bank.LimitBreach for LimitExceeded or a *validation.Errors for Validation
+func (e *Error) DecodeDetails(v interface{}) error {
+	if e.Details == nil {
+		return errors.New("the error has no details")
+	}
+	detailsJSON, err := json.Marshal(e.Details)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(detailsJSON, v)
+}
+
+// mapError turns the contract error carried by err into an *Error. Errors
+// that carry none, such as connection failures, are returned wrapped with the
+// transaction name.
+func mapError(name string, err error) error {
+	for _, message := range errorMessages(err) {
+		if !strings.Contains(message, `{"code":`) {
+			continue
+		}
+		parsed := contracterrors.Parse(message)
+		if parsed.Code == contracterrors.Internal && parsed.Message == message {
+			// No valid error object in the message
+			continue
+		}
+		return &Error{Transaction: name, Code: parsed.Code, Message: parsed.Message, Details: parsed.Details, err: err}
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// errorMessages returns the messages that may hold the contract's error. The
+// Fabric Gateway reports the chaincode's message in the details of the gRPC
+// status rather than in the error text.
+func errorMessages(err error) []string {
+	messages := []string{err.Error()}
+	var withStatus interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &withStatus) {
+		for _, detail := range withStatus.GRPCStatus().Details() {
+			if d, ok := detail.(interface{ GetMessage() string }); ok {
+				messages = append(messages, d.GetMessage())
+			}
+		}
+	}
+	return messages
+}
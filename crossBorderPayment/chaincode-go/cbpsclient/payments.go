@@ -0,0 +1,204 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cbpsclient
+
+import (
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+const paymentPrefix = bank.PaymentContractName + ":"
+
+// CreatePayment moves funds between two accounts, converting them at
+// payment.ExchangeRate when the currencies differ. A payment with a
+// BeneficiaryID may leave the receiver IDs empty.
+func (c *Client) CreatePayment(payment bank.PaymentInstruction) error {
+	name := paymentPrefix + "CreatePayment"
+	remittance := bank.Remittance{
+		RemittanceInfo:    payment.RemittanceInfo,
+		CreditorReference: payment.CreditorReference,
+		InvoiceNumbers:    payment.InvoiceNumbers,
+		PurposeCode:       payment.PurposeCode,
+	}
+	var remittanceJSON string
+	if remittance.RemittanceInfo != "" || remittance.CreditorReference != "" || len(remittance.InvoiceNumbers) > 0 || remittance.PurposeCode != "" {
+		var err error
+		remittanceJSON, err = formatJSON(name, remittance)
+		if err != nil {
+			return err
+		}
+	}
+	return c.submit(nil, name, payment.PaymentID, payment.SenderAccountID, payment.ReceiverAccountID,
+		payment.SenderCustomerID, payment.ReceiverCustomerID, formatFloat(payment.Amount), formatFloat(payment.ExchangeRate), payment.Date,
+		payment.BeneficiaryID, remittanceJSON)
+}
+
+// CreatePaymentBatch executes payment instructions in one transaction. mode
+// is bank.BatchModeAtomic or bank.BatchModeBestEffort.
+func (c *Client) CreatePaymentBatch(batchID string, instructions []bank.PaymentInstruction, mode string) (*bank.PaymentBatch, error) {
+	name := paymentPrefix + "CreatePaymentBatch"
+	if instructions == nil {
+		instructions = []bank.PaymentInstruction{}
+	}
+	instructionsJSON, err := formatJSON(name, instructions)
+	if err != nil {
+		return nil, err
+	}
+	result := new(bank.PaymentBatch)
+	err = c.submit(result, name, batchID, instructionsJSON, mode)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryPaymentBatch returns the report of a payment batch
+func (c *Client) QueryPaymentBatch(batchID string) (*bank.PaymentBatch, error) {
+	result := new(bank.PaymentBatch)
+	err := c.evaluate(result, paymentPrefix+"QueryPaymentBatch", batchID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryPayments returns the payments sent or received by an account
+func (c *Client) QueryPayments(accountID string) ([]*bank.Payment, error) {
+	var result []*bank.Payment
+	err := c.evaluate(&result, paymentPrefix+"QueryPayments", accountID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryHeldPayments returns the payments waiting for compliance review
+func (c *Client) QueryHeldPayments() ([]*bank.Payment, error) {
+	var result []*bank.Payment
+	err := c.evaluate(&result, paymentPrefix+"QueryHeldPayments")
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreatePaymentFromPacs008 creates a payment from a pacs.008.001.08 message
+// and returns it, settled or held for review
+func (c *Client) CreatePaymentFromPacs008(message []byte) (*bank.Payment, error) {
+	result := new(bank.Payment)
+	err := c.submit(result, paymentPrefix+"CreatePaymentFromPacs008", string(message))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExportPaymentPacs008 returns a payment as a pacs.008.001.08 message
+func (c *Client) ExportPaymentPacs008(paymentID string) ([]byte, error) {
+	// The contract returns strings as they are rather than as JSON
+	payload, err := c.transport.EvaluateTransaction(paymentPrefix+"ExportPaymentPacs008", paymentID)
+	if err != nil {
+		return nil, mapError(paymentPrefix+"ExportPaymentPacs008", err)
+	}
+	return payload, nil
+}
+
+// ReleaseHeldPayment settles a held payment
+func (c *Client) ReleaseHeldPayment(paymentID string) error {
+	return c.submit(nil, paymentPrefix+"ReleaseHeldPayment", paymentID)
+}
+
+// RejectHeldPayment closes a held payment without moving any funds
+func (c *Client) RejectHeldPayment(paymentID string) error {
+	return c.submit(nil, paymentPrefix+"RejectHeldPayment", paymentID)
+}
+
+// ApprovePayment signs a payment from a joint account for one of its holders
+// and returns it, settled, held for review or still waiting for signatures
+func (c *Client) ApprovePayment(paymentID string, customerID string) (*bank.Payment, error) {
+	result := new(bank.Payment)
+	err := c.submit(result, paymentPrefix+"ApprovePayment", paymentID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeclinePayment refuses a payment from a joint account for one of its holders
+func (c *Client) DeclinePayment(paymentID string, customerID string) error {
+	return c.submit(nil, paymentPrefix+"DeclinePayment", paymentID, customerID)
+}
+
+// QueryPaymentsAwaitingApproval returns the payments from an account that
+// still need signatures from its holders
+func (c *Client) QueryPaymentsAwaitingApproval(accountID string) ([]*bank.Payment, error) {
+	var result []*bank.Payment
+	err := c.evaluate(&result, paymentPrefix+"QueryPaymentsAwaitingApproval", accountID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RequestToPay holds the arguments of CreatePaymentRequest. Dates are
+// YYYY-MM-DD and ExpiryDate defaults to DueDate.
+type RequestToPay struct {
+	RequestID       string
+	PayeeAccountID  string
+	PayerCustomerID string
+	Amount          float64
+	Currency        string
+	DueDate         string
+	ExpiryDate      string
+	Reference       string
+	PurposeCode     string
+}
+
+// CreatePaymentRequest asks a customer to pay into the payee's account
+func (c *Client) CreatePaymentRequest(req RequestToPay) (*bank.PaymentRequest, error) {
+	result := new(bank.PaymentRequest)
+	err := c.submit(result, paymentPrefix+"CreatePaymentRequest", req.RequestID, req.PayeeAccountID, req.PayerCustomerID,
+		formatFloat(req.Amount), req.Currency, req.DueDate, req.ExpiryDate, req.Reference, req.PurposeCode)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AcceptPaymentRequest pays a payment request from payerAccountID at the
+// banks' exchange rate and returns the payment, settled or held for review
+func (c *Client) AcceptPaymentRequest(requestID string, paymentID string, payerAccountID string) (*bank.Payment, error) {
+	result := new(bank.Payment)
+	err := c.submit(result, paymentPrefix+"AcceptPaymentRequest", requestID, paymentID, payerAccountID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeclinePaymentRequest refuses a payment request
+func (c *Client) DeclinePaymentRequest(requestID string, reason string) error {
+	return c.submit(nil, paymentPrefix+"DeclinePaymentRequest", requestID, reason)
+}
+
+// QueryPaymentRequest returns a payment request
+func (c *Client) QueryPaymentRequest(requestID string) (*bank.PaymentRequest, error) {
+	result := new(bank.PaymentRequest)
+	err := c.evaluate(result, paymentPrefix+"QueryPaymentRequest", requestID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryPendingPaymentRequests returns the unexpired requests a customer has
+// yet to accept or decline
+func (c *Client) QueryPendingPaymentRequests(customerID string) ([]*bank.PaymentRequest, error) {
+	var result []*bank.PaymentRequest
+	err := c.evaluate(&result, paymentPrefix+"QueryPendingPaymentRequests", customerID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
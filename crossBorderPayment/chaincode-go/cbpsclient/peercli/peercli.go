@@ -0,0 +1,156 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package peercli is a cbpsclient.Transport that calls the chaincode with the
+// peer CLI's chaincode query and invoke commands. It needs no Fabric SDK, only
+// the peer binary and an MSP directory for the identity to act as, as set up
+// by the test network's scripts. Every call runs a peer process that opens its
+// own connections to the peer and orderer, so the transport suits scripts and
+// low volumes rather than a busy service.
+package peercli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Options says how to reach the chaincode through the peer CLI. Settings not
+// covered here, such as the peer's own address and TLS, come from the usual
+// CORE_PEER_* environment variables.
+type Options struct {
+	PeerBinary         string
+	Channel            string
+	Chaincode          string
+	Orderer            string
+	OrdererTLSHostname string
+	OrdererCAFile      string
+	PeerAddresses      []string
+	TLSRootCertFiles   []string
+
+	// Env is added to the environment of the peer CLI, for example
+	// CORE_PEER_MSPCONFIGPATH and CORE_PEER_LOCALMSPID to act as another
+	// identity
+	Env []string
+}
+
+// runFunc runs a command with extra environment variables and returns its
+// stdout and stderr
+type runFunc func(env []string, name string, args ...string) ([]byte, []byte, error)
+
+func execRun(env []string, name string, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// Transport sends transactions through the peer CLI. It is safe for
+// concurrent use.
+type Transport struct {
+	options Options
+	run     runFunc
+}
+
+// New returns a transport that runs the peer CLI with options
+func New(options Options) *Transport {
+	return &Transport{options: options, run: execRun}
+}
+
+// EvaluateTransaction runs name on the peer without committing it
+func (t *Transport) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	ctor, err := ctorJSON(name, args)
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr, err := t.run(t.options.Env, t.options.PeerBinary, "chaincode", "query", "-C", t.options.Channel, "-n", t.options.Chaincode, "-c", ctor)
+	if err != nil {
+		return nil, peerError(stderr, err)
+	}
+	return bytes.TrimSuffix(stdout, []byte("\n")), nil
+}
+
+// SubmitTransaction endorses name on the configured peers, orders it and
+// waits for it to commit
+func (t *Transport) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	ctor, err := ctorJSON(name, args)
+	if err != nil {
+		return nil, err
+	}
+	invokeArgs := []string{"chaincode", "invoke", "-C", t.options.Channel, "-n", t.options.Chaincode, "--waitForEvent"}
+	if t.options.Orderer != "" {
+		invokeArgs = append(invokeArgs, "-o", t.options.Orderer)
+	}
+	if t.options.OrdererTLSHostname != "" {
+		invokeArgs = append(invokeArgs, "--ordererTLSHostnameOverride", t.options.OrdererTLSHostname)
+	}
+	if t.options.OrdererCAFile != "" {
+		invokeArgs = append(invokeArgs, "--tls", "--cafile", t.options.OrdererCAFile)
+	}
+	for i, address := range t.options.PeerAddresses {
+		invokeArgs = append(invokeArgs, "--peerAddresses", address)
+		if i < len(t.options.TLSRootCertFiles) {
+			invokeArgs = append(invokeArgs, "--tlsRootCertFiles", t.options.TLSRootCertFiles[i])
+		}
+	}
+	invokeArgs = append(invokeArgs, "-c", ctor)
+
+	_, stderr, err := t.run(t.options.Env, t.options.PeerBinary, invokeArgs...)
+	if err != nil {
+		return nil, peerError(stderr, err)
+	}
+	// The peer CLI logs the result as "status:200 payload:\"...\"", leaving
+	// out the payload when it is empty
+	payload, ok := quotedField(string(stderr), "payload:")
+	if !ok {
+		return nil, nil
+	}
+	return []byte(payload), nil
+}
+
+func ctorJSON(name string, args []string) (string, error) {
+	ctor, err := json.Marshal(struct {
+		Args []string `json:"Args"`
+	}{Args: append([]string{name}, args...)})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments of %s: %v", name, err)
+	}
+	return string(ctor), nil
+}
+
+// peerError extracts the chaincode's message from the peer CLI's output so
+// that cbpsclient can find the contract error in it
+func peerError(stderr []byte, err error) error {
+	if message, ok := quotedField(string(stderr), "message:"); ok {
+		return fmt.Errorf("%s", message)
+	}
+	return fmt.Errorf("peer CLI failed (%v): %s", err, strings.TrimSpace(string(stderr)))
+}
+
+// quotedField returns the unquoted string following field in protobuf text
+// output
+func quotedField(output string, field string) (string, bool) {
+	i := strings.LastIndex(output, field+`"`)
+	if i < 0 {
+		return "", false
+	}
+	quoted, err := strconv.QuotedPrefix(output[i+len(field):])
+	if err != nil {
+		return "", false
+	}
+	value, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
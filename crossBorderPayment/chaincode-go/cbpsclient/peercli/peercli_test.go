@@ -0,0 +1,89 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package peercli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+)
+
+// fakePeer records the peer CLI command line and answers with canned output
+type fakePeer struct {
+	env    []string
+	args   []string
+	stdout string
+	stderr string
+	err    error
+}
+
+func (p *fakePeer) run(env []string, name string, args ...string) ([]byte, []byte, error) {
+	p.env = env
+	p.args = append([]string{name}, args...)
+	return []byte(p.stdout), []byte(p.stderr), p.err
+}
+
+func TestPeerTransport(t *testing.T) {
+	peer := &fakePeer{}
+	transport := &Transport{
+		options: Options{
+			PeerBinary:         "peer",
+			Channel:            "bankschannel",
+			Chaincode:          "bank",
+			Orderer:            "localhost:7050",
+			OrdererTLSHostname: "orderer.example.com",
+			OrdererCAFile:      "orderer-ca.pem",
+			PeerAddresses:      []string{"localhost:7051", "localhost:9051"},
+			TLSRootCertFiles:   []string{"org1.pem", "org2.pem"},
+			Env:                []string{"CORE_PEER_LOCALMSPID=Org1MSP"},
+		},
+		run: peer.run,
+	}
+
+	peer.stdout = "{\"bankID\":\"BANK1\"}\n"
+	payload, err := transport.EvaluateTransaction("bank:QueryBank", "BANK1")
+	if err != nil || string(payload) != `{"bankID":"BANK1"}` {
+		t.Fatalf("EvaluateTransaction = %q, %v", payload, err)
+	}
+	wantArgs := []string{"peer", "chaincode", "query", "-C", "bankschannel", "-n", "bank", "-c", `{"Args":["bank:QueryBank","BANK1"]}`}
+	if !reflect.DeepEqual(peer.args, wantArgs) || !reflect.DeepEqual(peer.env, []string{"CORE_PEER_LOCALMSPID=Org1MSP"}) {
+		t.Fatalf("ran %q with %q, want %q", peer.args, peer.env, wantArgs)
+	}
+
+	peer.stdout = ""
+	peer.stderr = `2024-01-02 10:00:00.000 UTC 0001 INFO [chaincodeCmd] chaincodeInvokeOrQuery -> Chaincode invoke successful. result: status:200 payload:"{\"batchID\":\"B1\",\"note\":\"caf\303\251\"}"` + "\n"
+	payload, err = transport.SubmitTransaction("payment:CreatePaymentBatch", "B1", "[]", "atomic")
+	if err != nil || string(payload) != `{"batchID":"B1","note":"café"}` {
+		t.Fatalf("SubmitTransaction = %q, %v", payload, err)
+	}
+	wantArgs = []string{"peer", "chaincode", "invoke", "-C", "bankschannel", "-n", "bank", "--waitForEvent",
+		"-o", "localhost:7050", "--ordererTLSHostnameOverride", "orderer.example.com", "--tls", "--cafile", "orderer-ca.pem",
+		"--peerAddresses", "localhost:7051", "--tlsRootCertFiles", "org1.pem", "--peerAddresses", "localhost:9051", "--tlsRootCertFiles", "org2.pem",
+		"-c", `{"Args":["payment:CreatePaymentBatch","B1","[]","atomic"]}`}
+	if !reflect.DeepEqual(peer.args, wantArgs) {
+		t.Fatalf("ran %q, want %q", peer.args, wantArgs)
+	}
+
+	peer.stderr = "Chaincode invoke successful. result: status:200\n"
+	payload, err = transport.SubmitTransaction("bank:UpdateExchangeRate", "BANK1", "1.1")
+	if err != nil || len(payload) != 0 {
+		t.Fatalf("SubmitTransaction = %q, %v", payload, err)
+	}
+
+	peer.stderr = `Error: endorsement failure during query. response: status:500 message:"{\"code\":\"INSUFFICIENT_FUNDS\",\"message\":\"account A1 has insufficient funds\"}"` + "\n"
+	peer.err = errors.New("exit status 1")
+	_, err = cbpsclient.New(transport).QueryAccount("A1")
+	if !errors.Is(err, cbpsclient.ErrInsufficientFunds) {
+		t.Fatalf("error = %v, want the contract's code", err)
+	}
+
+	peer.stderr = "Error: error getting endorser client for query: endorser client failed to connect\n"
+	_, err = transport.EvaluateTransaction("bank:QueryBank", "BANK1")
+	if err == nil || errors.Is(err, cbpsclient.ErrInternal) || err.Error() != "peer CLI failed (exit status 1): Error: error getting endorser client for query: endorser client failed to connect" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package ccaas runs the chaincode either as an external chaincode server
+// (chaincode-as-a-service), which the peer connects to, or in the classic
+// peer-launched mode, where the chaincode connects to the peer.
+package ccaas
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	// EnvServerAddress is the listen address of the chaincode server. When it
+	// is empty the chaincode runs in peer-launched mode.
+	EnvServerAddress = "CHAINCODE_SERVER_ADDRESS"
+	// EnvChaincodeID is the package ID the chaincode was installed with
+	EnvChaincodeID = "CHAINCODE_ID"
+	// EnvPeerChaincodeID is the package ID variable set by the peer, used if
+	// EnvChaincodeID is empty
+	EnvPeerChaincodeID = "CORE_CHAINCODE_ID_NAME"
+	// EnvTLSCert and EnvTLSKey are the PEM files of the server's TLS
+	// certificate and key. TLS is enabled when both are set.
+	EnvTLSCert = "CHAINCODE_TLS_CERT"
+	EnvTLSKey  = "CHAINCODE_TLS_KEY"
+	// EnvClientCACert is a PEM file of the CAs that issue the peers' client
+	// certificates. When it is set the server requires and verifies them.
+	EnvClientCACert = "CHAINCODE_CLIENT_CA_CERT"
+	// EnvHealthAddress is the listen address of the HTTP health endpoint. The
+	// endpoint is off when it is empty.
+	EnvHealthAddress = "CHAINCODE_HEALTH_ADDRESS"
+	// EnvShutdownTimeout is how long a stopping server waits for running
+	// transactions, as a Go duration
+	EnvShutdownTimeout = "CHAINCODE_SHUTDOWN_TIMEOUT"
+)
+
+// DefaultShutdownTimeout is used when EnvShutdownTimeout is not set
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Config is how the chaincode process serves the chaincode
+type Config struct {
+	// Address is the listen address of the chaincode server, empty in
+	// peer-launched mode
+	Address     string
+	ChaincodeID string
+	// TLS is the server's TLS configuration, nil if TLS is disabled
+	TLS             *tls.Config
+	HealthAddress   string
+	ShutdownTimeout time.Duration
+}
+
+// ServerMode reports whether the chaincode runs as an external server
+func (c *Config) ServerMode() bool {
+	return c.Address != ""
+}
+
+// ConfigFromEnv reads the configuration from the environment through getenv,
+// which is normally os.Getenv
+func ConfigFromEnv(getenv func(string) string) (*Config, error) {
+	config := &Config{
+		Address:         getenv(EnvServerAddress),
+		ChaincodeID:     getenv(EnvChaincodeID),
+		HealthAddress:   getenv(EnvHealthAddress),
+		ShutdownTimeout: DefaultShutdownTimeout,
+	}
+	if config.ChaincodeID == "" {
+		config.ChaincodeID = getenv(EnvPeerChaincodeID)
+	}
+
+	if timeout := getenv(EnvShutdownTimeout); timeout != "" {
+		var err error
+		config.ShutdownTimeout, err = time.ParseDuration(timeout)
+		if err != nil || config.ShutdownTimeout < 0 {
+			return nil, fmt.Errorf("%s must be a non-negative duration, got %q", EnvShutdownTimeout, timeout)
+		}
+	}
+
+	if !config.ServerMode() {
+		return config, nil
+	}
+	if config.ChaincodeID == "" {
+		return nil, fmt.Errorf("%s must be set when %s is set", EnvChaincodeID, EnvServerAddress)
+	}
+
+	tlsConfig, err := loadTLSConfig(getenv(EnvTLSCert), getenv(EnvTLSKey), getenv(EnvClientCACert))
+	if err != nil {
+		return nil, err
+	}
+	config.TLS = tlsConfig
+	return config, nil
+}
+
+// loadTLSConfig returns the server TLS configuration, or nil if neither a
+// certificate nor a key is given
+func loadTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, fmt.Errorf("%s requires %s and %s", EnvClientCACert, EnvTLSCert, EnvTLSKey)
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("%s and %s must be set together", EnvTLSCert, EnvTLSKey)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS key pair: %v", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if clientCAFile != "" {
+		caPEM, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA certificate: %v", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,104 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccaas
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed certificate and its key to dir
+func writeKeyPair(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "chaincode"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t, t.TempDir())
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantErr    string
+		wantServer bool
+		wantTLS    bool
+		wantMTLS   bool
+		wantID     string
+	}{
+		{name: "peer-launched", env: map[string]string{EnvPeerChaincodeID: "bank:1"}, wantID: "bank:1"},
+		{name: "server", env: map[string]string{EnvServerAddress: "0.0.0.0:9999", EnvChaincodeID: "bank_1.0:abc"}, wantServer: true, wantID: "bank_1.0:abc"},
+		{name: "peer chaincode ID", env: map[string]string{EnvServerAddress: ":9999", EnvPeerChaincodeID: "bank_1.0:def"}, wantServer: true, wantID: "bank_1.0:def"},
+		{name: "server without ID", env: map[string]string{EnvServerAddress: ":9999"}, wantErr: EnvChaincodeID},
+		{name: "tls", env: map[string]string{EnvServerAddress: ":9999", EnvChaincodeID: "id", EnvTLSCert: certFile, EnvTLSKey: keyFile}, wantServer: true, wantTLS: true, wantID: "id"},
+		{name: "mutual tls", env: map[string]string{EnvServerAddress: ":9999", EnvChaincodeID: "id", EnvTLSCert: certFile, EnvTLSKey: keyFile, EnvClientCACert: certFile}, wantServer: true, wantTLS: true, wantMTLS: true, wantID: "id"},
+		{name: "cert without key", env: map[string]string{EnvServerAddress: ":9999", EnvChaincodeID: "id", EnvTLSCert: certFile}, wantErr: "must be set together"},
+		{name: "client CA without TLS", env: map[string]string{EnvServerAddress: ":9999", EnvChaincodeID: "id", EnvClientCACert: certFile}, wantErr: "requires"},
+		{name: "missing key file", env: map[string]string{EnvServerAddress: ":9999", EnvChaincodeID: "id", EnvTLSCert: certFile, EnvTLSKey: keyFile + ".missing"}, wantErr: "failed to load TLS key pair"},
+		{name: "bad shutdown timeout", env: map[string]string{EnvShutdownTimeout: "soon"}, wantErr: EnvShutdownTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ConfigFromEnv(func(key string) string { return tt.env[key] })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.ServerMode() != tt.wantServer || config.ChaincodeID != tt.wantID || (config.TLS != nil) != tt.wantTLS {
+				t.Fatalf("unexpected config %+v", config)
+			}
+			if tt.wantMTLS != (config.TLS != nil && config.TLS.ClientAuth == tls.RequireAndVerifyClientCert) {
+				t.Fatalf("client verification = %v, want %v", !tt.wantMTLS, tt.wantMTLS)
+			}
+			if config.ShutdownTimeout != DefaultShutdownTimeout {
+				t.Fatalf("shutdown timeout = %v, want the default", config.ShutdownTimeout)
+			}
+		})
+	}
+}
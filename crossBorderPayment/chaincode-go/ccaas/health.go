@@ -0,0 +1,71 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccaas
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// HealthPath is the path of the health endpoint
+const HealthPath = "/healthz"
+
+// Health statuses
+const (
+	StatusStarting = "starting"
+	StatusServing  = "serving"
+	StatusStopping = "stopping"
+)
+
+// Health is the state reported by the health endpoint. It answers 200 while
+// the chaincode is serving and 503 otherwise, so that orchestrators stop
+// routing to a process that is starting or shutting down.
+type Health struct {
+	mode   string
+	status atomic.Value
+}
+
+// HealthReport is the JSON body of the health endpoint
+type HealthReport struct {
+	Status string `json:"status"`
+	Mode   string `json:"mode"`
+}
+
+// NewHealth returns a starting Health for the mode of config
+func NewHealth(config *Config) *Health {
+	h := &Health{mode: "peer-launched"}
+	if config.ServerMode() {
+		h.mode = "server"
+	}
+	h.status.Store(StatusStarting)
+	return h
+}
+
+// SetStatus changes the reported status
+func (h *Health) SetStatus(status string) {
+	h.status.Store(status)
+}
+
+// Status returns the reported status
+func (h *Health) Status() string {
+	return h.status.Load().(string)
+}
+
+// ServeHTTP writes the health report
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := HealthReport{Status: h.Status(), Mode: h.mode}
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusServing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,141 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccaas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// gRPC settings matching those of the shim's own chaincode server and the
+// peer
+const (
+	maxMessageSize    = 100 * 1024 * 1024
+	keepaliveTime     = 1 * time.Minute
+	keepaliveTimeout  = 20 * time.Second
+	keepaliveMinTime  = 1 * time.Minute
+	connectionTimeout = 5 * time.Second
+)
+
+// Run serves cc as configured until ctx is cancelled or serving fails. In
+// server mode a cancelled ctx stops accepting connections and waits up to
+// config.ShutdownTimeout for running transactions before closing them. In
+// peer-launched mode the peer owns the connection, so Run returns as soon as
+// ctx is cancelled.
+func Run(ctx context.Context, config *Config, cc shim.Chaincode) error {
+	health := NewHealth(config)
+	if config.HealthAddress != "" {
+		healthServer, err := startHealthServer(config.HealthAddress, health)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_ = healthServer.Shutdown(shutdownCtx)
+		}()
+	}
+
+	if !config.ServerMode() {
+		log.Printf("starting chaincode in peer-launched mode")
+		served := make(chan error, 1)
+		go func() {
+			served <- shim.Start(cc)
+		}()
+		health.SetStatus(StatusServing)
+		select {
+		case err := <-served:
+			health.SetStatus(StatusStopping)
+			return err
+		case <-ctx.Done():
+			health.SetStatus(StatusStopping)
+			return nil
+		}
+	}
+
+	return serve(ctx, config, cc, health)
+}
+
+// serve runs the chaincode server and stops it gracefully when ctx is done
+func serve(ctx context.Context, config *Config, cc shim.Chaincode, health *Health) error {
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", config.Address, err)
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{Time: keepaliveTime, Timeout: keepaliveTimeout}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: keepaliveMinTime, PermitWithoutStream: true}),
+		grpc.MaxSendMsgSize(maxMessageSize),
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.ConnectionTimeout(connectionTimeout),
+	}
+	if config.TLS != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(config.TLS)))
+	}
+	server := grpc.NewServer(serverOpts...)
+	// ChaincodeServer only handles the peer's Connect stream here; its own
+	// Start would create a server that cannot be stopped
+	pb.RegisterChaincodeServer(server, &shim.ChaincodeServer{CCID: config.ChaincodeID, Address: config.Address, CC: cc})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	health.SetStatus(StatusServing)
+	log.Printf("chaincode %s listening on %s, TLS enabled: %t", config.ChaincodeID, listener.Addr(), config.TLS != nil)
+
+	select {
+	case err := <-served:
+		health.SetStatus(StatusStopping)
+		return err
+	case <-ctx.Done():
+	}
+
+	health.SetStatus(StatusStopping)
+	log.Printf("stopping chaincode server, waiting up to %v for running transactions", config.ShutdownTimeout)
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(config.ShutdownTimeout):
+		log.Printf("shutdown timeout reached, closing remaining connections")
+		server.Stop()
+		<-stopped
+	}
+	return nil
+}
+
+func startHealthServer(address string, health *Health) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(HealthPath, health)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("health endpoint stopped: %v", err)
+		}
+	}()
+	log.Printf("health endpoint listening on %s%s", listener.Addr(), HealthPath)
+	return server, nil
+}
@@ -0,0 +1,122 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ccaas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type nopChaincode struct{}
+
+func (nopChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func (nopChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return shim.Success(nil)
+}
+
+func TestHealth(t *testing.T) {
+	health := NewHealth(&Config{Address: ":9999"})
+
+	tests := []struct {
+		status     string
+		method     string
+		wantStatus int
+	}{
+		{status: StatusStarting, method: http.MethodGet, wantStatus: http.StatusServiceUnavailable},
+		{status: StatusServing, method: http.MethodGet, wantStatus: http.StatusOK},
+		{status: StatusServing, method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed},
+		{status: StatusStopping, method: http.MethodGet, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		health.SetStatus(tt.status)
+		recorder := httptest.NewRecorder()
+		health.ServeHTTP(recorder, httptest.NewRequest(tt.method, HealthPath, nil))
+		if recorder.Code != tt.wantStatus {
+			t.Fatalf("%s %s while %s = %d, want %d", tt.method, HealthPath, tt.status, recorder.Code, tt.wantStatus)
+		}
+		if tt.method != http.MethodGet {
+			continue
+		}
+		var report HealthReport
+		if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil || report.Status != tt.status || report.Mode != "server" {
+			t.Fatalf("unexpected report %s (%v)", recorder.Body.String(), err)
+		}
+	}
+}
+
+func TestServeStopsGracefully(t *testing.T) {
+	config := &Config{Address: "127.0.0.1:0", ChaincodeID: "bank:1", ShutdownTimeout: time.Second}
+	health := NewHealth(config)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, config, nopChaincode{}, health)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for health.Status() != StatusServing {
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("server did not stop")
+	}
+	if health.Status() != StatusStopping {
+		t.Fatalf("health status = %s after shutdown, want %s", health.Status(), StatusStopping)
+	}
+}
+
+func TestServeAcceptsConnections(t *testing.T) {
+	// Find a free port, as serve does not report the one it picked
+	probe := httptest.NewServer(http.NotFoundHandler())
+	config := &Config{Address: probe.Listener.Addr().String(), ChaincodeID: "bank:1", ShutdownTimeout: time.Second}
+	probe.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	health := NewHealth(config)
+	go func() {
+		_ = serve(ctx, config, nopChaincode{}, health)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for health.Status() != StatusServing {
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	dialCtx, dialCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer dialCancel()
+	conn, err := grpc.DialContext(dialCtx, config.Address, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("failed to connect to the chaincode server: %v", err)
+	}
+	defer conn.Close()
+	if _, err := pb.NewChaincodeClient(conn).Connect(dialCtx); err != nil {
+		t.Fatalf("failed to open the chaincode stream: %v", err)
+	}
+}
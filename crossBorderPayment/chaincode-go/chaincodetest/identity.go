@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincodetest
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Identity is an in-memory cid.ClientIdentity
+type Identity struct {
+	MSPID      string
+	CommonName string
+	Attributes map[string]string
+}
+
+// NewIdentity returns an identity of mspID with the given common name and
+// Fabric CA attributes
+func NewIdentity(mspID string, commonName string, attributes map[string]string) *Identity {
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	return &Identity{MSPID: mspID, CommonName: commonName, Attributes: attributes}
+}
+
+// ID returns the decoded form of GetID, as stored by contracts that record
+// the submitting client
+func (i *Identity) ID() string {
+	return fmt.Sprintf("x509::CN=%s,OU=client::CN=ca.%s", i.CommonName, i.MSPID)
+}
+
+// GetID returns the base64 encoded x509 ID, in the same format as the cid package
+func (i *Identity) GetID() (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(i.ID())), nil
+}
+
+// GetMSPID returns the identity's MSP ID
+func (i *Identity) GetMSPID() (string, error) {
+	return i.MSPID, nil
+}
+
+// GetAttributeValue returns the value of a Fabric CA attribute
+func (i *Identity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := i.Attributes[attrName]
+	return value, found, nil
+}
+
+// AssertAttributeValue returns an error unless the attribute has attrValue
+func (i *Identity) AssertAttributeValue(attrName, attrValue string) error {
+	value, found := i.Attributes[attrName]
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+	return nil
+}
+
+// GetX509Certificate returns nil; the in-memory identity has no certificate
+func (i *Identity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
+}
+
+// NewContext returns a transaction context backed by stub and identity
+func NewContext(stub *Stub, identity *Identity) *contractapi.TransactionContext {
+	ctx := new(contractapi.TransactionContext)
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
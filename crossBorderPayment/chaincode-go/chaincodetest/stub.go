@@ -0,0 +1,356 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package chaincodetest provides an in-memory chaincode stub, client identity
+// and transaction context for testing contracts without a Fabric network.
+//
+// The stub follows the peer's read-your-committed-writes semantics: GetState
+// and the iterators only see state committed by earlier transactions, never the
+// pending writes of the running one. Transactions are started with Begin and
+// finished with Commit or Rollback, or run in one go with Transact.
+package chaincodetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	compositeKeyNamespace = "\x00"
+	minUnicodeRuneValue   = 0
+)
+
+// Stub is an in-memory shim.ChaincodeStubInterface. Methods the contracts do
+// not use are left to the embedded nil interface and panic when called.
+type Stub struct {
+	shim.ChaincodeStubInterface
+
+	// Now is the timestamp of the next transaction. It is advanced by one
+	// second on every Begin so transactions have distinct, ordered times.
+	Now       time.Time
+	ChannelID string
+
+	state      map[string][]byte
+	history    map[string][]*queryresult.KeyModification
+	validation map[string][]byte
+
+	txCount     int
+	txID        string
+	txTimestamp time.Time
+	writes      map[string][]byte
+	deletes     map[string]bool
+	pendingEP   map[string][]byte
+	event       *Event
+	events      []Event
+}
+
+// Event is a chaincode event set by a committed transaction
+type Event struct {
+	TxID    string
+	Name    string
+	Payload []byte
+}
+
+// NewStub returns an empty stub whose clock starts at 2024-01-01 UTC
+func NewStub() *Stub {
+	return &Stub{
+		Now:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ChannelID:  "testchannel",
+		state:      map[string][]byte{},
+		history:    map[string][]*queryresult.KeyModification{},
+		validation: map[string][]byte{},
+	}
+}
+
+// Begin starts a transaction. It panics if one is already running.
+func (s *Stub) Begin() {
+	if s.writes != nil {
+		panic("chaincodetest: transaction already in progress")
+	}
+	s.txCount++
+	s.txID = fmt.Sprintf("tx%d", s.txCount)
+	s.txTimestamp = s.Now
+	s.Now = s.Now.Add(time.Second)
+	s.writes = map[string][]byte{}
+	s.deletes = map[string]bool{}
+	s.pendingEP = map[string][]byte{}
+	s.event = nil
+}
+
+// Commit applies the running transaction's writes, deletes and event
+func (s *Stub) Commit() {
+	s.mustBeInTx()
+
+	timestamp := timestamppb.New(s.txTimestamp)
+	for _, key := range sortedKeys(s.writes) {
+		s.state[key] = s.writes[key]
+		s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.txID, Value: s.writes[key], Timestamp: timestamp})
+	}
+	for key := range s.deletes {
+		delete(s.state, key)
+		s.history[key] = append(s.history[key], &queryresult.KeyModification{TxId: s.txID, Timestamp: timestamp, IsDelete: true})
+	}
+	for key, ep := range s.pendingEP {
+		s.validation[key] = ep
+	}
+	if s.event != nil {
+		s.events = append(s.events, *s.event)
+	}
+
+	s.end()
+}
+
+// Rollback discards the running transaction
+func (s *Stub) Rollback() {
+	s.mustBeInTx()
+	s.end()
+}
+
+// Transact runs fn as one transaction, committing if it returns nil and
+// rolling back otherwise. The error of fn is returned unchanged.
+func (s *Stub) Transact(fn func() error) error {
+	s.Begin()
+	err := fn()
+	if err != nil {
+		s.Rollback()
+		return err
+	}
+	s.Commit()
+	return nil
+}
+
+// Events returns the events of committed transactions in commit order
+func (s *Stub) Events() []Event {
+	return append([]Event(nil), s.events...)
+}
+
+// Keys returns every committed key in sorted order
+func (s *Stub) Keys() []string {
+	return sortedKeys(s.state)
+}
+
+// Committed returns the committed value of key, or nil
+func (s *Stub) Committed(key string) []byte {
+	return s.state[key]
+}
+
+// Seed writes a committed value directly, bypassing transactions
+func (s *Stub) Seed(key string, value []byte) {
+	s.state[key] = value
+}
+
+func (s *Stub) end() {
+	s.txID = ""
+	s.writes = nil
+	s.deletes = nil
+	s.pendingEP = nil
+	s.event = nil
+}
+
+func (s *Stub) mustBeInTx() {
+	if s.writes == nil {
+		panic("chaincodetest: no transaction in progress, call Begin or Transact first")
+	}
+}
+
+// GetTxID returns the ID of the running transaction
+func (s *Stub) GetTxID() string {
+	s.mustBeInTx()
+	return s.txID
+}
+
+// GetChannelID returns the stub's channel name
+func (s *Stub) GetChannelID() string {
+	return s.ChannelID
+}
+
+// GetTxTimestamp returns the timestamp of the running transaction
+func (s *Stub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	s.mustBeInTx()
+	return timestamppb.New(s.txTimestamp), nil
+}
+
+// GetState returns the committed value of key. Writes of the running
+// transaction are not visible, as on a peer.
+func (s *Stub) GetState(key string) ([]byte, error) {
+	s.mustBeInTx()
+	return s.state[key], nil
+}
+
+// PutState records a write for the running transaction
+func (s *Stub) PutState(key string, value []byte) error {
+	s.mustBeInTx()
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	delete(s.deletes, key)
+	s.writes[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// DelState records a delete for the running transaction
+func (s *Stub) DelState(key string) error {
+	s.mustBeInTx()
+	delete(s.writes, key)
+	s.deletes[key] = true
+	return nil
+}
+
+// SetStateValidationParameter records a key-level endorsement policy
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	s.mustBeInTx()
+	s.pendingEP[key] = ep
+	return nil
+}
+
+// GetStateValidationParameter returns the committed key-level endorsement policy
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	s.mustBeInTx()
+	return s.validation[key], nil
+}
+
+// SetEvent sets the event of the running transaction, replacing any earlier one
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	s.mustBeInTx()
+	if name == "" {
+		return fmt.Errorf("event name can not be empty string")
+	}
+	s.event = &Event{TxID: s.txID, Name: name, Payload: payload}
+	return nil
+}
+
+// CreateCompositeKey builds a composite key the same way as the peer shim
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey splits a key built by CreateCompositeKey
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	parts := strings.Split(compositeKey[len(compositeKeyNamespace):], string(rune(minUnicodeRuneValue)))
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	// The key ends with a separator, so the last part is always empty
+	return parts[0], parts[1 : len(parts)-1], nil
+}
+
+// GetStateByRange iterates over committed simple keys in [startKey, endKey).
+// Empty bounds are open, and composite keys are never returned.
+func (s *Stub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	s.mustBeInTx()
+	kvs := []*queryresult.KV{}
+	for _, key := range sortedKeys(s.state) {
+		if strings.HasPrefix(key, compositeKeyNamespace) {
+			continue
+		}
+		if (startKey == "" || key >= startKey) && (endKey == "" || key < endKey) {
+			kvs = append(kvs, &queryresult.KV{Namespace: s.ChannelID, Key: key, Value: s.state[key]})
+		}
+	}
+	return &StateIterator{kvs: kvs}, nil
+}
+
+// GetStateByPartialCompositeKey iterates over committed composite keys that
+// start with objectType and keys
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	s.mustBeInTx()
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := []*queryresult.KV{}
+	for _, key := range sortedKeys(s.state) {
+		if strings.HasPrefix(key, prefix) {
+			kvs = append(kvs, &queryresult.KV{Namespace: s.ChannelID, Key: key, Value: s.state[key]})
+		}
+	}
+	return &StateIterator{kvs: kvs}, nil
+}
+
+// GetHistoryForKey iterates over the committed modifications of key, newest first
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	s.mustBeInTx()
+	history := s.history[key]
+	modifications := make([]*queryresult.KeyModification, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		modifications = append(modifications, history[i])
+	}
+	return &HistoryIterator{modifications: modifications}, nil
+}
+
+// StateIterator is an in-memory shim.StateQueryIteratorInterface
+type StateIterator struct {
+	kvs    []*queryresult.KV
+	closed bool
+}
+
+// HasNext reports whether Next will return another result
+func (it *StateIterator) HasNext() bool {
+	return !it.closed && len(it.kvs) > 0
+}
+
+// Next returns the next result
+func (it *StateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv, nil
+}
+
+// Close releases the iterator
+func (it *StateIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// HistoryIterator is an in-memory shim.HistoryQueryIteratorInterface
+type HistoryIterator struct {
+	modifications []*queryresult.KeyModification
+	closed        bool
+}
+
+// HasNext reports whether Next will return another result
+func (it *HistoryIterator) HasNext() bool {
+	return !it.closed && len(it.modifications) > 0
+}
+
+// Next returns the next modification
+func (it *HistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	modification := it.modifications[0]
+	it.modifications = it.modifications[1:]
+	return modification, nil
+}
+
+// Close releases the iterator
+func (it *HistoryIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,145 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincodetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStubReadsCommittedState(t *testing.T) {
+	stub := NewStub()
+	stub.Seed("a", []byte("1"))
+
+	err := stub.Transact(func() error {
+		if err := stub.PutState("a", []byte("2")); err != nil {
+			return err
+		}
+		value, err := stub.GetState("a")
+		if err != nil {
+			return err
+		}
+		if string(value) != "1" {
+			t.Fatalf("GetState saw pending write %q, want committed value 1", value)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transact: %v", err)
+	}
+	if value := stub.Committed("a"); string(value) != "2" {
+		t.Fatalf("committed value = %q, want 2", value)
+	}
+}
+
+func TestStubRollback(t *testing.T) {
+	stub := NewStub()
+	stub.Seed("a", []byte("1"))
+
+	failure := errors.New("failed")
+	err := stub.Transact(func() error {
+		_ = stub.PutState("b", []byte("1"))
+		_ = stub.DelState("a")
+		_ = stub.SetEvent("Created", nil)
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("Transact error = %v, want %v", err, failure)
+	}
+	if keys := stub.Keys(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Fatalf("keys = %v, want [a]", keys)
+	}
+	if events := stub.Events(); len(events) != 0 {
+		t.Fatalf("rolled back transaction emitted %v", events)
+	}
+}
+
+func TestStubKeepsLastEvent(t *testing.T) {
+	stub := NewStub()
+	err := stub.Transact(func() error {
+		_ = stub.SetEvent("First", nil)
+		return stub.SetEvent("Second", []byte("x"))
+	})
+	if err != nil {
+		t.Fatalf("Transact: %v", err)
+	}
+	events := stub.Events()
+	if len(events) != 1 || events[0].Name != "Second" || events[0].TxID != "tx1" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
+
+func TestStubCompositeKeys(t *testing.T) {
+	stub := NewStub()
+	for _, attributes := range [][]string{{"name", "A"}, {"name", "B"}, {"country", "DE"}} {
+		key, err := stub.CreateCompositeKey("Entry", attributes)
+		if err != nil {
+			t.Fatalf("CreateCompositeKey: %v", err)
+		}
+		stub.Seed(key, []byte(attributes[1]))
+	}
+	stub.Seed("plain", []byte("x"))
+
+	stub.Begin()
+	defer stub.Rollback()
+
+	iterator, err := stub.GetStateByPartialCompositeKey("Entry", []string{"name"})
+	if err != nil {
+		t.Fatalf("GetStateByPartialCompositeKey: %v", err)
+	}
+	var values []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		objectType, attributes, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil || objectType != "Entry" || attributes[0] != "name" {
+			t.Fatalf("SplitCompositeKey(%q) = %s, %v, %v", kv.Key, objectType, attributes, err)
+		}
+		values = append(values, string(kv.Value))
+	}
+	if !reflect.DeepEqual(values, []string{"A", "B"}) {
+		t.Fatalf("values = %v, want [A B]", values)
+	}
+
+	iterator, err = stub.GetStateByRange("", "")
+	if err != nil {
+		t.Fatalf("GetStateByRange: %v", err)
+	}
+	kv, _ := iterator.Next()
+	if kv.Key != "plain" || iterator.HasNext() {
+		t.Fatalf("range query returned composite keys")
+	}
+
+	if _, _, err := stub.SplitCompositeKey("plain"); err == nil {
+		t.Fatalf("SplitCompositeKey accepted a simple key")
+	}
+}
+
+func TestStubHistory(t *testing.T) {
+	stub := NewStub()
+	for _, value := range []string{"1", "2"} {
+		value := value
+		_ = stub.Transact(func() error { return stub.PutState("a", []byte(value)) })
+	}
+	_ = stub.Transact(func() error { return stub.DelState("a") })
+
+	stub.Begin()
+	defer stub.Rollback()
+	iterator, err := stub.GetHistoryForKey("a")
+	if err != nil {
+		t.Fatalf("GetHistoryForKey: %v", err)
+	}
+	var txIDs []string
+	for iterator.HasNext() {
+		modification, _ := iterator.Next()
+		txIDs = append(txIDs, modification.TxId)
+	}
+	if !reflect.DeepEqual(txIDs, []string{"tx3", "tx2", "tx1"}) {
+		t.Fatalf("history = %v, want newest first", txIDs)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Command cbpsapi serves the REST API of the restapi package. It calls the
+// chaincode through the peer CLI as the wallet identity each API key maps to,
+// so it needs the peer binary, the CORE_PEER_* environment of the peer to
+// connect to, and the MSP directory of each organization whose identities it
+// acts as.
+//
+// Usage:
+//
+//	cbpsapi -wallet <dir> -api-keys <file> -msp-dirs <mspid>=<dir>[,...] [flags]
+//	cbpsapi -openapi <metadata.json>
+//
+// The API keys file is a JSON object from API key to wallet label. With
+// -openapi, cbpsapi prints the OpenAPI document for contract metadata saved
+// with the peer CLI and exits.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/peercli"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/restapi"
+)
+
+func main() {
+	err := run(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+func run(args []string) error {
+	envOr := func(key string, fallback string) string {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	var options peercli.Options
+	var peerAddresses, tlsRootCertFiles string
+	flags := flag.NewFlagSet("cbpsapi", flag.ContinueOnError)
+	listen := flags.String("listen", envOr("CBPS_API_LISTEN", ":8080"), "address to serve the API on (CBPS_API_LISTEN)")
+	walletDir := flags.String("wallet", os.Getenv("CBPS_WALLET"), "directory of the wallet's <label>.id files (CBPS_WALLET)")
+	apiKeysFile := flags.String("api-keys", os.Getenv("CBPS_API_KEYS"), "JSON file mapping API keys to wallet labels (CBPS_API_KEYS)")
+	mspDirs := flags.String("msp-dirs", os.Getenv("CBPS_MSP_DIRS"), "comma-separated <mspid>=<dir> MSP directories whose CA certificates identities use (CBPS_MSP_DIRS)")
+	specIdentity := flags.String("spec-identity", os.Getenv("CBPS_SPEC_IDENTITY"), "wallet label used to read the contract metadata for /openapi.json (CBPS_SPEC_IDENTITY)")
+	poolSize := flags.Int("pool-size", 32, "identities kept ready, one MSP directory each")
+	idleTimeout := flags.Duration("idle-timeout", 10*time.Minute, "how long an unused identity is kept ready")
+	idempotencyTTL := flags.Duration("idempotency-ttl", restapi.DefaultIdempotencyTTL, "how long responses are kept for retried requests")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 30*time.Second, "how long a stopping server waits for running requests")
+	openAPI := flags.String("openapi", "", "print the OpenAPI document for this contract metadata file and exit")
+	flags.StringVar(&options.PeerBinary, "peer", envOr("CBPS_PEER_BINARY", "peer"), "path of the peer CLI (CBPS_PEER_BINARY)")
+	flags.StringVar(&options.Channel, "channel", envOr("CBPS_CHANNEL", "bankschannel"), "channel of the chaincode (CBPS_CHANNEL)")
+	flags.StringVar(&options.Chaincode, "chaincode", envOr("CBPS_CHAINCODE", "bank"), "chaincode name (CBPS_CHAINCODE)")
+	flags.StringVar(&options.Orderer, "orderer", os.Getenv("CBPS_ORDERER"), "orderer address for submitted transactions (CBPS_ORDERER)")
+	flags.StringVar(&options.OrdererTLSHostname, "orderer-tls-hostname", os.Getenv("CBPS_ORDERER_TLS_HOSTNAME"), "host name to verify the orderer's TLS certificate against (CBPS_ORDERER_TLS_HOSTNAME)")
+	flags.StringVar(&options.OrdererCAFile, "orderer-ca", os.Getenv("CBPS_ORDERER_CA"), "PEM file of the orderer's TLS CA, enables TLS (CBPS_ORDERER_CA)")
+	flags.StringVar(&peerAddresses, "peer-addresses", os.Getenv("CBPS_PEER_ADDRESSES"), "comma-separated endorsing peers for submitted transactions (CBPS_PEER_ADDRESSES)")
+	flags.StringVar(&tlsRootCertFiles, "tls-root-certs", os.Getenv("CBPS_TLS_ROOT_CERTS"), "comma-separated TLS CA files of those peers, in the same order (CBPS_TLS_ROOT_CERTS)")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *openAPI != "" {
+		metadata, err := ioutil.ReadFile(*openAPI)
+		if err != nil {
+			return fmt.Errorf("failed to read the contract metadata: %v", err)
+		}
+		spec, err := restapi.GenerateOpenAPI(metadata)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Printf("%s\n", spec)
+		return err
+	}
+
+	if *walletDir == "" || *apiKeysFile == "" || *mspDirs == "" {
+		return fmt.Errorf("-wallet, -api-keys and -msp-dirs are required")
+	}
+	keysJSON, err := ioutil.ReadFile(*apiKeysFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the API keys: %v", err)
+	}
+	var keys map[string]string
+	err = json.Unmarshal(keysJSON, &keys)
+	if err != nil {
+		return fmt.Errorf("failed to parse the API keys: %v", err)
+	}
+	orgMSPDirs, err := parseMSPDirs(*mspDirs)
+	if err != nil {
+		return err
+	}
+	options.PeerAddresses = splitList(peerAddresses)
+	options.TLSRootCertFiles = splitList(tlsRootCertFiles)
+
+	pool := restapi.NewPool(peerDialer(options, orgMSPDirs), *poolSize, *idleTimeout)
+	defer pool.Close()
+	server := &http.Server{
+		Addr: *listen,
+		Handler: restapi.NewServer(restapi.Options{
+			Gateway:        pool,
+			Wallet:         &restapi.FileWallet{Dir: *walletDir},
+			Authenticate:   restapi.APIKeys(keys),
+			SpecIdentity:   *specIdentity,
+			IdempotencyTTL: *idempotencyTTL,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("serving the REST API on %s", *listen)
+		errs <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("stopping the REST API, waiting up to %v for running requests", *shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// peerDialer connects as an identity by giving the peer CLI an MSP directory
+// of its own: the identity's certificate and key from the wallet, and the CA
+// certificates of its organization's MSP directory. The directory is removed
+// when the pool drops the identity. Each call still runs a peer process with
+// connections of its own.
+func peerDialer(options peercli.Options, orgMSPDirs map[string]string) restapi.Dialer {
+	return func(identity *restapi.Identity) (cbpsclient.Transport, io.Closer, error) {
+		orgDir, ok := orgMSPDirs[identity.MSPID]
+		if !ok {
+			return nil, nil, fmt.Errorf("no MSP directory is configured for %s of identity %s", identity.MSPID, identity.Label)
+		}
+		dir, err := ioutil.TempDir("", "cbpsapi-msp-")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create the MSP directory of %s: %v", identity.Label, err)
+		}
+		err = writeMSPDir(dir, orgDir, identity)
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, nil, fmt.Errorf("failed to create the MSP directory of %s: %v", identity.Label, err)
+		}
+
+		identityOptions := options
+		identityOptions.Env = append(append([]string(nil), options.Env...),
+			"CORE_PEER_MSPCONFIGPATH="+dir,
+			"CORE_PEER_LOCALMSPID="+identity.MSPID,
+		)
+		return peercli.New(identityOptions), removeDir(dir), nil
+	}
+}
+
+// writeMSPDir lays out an MSP directory for identity in dir
+func writeMSPDir(dir string, orgDir string, identity *restapi.Identity) error {
+	for _, sub := range []string{"cacerts", "intermediatecerts", "tlscacerts", "tlsintermediatecerts"} {
+		err := copyDir(filepath.Join(orgDir, sub), filepath.Join(dir, sub))
+		if err != nil {
+			return err
+		}
+	}
+	err := copyFile(filepath.Join(orgDir, "config.yaml"), filepath.Join(dir, "config.yaml"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for sub, file := range map[string]struct {
+		name     string
+		contents []byte
+	}{
+		"signcerts": {name: "cert.pem", contents: identity.Certificate},
+		"keystore":  {name: "priv_sk", contents: identity.PrivateKey},
+	} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0700)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, sub, file.name), file.contents, 0600)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDir copies the files of a directory, doing nothing if it does not exist
+func copyDir(from string, to string) error {
+	entries, err := ioutil.ReadDir(from)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(to, 0700)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		err = copyFile(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(from string, to string) error {
+	contents, err := ioutil.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(to, contents, 0600)
+}
+
+// removeDir is an io.Closer that removes a directory
+type removeDir string
+
+func (d removeDir) Close() error {
+	return os.RemoveAll(string(d))
+}
+
+func parseMSPDirs(s string) (map[string]string, error) {
+	dirs := make(map[string]string)
+	for _, entry := range splitList(s) {
+		i := strings.Index(entry, "=")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("MSP directory %q is not <mspid>=<dir>", entry)
+		}
+		dirs[entry[:i]] = entry[i+1:]
+	}
+	return dirs, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
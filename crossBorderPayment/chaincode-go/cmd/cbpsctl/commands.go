@@ -0,0 +1,1346 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+var groups = []group{
+	{
+		name:    "bank",
+		summary: "Register and inspect banks",
+		commands: []command{
+			{name: "create", args: "--id ID --name NAME --password PASSWORD --country CC --currency CUR --reserves AMOUNT --rate RATE [--bic BIC]", summary: "register a bank, administered by the calling identity", run: createBank},
+			{name: "update", args: "--id ID --name NAME --reserves AMOUNT --country CC", summary: "propose a new profile for a bank, applied once another administrator approves it", run: updateBank},
+			{name: "show", args: "BANK...", summary: "show banks", run: showBanks},
+			{name: "by-bic", args: "BIC", summary: "show the bank with a BIC, or the head office of a branch BIC", run: bankByBIC},
+			{name: "accounts", args: "BANK", summary: "list the accounts held at a bank", run: bankAccounts},
+			{name: "customers", args: "BANK", summary: "list the customers of a bank", run: bankCustomers},
+			{name: "purpose-rules", args: "BANK", summary: "list the countries a bank requires purpose codes for", run: purposeCodeRules},
+			{name: "set-purpose-rule", args: "[--codes LIST] BANK COUNTRY", summary: "require a purpose code, optionally one of LIST, on a bank's payments with a country", run: setPurposeCodeRule},
+			{name: "remove-purpose-rule", args: "BANK COUNTRY", summary: "stop requiring a purpose code on a bank's payments with a country", run: removePurposeCodeRule},
+		},
+	},
+	{
+		name:    "customer",
+		summary: "Register customers and manage their KYC",
+		commands: []command{
+			{name: "create", args: "--id ID --password PASSWORD --name NAME --surname SURNAME", summary: "register a customer", run: createCustomer},
+			{name: "show", args: "CUSTOMER...", summary: "show customers", run: showCustomers},
+			{name: "accounts", args: "CUSTOMER", summary: "list a customer's accounts", run: customerAccounts},
+			{name: "add-document", args: "BANK CUSTOMER HASH", summary: "record the hash of a KYC document", run: addKYCDocument},
+			{name: "verify", args: "--bank BANK --risk RATING --valid-until YYYY-MM-DD CUSTOMER", summary: "mark a customer's KYC as verified", run: verifyCustomer},
+			{name: "enroll-client", args: "BANK CUSTOMER CLIENT_ID", summary: "record the client identity that acts as a verified customer", run: enrollCustomerClient},
+			{name: "reject", args: "BANK CUSTOMER", summary: "mark a customer's KYC as rejected", run: rejectCustomer},
+			{name: "set-tier", args: "CUSTOMER TIER", summary: "move a customer to another limit tier", run: setCustomerTier},
+		},
+	},
+	{
+		name:    "beneficiary",
+		summary: "Manage the accounts customers save to pay by nickname",
+		commands: []command{
+			{name: "add", args: "--customer CUSTOMER --id ID --nickname NAME --account ACCOUNT --name HOLDER [--payment-limit AMOUNT] [--daily-limit AMOUNT]", summary: "save a beneficiary after checking the account holder's name", run: addBeneficiary},
+			{name: "update", args: "--customer CUSTOMER --id ID --nickname NAME [--payment-limit AMOUNT] [--daily-limit AMOUNT]", summary: "change a beneficiary's nickname and limits", run: updateBeneficiary},
+			{name: "remove", args: "CUSTOMER BENEFICIARY", summary: "delete a beneficiary", run: removeBeneficiary},
+			{name: "list", args: "CUSTOMER", summary: "list a customer's beneficiaries", run: listBeneficiaries},
+		},
+	},
+	{
+		name:    "account",
+		summary: "Open, inspect and change the status of accounts",
+		commands: []command{
+			{name: "create", args: "--id ID --customer CUSTOMER --bank BANK [--balance AMOUNT] [--iban IBAN | --routing-scheme SCHEME --routing-number NUMBER --account-number NUMBER]", summary: "open an account", run: createAccount},
+			{name: "show", args: "ACCOUNT...", summary: "show accounts", run: showAccounts},
+			{name: "by-iban", args: "IBAN", summary: "show the account with an IBAN", run: accountByIBAN},
+			{name: "by-number", args: "SCHEME ROUTING-NUMBER ACCOUNT-NUMBER", summary: "show the account with a local account number", run: accountByNumber},
+			{name: "adjust-balance", args: "ACCOUNT AMOUNT", summary: "propose to credit an account, or debit it with a negative amount, applied once another administrator approves it", run: adjustBalance},
+			{name: "freeze", args: "[--reason REASON] ACCOUNT", summary: "block all payments of an account", run: freezeAccount},
+			{name: "unfreeze", args: "ACCOUNT", summary: "make a frozen account active again", run: accountStatus("unfrozen", (*cbpsclient.Client).UnfreezeAccount)},
+			{name: "dormant", args: "ACCOUNT", summary: "flag an unused account as dormant", run: accountStatus("marked dormant", (*cbpsclient.Client).MarkAccountDormant)},
+			{name: "reactivate", args: "ACCOUNT", summary: "make a dormant account active again", run: accountStatus("reactivated", (*cbpsclient.Client).ReactivateAccount)},
+			{name: "add-holder", args: "ACCOUNT CUSTOMER", summary: "make a customer a joint holder of an account", run: addAccountHolder},
+			{name: "remove-holder", args: "ACCOUNT CUSTOMER", summary: "take a joint holder off an account", run: removeAccountHolder},
+			{name: "signing-rule", args: "[--required N] ACCOUNT any_one|all|n_of_m", summary: "set how many holders must sign an account's payments", run: setSigningRule},
+			{name: "close", args: "[--sweep ACCOUNT] ACCOUNT", summary: "close an account, sweeping its balance to another account of the customer", run: closeAccount},
+		},
+	},
+	{
+		name:    "payment",
+		summary: "Send payments and review held ones",
+		commands: []command{
+			{name: "create", args: "--id ID --from ACCOUNT (--to ACCOUNT --receiver CUSTOMER | --beneficiary ID) --sender CUSTOMER --amount AMOUNT [--rate RATE] --date YYYY-MM-DD [--purpose CODE] [--reference RF] [--invoices LIST] [--remittance TEXT]", summary: "send a payment", run: createPayment},
+			{name: "list", args: "ACCOUNT", summary: "list the payments of an account", run: listPayments},
+			{name: "batch", args: "--id ID --file FILE [--mode atomic|best-effort]", summary: "send the JSON array of payment instructions in FILE as one batch", run: createBatch},
+			{name: "show-batch", args: "BATCH", summary: "show the report of a batch", run: showBatch},
+			{name: "awaiting", args: "ACCOUNT", summary: "list the payments from an account that still need its holders' signatures", run: paymentsAwaitingApproval},
+			{name: "approve", args: "PAYMENT CUSTOMER", summary: "sign a payment from a joint account as one of its holders, with their enrolled identity", run: approvePayment},
+			{name: "decline", args: "PAYMENT CUSTOMER", summary: "refuse a payment from a joint account for one of its holders", run: declinePayment},
+			{name: "held", args: "", summary: "list the payments held for compliance review", run: heldPayments},
+			{name: "release", args: "PAYMENT", summary: "settle a held payment", run: heldPaymentDecision("released", (*cbpsclient.Client).ReleaseHeldPayment)},
+			{name: "reject", args: "PAYMENT", summary: "close a held payment without moving funds", run: heldPaymentDecision("rejected", (*cbpsclient.Client).RejectHeldPayment)},
+		},
+	},
+	{
+		name:    "request",
+		summary: "Ask customers for payments and answer their requests",
+		commands: []command{
+			{name: "create", args: "--id ID --account ACCOUNT --payer CUSTOMER --amount AMOUNT --currency CUR --due YYYY-MM-DD [--expires YYYY-MM-DD] --reference REF [--purpose CODE]", summary: "ask a customer to pay into an account", run: createPaymentRequest},
+			{name: "accept", args: "--id ID --payment ID --from ACCOUNT", summary: "pay a request from one of the payer's accounts at the banks' exchange rate", run: acceptPaymentRequest},
+			{name: "decline", args: "[--reason REASON] REQUEST", summary: "refuse a request", run: declinePaymentRequest},
+			{name: "show", args: "REQUEST", summary: "show a request", run: showPaymentRequest},
+			{name: "pending", args: "CUSTOMER", summary: "list the requests a customer has yet to answer", run: pendingPaymentRequests},
+		},
+	},
+	{
+		name:    "fx",
+		summary: "Publish and inspect the banks' exchange rates",
+		commands: []command{
+			{name: "set", args: "BANK RATE", summary: "publish a new exchange rate for a bank's currency", run: setRate},
+			{name: "show", args: "BANK...", summary: "show the exchange rates of banks and when they were set", run: showRates},
+		},
+	},
+	{
+		name:    "operation",
+		summary: "Approve administrative changes proposed by another administrator",
+		commands: []command{
+			{name: "pending", args: "", summary: "list the operations waiting for approval", run: pendingOperations},
+			{name: "show", args: "OPERATION", summary: "show an operation and who decided it", run: showOperation},
+			{name: "approve", args: "OPERATION", summary: "apply an operation proposed by another administrator", run: approveOperation},
+			{name: "reject", args: "[--reason REASON] OPERATION", summary: "close an operation without applying it", run: rejectOperation},
+		},
+	},
+	{
+		name:    "membership",
+		summary: "Vote on which banks belong to the consortium",
+		commands: []command{
+			{name: "members", args: "", summary: "list the active member banks", run: memberBanks},
+			{name: "open", args: "", summary: "list the membership proposals still being voted on", run: openMembershipProposals},
+			{name: "history", args: "BANK", summary: "list the votes held on a bank's membership", run: membershipHistory},
+			{name: "propose", args: "--as BANK --reason REASON BANK ACTION", summary: "propose to suspend, reinstate or expel a bank, voting for it as member BANK", run: proposeMembershipChange},
+			{name: "vote", args: "--as BANK [--against] BANK", summary: "vote as member BANK on the open proposal for a bank", run: voteOnMembership},
+			{name: "withdraw", args: "--reason REASON BANK", summary: "withdraw the open proposal for a bank without a decision", run: withdrawMembershipProposal},
+			{name: "admit-founding", args: "BANK", summary: "admit the first member of the consortium, once the state migration has completed", run: admitFoundingBank},
+		},
+	},
+	{
+		name:    "report",
+		summary: "Audit and compliance reports",
+		commands: []command{
+			{name: "invariants", args: "", summary: "check that no money was created or destroyed, failing if it was", run: reportInvariants},
+			{name: "limits", args: "CUSTOMER CURRENCY", summary: "show a customer's current limit usage", run: reportLimitUsage},
+			{name: "tiers", args: "TIER", summary: "show the limits of a tier", run: reportTierLimits},
+			{name: "watchlist", args: "", summary: "list the watchlist", run: reportWatchlist},
+			{name: "purpose-codes", args: "", summary: "list the purpose codes payments may carry", run: reportPurposeCodes},
+			{name: "config", args: "", summary: "show the chaincode configuration", run: reportConfig},
+		},
+	},
+}
+
+func createBank(c *cli, args []string) error {
+	var req cbpsclient.CreateBankRequest
+	fs := flag.NewFlagSet("bank create", flag.ContinueOnError)
+	fs.StringVar(&req.BankID, "id", "", "")
+	fs.StringVar(&req.Name, "name", "", "")
+	fs.StringVar(&req.Password, "password", "", "")
+	fs.StringVar(&req.Country, "country", "", "")
+	fs.StringVar(&req.Currency, "currency", "", "")
+	fs.Float64Var(&req.Reserves, "reserves", 0, "")
+	fs.Float64Var(&req.ExchangeRate, "rate", 0, "")
+	fs.StringVar(&req.BIC, "bic", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "name", "password", "country", "currency", "reserves", "rate")
+	if err != nil {
+		return err
+	}
+	err = c.client.CreateBank(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("bank %s created, awaiting admission by the member banks", req.BankID)
+}
+
+func updateBank(c *cli, args []string) error {
+	var req cbpsclient.UpdateBankProfileRequest
+	fs := flag.NewFlagSet("bank update", flag.ContinueOnError)
+	fs.StringVar(&req.BankID, "id", "", "")
+	fs.StringVar(&req.Name, "name", "", "")
+	fs.Float64Var(&req.Reserves, "reserves", 0, "")
+	fs.StringVar(&req.Country, "country", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "name", "reserves", "country")
+	if err != nil {
+		return err
+	}
+	operation, err := c.client.UpdateBankProfile(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("update of bank %s proposed as operation %s, awaiting approval", req.BankID, operation.OperationID)
+}
+
+func queryBanks(c *cli, ids []string) ([]*bank.Bank, error) {
+	banks := make([]*bank.Bank, 0, len(ids))
+	for _, id := range ids {
+		b, err := c.client.QueryBank(id)
+		if err != nil {
+			return nil, err
+		}
+		banks = append(banks, b)
+	}
+	return banks, nil
+}
+
+func showBanks(c *cli, args []string) error {
+	ids, err := parseArgs(flag.NewFlagSet("bank show", flag.ContinueOnError), args, -1)
+	if err != nil {
+		return err
+	}
+	banks, err := queryBanks(c, ids)
+	if err != nil {
+		return err
+	}
+	return printBanks(c, banks)
+}
+
+func bankByBIC(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("bank by-bic", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	b, err := c.client.QueryBankByBIC(rest[0])
+	if err != nil {
+		return err
+	}
+	return printBanks(c, []*bank.Bank{b})
+}
+
+func printBanks(c *cli, banks []*bank.Bank) error {
+	return c.out.print(banks, func() *table {
+		t := &table{headers: []string{"BANK", "NAME", "BIC", "COUNTRY", "CURRENCY", "RESERVES", "ACCOUNTS", "STATUS"}}
+		for _, b := range banks {
+			t.add(b.BankID, b.Name, orDash(b.BIC), b.Country, b.Currency, formatAmount(b.Reserves), strconv.Itoa(len(b.AccountIDs)), orDash(b.Status))
+		}
+		return t
+	})
+}
+
+func bankAccounts(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("bank accounts", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	accounts, err := c.client.QueryBankAccounts(rest[0])
+	if err != nil {
+		return err
+	}
+	return printAccounts(c, accounts)
+}
+
+func bankCustomers(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("bank customers", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	customers, err := c.client.QueryCustomersByBank(rest[0])
+	if err != nil {
+		return err
+	}
+	return printCustomers(c, customers)
+}
+
+func purposeCodeRules(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("bank purpose-rules", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	rules, err := c.client.QueryPurposeCodeRules(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.out.print(rules, func() *table {
+		t := &table{headers: []string{"BANK", "COUNTRY", "PURPOSE CODES", "SET BY", "DATE"}}
+		for _, rule := range rules {
+			t.add(rule.BankID, rule.Country, orDash(strings.Join(rule.PurposeCodes, ",")), rule.SetBy, rule.Date)
+		}
+		return t
+	})
+}
+
+func setPurposeCodeRule(c *cli, args []string) error {
+	fs := flag.NewFlagSet("bank set-purpose-rule", flag.ContinueOnError)
+	codes := fs.String("codes", "", "")
+	rest, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.SetPurposeCodeRule(rest[0], rest[1], splitList(*codes))
+	if err != nil {
+		return err
+	}
+	return c.out.done("bank %s requires a purpose code for payments with %s", rest[0], rest[1])
+}
+
+func removePurposeCodeRule(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("bank remove-purpose-rule", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.RemovePurposeCodeRule(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("purpose code rule of bank %s for %s removed", rest[0], rest[1])
+}
+
+func createCustomer(c *cli, args []string) error {
+	var req cbpsclient.CustomerRequest
+	fs := flag.NewFlagSet("customer create", flag.ContinueOnError)
+	fs.StringVar(&req.CustomerID, "id", "", "")
+	fs.StringVar(&req.Password, "password", "", "")
+	fs.StringVar(&req.Name, "name", "", "")
+	fs.StringVar(&req.Surname, "surname", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "password", "name", "surname")
+	if err != nil {
+		return err
+	}
+	err = c.client.CreateCustomer(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("customer %s created", req.CustomerID)
+}
+
+func showCustomers(c *cli, args []string) error {
+	ids, err := parseArgs(flag.NewFlagSet("customer show", flag.ContinueOnError), args, -1)
+	if err != nil {
+		return err
+	}
+	customers := make([]*bank.Customer, 0, len(ids))
+	for _, id := range ids {
+		customer, err := c.client.QueryCustomer(id)
+		if err != nil {
+			return err
+		}
+		customers = append(customers, customer)
+	}
+	return printCustomers(c, customers)
+}
+
+func printCustomers(c *cli, customers []*bank.Customer) error {
+	return c.out.print(customers, func() *table {
+		t := &table{headers: []string{"CUSTOMER", "NAME", "SURNAME", "TIER", "KYC", "VERIFIED BY", "KYC EXPIRES", "ACCOUNTS"}}
+		for _, customer := range customers {
+			t.add(customer.CustomerID, customer.Name, customer.Surname, orDash(customer.Tier), orDash(customer.KYCStatus),
+				orDash(customer.VerifyingBankID), orDash(customer.KYCExpiryDate), strconv.Itoa(len(customer.AccountIDs)))
+		}
+		return t
+	})
+}
+
+func customerAccounts(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("customer accounts", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	accounts, err := c.client.QueryCustomerAccounts(rest[0])
+	if err != nil {
+		return err
+	}
+	return printAccounts(c, accounts)
+}
+
+func addKYCDocument(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("customer add-document", flag.ContinueOnError), args, 3)
+	if err != nil {
+		return err
+	}
+	err = c.client.AddKYCDocument(rest[0], rest[1], rest[2])
+	if err != nil {
+		return err
+	}
+	return c.out.done("document recorded for customer %s", rest[1])
+}
+
+func verifyCustomer(c *cli, args []string) error {
+	var req cbpsclient.VerifyCustomerRequest
+	fs := flag.NewFlagSet("customer verify", flag.ContinueOnError)
+	fs.StringVar(&req.BankID, "bank", "", "")
+	fs.StringVar(&req.RiskRating, "risk", "", "")
+	fs.StringVar(&req.ValidUntil, "valid-until", "", "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "bank", "risk", "valid-until")
+	if err != nil {
+		return err
+	}
+	req.CustomerID = rest[0]
+	err = c.client.VerifyCustomer(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("customer %s verified by %s until %s", req.CustomerID, req.BankID, req.ValidUntil)
+}
+
+func enrollCustomerClient(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("customer enroll-client", flag.ContinueOnError), args, 3)
+	if err != nil {
+		return err
+	}
+	err = c.client.EnrollCustomerClient(rest[0], rest[1], rest[2])
+	if err != nil {
+		return err
+	}
+	return c.out.done("client %s enrolled for customer %s", rest[2], rest[1])
+}
+
+func rejectCustomer(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("customer reject", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.RejectCustomer(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("customer %s rejected by %s", rest[1], rest[0])
+}
+
+func setCustomerTier(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("customer set-tier", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.SetCustomerTier(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("customer %s moved to tier %s", rest[0], rest[1])
+}
+
+func addBeneficiary(c *cli, args []string) error {
+	var req cbpsclient.BeneficiaryRequest
+	fs := flag.NewFlagSet("beneficiary add", flag.ContinueOnError)
+	fs.StringVar(&req.CustomerID, "customer", "", "")
+	fs.StringVar(&req.BeneficiaryID, "id", "", "")
+	fs.StringVar(&req.Nickname, "nickname", "", "")
+	fs.StringVar(&req.ReceiverAccountID, "account", "", "")
+	fs.StringVar(&req.Name, "name", "", "")
+	fs.Float64Var(&req.PaymentLimit, "payment-limit", 0, "")
+	fs.Float64Var(&req.DailyLimit, "daily-limit", 0, "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "customer", "id", "nickname", "account", "name")
+	if err != nil {
+		return err
+	}
+	beneficiary, err := c.client.AddBeneficiary(req)
+	if err != nil {
+		return err
+	}
+	if beneficiary.NameCheck == bank.NameCloseMatch {
+		return c.out.done("beneficiary %s saved; %s only nearly matches the holder of account %s", req.BeneficiaryID, req.Name, req.ReceiverAccountID)
+	}
+	return c.out.done("beneficiary %s saved", req.BeneficiaryID)
+}
+
+func updateBeneficiary(c *cli, args []string) error {
+	var req cbpsclient.BeneficiaryRequest
+	fs := flag.NewFlagSet("beneficiary update", flag.ContinueOnError)
+	fs.StringVar(&req.CustomerID, "customer", "", "")
+	fs.StringVar(&req.BeneficiaryID, "id", "", "")
+	fs.StringVar(&req.Nickname, "nickname", "", "")
+	fs.Float64Var(&req.PaymentLimit, "payment-limit", 0, "")
+	fs.Float64Var(&req.DailyLimit, "daily-limit", 0, "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "customer", "id", "nickname")
+	if err != nil {
+		return err
+	}
+	err = c.client.UpdateBeneficiary(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("beneficiary %s updated", req.BeneficiaryID)
+}
+
+func removeBeneficiary(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("beneficiary remove", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.RemoveBeneficiary(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("beneficiary %s removed", rest[1])
+}
+
+func listBeneficiaries(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("beneficiary list", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	beneficiaries, err := c.client.QueryBeneficiaries(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.out.print(beneficiaries, func() *table {
+		t := &table{headers: []string{"BENEFICIARY", "NICKNAME", "ACCOUNT", "CUSTOMER", "NAME CHECK", "PAYMENT LIMIT", "DAILY LIMIT", "CURRENCY"}}
+		for _, b := range beneficiaries {
+			t.add(b.BeneficiaryID, b.Nickname, b.ReceiverAccountID, b.ReceiverCustomerID, b.NameCheck,
+				formatAmount(b.PaymentLimit), formatAmount(b.DailyLimit), b.Currency)
+		}
+		return t
+	})
+}
+
+func createAccount(c *cli, args []string) error {
+	var req cbpsclient.CreateAccountRequest
+	fs := flag.NewFlagSet("account create", flag.ContinueOnError)
+	fs.StringVar(&req.AccountID, "id", "", "")
+	fs.StringVar(&req.CustomerID, "customer", "", "")
+	fs.StringVar(&req.BankID, "bank", "", "")
+	fs.Float64Var(&req.Balance, "balance", 0, "")
+	fs.StringVar(&req.IBAN, "iban", "", "")
+	fs.StringVar(&req.RoutingScheme, "routing-scheme", "", "")
+	fs.StringVar(&req.RoutingNumber, "routing-number", "", "")
+	fs.StringVar(&req.AccountNumber, "account-number", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "customer", "bank")
+	if err != nil {
+		return err
+	}
+	err = c.client.CreateAccount(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("account %s opened", req.AccountID)
+}
+
+func showAccounts(c *cli, args []string) error {
+	ids, err := parseArgs(flag.NewFlagSet("account show", flag.ContinueOnError), args, -1)
+	if err != nil {
+		return err
+	}
+	accounts := make([]*bank.Account, 0, len(ids))
+	for _, id := range ids {
+		account, err := c.client.QueryAccount(id)
+		if err != nil {
+			return err
+		}
+		accounts = append(accounts, account)
+	}
+	return printAccounts(c, accounts)
+}
+
+func accountByIBAN(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("account by-iban", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	account, err := c.client.QueryAccountByIBAN(rest[0])
+	if err != nil {
+		return err
+	}
+	return printAccounts(c, []*bank.Account{account})
+}
+
+func accountByNumber(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("account by-number", flag.ContinueOnError), args, 3)
+	if err != nil {
+		return err
+	}
+	account, err := c.client.QueryAccountByNumber(rest[0], rest[1], rest[2])
+	if err != nil {
+		return err
+	}
+	return printAccounts(c, []*bank.Account{account})
+}
+
+func printAccounts(c *cli, accounts []*bank.Account) error {
+	return c.out.print(accounts, func() *table {
+		t := &table{headers: []string{"ACCOUNT", "CUSTOMER", "BANK", "BALANCE", "CURRENCY", "STATUS", "REASON"}}
+		for _, account := range accounts {
+			customer := account.CustomerID
+			if len(account.Holders) > 0 {
+				customer = strings.Join(account.Holders, ",")
+			}
+			t.add(account.AccountID, customer, account.BankID, formatAmount(account.Balance), account.Currency,
+				orDash(account.Status), orDash(account.StatusReason))
+		}
+		return t
+	})
+}
+
+func adjustBalance(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("account adjust-balance", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	amount, err := strconv.ParseFloat(rest[1], 64)
+	if err != nil {
+		return usagef("invalid amount %q", rest[1])
+	}
+	operation, err := c.client.UpdateBalance(rest[0], amount)
+	if err != nil {
+		return err
+	}
+	return c.out.done("adjustment of account %s by %s proposed as operation %s, awaiting approval", rest[0], formatRate(amount), operation.OperationID)
+}
+
+func freezeAccount(c *cli, args []string) error {
+	fs := flag.NewFlagSet("account freeze", flag.ContinueOnError)
+	reason := fs.String("reason", "", "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = c.client.FreezeAccount(rest[0], *reason)
+	if err != nil {
+		return err
+	}
+	return c.out.done("account %s frozen", rest[0])
+}
+
+func addAccountHolder(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("account add-holder", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.AddAccountHolder(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("customer %s added to account %s", rest[1], rest[0])
+}
+
+func removeAccountHolder(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("account remove-holder", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.RemoveAccountHolder(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("customer %s removed from account %s", rest[1], rest[0])
+}
+
+func setSigningRule(c *cli, args []string) error {
+	fs := flag.NewFlagSet("account signing-rule", flag.ContinueOnError)
+	required := fs.Int("required", 0, "")
+	rest, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.SetSigningRule(rest[0], rest[1], *required)
+	if err != nil {
+		return err
+	}
+	return c.out.done("signing rule of account %s set to %s", rest[0], rest[1])
+}
+
+// accountStatus returns a command that applies a status change without
+// options to one account
+func accountStatus(verb string, change func(*cbpsclient.Client, string) error) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		rest, err := parseArgs(flag.NewFlagSet("account", flag.ContinueOnError), args, 1)
+		if err != nil {
+			return err
+		}
+		err = change(c.client, rest[0])
+		if err != nil {
+			return err
+		}
+		return c.out.done("account %s %s", rest[0], verb)
+	}
+}
+
+func closeAccount(c *cli, args []string) error {
+	fs := flag.NewFlagSet("account close", flag.ContinueOnError)
+	sweep := fs.String("sweep", "", "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = c.client.CloseAccount(rest[0], *sweep)
+	if err != nil {
+		return err
+	}
+	return c.out.done("account %s closed", rest[0])
+}
+
+func createPayment(c *cli, args []string) error {
+	var payment bank.PaymentInstruction
+	fs := flag.NewFlagSet("payment create", flag.ContinueOnError)
+	fs.StringVar(&payment.PaymentID, "id", "", "")
+	fs.StringVar(&payment.SenderAccountID, "from", "", "")
+	fs.StringVar(&payment.ReceiverAccountID, "to", "", "")
+	fs.StringVar(&payment.SenderCustomerID, "sender", "", "")
+	fs.StringVar(&payment.ReceiverCustomerID, "receiver", "", "")
+	fs.Float64Var(&payment.Amount, "amount", 0, "")
+	fs.Float64Var(&payment.ExchangeRate, "rate", 1, "")
+	fs.StringVar(&payment.Date, "date", "", "")
+	fs.StringVar(&payment.BeneficiaryID, "beneficiary", "", "")
+	fs.StringVar(&payment.PurposeCode, "purpose", "", "")
+	fs.StringVar(&payment.CreditorReference, "reference", "", "")
+	invoices := fs.String("invoices", "", "")
+	fs.StringVar(&payment.RemittanceInfo, "remittance", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	payment.InvoiceNumbers = splitList(*invoices)
+	required := []string{"id", "from", "sender", "amount", "date"}
+	if payment.BeneficiaryID == "" {
+		required = append(required, "to", "receiver")
+	}
+	err = requireFlags(fs, required...)
+	if err != nil {
+		return err
+	}
+	err = c.client.CreatePayment(payment)
+	if err != nil {
+		return err
+	}
+	to := payment.ReceiverAccountID
+	if payment.BeneficiaryID != "" {
+		to = "beneficiary " + payment.BeneficiaryID
+	}
+	return c.out.done("payment %s of %s sent from %s to %s", payment.PaymentID, formatAmount(payment.Amount), payment.SenderAccountID, to)
+}
+
+func listPayments(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("payment list", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	payments, err := c.client.QueryPayments(rest[0])
+	if err != nil {
+		return err
+	}
+	return printPayments(c, payments)
+}
+
+func printPayments(c *cli, payments []*bank.Payment) error {
+	return c.out.print(payments, func() *table {
+		t := &table{headers: []string{"PAYMENT", "DATE", "FROM", "TO", "AMOUNT", "FEE", "RATE", "STATUS", "SCREENING HITS"}}
+		for _, payment := range payments {
+			t.add(payment.PaymentID, payment.Date, payment.SenderAccountID, payment.ReceiverAccountID, formatAmount(payment.Amount),
+				formatAmount(payment.Fee), formatRate(payment.ExchangeRate), orDash(payment.Status), orDash(strings.Join(payment.ScreeningHits, ",")))
+		}
+		return t
+	})
+}
+
+func createBatch(c *cli, args []string) error {
+	fs := flag.NewFlagSet("payment batch", flag.ContinueOnError)
+	batchID := fs.String("id", "", "")
+	file := fs.String("file", "", "")
+	mode := fs.String("mode", bank.BatchModeAtomic, "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "file")
+	if err != nil {
+		return err
+	}
+	instructionsJSON, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read payment instructions: %v", err)
+	}
+	var instructions []bank.PaymentInstruction
+	err = json.Unmarshal(instructionsJSON, &instructions)
+	if err != nil {
+		return fmt.Errorf("failed to parse payment instructions in %s: %v", *file, err)
+	}
+	batch, err := c.client.CreatePaymentBatch(*batchID, instructions, *mode)
+	if err != nil {
+		return err
+	}
+	return printBatch(c, batch)
+}
+
+func showBatch(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("payment show-batch", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	batch, err := c.client.QueryPaymentBatch(rest[0])
+	if err != nil {
+		return err
+	}
+	return printBatch(c, batch)
+}
+
+func printBatch(c *cli, batch *bank.PaymentBatch) error {
+	return c.out.print(batch, func() *table {
+		t := &table{headers: []string{"LINE", "PAYMENT", "STATUS", "CODE", "ERROR"}}
+		for _, line := range batch.Results {
+			t.add(strconv.Itoa(line.Line), line.PaymentID, line.Status, orDash(line.Code), orDash(line.Error))
+		}
+		t.add("", fmt.Sprintf("batch %s (%s)", batch.BatchID, batch.Mode), batch.Status,
+			fmt.Sprintf("%d settled, %d held, %d failed", batch.Settled, batch.Held, batch.Failed), "")
+		return t
+	})
+}
+
+func paymentsAwaitingApproval(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("payment awaiting", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	payments, err := c.client.QueryPaymentsAwaitingApproval(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.out.print(payments, func() *table {
+		t := &table{headers: []string{"PAYMENT", "DATE", "FROM", "TO", "AMOUNT", "SIGNED BY", "SIGNATURES"}}
+		for _, payment := range payments {
+			t.add(payment.PaymentID, payment.Date, payment.SenderAccountID, payment.ReceiverAccountID, formatAmount(payment.Amount),
+				strings.Join(payment.Approvals, ","), fmt.Sprintf("%d/%d", len(payment.Approvals), payment.RequiredApprovals))
+		}
+		return t
+	})
+}
+
+func approvePayment(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("payment approve", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	payment, err := c.client.ApprovePayment(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	if payment.Status == bank.PaymentStatusPendingApproval {
+		return c.out.done("payment %s signed by %s, %d of %d signatures", payment.PaymentID, rest[1], len(payment.Approvals), payment.RequiredApprovals)
+	}
+	return c.out.done("payment %s signed by %s and %s", payment.PaymentID, rest[1], payment.Status)
+}
+
+func declinePayment(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("payment decline", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	err = c.client.DeclinePayment(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.done("payment %s declined by %s", rest[0], rest[1])
+}
+
+func heldPayments(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("payment held", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	payments, err := c.client.QueryHeldPayments()
+	if err != nil {
+		return err
+	}
+	return printPayments(c, payments)
+}
+
+// heldPaymentDecision returns a command that clears or rejects a held payment
+func heldPaymentDecision(verb string, decide func(*cbpsclient.Client, string) error) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		rest, err := parseArgs(flag.NewFlagSet("payment", flag.ContinueOnError), args, 1)
+		if err != nil {
+			return err
+		}
+		err = decide(c.client, rest[0])
+		if err != nil {
+			return err
+		}
+		return c.out.done("payment %s %s", rest[0], verb)
+	}
+}
+
+func createPaymentRequest(c *cli, args []string) error {
+	var req cbpsclient.RequestToPay
+	fs := flag.NewFlagSet("request create", flag.ContinueOnError)
+	fs.StringVar(&req.RequestID, "id", "", "")
+	fs.StringVar(&req.PayeeAccountID, "account", "", "")
+	fs.StringVar(&req.PayerCustomerID, "payer", "", "")
+	fs.Float64Var(&req.Amount, "amount", 0, "")
+	fs.StringVar(&req.Currency, "currency", "", "")
+	fs.StringVar(&req.DueDate, "due", "", "")
+	fs.StringVar(&req.ExpiryDate, "expires", "", "")
+	fs.StringVar(&req.Reference, "reference", "", "")
+	fs.StringVar(&req.PurposeCode, "purpose", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "account", "payer", "amount", "currency", "due", "reference")
+	if err != nil {
+		return err
+	}
+	request, err := c.client.CreatePaymentRequest(req)
+	if err != nil {
+		return err
+	}
+	return c.out.done("payment request %s for %s %s sent to %s, expiring %s", request.RequestID, formatAmount(request.Amount), request.Currency,
+		request.PayerCustomerID, request.ExpiryDate)
+}
+
+func acceptPaymentRequest(c *cli, args []string) error {
+	fs := flag.NewFlagSet("request accept", flag.ContinueOnError)
+	requestID := fs.String("id", "", "")
+	paymentID := fs.String("payment", "", "")
+	from := fs.String("from", "", "")
+	_, err := parseArgs(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "id", "payment", "from")
+	if err != nil {
+		return err
+	}
+	payment, err := c.client.AcceptPaymentRequest(*requestID, *paymentID, *from)
+	if err != nil {
+		return err
+	}
+	return c.out.done("payment request %s accepted; payment %s of %s is %s", *requestID, payment.PaymentID, formatAmount(payment.Amount), payment.Status)
+}
+
+func declinePaymentRequest(c *cli, args []string) error {
+	fs := flag.NewFlagSet("request decline", flag.ContinueOnError)
+	reason := fs.String("reason", "", "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = c.client.DeclinePaymentRequest(rest[0], *reason)
+	if err != nil {
+		return err
+	}
+	return c.out.done("payment request %s declined", rest[0])
+}
+
+func showPaymentRequest(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("request show", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	request, err := c.client.QueryPaymentRequest(rest[0])
+	if err != nil {
+		return err
+	}
+	return printPaymentRequests(c, []*bank.PaymentRequest{request})
+}
+
+func pendingPaymentRequests(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("request pending", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	requests, err := c.client.QueryPendingPaymentRequests(rest[0])
+	if err != nil {
+		return err
+	}
+	return printPaymentRequests(c, requests)
+}
+
+func printPaymentRequests(c *cli, requests []*bank.PaymentRequest) error {
+	return c.out.print(requests, func() *table {
+		t := &table{headers: []string{"REQUEST", "PAYEE", "ACCOUNT", "PAYER", "AMOUNT", "CURRENCY", "DUE", "EXPIRES", "REFERENCE", "STATUS", "PAYMENT"}}
+		for _, r := range requests {
+			t.add(r.RequestID, r.PayeeCustomerID, r.PayeeAccountID, r.PayerCustomerID, formatAmount(r.Amount), r.Currency,
+				r.DueDate, r.ExpiryDate, r.Reference, r.Status, orDash(r.PaymentID))
+		}
+		return t
+	})
+}
+
+func setRate(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("fx set", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	rate, err := strconv.ParseFloat(rest[1], 64)
+	if err != nil {
+		return usagef("invalid rate %q", rest[1])
+	}
+	err = c.client.UpdateExchangeRate(rest[0], rate)
+	if err != nil {
+		return err
+	}
+	return c.out.done("exchange rate of bank %s set to %s", rest[0], formatRate(rate))
+}
+
+func showRates(c *cli, args []string) error {
+	ids, err := parseArgs(flag.NewFlagSet("fx show", flag.ContinueOnError), args, -1)
+	if err != nil {
+		return err
+	}
+	banks, err := queryBanks(c, ids)
+	if err != nil {
+		return err
+	}
+	type rate struct {
+		BankID       string  `json:"bankID"`
+		Currency     string  `json:"currency"`
+		ExchangeRate float64 `json:"exchangeRate"`
+		Date         string  `json:"exchangeRateDate,omitempty"`
+	}
+	rates := make([]rate, 0, len(banks))
+	for _, b := range banks {
+		rates = append(rates, rate{BankID: b.BankID, Currency: b.Currency, ExchangeRate: b.ExchangeRate, Date: b.ExchangeRateDate})
+	}
+	return c.out.print(rates, func() *table {
+		t := &table{headers: []string{"BANK", "CURRENCY", "RATE", "SET ON"}}
+		for _, r := range rates {
+			t.add(r.BankID, r.Currency, formatRate(r.ExchangeRate), orDash(r.Date))
+		}
+		return t
+	})
+}
+
+func pendingOperations(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("operation pending", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	operations, err := c.client.QueryPendingOperations()
+	if err != nil {
+		return err
+	}
+	return printOperations(c, operations)
+}
+
+func showOperation(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("operation show", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	operation, err := c.client.QueryOperation(rest[0])
+	if err != nil {
+		return err
+	}
+	return printOperations(c, []*bank.PendingOperation{operation})
+}
+
+func approveOperation(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("operation approve", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.ApproveOperation(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.out.done("operation %s approved and applied", rest[0])
+}
+
+func rejectOperation(c *cli, args []string) error {
+	fs := flag.NewFlagSet("operation reject", flag.ContinueOnError)
+	reason := fs.String("reason", "", "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = c.client.RejectOperation(rest[0], *reason)
+	if err != nil {
+		return err
+	}
+	return c.out.done("operation %s rejected", rest[0])
+}
+
+func printOperations(c *cli, operations []*bank.PendingOperation) error {
+	return c.out.print(operations, func() *table {
+		t := &table{headers: []string{"OPERATION", "TYPE", "CHANGE", "STATUS", "PROPOSED BY", "EXPIRES", "DECIDED BY", "REASON"}}
+		for _, o := range operations {
+			t.add(o.OperationID, o.Type, describeOperation(o), o.Status, o.ProposedBy, o.ExpiresAt, orDash(o.DecidedBy), orDash(o.Reason))
+		}
+		return t
+	})
+}
+
+// describeOperation summarizes the change an operation makes
+func describeOperation(o *bank.PendingOperation) string {
+	switch {
+	case o.BankProfile != nil:
+		p := o.BankProfile
+		return fmt.Sprintf("%s: %s, %s, reserves %s", p.BankID, p.Name, p.Country, formatAmount(p.Reserves))
+	case o.Balance != nil:
+		return fmt.Sprintf("%s: %s", o.Balance.AccountID, formatRate(o.Balance.Amount))
+	}
+	return "-"
+}
+
+func memberBanks(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("membership members", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	banks, err := c.client.QueryMemberBanks()
+	if err != nil {
+		return err
+	}
+	return printBanks(c, banks)
+}
+
+func openMembershipProposals(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("membership open", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	proposals, err := c.client.QueryOpenMembershipProposals()
+	if err != nil {
+		return err
+	}
+	return printMembershipProposals(c, proposals)
+}
+
+func membershipHistory(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("membership history", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	proposals, err := c.client.QueryMembershipProposals(rest[0])
+	if err != nil {
+		return err
+	}
+	return printMembershipProposals(c, proposals)
+}
+
+func proposeMembershipChange(c *cli, args []string) error {
+	fs := flag.NewFlagSet("membership propose", flag.ContinueOnError)
+	proposer := fs.String("as", "", "")
+	reason := fs.String("reason", "", "")
+	rest, err := parseArgs(fs, args, 2)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "as", "reason")
+	if err != nil {
+		return err
+	}
+	proposal, err := c.client.ProposeMembershipChange(rest[0], *proposer, rest[1], *reason)
+	if err != nil {
+		return err
+	}
+	return c.out.done("proposal %s to %s bank %s is %s", proposal.ProposalID, proposal.Action, rest[0], proposal.Status)
+}
+
+func voteOnMembership(c *cli, args []string) error {
+	fs := flag.NewFlagSet("membership vote", flag.ContinueOnError)
+	voter := fs.String("as", "", "")
+	against := fs.Bool("against", false, "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "as")
+	if err != nil {
+		return err
+	}
+	proposal, err := c.client.VoteOnMembership(rest[0], *voter, !*against)
+	if err != nil {
+		return err
+	}
+	return c.out.done("vote recorded, proposal %s to %s bank %s is %s", proposal.ProposalID, proposal.Action, rest[0], proposal.Status)
+}
+
+func withdrawMembershipProposal(c *cli, args []string) error {
+	fs := flag.NewFlagSet("membership withdraw", flag.ContinueOnError)
+	reason := fs.String("reason", "", "")
+	rest, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	err = requireFlags(fs, "reason")
+	if err != nil {
+		return err
+	}
+	proposal, err := c.client.WithdrawMembershipProposal(rest[0], *reason)
+	if err != nil {
+		return err
+	}
+	return c.out.done("proposal %s to %s bank %s withdrawn", proposal.ProposalID, proposal.Action, rest[0])
+}
+
+func admitFoundingBank(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("membership admit-founding", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.AdmitFoundingBank(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.out.done("bank %s admitted as the founding member", rest[0])
+}
+
+func printMembershipProposals(c *cli, proposals []*bank.MembershipProposal) error {
+	return c.out.print(proposals, func() *table {
+		t := &table{headers: []string{"PROPOSAL", "BANK", "ACTION", "STATUS", "FOR", "AGAINST", "REQUIRED", "DATE", "REASON"}}
+		for _, p := range proposals {
+			t.add(p.ProposalID, p.BankID, p.Action, p.Status, orDash(strings.Join(p.VotesFor, ",")), orDash(strings.Join(p.VotesAgainst, ",")),
+				strconv.Itoa(p.RequiredVotes), p.Date, orDash(p.Reason))
+		}
+		return t
+	})
+}
+
+func reportInvariants(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("report invariants", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	report, err := c.client.VerifyInvariants()
+	if err != nil {
+		return err
+	}
+	err = c.out.print(report, func() *table {
+		t := &table{headers: []string{"CURRENCY", "BALANCES", "EXPECTED", "RESERVES", "EXPECTED", "IN", "OUT", "FEES"}}
+		for _, totals := range report.Totals {
+			t.add(totals.Currency, formatAmount(totals.AccountBalances), formatAmount(totals.ExpectedBalances),
+				formatAmount(totals.BankReserves), formatAmount(totals.ExpectedReserves), formatAmount(totals.PaymentsIn), formatAmount(totals.PaymentsOut),
+				formatAmount(totals.Fees))
+		}
+		for _, violation := range report.Violations {
+			t.add("VIOLATION", violation)
+		}
+		return t
+	})
+	if err != nil {
+		return err
+	}
+	if !report.Holds {
+		return errors.New("the ledger invariants do not hold")
+	}
+	return nil
+}
+
+func reportLimitUsage(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("report limits", flag.ContinueOnError), args, 2)
+	if err != nil {
+		return err
+	}
+	counters, err := c.client.QueryLimitUsage(rest[0], rest[1])
+	if err != nil {
+		return err
+	}
+	return c.out.print(counters, func() *table {
+		t := &table{headers: []string{"CUSTOMER", "CURRENCY", "PERIOD", "AMOUNT", "PAYMENTS"}}
+		for _, counter := range counters {
+			t.add(counter.CustomerID, counter.Currency, counter.Period, formatAmount(counter.Amount), strconv.Itoa(counter.Count))
+		}
+		return t
+	})
+}
+
+func reportTierLimits(c *cli, args []string) error {
+	rest, err := parseArgs(flag.NewFlagSet("report tiers", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	limits, err := c.client.QueryTierLimits(rest[0])
+	if err != nil {
+		return err
+	}
+	return c.out.print(limits, func() *table {
+		t := &table{headers: []string{"TIER", "CURRENCY", "PER PAYMENT", "DAILY", "MONTHLY", "DAILY PAYMENTS", "MONTHLY PAYMENTS"}}
+		for _, limit := range limits {
+			t.add(limit.Tier, limit.Currency, formatAmount(limit.PerTransactionMax), formatAmount(limit.DailyLimit), formatAmount(limit.MonthlyLimit),
+				strconv.Itoa(limit.MaxDailyPayments), strconv.Itoa(limit.MaxMonthlyPayments))
+		}
+		return t
+	})
+}
+
+func reportWatchlist(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("report watchlist", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	entries, err := c.client.QueryWatchlist()
+	if err != nil {
+		return err
+	}
+	return c.out.print(entries, func() *table {
+		t := &table{headers: []string{"TYPE", "VALUE", "REASON", "ADDED BY", "DATE"}}
+		for _, entry := range entries {
+			t.add(entry.EntryType, entry.Value, entry.Reason, entry.AddedBy, entry.Date)
+		}
+		return t
+	})
+}
+
+func reportPurposeCodes(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("report purpose-codes", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	codes, err := c.client.QueryPurposeCodes()
+	if err != nil {
+		return err
+	}
+	return c.out.print(codes, func() *table {
+		t := &table{headers: []string{"CODE", "DESCRIPTION", "ADDED BY", "DATE"}}
+		for _, code := range codes {
+			t.add(code.Code, code.Description, code.AddedBy, code.Date)
+		}
+		return t
+	})
+}
+
+func reportConfig(c *cli, args []string) error {
+	_, err := parseArgs(flag.NewFlagSet("report config", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	config, err := c.client.GetConfig()
+	if err != nil {
+		return err
+	}
+	return c.out.print(config, func() *table {
+		t := &table{headers: []string{"SETTING", "VALUE"}}
+		t.add("version", strconv.Itoa(config.Version))
+		t.add("supported currencies", orDash(strings.Join(config.SupportedCurrencies, ",")))
+		t.add("rate max age (s)", strconv.Itoa(config.RateMaxAgeSeconds))
+		t.add("default fee rate", formatRate(config.DefaultFeeRate))
+		t.add("default flat fee", formatAmount(config.DefaultFlatFee))
+		t.add("admin MSPs", orDash(strings.Join(config.AdminMSPs, ",")))
+		t.add("operation expiry (s)", strconv.Itoa(config.OperationExpirySeconds))
+		t.add("membership quorum (%)", strconv.Itoa(config.MembershipQuorumPercent))
+		return t
+	})
+}
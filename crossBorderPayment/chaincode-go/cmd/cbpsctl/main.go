@@ -0,0 +1,252 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Command cbpsctl operates the cross-border payment network from the command
+// line. It calls the bank chaincode through the peer CLI, so it needs the peer
+// binary and the CORE_PEER_* environment of the identity to act as, like the
+// test network's scripts.
+//
+// Usage:
+//
+//	cbpsctl [global flags] <group> <command> [flags] [arguments]
+//
+// Run cbpsctl -h for the global flags and cbpsctl help <group> for the
+// commands of a group.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/peercli"
+)
+
+// cli is what commands work with
+type cli struct {
+	client *cbpsclient.Client
+	out    *printer
+}
+
+// command is one subcommand of a group
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// group is a set of subcommands about one kind of object
+type group struct {
+	name     string
+	summary  string
+	commands []command
+}
+
+// usageError is returned for invalid command lines, which exit with status 2
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv, func(options peercli.Options) cbpsclient.Transport {
+		return peercli.New(options)
+	}))
+}
+
+// run executes a command line and returns the exit status. connect opens the
+// transport to the chaincode.
+func run(args []string, stdout io.Writer, stderr io.Writer, getenv func(string) string, connect func(peercli.Options) cbpsclient.Transport) int {
+	envOr := func(key string, fallback string) string {
+		if value := getenv(key); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	var options peercli.Options
+	var peerAddresses, tlsRootCertFiles string
+	global := flag.NewFlagSet("cbpsctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.StringVar(&options.PeerBinary, "peer", envOr("CBPS_PEER_BINARY", "peer"), "path of the peer CLI (CBPS_PEER_BINARY)")
+	global.StringVar(&options.Channel, "channel", envOr("CBPS_CHANNEL", "bankschannel"), "channel of the chaincode (CBPS_CHANNEL)")
+	global.StringVar(&options.Chaincode, "chaincode", envOr("CBPS_CHAINCODE", "bank"), "chaincode name (CBPS_CHAINCODE)")
+	global.StringVar(&options.Orderer, "orderer", getenv("CBPS_ORDERER"), "orderer address for submitted transactions (CBPS_ORDERER)")
+	global.StringVar(&options.OrdererTLSHostname, "orderer-tls-hostname", getenv("CBPS_ORDERER_TLS_HOSTNAME"), "host name to verify the orderer's TLS certificate against (CBPS_ORDERER_TLS_HOSTNAME)")
+	global.StringVar(&options.OrdererCAFile, "orderer-ca", getenv("CBPS_ORDERER_CA"), "PEM file of the orderer's TLS CA, enables TLS (CBPS_ORDERER_CA)")
+	global.StringVar(&peerAddresses, "peer-addresses", getenv("CBPS_PEER_ADDRESSES"), "comma-separated endorsing peers for submitted transactions (CBPS_PEER_ADDRESSES)")
+	global.StringVar(&tlsRootCertFiles, "tls-root-certs", getenv("CBPS_TLS_ROOT_CERTS"), "comma-separated TLS CA files of those peers, in the same order (CBPS_TLS_ROOT_CERTS)")
+	output := global.String("output", envOr("CBPS_OUTPUT", outputTable), "output format, table or json (CBPS_OUTPUT)")
+	dryRun := global.Bool("dry-run", false, "evaluate transactions that would be submitted, without committing them")
+	global.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: cbpsctl [global flags] <group> <command> [flags] [arguments]\n\nGroups:\n")
+		for _, g := range groups {
+			fmt.Fprintf(stderr, "  %-10s %s\n", g.name, g.summary)
+		}
+		fmt.Fprintf(stderr, "\nRun cbpsctl help <group> for its commands.\n\nGlobal flags:\n")
+		global.PrintDefaults()
+	}
+
+	err := global.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *output != outputTable && *output != outputJSON {
+		fmt.Fprintf(stderr, "Error: unknown output format %q, want %s or %s\n", *output, outputTable, outputJSON)
+		return 2
+	}
+	options.PeerAddresses = splitList(peerAddresses)
+	options.TLSRootCertFiles = splitList(tlsRootCertFiles)
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		global.Usage()
+		return 2
+	}
+	if rest[0] == "help" {
+		if len(rest) == 1 {
+			global.Usage()
+			return 0
+		}
+		return printGroupUsage(stderr, rest[1])
+	}
+	g, ok := findGroup(rest[0])
+	if !ok {
+		fmt.Fprintf(stderr, "Error: unknown group %q\n", rest[0])
+		global.Usage()
+		return 2
+	}
+	if len(rest) == 1 {
+		printGroupUsage(stderr, g.name)
+		return 2
+	}
+	cmd, ok := g.find(rest[1])
+	if !ok {
+		fmt.Fprintf(stderr, "Error: unknown command %q\n", g.name+" "+rest[1])
+		printGroupUsage(stderr, g.name)
+		return 2
+	}
+
+	transport := connect(options)
+	if *dryRun {
+		transport = dryRunTransport{Transport: transport}
+	}
+	c := &cli{
+		client: cbpsclient.New(transport),
+		out:    &printer{w: stdout, format: *output, dryRun: *dryRun},
+	}
+	err = cmd.run(c, rest[2:])
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(stderr, "Usage: cbpsctl %s %s %s\n", g.name, cmd.name, cmd.args)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// dryRunTransport evaluates submitted transactions, so they are endorsed and
+// checked by the contract but never committed
+type dryRunTransport struct {
+	cbpsclient.Transport
+}
+
+func (t dryRunTransport) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	return t.EvaluateTransaction(name, args...)
+}
+
+func findGroup(name string) (*group, bool) {
+	for i := range groups {
+		if groups[i].name == name {
+			return &groups[i], true
+		}
+	}
+	return nil, false
+}
+
+func (g *group) find(name string) (*command, bool) {
+	for i := range g.commands {
+		if g.commands[i].name == name {
+			return &g.commands[i], true
+		}
+	}
+	return nil, false
+}
+
+func printGroupUsage(w io.Writer, name string) int {
+	g, ok := findGroup(name)
+	if !ok {
+		fmt.Fprintf(w, "Error: unknown group %q\n", name)
+		return 2
+	}
+	fmt.Fprintf(w, "%s\n\nCommands:\n", g.summary)
+	for _, cmd := range g.commands {
+		fmt.Fprintf(w, "  cbpsctl %s %s %s\n      %s\n", g.name, cmd.name, cmd.args, cmd.summary)
+	}
+	return 0
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseArgs parses a command's flags and checks that exactly want positional
+// arguments follow them, or at least one when want is negative
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	rest := fs.Args()
+	switch {
+	case want < 0 && len(rest) == 0:
+		return nil, usagef("missing arguments")
+	case want >= 0 && len(rest) != want:
+		return nil, usagef("want %d arguments, got %d", want, len(rest))
+	}
+	return rest, nil
+}
+
+// requireFlags checks that the named flags were set
+func requireFlags(fs *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	var missing []string
+	for _, name := range names {
+		if !set[name] {
+			missing = append(missing, "--"+name)
+		}
+	}
+	if len(missing) > 0 {
+		return usagef("missing required flags %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
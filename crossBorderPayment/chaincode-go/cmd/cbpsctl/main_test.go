@@ -0,0 +1,104 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/cbpsclienttest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/peercli"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+// runCLI runs a command line against transport and returns its exit status
+// and output
+func runCLI(transport *cbpsclienttest.Transport, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, &stdout, &stderr, func(string) string { return "" }, func(peercli.Options) cbpsclient.Transport {
+		return transport
+	})
+	return status, stdout.String(), stderr.String()
+}
+
+func TestShowOutput(t *testing.T) {
+	transport := cbpsclienttest.NewTransport()
+	transport.Return("bank:QueryBank", &bank.Bank{BankID: "BANK1", Name: "First Bank", Country: "DE", Currency: "EUR", Reserves: 1500, AccountIDs: []string{"A1", "A2"}, Status: bank.BankActive})
+
+	status, stdout, stderr := runCLI(transport, "bank", "show", "BANK1")
+	if status != 0 {
+		t.Fatalf("status = %d: %s", status, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "BANK ") || strings.Join(strings.Fields(lines[1]), " ") != "BANK1 First Bank - DE EUR 1500.00 2 active" {
+		t.Fatalf("unexpected table:\n%s", stdout)
+	}
+
+	status, stdout, stderr = runCLI(transport, "--output", "json", "bank", "show", "BANK1")
+	if status != 0 {
+		t.Fatalf("status = %d: %s", status, stderr)
+	}
+	var banks []*bank.Bank
+	if err := json.Unmarshal([]byte(stdout), &banks); err != nil || len(banks) != 1 || banks[0].Reserves != 1500 {
+		t.Fatalf("unexpected JSON output %s (%v)", stdout, err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	transport := cbpsclienttest.NewTransport()
+	transport.Return("payment:CreatePayment", nil)
+	args := []string{"payment", "create", "--id", "P1", "--from", "A1", "--to", "A2", "--sender", "C1", "--receiver", "C2", "--amount", "25", "--date", "2024-01-02"}
+
+	status, stdout, stderr := runCLI(transport, append([]string{"--dry-run"}, args...)...)
+	if status != 0 {
+		t.Fatalf("status = %d: %s", status, stderr)
+	}
+	call, _ := transport.LastCall()
+	if call.Submit || call.Name != "payment:CreatePayment" || call.Args[5] != "25" || call.Args[6] != "1" {
+		t.Fatalf("unexpected call %+v", call)
+	}
+	if !strings.Contains(stdout, "dry run") {
+		t.Fatalf("output does not mention the dry run: %s", stdout)
+	}
+
+	status, _, stderr = runCLI(transport, args...)
+	if call, _ := transport.LastCall(); status != 0 || !call.Submit {
+		t.Fatalf("status = %d, call %+v: %s", status, call, stderr)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	transport := cbpsclienttest.NewTransport()
+	transport.Fail("account:QueryAccount", contracterrors.New(contracterrors.NotFound, "account A9 does not exist"))
+	transport.Return("admin:VerifyInvariants", &bank.InvariantReport{Holds: false, Totals: []*bank.CurrencyTotals{}, Violations: []string{"EUR balances are off by 10"}})
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStatus int
+		wantStderr string
+	}{
+		{name: "contract error", args: []string{"account", "show", "A9"}, wantStatus: 1, wantStderr: "NOT_FOUND: account A9 does not exist"},
+		{name: "invariants broken", args: []string{"report", "invariants"}, wantStatus: 1, wantStderr: "do not hold"},
+		{name: "missing flag", args: []string{"bank", "create", "--id", "BANK1"}, wantStatus: 2, wantStderr: "--name"},
+		{name: "missing argument", args: []string{"account", "show"}, wantStatus: 2, wantStderr: "Usage: cbpsctl account show"},
+		{name: "bad amount", args: []string{"account", "adjust-balance", "A1", "lots"}, wantStatus: 2, wantStderr: "invalid amount"},
+		{name: "unknown group", args: []string{"loans", "list"}, wantStatus: 2, wantStderr: "unknown group"},
+		{name: "unknown command", args: []string{"bank", "delete"}, wantStatus: 2, wantStderr: "unknown command"},
+		{name: "bad output", args: []string{"--output", "yaml", "bank", "show", "B"}, wantStatus: 2, wantStderr: "unknown output format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, stderr := runCLI(transport, tt.args...)
+			if status != tt.wantStatus || !strings.Contains(stderr, tt.wantStderr) {
+				t.Fatalf("status = %d, stderr %q, want %d and %q", status, stderr, tt.wantStatus, tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer writes results as JSON or as aligned tables
+type printer struct {
+	w      io.Writer
+	format string
+	dryRun bool
+}
+
+// table is the tabular form of a result
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+// print writes v as indented JSON, or the table built by toTable
+func (p *printer) print(v interface{}, toTable func() *table) error {
+	if p.format == outputJSON {
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %v", err)
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", out)
+		return err
+	}
+
+	t := toTable()
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.headers, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// done reports a transaction that returns no result
+func (p *printer) done(format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	if p.dryRun {
+		message += " (dry run, nothing was committed)"
+	}
+	if p.format == outputJSON {
+		return p.print(struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			DryRun  bool   `json:"dryRun"`
+		}{Status: "ok", Message: message, DryRun: p.dryRun}, nil)
+	}
+	_, err := fmt.Fprintln(p.w, message)
+	return err
+}
+
+func formatAmount(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+func formatRate(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// orDash shows empty optional fields as a dash so table columns stay aligned
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
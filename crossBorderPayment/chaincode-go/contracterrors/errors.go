@@ -0,0 +1,143 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package contracterrors is the catalogue of errors returned by the bank
+// contract. Every error carries a stable code and is serialised as a JSON
+// object in the error message, so clients can branch on the code with Parse
+// instead of matching the text, which may change.
+package contracterrors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// Code identifies a kind of failure. Codes never change once published.
+type Code string
+
+// Error codes
+const (
+	// NotFound means an object named by the request does not exist
+	NotFound Code = "NOT_FOUND"
+	// AlreadyExists means the request would create an object that exists
+	AlreadyExists Code = "ALREADY_EXISTS"
+	// InsufficientFunds means the sending account cannot cover the amount
+	InsufficientFunds Code = "INSUFFICIENT_FUNDS"
+	// Forbidden means the submitting client may not perform the operation
+	Forbidden Code = "FORBIDDEN"
+	// Validation means an argument failed validation. Details holds the
+	// failed rules.
+	Validation Code = "VALIDATION"
+	// RateStale means an exchange rate is older than the network accepts
+	RateStale Code = "RATE_STALE"
+	// InvalidState means the object's status does not allow the operation,
+	// for example a payment from a frozen account
+	InvalidState Code = "INVALID_STATE"
+	// LimitExceeded means a payment would take the sender over their limits.
+	// Details holds the breach.
+	LimitExceeded Code = "LIMIT_EXCEEDED"
+	// Internal is any other failure, such as an error reading the world state
+	Internal Code = "INTERNAL"
+)
+
+// Error is an error with a code, a human readable message and optional
+// details for the code
+type Error struct {
+	Code    Code        `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// New returns an error with code and a formatted message
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetails sets the error's details and returns it
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// Error returns the error as a JSON object
+func (e *Error) Error() string {
+	errJSON, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf(`{"code":%q,"message":%q}`, e.Code, e.Message)
+	}
+	return string(errJSON)
+}
+
+// From converts err to an *Error. Validation failures become Validation
+// errors with the failed rules as details, and errors without a code become
+// Internal. From returns nil if err is nil.
+func From(err error) error {
+	if err == nil {
+		return nil
+	}
+	return from(err)
+}
+
+func from(err error) *Error {
+	switch e := err.(type) {
+	case *Error:
+		return e
+	case validation.Errors:
+		return &Error{Code: Validation, Message: e.Error(), Details: e}
+	case *validation.Error:
+		return &Error{Code: Validation, Message: e.Error(), Details: validation.Errors{e}}
+	default:
+		return &Error{Code: Internal, Message: err.Error()}
+	}
+}
+
+// Wrap prefixes the message of err with a formatted description, keeping its
+// code and details. Wrap returns nil if err is nil.
+func Wrap(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	e := from(err)
+	return &Error{Code: e.Code, Message: fmt.Sprintf(format, args...) + ": " + e.Message, Details: e.Details}
+}
+
+// CodeOf returns the code of err, Internal for errors without one and the
+// empty code for nil
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	return from(err).Code
+}
+
+// Message returns the message of err without the code and details
+func Message(err error) string {
+	if err == nil {
+		return ""
+	}
+	return from(err).Message
+}
+
+// Parse finds the error object in a message received from the contract. Peers
+// and gateways prefix the contract's message with their own text, so the
+// object may start anywhere in message. A message without an error object is
+// returned as an Internal error.
+func Parse(message string) *Error {
+	for start := strings.Index(message, `{"code":`); start >= 0; {
+		var e Error
+		decoder := json.NewDecoder(strings.NewReader(message[start:]))
+		if decoder.Decode(&e) == nil && e.Code != "" {
+			return &e
+		}
+		next := strings.Index(message[start+1:], `{"code":`)
+		if next < 0 {
+			break
+		}
+		start += 1 + next
+	}
+	return &Error{Code: Internal, Message: message}
+}
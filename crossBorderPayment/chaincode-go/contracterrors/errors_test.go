@@ -0,0 +1,76 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contracterrors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+func TestErrorRoundTrip(t *testing.T) {
+	err := New(InsufficientFunds, "account %s cannot pay %v", "A1", 10.5).WithDetails(map[string]string{"accountID": "A1"})
+	want := `{"code":"INSUFFICIENT_FUNDS","message":"account A1 cannot pay 10.5","details":{"accountID":"A1"}}`
+	if err.Error() != want {
+		t.Fatalf("Error() = %s, want %s", err.Error(), want)
+	}
+
+	parsed := Parse("transaction returned with failure: " + err.Error())
+	if parsed.Code != InsufficientFunds || parsed.Message != "account A1 cannot pay 10.5" {
+		t.Fatalf("unexpected parsed error %+v", parsed)
+	}
+	if details, ok := parsed.Details.(map[string]interface{}); !ok || details["accountID"] != "A1" {
+		t.Fatalf("unexpected parsed details %#v", parsed.Details)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		message     string
+		wantCode    Code
+		wantMessage string
+	}{
+		{message: "peer unavailable", wantCode: Internal, wantMessage: "peer unavailable"},
+		{message: `truncated {"code":`, wantCode: Internal, wantMessage: `truncated {"code":`},
+		{message: `bad {"code": 1} then {"code":"NOT_FOUND","message":"x"}`, wantCode: NotFound, wantMessage: "x"},
+	}
+
+	for _, tt := range tests {
+		parsed := Parse(tt.message)
+		if parsed.Code != tt.wantCode || parsed.Message != tt.wantMessage {
+			t.Fatalf("Parse(%q) = %+v, want %s %q", tt.message, parsed, tt.wantCode, tt.wantMessage)
+		}
+	}
+}
+
+func TestFrom(t *testing.T) {
+	if From(nil) != nil {
+		t.Fatalf("From(nil) is not nil")
+	}
+
+	failed := validation.Check(validation.ID("bankID", ""), validation.Currency("currency", "ABC"))
+	err := From(failed)
+	if CodeOf(err) != Validation || len(err.(*Error).Details.(validation.Errors)) != 2 {
+		t.Fatalf("unexpected validation error %v", err)
+	}
+	if CodeOf(From(validation.ID("bankID", ""))) != Validation {
+		t.Fatalf("single rule failure is not a validation error")
+	}
+	if CodeOf(errors.New("disk full")) != Internal || Message(errors.New("disk full")) != "disk full" {
+		t.Fatalf("plain errors must be internal")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(nil, "context") != nil {
+		t.Fatalf("Wrap(nil) is not nil")
+	}
+
+	err := Wrap(New(NotFound, "account A1 does not exist"), "failed to update %s", "sender")
+	if CodeOf(err) != NotFound || Message(err) != "failed to update sender: account A1 does not exist" {
+		t.Fatalf("unexpected wrapped error %v", err)
+	}
+}
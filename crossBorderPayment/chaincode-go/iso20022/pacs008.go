@@ -0,0 +1,279 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package iso20022 reads and writes ISO 20022 pacs.008 (FI-to-FI customer
+// credit transfer) messages, version pacs.008.001.08 as used by CBPR+. The
+// types cover the elements the bank chaincode maps onto payments; other
+// elements of a message are ignored when it is read.
+//
+// ParsePacs008 checks a message against the rules the pacs.008.001.08 schema sets for
+// those elements, such as required elements, text lengths, code lists and the
+// patterns of BICs, IBANs and UETRs, and reports every failure as a
+// validation.Errors value whose fields are paths into the message.
+package iso20022
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// Pacs008Namespace is the XML namespace of pacs.008.001.08 documents
+const Pacs008Namespace = "urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08"
+
+// Settlement methods (SettlementMethod1Code)
+const (
+	SettlementInstructedAgent  = "INDA"
+	SettlementInstructingAgent = "INGA"
+	SettlementCover            = "COVE"
+	SettlementClearing         = "CLRG"
+)
+
+// Charge bearers (ChargeBearerType1Code)
+const (
+	ChargeBearerDebtor   = "DEBT"
+	ChargeBearerCreditor = "CRED"
+	ChargeBearerShared   = "SHAR"
+	ChargeBearerSLEV     = "SLEV"
+)
+
+// Document types of referred documents (DocumentType6Code) and creditor
+// references (DocumentType3Code) that payments map
+const (
+	DocumentCommercialInvoice = "CINV"
+	ReferenceStructured       = "SCOR"
+)
+
+// NotProvided is the end-to-end ID of payments whose originator gave none
+const NotProvided = "NOTPROVIDED"
+
+// Pacs008 is a pacs.008 document
+type Pacs008 struct {
+	XMLName        xml.Name               `xml:"Document"`
+	CreditTransfer FIToFICustomerTransfer `xml:"FIToFICstmrCdtTrf"`
+}
+
+// FIToFICustomerTransfer is the FIToFICstmrCdtTrf message
+type FIToFICustomerTransfer struct {
+	GroupHeader  GroupHeader                 `xml:"GrpHdr"`
+	Transactions []CreditTransferTransaction `xml:"CdtTrfTxInf"`
+}
+
+// GroupHeader identifies the message
+type GroupHeader struct {
+	MessageID            string         `xml:"MsgId"`
+	CreationDateTime     string         `xml:"CreDtTm"`
+	NumberOfTransactions string         `xml:"NbOfTxs"`
+	SettlementInfo       SettlementInfo `xml:"SttlmInf"`
+}
+
+// SettlementInfo says how the agents settle the message's transactions
+type SettlementInfo struct {
+	Method string `xml:"SttlmMtd"`
+}
+
+// CreditTransferTransaction is one payment of the message
+type CreditTransferTransaction struct {
+	PaymentID                 PaymentIdentification `xml:"PmtId"`
+	InterbankSettlementAmount Amount                `xml:"IntrBkSttlmAmt"`
+	InterbankSettlementDate   string                `xml:"IntrBkSttlmDt,omitempty"`
+	InstructedAmount          *Amount               `xml:"InstdAmt"`
+	ExchangeRate              string                `xml:"XchgRate,omitempty"`
+	ChargeBearer              string                `xml:"ChrgBr"`
+	Debtor                    Party                 `xml:"Dbtr"`
+	DebtorAccount             *CashAccount          `xml:"DbtrAcct"`
+	DebtorAgent               Agent                 `xml:"DbtrAgt"`
+	CreditorAgent             Agent                 `xml:"CdtrAgt"`
+	Creditor                  Party                 `xml:"Cdtr"`
+	CreditorAccount           *CashAccount          `xml:"CdtrAcct"`
+	Purpose                   *Purpose              `xml:"Purp"`
+	RemittanceInfo            *RemittanceInfo       `xml:"RmtInf"`
+}
+
+// PaymentIdentification holds the references of a transaction
+type PaymentIdentification struct {
+	InstructionID string `xml:"InstrId,omitempty"`
+	EndToEndID    string `xml:"EndToEndId"`
+	TransactionID string `xml:"TxId,omitempty"`
+	UETR          string `xml:"UETR,omitempty"`
+}
+
+// Amount is a decimal amount in a currency
+type Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// Party is a debtor or creditor
+type Party struct {
+	Name string   `xml:"Nm,omitempty"`
+	ID   *PartyID `xml:"Id"`
+}
+
+// PartyID identifies an organisation or a person
+type PartyID struct {
+	Organisation *GenericIDs `xml:"OrgId"`
+	Private      *GenericIDs `xml:"PrvtId"`
+}
+
+// GenericIDs are identifiers issued under some scheme
+type GenericIDs struct {
+	Other []GenericID `xml:"Othr"`
+}
+
+// GenericID is an identifier that is not a BIC, LEI or IBAN
+type GenericID struct {
+	ID string `xml:"Id"`
+}
+
+// CashAccount identifies an account by IBAN or by another identifier
+type CashAccount struct {
+	ID       AccountID `xml:"Id"`
+	Currency string    `xml:"Ccy,omitempty"`
+}
+
+// AccountID holds exactly one of IBAN and Other
+type AccountID struct {
+	IBAN  string     `xml:"IBAN,omitempty"`
+	Other *GenericID `xml:"Othr"`
+}
+
+// Agent is a financial institution taking part in the transfer
+type Agent struct {
+	FinancialInstitution FinancialInstitution `xml:"FinInstnId"`
+}
+
+// FinancialInstitution identifies an agent by BIC, name or another identifier
+type FinancialInstitution struct {
+	BICFI string     `xml:"BICFI,omitempty"`
+	Name  string     `xml:"Nm,omitempty"`
+	Other *GenericID `xml:"Othr"`
+}
+
+// Purpose is the purpose of a transaction as an external purpose code
+type Purpose struct {
+	Code string `xml:"Cd"`
+}
+
+// RemittanceInfo is the remittance information of a transaction, as text, as
+// structured references, or both
+type RemittanceInfo struct {
+	Unstructured []string               `xml:"Ustrd"`
+	Structured   []StructuredRemittance `xml:"Strd"`
+}
+
+// StructuredRemittance holds the documents a transaction pays and the
+// creditor's reference for it
+type StructuredRemittance struct {
+	ReferredDocuments []ReferredDocument `xml:"RfrdDocInf"`
+	CreditorReference *CreditorReference `xml:"CdtrRefInf"`
+}
+
+// ReferredDocument identifies a document, such as an invoice, by its number
+type ReferredDocument struct {
+	Type   *DocumentType `xml:"Tp"`
+	Number string        `xml:"Nb,omitempty"`
+}
+
+// DocumentType is the type of a referred document or a creditor reference,
+// as a code or as a proprietary value. Issuer is only used by creditor
+// references.
+type DocumentType struct {
+	Code        string `xml:"CdOrPrtry>Cd,omitempty"`
+	Proprietary string `xml:"CdOrPrtry>Prtry,omitempty"`
+	Issuer      string `xml:"Issr,omitempty"`
+}
+
+// CreditorReference is the reference the creditor gave the debtor to quote
+type CreditorReference struct {
+	Type      *DocumentType `xml:"Tp"`
+	Reference string        `xml:"Ref,omitempty"`
+}
+
+// ParsePacs008 reads a pacs.008.001.08 document and checks it against the
+// schema rules
+func ParsePacs008(data []byte) (*Pacs008, error) {
+	var doc Pacs008
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, validation.Errors{schemaError("Document", "", "is not well-formed XML: %v", err)}
+	}
+	err = doc.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Marshal writes the document as XML in the pacs.008.001.08 namespace
+func (d *Pacs008) Marshal() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&out)
+	encoder.Indent("", "  ")
+	err := encoder.EncodeElement(d, xml.StartElement{
+		Name: xml.Name{Local: "Document"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Pacs008Namespace}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pacs.008 document: %v", err)
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// ParseAmount returns the value of an amount
+func ParseAmount(value string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%q is not a decimal number", value)
+	}
+	return f, nil
+}
+
+// FormatAmount writes an amount with at most the 5 fraction digits the
+// schema allows
+func FormatAmount(amount float64) string {
+	return formatDecimal(amount, 18, 5)
+}
+
+// FormatRate writes an exchange rate with at most the 11 digits, 10 of them
+// after the point, that the schema allows
+func FormatRate(rate float64) string {
+	return formatDecimal(rate, 11, 10)
+}
+
+func formatDecimal(f float64, totalDigits int, fractionDigits int) string {
+	intDigits := len(strconv.FormatFloat(math.Trunc(math.Abs(f)), 'f', 0, 64))
+	if intDigits+fractionDigits > totalDigits {
+		fractionDigits = totalDigits - intDigits
+	}
+	if fractionDigits < 0 {
+		fractionDigits = 0
+	}
+	s := strconv.FormatFloat(f, 'f', fractionDigits, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// ParseDateTime reads an ISODateTime, which may leave out the time zone.
+// Times without a zone are taken as UTC.
+func ParseDateTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an ISO date and time", value)
+}
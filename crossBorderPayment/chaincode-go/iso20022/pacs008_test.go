@@ -0,0 +1,146 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package iso20022
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const message = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08">
+  <FIToFICstmrCdtTrf>
+    <GrpHdr>
+      <MsgId>MSG-1</MsgId>
+      <CreDtTm>2024-03-01T10:00:00.123</CreDtTm>
+      <NbOfTxs>1</NbOfTxs>
+      <SttlmInf><SttlmMtd>INDA</SttlmMtd></SttlmInf>
+    </GrpHdr>
+    <CdtTrfTxInf>
+      <PmtId>
+        <InstrId>P1</InstrId>
+        <EndToEndId>NOTPROVIDED</EndToEndId>
+        <UETR>eb6305c9-1f7f-49de-aed0-16487c27b42d</UETR>
+      </PmtId>
+      <PmtTpInf><SvcLvl><Cd>G001</Cd></SvcLvl></PmtTpInf>
+      <IntrBkSttlmAmt Ccy="EUR">1234.5</IntrBkSttlmAmt>
+      <IntrBkSttlmDt>2024-03-01</IntrBkSttlmDt>
+      <ChrgBr>DEBT</ChrgBr>
+      <Dbtr><Nm>Alice Smith</Nm><PstlAdr><Ctry>DE</Ctry></PstlAdr></Dbtr>
+      <DbtrAcct><Id><IBAN>DE89370400440532013000</IBAN></Id></DbtrAcct>
+      <DbtrAgt><FinInstnId><BICFI>COBADEFFXXX</BICFI></FinInstnId></DbtrAgt>
+      <CdtrAgt><FinInstnId><BICFI>BNPAFRPP</BICFI></FinInstnId></CdtrAgt>
+      <Cdtr><Nm>Bob Jones</Nm><Id><OrgId><Othr><Id>C2</Id></Othr></OrgId></Id></Cdtr>
+      <CdtrAcct><Id><Othr><Id>A2</Id></Othr></Id></CdtrAcct>
+      <Purp><Cd>SUPP</Cd></Purp>
+      <RmtInf>
+        <Ustrd>March delivery</Ustrd>
+        <Strd>
+          <RfrdDocInf><Tp><CdOrPrtry><Cd>CINV</Cd></CdOrPrtry></Tp><Nb>INV-17</Nb></RfrdDocInf>
+          <RfrdDocInf><Tp><CdOrPrtry><Cd>CINV</Cd></CdOrPrtry></Tp><Nb>INV-18</Nb></RfrdDocInf>
+          <CdtrRefInf><Tp><CdOrPrtry><Cd>SCOR</Cd></CdOrPrtry><Issr>ISO</Issr></Tp><Ref>RF18539007547034</Ref></CdtrRefInf>
+        </Strd>
+      </RmtInf>
+    </CdtTrfTxInf>
+  </FIToFICstmrCdtTrf>
+</Document>`
+
+func TestParsePacs008(t *testing.T) {
+	doc, err := ParsePacs008([]byte(message))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tx := doc.CreditTransfer.Transactions[0]
+	if tx.InterbankSettlementAmount != (Amount{Currency: "EUR", Value: "1234.5"}) || tx.DebtorAccount.ID.IBAN != "DE89370400440532013000" ||
+		tx.DebtorAgent.FinancialInstitution.BICFI != "COBADEFFXXX" || tx.Creditor.ID.Organisation.Other[0].ID != "C2" {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	structured := tx.RemittanceInfo.Structured[0]
+	if tx.Purpose.Code != "SUPP" || len(structured.ReferredDocuments) != 2 || structured.ReferredDocuments[1].Number != "INV-18" ||
+		structured.CreditorReference.Type.Code != ReferenceStructured || structured.CreditorReference.Reference != "RF18539007547034" {
+		t.Fatalf("unexpected remittance %+v %+v", tx.Purpose, tx.RemittanceInfo)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		replace    []string
+		wantFields []string
+	}{
+		{name: "BIC", replace: []string{"BNPAFRPP", "BNPA-FR"}, wantFields: []string{"CdtTrfTxInf[0]/CdtrAgt/FinInstnId/BICFI"}},
+		{name: "IBAN", replace: []string{"DE89370400440532013000", "DE 89 3704 0044 0532 0130 00"}, wantFields: []string{"CdtTrfTxInf[0]/DbtrAcct/Id/IBAN"}},
+		{name: "fraction digits", replace: []string{"1234.5<", "1234.123456<"}, wantFields: []string{"CdtTrfTxInf[0]/IntrBkSttlmAmt"}},
+		{name: "negative amount", replace: []string{"1234.5<", "-1<"}, wantFields: []string{"CdtTrfTxInf[0]/IntrBkSttlmAmt"}},
+		{name: "currency", replace: []string{`Ccy="EUR"`, `Ccy="euro"`}, wantFields: []string{"CdtTrfTxInf[0]/IntrBkSttlmAmt/@Ccy"}},
+		{name: "transaction count", replace: []string{"<NbOfTxs>1<", "<NbOfTxs>2<"}, wantFields: []string{"GrpHdr/NbOfTxs"}},
+		{name: "Max35Text", replace: []string{"<InstrId>P1<", "<InstrId>" + strings.Repeat("P", 36) + "<"}, wantFields: []string{"CdtTrfTxInf[0]/PmtId/InstrId"}},
+		{name: "missing required", replace: []string{"<EndToEndId>NOTPROVIDED</EndToEndId>", "", "<SttlmMtd>INDA</SttlmMtd>", ""}, wantFields: []string{"GrpHdr/SttlmInf/SttlmMtd", "CdtTrfTxInf[0]/PmtId/EndToEndId"}},
+		{name: "purpose", replace: []string{"<Cd>SUPP<", "<Cd>SUPPLIER<"}, wantFields: []string{"CdtTrfTxInf[0]/Purp/Cd"}},
+		{name: "document type", replace: []string{"<Cd>SCOR</Cd>", "<Cd>SCOR</Cd><Prtry>REF</Prtry>"}, wantFields: []string{"CdtTrfTxInf[0]/RmtInf/Strd[0]/CdtrRefInf/Tp/CdOrPrtry"}},
+		{name: "dates", replace: []string{"2024-03-01T10:00:00.123", "yesterday", "<IntrBkSttlmDt>2024-03-01", "<IntrBkSttlmDt>01.03.2024"}, wantFields: []string{"GrpHdr/CreDtTm", "CdtTrfTxInf[0]/IntrBkSttlmDt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePacs008([]byte(strings.NewReplacer(tt.replace...).Replace(message)))
+			failed, ok := err.(validation.Errors)
+			if !ok {
+				t.Fatalf("err = %v, want validation errors", err)
+			}
+			var fields []string
+			for _, e := range failed {
+				fields = append(fields, e.Field)
+				if e.Rule != RuleSchema {
+					t.Fatalf("rule = %s", e.Rule)
+				}
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("failed fields %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	doc, err := ParsePacs008([]byte(message))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := doc.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(out), `<Document xmlns="`+Pacs008Namespace+`">`) {
+		t.Fatalf("unexpected root element:\n%s", out)
+	}
+	again, err := ParsePacs008(out)
+	if err != nil {
+		t.Fatalf("marshalled document is invalid: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(again.CreditTransfer, doc.CreditTransfer) {
+		t.Fatalf("round trip changed the document:\n%s", out)
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{got: FormatAmount(90.00000000001), want: "90"},
+		{got: FormatAmount(12.345678), want: "12.34568"},
+		{got: FormatRate(0.8888888888888), want: "0.8888888889"},
+		{got: FormatRate(123.456789012345), want: "123.45678901"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Fatalf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package iso20022
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// RuleSchema is the validation rule reported for messages that break the
+// pacs.008 schema
+const RuleSchema = "iso20022_schema"
+
+// Patterns of the schema's simple types
+var (
+	bicPattern      = regexp.MustCompile(`^[A-Z0-9]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	ibanPattern     = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[a-zA-Z0-9]{1,30}$`)
+	uetrPattern     = regexp.MustCompile(`^[a-f0-9]{8}-[a-f0-9]{4}-4[a-f0-9]{3}-[89ab][a-f0-9]{3}-[a-f0-9]{12}$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	numericPattern  = regexp.MustCompile(`^[0-9]{1,15}$`)
+	decimalPattern  = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+func schemaError(field string, value string, format string, args ...interface{}) *validation.Error {
+	return &validation.Error{Field: field, Rule: RuleSchema, Value: value, Message: fmt.Sprintf(format, args...)}
+}
+
+// checker gathers the schema failures of one document
+type checker struct {
+	failed validation.Errors
+}
+
+func (c *checker) fail(field string, value string, format string, args ...interface{}) {
+	c.failed = append(c.failed, schemaError(field, value, format, args...))
+}
+
+// text checks a MaxNText element, which must hold 1 to max characters if
+// present
+func (c *checker) text(field string, value string, max int, required bool) {
+	if value == "" {
+		if required {
+			c.fail(field, value, "is required")
+		}
+		return
+	}
+	if utf8.RuneCountInString(value) > max {
+		c.fail(field, value, "must be at most %d characters", max)
+	}
+}
+
+func (c *checker) pattern(field string, value string, pattern *regexp.Regexp, description string) {
+	if value != "" && !pattern.MatchString(value) {
+		c.fail(field, value, "is not %s", description)
+	}
+}
+
+func (c *checker) code(field string, value string, codes ...string) {
+	for _, code := range codes {
+		if value == code {
+			return
+		}
+	}
+	c.fail(field, value, "must be one of %s", strings.Join(codes, ", "))
+}
+
+// decimal checks a decimal against the schema's totalDigits and
+// fractionDigits facets
+func (c *checker) decimal(field string, value string, totalDigits int, fractionDigits int) {
+	if !decimalPattern.MatchString(value) {
+		c.fail(field, value, "is not a non-negative decimal number")
+		return
+	}
+	intPart, fraction := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		intPart, fraction = value[:i], strings.TrimRight(value[i+1:], "0")
+	}
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(fraction) > fractionDigits {
+		c.fail(field, value, "must have at most %d digits after the decimal point", fractionDigits)
+	} else if len(intPart)+len(fraction) > totalDigits {
+		c.fail(field, value, "must have at most %d digits", totalDigits)
+	}
+}
+
+func (c *checker) amount(field string, amount *Amount) {
+	if !currencyPattern.MatchString(amount.Currency) {
+		c.fail(field+"/@Ccy", amount.Currency, "is not a currency code")
+	}
+	c.decimal(field, strings.TrimSpace(amount.Value), 18, 5)
+}
+
+func (c *checker) party(field string, party *Party) {
+	c.text(field+"/Nm", party.Name, 140, false)
+	if party.ID == nil {
+		return
+	}
+	switch {
+	case party.ID.Organisation != nil && party.ID.Private != nil:
+		c.fail(field+"/Id", "", "must hold either OrgId or PrvtId")
+	case party.ID.Organisation != nil:
+		c.genericIDs(field+"/Id/OrgId", party.ID.Organisation)
+	case party.ID.Private != nil:
+		c.genericIDs(field+"/Id/PrvtId", party.ID.Private)
+	default:
+		c.fail(field+"/Id", "", "must hold OrgId or PrvtId")
+	}
+}
+
+func (c *checker) genericIDs(field string, ids *GenericIDs) {
+	for i, other := range ids.Other {
+		c.text(fmt.Sprintf("%s/Othr[%d]/Id", field, i), other.ID, 35, true)
+	}
+}
+
+func (c *checker) account(field string, account *CashAccount) {
+	if account == nil {
+		return
+	}
+	switch {
+	case account.ID.IBAN != "" && account.ID.Other != nil:
+		c.fail(field+"/Id", "", "must hold either IBAN or Othr")
+	case account.ID.IBAN != "":
+		c.pattern(field+"/Id/IBAN", account.ID.IBAN, ibanPattern, "an IBAN")
+	case account.ID.Other != nil:
+		c.text(field+"/Id/Othr/Id", account.ID.Other.ID, 34, true)
+	default:
+		c.fail(field+"/Id", "", "must hold IBAN or Othr")
+	}
+	c.pattern(field+"/Ccy", account.Currency, currencyPattern, "a currency code")
+}
+
+func (c *checker) structuredRemittance(field string, structured *StructuredRemittance) {
+	for i, document := range structured.ReferredDocuments {
+		documentField := fmt.Sprintf("%s/RfrdDocInf[%d]", field, i)
+		c.documentType(documentField+"/Tp", document.Type)
+		c.text(documentField+"/Nb", document.Number, 35, false)
+	}
+	if reference := structured.CreditorReference; reference != nil {
+		c.documentType(field+"/CdtrRefInf/Tp", reference.Type)
+		c.text(field+"/CdtrRefInf/Ref", reference.Reference, 35, false)
+	}
+}
+
+func (c *checker) documentType(field string, documentType *DocumentType) {
+	if documentType == nil {
+		return
+	}
+	if (documentType.Code == "") == (documentType.Proprietary == "") {
+		c.fail(field+"/CdOrPrtry", "", "must hold exactly one of Cd and Prtry")
+	}
+	c.text(field+"/CdOrPrtry/Cd", documentType.Code, 4, false)
+	c.text(field+"/CdOrPrtry/Prtry", documentType.Proprietary, 35, false)
+	c.text(field+"/Issr", documentType.Issuer, 35, false)
+}
+
+func (c *checker) agent(field string, agent *Agent) {
+	institution := &agent.FinancialInstitution
+	if institution.BICFI == "" && institution.Name == "" && institution.Other == nil {
+		c.fail(field+"/FinInstnId", "", "must identify the agent")
+	}
+	c.pattern(field+"/FinInstnId/BICFI", institution.BICFI, bicPattern, "a BIC")
+	c.text(field+"/FinInstnId/Nm", institution.Name, 140, false)
+	if institution.Other != nil {
+		c.text(field+"/FinInstnId/Othr/Id", institution.Other.ID, 35, true)
+	}
+}
+
+// Validate checks the document against the pacs.008.001.08 schema rules for
+// the elements of this package
+func (d *Pacs008) Validate() error {
+	c := &checker{}
+	if d.XMLName.Space != Pacs008Namespace {
+		c.fail("Document/@xmlns", d.XMLName.Space, "must be %s", Pacs008Namespace)
+	}
+
+	header := &d.CreditTransfer.GroupHeader
+	c.text("GrpHdr/MsgId", header.MessageID, 35, true)
+	if _, err := ParseDateTime(header.CreationDateTime); err != nil {
+		c.fail("GrpHdr/CreDtTm", header.CreationDateTime, "is not an ISO date and time")
+	}
+	if !numericPattern.MatchString(header.NumberOfTransactions) {
+		c.fail("GrpHdr/NbOfTxs", header.NumberOfTransactions, "must be 1 to 15 digits")
+	} else if n, _ := strconv.Atoi(header.NumberOfTransactions); n != len(d.CreditTransfer.Transactions) {
+		c.fail("GrpHdr/NbOfTxs", header.NumberOfTransactions, "does not match the %d CdtTrfTxInf elements", len(d.CreditTransfer.Transactions))
+	}
+	c.code("GrpHdr/SttlmInf/SttlmMtd", header.SettlementInfo.Method, SettlementInstructedAgent, SettlementInstructingAgent, SettlementCover, SettlementClearing)
+
+	if len(d.CreditTransfer.Transactions) == 0 {
+		c.fail("CdtTrfTxInf", "", "is required")
+	}
+	for i := range d.CreditTransfer.Transactions {
+		tx := &d.CreditTransfer.Transactions[i]
+		prefix := fmt.Sprintf("CdtTrfTxInf[%d]/", i)
+
+		c.text(prefix+"PmtId/InstrId", tx.PaymentID.InstructionID, 35, false)
+		c.text(prefix+"PmtId/EndToEndId", tx.PaymentID.EndToEndID, 35, true)
+		c.text(prefix+"PmtId/TxId", tx.PaymentID.TransactionID, 35, false)
+		c.pattern(prefix+"PmtId/UETR", tx.PaymentID.UETR, uetrPattern, "a UUID version 4 in lower case")
+
+		c.amount(prefix+"IntrBkSttlmAmt", &tx.InterbankSettlementAmount)
+		if tx.InterbankSettlementDate != "" {
+			if _, err := time.Parse("2006-01-02", tx.InterbankSettlementDate); err != nil {
+				c.fail(prefix+"IntrBkSttlmDt", tx.InterbankSettlementDate, "is not an ISO date")
+			}
+		}
+		if tx.InstructedAmount != nil {
+			c.amount(prefix+"InstdAmt", tx.InstructedAmount)
+		}
+		if tx.ExchangeRate != "" {
+			c.decimal(prefix+"XchgRate", tx.ExchangeRate, 11, 10)
+		}
+		c.code(prefix+"ChrgBr", tx.ChargeBearer, ChargeBearerDebtor, ChargeBearerCreditor, ChargeBearerShared, ChargeBearerSLEV)
+
+		c.party(prefix+"Dbtr", &tx.Debtor)
+		c.account(prefix+"DbtrAcct", tx.DebtorAccount)
+		c.agent(prefix+"DbtrAgt", &tx.DebtorAgent)
+		c.agent(prefix+"CdtrAgt", &tx.CreditorAgent)
+		c.party(prefix+"Cdtr", &tx.Creditor)
+		c.account(prefix+"CdtrAcct", tx.CreditorAccount)
+		if tx.Purpose != nil {
+			c.text(prefix+"Purp/Cd", tx.Purpose.Code, 4, true)
+		}
+		if tx.RemittanceInfo != nil {
+			for j, line := range tx.RemittanceInfo.Unstructured {
+				c.text(fmt.Sprintf("%sRmtInf/Ustrd[%d]", prefix, j), line, 140, true)
+			}
+			for j, structured := range tx.RemittanceInfo.Structured {
+				c.structuredRemittance(fmt.Sprintf("%sRmtInf/Strd[%d]", prefix, j), &structured)
+			}
+		}
+	}
+
+	if len(c.failed) > 0 {
+		return c.failed
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader names the header clients set to make a write safe to
+// retry. A retried request with the same key gets the first response back
+// instead of running again.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed for a retried request
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// DefaultIdempotencyTTL is how long responses are kept for retries
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyClaimTimeout is how long a key stays claimed by a request that
+// never finished, after which a retry runs the request again
+const idempotencyClaimTimeout = 5 * time.Minute
+
+// recordedResponse is a response kept for an idempotency key
+type recordedResponse struct {
+	fingerprint string
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore keeps the responses of writes by identity and key
+type idempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	responses map[string]*recordedResponse
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &idempotencyStore{ttl: ttl, now: time.Now, responses: make(map[string]*recordedResponse)}
+}
+
+// idempotencyState is what begin found for a key
+type idempotencyState int
+
+const (
+	// idempotencyNew means the request should run and its response be
+	// recorded with finish
+	idempotencyNew idempotencyState = iota
+	// idempotencyReplay means the request already ran, the response is
+	// returned
+	idempotencyReplay
+	// idempotencyInProgress means the first request with the key is still
+	// running
+	idempotencyInProgress
+	// idempotencyMismatch means the key was used for a different request
+	idempotencyMismatch
+)
+
+// fingerprint identifies a request so that a key cannot be reused for another
+// one
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// begin claims key for a request unless it was used before. A claim that is
+// neither finished nor released expires after idempotencyClaimTimeout.
+func (s *idempotencyStore) begin(key string, fingerprint string) (idempotencyState, *recordedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, response := range s.responses {
+		if now.After(response.expires) {
+			delete(s.responses, k)
+		}
+	}
+
+	response, ok := s.responses[key]
+	switch {
+	case !ok:
+		s.responses[key] = &recordedResponse{fingerprint: fingerprint, expires: now.Add(idempotencyClaimTimeout)}
+		return idempotencyNew, nil
+	case response.fingerprint != fingerprint:
+		return idempotencyMismatch, nil
+	case !response.done:
+		return idempotencyInProgress, nil
+	default:
+		return idempotencyReplay, response
+	}
+}
+
+// finish records the response for key. Server errors are not recorded, so a
+// retry runs the request again.
+func (s *idempotencyStore) finish(key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status >= http.StatusInternalServerError {
+		delete(s.responses, key)
+		return
+	}
+	response := s.responses[key]
+	response.done = true
+	response.status = status
+	response.header = header.Clone()
+	response.body = body
+	response.expires = s.now().Add(s.ttl)
+}
+
+// release drops the claim on key if its response was not recorded, so a
+// retry of a request that failed without a response runs again
+func (s *idempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if response, ok := s.responses[key]; ok && !response.done {
+		delete(s.responses, key)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnknownIdentity is returned by wallets for labels they do not hold
+var ErrUnknownIdentity = errors.New("unknown identity")
+
+// Identity is an enrolled client the service can act as
+type Identity struct {
+	Label       string
+	MSPID       string
+	Certificate []byte
+	PrivateKey  []byte
+}
+
+// Wallet holds the identities of the service's callers
+type Wallet interface {
+	Get(label string) (*Identity, error)
+}
+
+// FileWallet reads identities from a directory of <label>.id files in the
+// format the Node application's wallet writes
+type FileWallet struct {
+	Dir string
+}
+
+// walletEntry is the JSON of a wallet identity file
+type walletEntry struct {
+	Credentials struct {
+		Certificate string `json:"certificate"`
+		PrivateKey  string `json:"privateKey"`
+	} `json:"credentials"`
+	MSPID string `json:"mspId"`
+	Type  string `json:"type"`
+}
+
+// Get reads the identity stored under label
+func (w *FileWallet) Get(label string) (*Identity, error) {
+	if label == "" || label != filepath.Base(label) || strings.HasPrefix(label, ".") {
+		return nil, ErrUnknownIdentity
+	}
+	entryJSON, err := ioutil.ReadFile(filepath.Join(w.Dir, label+".id"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrUnknownIdentity
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity %s: %v", label, err)
+	}
+	var entry walletEntry
+	err = json.Unmarshal(entryJSON, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse identity %s: %v", label, err)
+	}
+	if entry.Type != "X.509" || entry.MSPID == "" || entry.Credentials.Certificate == "" || entry.Credentials.PrivateKey == "" {
+		return nil, fmt.Errorf("identity %s is not an X.509 identity with an MSP ID, certificate and key", label)
+	}
+	return &Identity{
+		Label:       label,
+		MSPID:       entry.MSPID,
+		Certificate: []byte(entry.Credentials.Certificate),
+		PrivateKey:  []byte(entry.Credentials.PrivateKey),
+	}, nil
+}
+
+// Authenticator returns the wallet label of the identity a request acts as,
+// or an error if the request is not authenticated
+type Authenticator func(r *http.Request) (string, error)
+
+// APIKeys authenticates requests by the bearer token in their Authorization
+// header. keys maps each API key to a wallet label.
+func APIKeys(keys map[string]string) Authenticator {
+	return func(r *http.Request) (string, error) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || token == r.Header.Get("Authorization") {
+			return "", errors.New("missing bearer token")
+		}
+		for key, label := range keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+				return label, nil
+			}
+		}
+		return "", errors.New("unknown API key")
+	}
+}
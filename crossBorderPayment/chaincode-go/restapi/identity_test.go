@@ -0,0 +1,44 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileWallet(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"appUser.id": `{"credentials":{"certificate":"CERT","privateKey":"KEY"},"mspId":"Org1MSP","type":"X.509","version":1}`,
+		"broken.id":  `{"credentials":{"certificate":"CERT"},"mspId":"Org1MSP","type":"X.509"}`,
+	}
+	for name, contents := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wallet := &FileWallet{Dir: dir}
+
+	identity, err := wallet.Get("appUser")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if identity.Label != "appUser" || identity.MSPID != "Org1MSP" || string(identity.Certificate) != "CERT" || string(identity.PrivateKey) != "KEY" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	for _, label := range []string{"nobody", "", "../appUser", ".hidden"} {
+		if _, err := wallet.Get(label); !errors.Is(err, ErrUnknownIdentity) {
+			t.Fatalf("Get(%q): err = %v, want ErrUnknownIdentity", label, err)
+		}
+	}
+	if _, err := wallet.Get("broken"); err == nil || errors.Is(err, ErrUnknownIdentity) {
+		t.Fatalf("incomplete identity: err = %v", err)
+	}
+}
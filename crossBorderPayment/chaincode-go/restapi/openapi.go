@@ -0,0 +1,316 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SpecPath is where the server publishes its OpenAPI document
+const SpecPath = "/openapi.json"
+
+// metadataTransaction returns the contract metadata the spec is generated from
+const metadataTransaction = "org.hyperledger.fabric:GetMetadata"
+
+// contractMetadata is the part of the contract metadata the spec uses
+type contractMetadata struct {
+	Info      map[string]interface{} `json:"info"`
+	Contracts map[string]struct {
+		Transactions []struct {
+			Name    string      `json:"name"`
+			Returns interface{} `json:"returns"`
+		} `json:"transactions"`
+	} `json:"contracts"`
+	Components struct {
+		Schemas map[string]map[string]interface{} `json:"schemas"`
+	} `json:"components"`
+}
+
+// returnsOf returns the schema of a namespaced transaction's result, nil if
+// it returns nothing, and false if the contract has no such transaction
+func (m *contractMetadata) returnsOf(transaction string) (interface{}, bool) {
+	contractName, name := "", transaction
+	if i := strings.Index(transaction, ":"); i >= 0 {
+		contractName, name = transaction[:i], transaction[i+1:]
+	}
+	contract, ok := m.Contracts[contractName]
+	if !ok {
+		return nil, false
+	}
+	for _, tx := range contract.Transactions {
+		if tx.Name == name {
+			return tx.Returns, true
+		}
+	}
+	return nil, false
+}
+
+// GenerateOpenAPI returns an OpenAPI 3 document of the API. The schemas of
+// the chaincode's objects and of the transactions' results come from the
+// contract metadata, so the document follows the deployed contract.
+func GenerateOpenAPI(metadataJSON []byte) ([]byte, error) {
+	var metadata contractMetadata
+	err := json.Unmarshal(metadataJSON, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the contract metadata: %v", err)
+	}
+
+	schemas := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"code", "message"},
+			"properties": map[string]interface{}{
+				"code":    map[string]interface{}{"type": "string"},
+				"message": map[string]interface{}{"type": "string"},
+				"details": map[string]interface{}{},
+			},
+		},
+	}
+	for name, schema := range metadata.Components.Schemas {
+		copied := make(map[string]interface{}, len(schema))
+		for k, v := range schema {
+			// $id is JSON Schema that OpenAPI 3.0 does not allow
+			if k != "$id" {
+				copied[k] = v
+			}
+		}
+		copied["type"] = "object"
+		schemas[name] = copied
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, rt := range routes {
+		operation, err := openAPIOperation(rt, &metadata)
+		if err != nil {
+			return nil, err
+		}
+		if paths[rt.pattern] == nil {
+			paths[rt.pattern] = make(map[string]interface{})
+		}
+		paths[rt.pattern][strings.ToLower(rt.method)] = operation
+	}
+
+	title, version := "Cross-border payments", "1.0.0"
+	if v, ok := metadata.Info["title"].(string); ok && v != "" {
+		title = v
+	}
+	if v, ok := metadata.Info["version"].(string); ok && v != "" {
+		version = v
+	}
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]interface{}{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"apiKey": []string{}}},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func openAPIOperation(rt *route, metadata *contractMetadata) (map[string]interface{}, error) {
+	returns, ok := metadata.returnsOf(rt.transaction)
+	if !ok {
+		return nil, fmt.Errorf("route %s %s calls %s, which the contract does not have", rt.method, rt.pattern, rt.transaction)
+	}
+	if rt.returns != "" {
+		returns, ok = metadata.returnsOf(rt.returns)
+		if !ok {
+			return nil, fmt.Errorf("route %s %s returns %s, which the contract does not have", rt.method, rt.pattern, rt.returns)
+		}
+	}
+	if rt.result != nil {
+		returns = schemaOf(reflect.TypeOf(rt.result), metadata)
+	}
+
+	operation := map[string]interface{}{
+		"summary":              rt.summary,
+		"operationId":          operationID(rt),
+		"x-fabric-transaction": rt.transaction,
+		"responses":            openAPIResponses(rt, returns),
+	}
+
+	var parameters []interface{}
+	for _, segment := range strings.Split(rt.pattern, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     strings.Trim(segment, "{}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+	}
+	if rt.method != http.MethodGet {
+		parameters = append(parameters, map[string]interface{}{
+			"name":        IdempotencyKeyHeader,
+			"in":          "header",
+			"description": "Makes the request safe to retry: a retry with the same key returns the first response",
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if rt.body != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(rt.body), metadata)},
+			},
+		}
+	}
+	return operation, nil
+}
+
+// operationID names an operation by its method and path, since several
+// routes call the same transaction
+func operationID(rt *route) string {
+	name := strings.ToLower(rt.method)
+	for _, segment := range strings.Split(strings.Trim(rt.pattern, "/"), "/") {
+		segment = strings.Trim(segment, "{}")
+		segment = strings.ReplaceAll(segment, "-", "")
+		if segment != "" {
+			name += strings.ToUpper(segment[:1]) + segment[1:]
+		}
+	}
+	return name
+}
+
+func openAPIResponses(rt *route, returns interface{}) map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "The request failed; code is the contract's error code",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+	success := map[string]interface{}{"description": http.StatusText(rt.status)}
+	if rt.status != http.StatusNoContent && returns != nil {
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": returns},
+		}
+	}
+	return map[string]interface{}{
+		fmt.Sprint(rt.status): success,
+		"default":             errorResponse,
+	}
+}
+
+// schemaOf describes a Go type. Types the metadata has a schema for are
+// referenced rather than described again.
+func schemaOf(t reflect.Type, metadata *contractMetadata) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), metadata)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), metadata)}
+	case reflect.Struct:
+		if _, ok := metadata.Components.Schemas[t.Name()]; ok {
+			return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		}
+	default:
+		return map[string]interface{}{}
+	}
+
+	properties := make(map[string]interface{})
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, options := field.Name, ""
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			name, options = tag, ""
+			if i := strings.Index(tag, ","); i >= 0 {
+				name, options = tag[:i], tag[i:]
+			}
+		}
+		properties[name] = schemaOf(field.Type, metadata)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// specCache keeps the generated document, which only changes when the
+// chaincode is upgraded and the service restarted
+type specCache struct {
+	mu   sync.Mutex
+	spec []byte
+}
+
+// serveSpec serves the OpenAPI document, reading the contract metadata as
+// the spec identity the first time
+func (s *Server) serveSpec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		res := jsonResponse(http.StatusMethodNotAllowed, &apiError{Code: CodeMethodNotAllowed, Message: fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path)})
+		res.header.Set("Allow", http.MethodGet)
+		writeResponse(w, res)
+		return
+	}
+
+	s.spec.mu.Lock()
+	defer s.spec.mu.Unlock()
+	if s.spec.spec == nil {
+		spec, err := s.generateSpec()
+		if err != nil {
+			writeResponse(w, errorResponse(err))
+			return
+		}
+		s.spec.spec = spec
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	writeResponse(w, &response{status: http.StatusOK, header: header, body: s.spec.spec})
+}
+
+func (s *Server) generateSpec() ([]byte, error) {
+	identity, err := s.options.Wallet.Get(s.options.SpecIdentity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec identity %s: %v", s.options.SpecIdentity, err)
+	}
+	transport, err := s.options.Gateway.Connect(identity)
+	if err != nil {
+		return nil, &unavailableError{err: err}
+	}
+	metadata, err := transport.EvaluateTransaction(metadataTransaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the contract metadata: %v", err)
+	}
+	return GenerateOpenAPI(metadata)
+}
@@ -0,0 +1,147 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+// metadataStub calls GetMetadata through the chaincode's Invoke
+type metadataStub struct {
+	*chaincodetest.Stub
+}
+
+func (s *metadataStub) GetFunctionAndParameters() (string, []string) {
+	return metadataTransaction, nil
+}
+
+func (s *metadataStub) GetCreator() ([]byte, error) {
+	return nil, errors.New("no creator")
+}
+
+// contractMetadataJSON returns the metadata of the chaincode in this tree
+func contractMetadataJSON(t *testing.T) []byte {
+	t.Helper()
+	chaincode, err := bank.NewChaincode()
+	if err != nil {
+		t.Fatalf("NewChaincode failed: %v", err)
+	}
+	response := chaincode.Invoke(&metadataStub{Stub: chaincodetest.NewStub()})
+	if response.Status != shim.OK {
+		t.Fatalf("GetMetadata failed: %s", response.Message)
+	}
+	return response.Payload
+}
+
+// openAPIDocument is the part of the document the tests check
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+		Transaction string `json:"x-fabric-transaction"`
+		RequestBody *struct {
+			Content map[string]struct {
+				Schema map[string]interface{} `json:"schema"`
+			} `json:"content"`
+		} `json:"requestBody"`
+		Responses map[string]struct {
+			Content map[string]struct {
+				Schema map[string]interface{} `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]map[string]interface{} `json:"schemas"`
+	} `json:"components"`
+}
+
+func TestGenerateOpenAPI(t *testing.T) {
+	specJSON, err := GenerateOpenAPI(contractMetadataJSON(t))
+	if err != nil {
+		t.Fatalf("GenerateOpenAPI failed: %v", err)
+	}
+	var doc openAPIDocument
+	err = json.Unmarshal(specJSON, &doc)
+	if err != nil {
+		t.Fatalf("spec is not JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q", doc.OpenAPI)
+	}
+
+	operationIDs := make(map[string]bool)
+	for _, rt := range routes {
+		operation, ok := doc.Paths[rt.pattern][strings.ToLower(rt.method)]
+		if !ok {
+			t.Fatalf("%s %s is not in the spec", rt.method, rt.pattern)
+		}
+		if operationIDs[operation.OperationID] {
+			t.Fatalf("operation ID %s is not unique", operation.OperationID)
+		}
+		operationIDs[operation.OperationID] = true
+		if operation.Transaction != rt.transaction {
+			t.Fatalf("%s %s: x-fabric-transaction = %s", rt.method, rt.pattern, operation.Transaction)
+		}
+		if (operation.RequestBody != nil) != (rt.body != nil) {
+			t.Fatalf("%s %s: request body does not match the route", rt.method, rt.pattern)
+		}
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		if _, ok := schema["$id"]; ok {
+			t.Fatalf("schema %s keeps $id", name)
+		}
+	}
+	if _, ok := doc.Components.Schemas["Payment"]; !ok {
+		t.Fatalf("contract schemas are missing")
+	}
+
+	bankSchema := doc.Paths["/banks/{bankID}"]["get"].Responses["200"].Content["application/json"].Schema
+	if bankSchema["$ref"] != "#/components/schemas/Bank" {
+		t.Fatalf("GET /banks/{bankID} returns %v", bankSchema)
+	}
+	paymentSchema := doc.Paths["/payments"]["post"].Responses["201"].Content["application/json"].Schema
+	if paymentSchema["$ref"] != "#/components/schemas/Payment" {
+		t.Fatalf("POST /payments returns %v", paymentSchema)
+	}
+	batchBody := doc.Paths["/payments/batches"]["post"].RequestBody.Content["application/json"].Schema
+	instructions := batchBody["properties"].(map[string]interface{})["instructions"].(map[string]interface{})
+	if instructions["type"] != "array" || instructions["items"].(map[string]interface{})["type"] != "object" {
+		t.Fatalf("batch instructions are %v", instructions)
+	}
+}
+
+func TestGenerateOpenAPIMissingTransaction(t *testing.T) {
+	_, err := GenerateOpenAPI([]byte(`{"contracts":{"bank":{"transactions":[{"name":"QueryBank"}]}}}`))
+	if err == nil || !strings.Contains(err.Error(), "which the contract does not have") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	server, gateway := newTestServer()
+	gateway.transport.Return(metadataTransaction, contractMetadataJSON(t))
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest(http.MethodGet, SpecPath, nil)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+			t.Fatalf("GET %s = %d", SpecPath, w.Code)
+		}
+	}
+	if len(gateway.transport.Calls()) != 1 || gateway.labels[0] != "spec" {
+		t.Fatalf("metadata read %d times as %v, want once as the spec identity", len(gateway.transport.Calls()), gateway.labels)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+)
+
+// Gateway connects the service to the chaincode as one of its identities
+type Gateway interface {
+	Connect(identity *Identity) (cbpsclient.Transport, error)
+}
+
+// Dialer opens a connection to the chaincode for identity. The closer, which
+// may be nil, releases the connection when the pool drops it.
+type Dialer func(identity *Identity) (cbpsclient.Transport, io.Closer, error)
+
+// Pool is a Gateway that keeps the transport dialed for each identity and
+// reuses it across requests. Whether that also reuses a network connection is
+// up to the Dialer: a peer CLI transport keeps only the identity's setup and
+// still connects on every call. It holds at most maxSize transports, dropping
+// the least recently used one to make room, and closes transports left idle
+// for longer than idleTimeout.
+type Pool struct {
+	dial        Dialer
+	maxSize     int
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu    sync.Mutex
+	conns map[string]*pooledConn
+}
+
+type pooledConn struct {
+	transport cbpsclient.Transport
+	closer    io.Closer
+	lastUsed  time.Time
+}
+
+// NewPool returns an empty pool that opens connections with dial
+func NewPool(dial Dialer, maxSize int, idleTimeout time.Duration) *Pool {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	return &Pool{dial: dial, maxSize: maxSize, idleTimeout: idleTimeout, now: time.Now, conns: make(map[string]*pooledConn)}
+}
+
+// Connect returns the pooled connection of identity, opening it if needed
+func (p *Pool) Connect(identity *Identity) (cbpsclient.Transport, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.dropIdle(now)
+	if conn, ok := p.conns[identity.Label]; ok {
+		conn.lastUsed = now
+		return conn.transport, nil
+	}
+
+	transport, closer, err := p.dial(identity)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.conns) >= p.maxSize {
+		p.dropLeastRecentlyUsed()
+	}
+	p.conns[identity.Label] = &pooledConn{transport: transport, closer: closer, lastUsed: now}
+	return transport, nil
+}
+
+// Len returns the number of open connections
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns)
+}
+
+// Close closes every connection
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for label := range p.conns {
+		p.drop(label)
+	}
+	return nil
+}
+
+func (p *Pool) dropIdle(now time.Time) {
+	if p.idleTimeout <= 0 {
+		return
+	}
+	for label, conn := range p.conns {
+		if now.Sub(conn.lastUsed) > p.idleTimeout {
+			p.drop(label)
+		}
+	}
+}
+
+func (p *Pool) dropLeastRecentlyUsed() {
+	oldest := ""
+	for label, conn := range p.conns {
+		if oldest == "" || conn.lastUsed.Before(p.conns[oldest].lastUsed) {
+			oldest = label
+		}
+	}
+	p.drop(oldest)
+}
+
+// drop closes and forgets a connection. Requests still holding its transport
+// may fail with a transport error.
+func (p *Pool) drop(label string) {
+	conn := p.conns[label]
+	delete(p.conns, label)
+	if conn.closer == nil {
+		return
+	}
+	err := conn.closer.Close()
+	if err != nil {
+		log.Printf("failed to close the connection of %s: %v", label, err)
+	}
+}
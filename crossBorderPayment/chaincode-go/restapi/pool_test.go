@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/cbpsclienttest"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func TestPool(t *testing.T) {
+	var dialed, closed []string
+	pool := NewPool(func(identity *Identity) (cbpsclient.Transport, io.Closer, error) {
+		if identity.Label == "broken" {
+			return nil, nil, errors.New("dial failed")
+		}
+		dialed = append(dialed, identity.Label)
+		return cbpsclienttest.NewTransport(), closerFunc(func() error {
+			closed = append(closed, identity.Label)
+			return nil
+		}), nil
+	}, 2, time.Minute)
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+	connect := func(label string) cbpsclient.Transport {
+		t.Helper()
+		transport, err := pool.Connect(&Identity{Label: label})
+		if err != nil {
+			t.Fatalf("Connect(%s) failed: %v", label, err)
+		}
+		now = now.Add(time.Second)
+		return transport
+	}
+
+	first := connect("alice")
+	if connect("alice") != first {
+		t.Fatalf("connection was not reused")
+	}
+	connect("bob")
+	connect("alice")
+	connect("carol")
+	if strings.Join(dialed, ",") != "alice,bob,carol" || strings.Join(closed, ",") != "bob" || pool.Len() != 2 {
+		t.Fatalf("dialed %v, closed %v, want bob evicted as least recently used", dialed, closed)
+	}
+
+	if _, err := pool.Connect(&Identity{Label: "broken"}); err == nil || pool.Len() != 2 {
+		t.Fatalf("failed dial: err = %v, %d connections", err, pool.Len())
+	}
+
+	now = now.Add(2 * time.Minute)
+	connect("dave")
+	sort.Strings(closed)
+	if strings.Join(closed, ",") != "alice,bob,carol" || pool.Len() != 1 {
+		t.Fatalf("closed %v after the idle timeout, %d connections", closed, pool.Len())
+	}
+
+	err := pool.Close()
+	if err != nil || pool.Len() != 0 || len(closed) != 4 {
+		t.Fatalf("Close: err = %v, %d connections, closed %v", err, pool.Len(), closed)
+	}
+}
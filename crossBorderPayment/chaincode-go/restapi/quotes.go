@@ -0,0 +1,119 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// QuoteRequest asks what a payment between two accounts would cost
+type QuoteRequest struct {
+	SenderAccountID   string  `json:"senderAccountID"`
+	ReceiverAccountID string  `json:"receiverAccountID"`
+	Amount            float64 `json:"amount"`
+}
+
+// Quote is the exchange rate and fee of a payment at the banks' current
+// rates. The fee is in the sender's currency and is debited with the amount.
+type Quote struct {
+	SenderAccountID   string  `json:"senderAccountID"`
+	ReceiverAccountID string  `json:"receiverAccountID"`
+	SourceCurrency    string  `json:"sourceCurrency"`
+	TargetCurrency    string  `json:"targetCurrency"`
+	Amount            float64 `json:"amount"`
+	ExchangeRate      float64 `json:"exchangeRate"`
+	ConvertedAmount   float64 `json:"convertedAmount"`
+	Fee               float64 `json:"fee"`
+	TotalDebit        float64 `json:"totalDebit"`
+
+	// ValidUntil is when the older of the two banks' rates goes stale, if
+	// the network limits the age of rates
+	ValidUntil string `json:"validUntil,omitempty"`
+}
+
+// quote prices a payment from the rates the sender's and receiver's banks
+// publish. Banks quote their currency per US dollar, so the rate from the
+// source to the target currency is the receiving bank's rate over the
+// sending bank's.
+func quote(client *cbpsclient.Client, req QuoteRequest) (*Quote, error) {
+	err := contracterrors.From(validation.Check(
+		validation.ID("senderAccountID", req.SenderAccountID),
+		validation.ID("receiverAccountID", req.ReceiverAccountID),
+		validation.PositiveAmount("amount", req.Amount),
+		validation.DistinctAccounts("receiverAccountID", req.SenderAccountID, req.ReceiverAccountID),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := client.QueryAccount(req.SenderAccountID)
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := client.QueryAccount(req.ReceiverAccountID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := client.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Quote{
+		SenderAccountID:   sender.AccountID,
+		ReceiverAccountID: receiver.AccountID,
+		SourceCurrency:    sender.Currency,
+		TargetCurrency:    receiver.Currency,
+		Amount:            req.Amount,
+		ExchangeRate:      1,
+		Fee:               config.Fee(req.Amount),
+	}
+	q.TotalDebit = roundCents(q.Amount + q.Fee)
+
+	if sender.Currency != receiver.Currency {
+		sendingBank, err := client.QueryBank(sender.BankID)
+		if err != nil {
+			return nil, err
+		}
+		receivingBank, err := client.QueryBank(receiver.BankID)
+		if err != nil {
+			return nil, err
+		}
+		if sendingBank.ExchangeRate <= 0 || receivingBank.ExchangeRate <= 0 {
+			return nil, contracterrors.New(contracterrors.InvalidState, "banks %s and %s have not both published an exchange rate", sendingBank.BankID, receivingBank.BankID)
+		}
+		q.ExchangeRate = receivingBank.ExchangeRate / sendingBank.ExchangeRate
+
+		if config.RateMaxAgeSeconds > 0 {
+			maxAge := time.Duration(config.RateMaxAgeSeconds) * time.Second
+			var validUntil time.Time
+			for _, b := range []*bank.Bank{sendingBank, receivingBank} {
+				rateDate, err := time.Parse(time.RFC3339, b.ExchangeRateDate)
+				if err != nil {
+					return nil, contracterrors.New(contracterrors.RateStale, "bank %s has not set its exchange rate", b.BankID)
+				}
+				if validUntil.IsZero() || rateDate.Add(maxAge).Before(validUntil) {
+					validUntil = rateDate.Add(maxAge)
+				}
+			}
+			if !time.Now().Before(validUntil) {
+				return nil, contracterrors.New(contracterrors.RateStale, "the exchange rates of banks %s and %s are too old to quote", sendingBank.BankID, receivingBank.BankID)
+			}
+			q.ValidUntil = validUntil.UTC().Format(time.RFC3339)
+		}
+	}
+	q.ConvertedAmount = roundCents(q.Amount * q.ExchangeRate)
+	return q, nil
+}
+
+func roundCents(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,830 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+// route maps a method and path pattern onto a contract transaction. Path
+// parameters are written {name}.
+type route struct {
+	method  string
+	pattern string
+	summary string
+	status  int
+
+	// transaction is the namespaced transaction the route calls and returns
+	// is the one whose result the route responds with, if different. The
+	// OpenAPI spec takes the response schema from the latter.
+	transaction string
+	returns     string
+
+	// body is a value of the request body's type, nil when there is none.
+	// result is a value of the response's type when the contract metadata
+	// does not describe it.
+	body   interface{}
+	result interface{}
+
+	handle func(r *request) (interface{}, error)
+}
+
+// Request bodies. Field names follow the JSON of the chaincode's objects.
+
+type createBankBody struct {
+	BankID       string  `json:"bankID"`
+	Name         string  `json:"name"`
+	Password     string  `json:"password"`
+	Country      string  `json:"country"`
+	Currency     string  `json:"currency"`
+	Reserves     float64 `json:"reserves"`
+	ExchangeRate float64 `json:"exchangeRate"`
+	BIC          string  `json:"bic,omitempty"`
+}
+
+type updateBankBody struct {
+	Name     string  `json:"name"`
+	Reserves float64 `json:"reserves"`
+	Country  string  `json:"country"`
+}
+
+type exchangeRateBody struct {
+	ExchangeRate float64 `json:"exchangeRate"`
+}
+
+// purposeCodeRuleBody leaves out the purpose codes to accept any listed one
+type purposeCodeRuleBody struct {
+	PurposeCodes []string `json:"purposeCodes,omitempty"`
+}
+
+type createCustomerBody struct {
+	CustomerID string `json:"customerID"`
+	Password   string `json:"password"`
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+}
+
+type updateCustomerBody struct {
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	Surname  string `json:"surname"`
+}
+
+type addBeneficiaryBody struct {
+	BeneficiaryID     string  `json:"beneficiaryID"`
+	Nickname          string  `json:"nickname"`
+	ReceiverAccountID string  `json:"receiverAccountID"`
+	Name              string  `json:"name"`
+	PaymentLimit      float64 `json:"paymentLimit,omitempty"`
+	DailyLimit        float64 `json:"dailyLimit,omitempty"`
+}
+
+type updateBeneficiaryBody struct {
+	Nickname     string  `json:"nickname"`
+	PaymentLimit float64 `json:"paymentLimit,omitempty"`
+	DailyLimit   float64 `json:"dailyLimit,omitempty"`
+}
+
+type createAccountBody struct {
+	AccountID     string  `json:"id"`
+	CustomerID    string  `json:"customerID"`
+	BankID        string  `json:"bankID"`
+	Balance       float64 `json:"balance"`
+	IBAN          string  `json:"iban,omitempty"`
+	RoutingScheme string  `json:"routingScheme,omitempty"`
+	RoutingNumber string  `json:"routingNumber,omitempty"`
+	AccountNumber string  `json:"accountNumber,omitempty"`
+}
+
+// createPaymentBody leaves out the exchange rate to use the quoted one and
+// the date to use today's. A payment to a beneficiary leaves out the receiver.
+type createPaymentBody struct {
+	PaymentID          string  `json:"paymentID"`
+	SenderCustomerID   string  `json:"senderCustomerID"`
+	ReceiverCustomerID string  `json:"receiverCustomerID"`
+	SenderAccountID    string  `json:"senderAccountID"`
+	ReceiverAccountID  string  `json:"receiverAccountID"`
+	Amount             float64 `json:"amount"`
+	ExchangeRate       float64 `json:"exchangeRate,omitempty"`
+	Date               string  `json:"date,omitempty"`
+	BeneficiaryID      string  `json:"beneficiaryID,omitempty"`
+
+	RemittanceInfo    string   `json:"remittanceInfo,omitempty"`
+	CreditorReference string   `json:"creditorReference,omitempty"`
+	InvoiceNumbers    []string `json:"invoiceNumbers,omitempty"`
+	PurposeCode       string   `json:"purposeCode,omitempty"`
+}
+
+type accountHolderBody struct {
+	CustomerID string `json:"customerID"`
+}
+
+type signingRuleBody struct {
+	SigningRule        string `json:"signingRule"`
+	RequiredSignatures int    `json:"requiredSignatures,omitempty"`
+}
+
+// paymentApprovalBody names the holder who signs or declines a payment
+type paymentApprovalBody struct {
+	CustomerID string `json:"customerID"`
+}
+
+type createPaymentRequestBody struct {
+	RequestID       string  `json:"requestID"`
+	PayeeAccountID  string  `json:"payeeAccountID"`
+	PayerCustomerID string  `json:"payerCustomerID"`
+	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency"`
+	DueDate         string  `json:"dueDate"`
+	ExpiryDate      string  `json:"expiryDate,omitempty"`
+	Reference       string  `json:"reference"`
+	PurposeCode     string  `json:"purposeCode,omitempty"`
+}
+
+type acceptPaymentRequestBody struct {
+	PaymentID      string `json:"paymentID"`
+	PayerAccountID string `json:"payerAccountID"`
+}
+
+type declinePaymentRequestBody struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type rejectOperationBody struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type proposeMembershipBody struct {
+	ProposerBankID string `json:"proposerBankID"`
+	Action         string `json:"action"`
+	Reason         string `json:"reason"`
+}
+
+type membershipVoteBody struct {
+	VoterBankID string `json:"voterBankID"`
+	InFavor     bool   `json:"inFavor"`
+}
+
+type withdrawMembershipBody struct {
+	Reason string `json:"reason"`
+}
+
+type admitFoundingBankBody struct {
+	BankID string `json:"bankID"`
+}
+
+type createBatchBody struct {
+	BatchID      string                    `json:"batchID"`
+	Mode         string                    `json:"mode,omitempty"`
+	Instructions []bank.PaymentInstruction `json:"instructions"`
+}
+
+var routes = []*route{
+	{method: http.MethodPost, pattern: "/banks", summary: "Register a bank administered by the caller", status: http.StatusCreated,
+		transaction: "bank:CreateBank", returns: "bank:QueryBank", body: createBankBody{}, handle: createBank},
+	{method: http.MethodGet, pattern: "/banks/{bankID}", summary: "Get a bank", status: http.StatusOK,
+		transaction: "bank:QueryBank", handle: getBank},
+	{method: http.MethodPut, pattern: "/banks/{bankID}", summary: "Propose a new profile for a bank, applied once another administrator approves it", status: http.StatusAccepted,
+		transaction: "bank:UpdateBankProfile", body: updateBankBody{}, handle: updateBank},
+	{method: http.MethodPut, pattern: "/banks/{bankID}/exchange-rate", summary: "Publish a bank's exchange rate, in units of its currency per US dollar", status: http.StatusOK,
+		transaction: "bank:UpdateExchangeRate", returns: "bank:QueryBank", body: exchangeRateBody{}, handle: updateExchangeRate},
+	{method: http.MethodGet, pattern: "/banks/by-bic/{bic}", summary: "Find a bank by its BIC, or a branch BIC by its head office", status: http.StatusOK,
+		transaction: "bank:QueryBankByBIC", handle: getBankByBIC},
+	{method: http.MethodGet, pattern: "/banks/{bankID}/accounts", summary: "List the accounts held at a bank", status: http.StatusOK,
+		transaction: "bank:QueryBankAccounts", handle: listBankAccounts},
+	{method: http.MethodGet, pattern: "/banks/{bankID}/customers", summary: "List the customers of a bank", status: http.StatusOK,
+		transaction: "bank:QueryCustomersByBank", handle: listBankCustomers},
+	{method: http.MethodGet, pattern: "/banks/{bankID}/purpose-code-rules", summary: "List the countries a bank requires purpose codes for", status: http.StatusOK,
+		transaction: "bank:QueryPurposeCodeRules", handle: listPurposeCodeRules},
+	{method: http.MethodPut, pattern: "/banks/{bankID}/purpose-code-rules/{country}", summary: "Require a purpose code on a bank's payments with a country", status: http.StatusNoContent,
+		transaction: "bank:SetPurposeCodeRule", body: purposeCodeRuleBody{}, handle: setPurposeCodeRule},
+	{method: http.MethodDelete, pattern: "/banks/{bankID}/purpose-code-rules/{country}", summary: "Stop requiring a purpose code on a bank's payments with a country", status: http.StatusNoContent,
+		transaction: "bank:RemovePurposeCodeRule", handle: removePurposeCodeRule},
+	{method: http.MethodGet, pattern: "/banks/{bankID}/membership-proposals", summary: "List the votes held on a bank's membership, oldest first", status: http.StatusOK,
+		transaction: "bank:QueryMembershipProposals", handle: listMembershipProposals},
+	{method: http.MethodPost, pattern: "/banks/{bankID}/membership-proposals", summary: "Propose to suspend, reinstate or expel a bank", status: http.StatusCreated,
+		transaction: "bank:ProposeMembershipChange", body: proposeMembershipBody{}, handle: proposeMembershipChange},
+	{method: http.MethodPost, pattern: "/banks/{bankID}/membership-votes", summary: "Vote on the open proposal for a bank's membership", status: http.StatusOK,
+		transaction: "bank:VoteOnMembership", body: membershipVoteBody{}, handle: voteOnMembership},
+	{method: http.MethodPost, pattern: "/banks/{bankID}/membership-withdrawals", summary: "Withdraw the open proposal for a bank's membership without a decision", status: http.StatusOK,
+		transaction: "bank:WithdrawMembershipProposal", body: withdrawMembershipBody{}, handle: withdrawMembershipProposal},
+	{method: http.MethodGet, pattern: "/membership-proposals", summary: "List the membership proposals still being voted on", status: http.StatusOK,
+		transaction: "bank:QueryOpenMembershipProposals", handle: listOpenMembershipProposals},
+	{method: http.MethodGet, pattern: "/members", summary: "List the active member banks", status: http.StatusOK,
+		transaction: "bank:QueryMemberBanks", handle: listMemberBanks},
+	{method: http.MethodPost, pattern: "/members", summary: "Admit the founding member of the consortium", status: http.StatusCreated,
+		transaction: "admin:AdmitFoundingBank", body: admitFoundingBankBody{}, handle: admitFoundingBank},
+	{method: http.MethodGet, pattern: "/purpose-codes", summary: "List the purpose codes payments may carry", status: http.StatusOK,
+		transaction: "admin:QueryPurposeCodes", handle: listPurposeCodes},
+
+	{method: http.MethodPost, pattern: "/customers", summary: "Register a customer", status: http.StatusCreated,
+		transaction: "customer:CreateCustomer", returns: "customer:QueryCustomer", body: createCustomerBody{}, handle: createCustomer},
+	{method: http.MethodGet, pattern: "/customers/{customerID}", summary: "Get a customer", status: http.StatusOK,
+		transaction: "customer:QueryCustomer", handle: getCustomer},
+	{method: http.MethodPut, pattern: "/customers/{customerID}", summary: "Update a customer's profile", status: http.StatusOK,
+		transaction: "customer:UpdateProfile", returns: "customer:QueryCustomer", body: updateCustomerBody{}, handle: updateCustomer},
+	{method: http.MethodGet, pattern: "/customers/{customerID}/accounts", summary: "List a customer's accounts", status: http.StatusOK,
+		transaction: "customer:QueryCustomerAccounts", handle: listCustomerAccounts},
+	{method: http.MethodPost, pattern: "/customers/{customerID}/beneficiaries", summary: "Save an account to pay by nickname, checking the holder's name", status: http.StatusCreated,
+		transaction: "customer:AddBeneficiary", body: addBeneficiaryBody{}, handle: addBeneficiary},
+	{method: http.MethodGet, pattern: "/customers/{customerID}/beneficiaries", summary: "List a customer's beneficiaries", status: http.StatusOK,
+		transaction: "customer:QueryBeneficiaries", handle: listBeneficiaries},
+	{method: http.MethodGet, pattern: "/customers/{customerID}/beneficiaries/{beneficiaryID}", summary: "Get a beneficiary", status: http.StatusOK,
+		transaction: "customer:QueryBeneficiary", handle: getBeneficiary},
+	{method: http.MethodPut, pattern: "/customers/{customerID}/beneficiaries/{beneficiaryID}", summary: "Change a beneficiary's nickname and limits", status: http.StatusOK,
+		transaction: "customer:UpdateBeneficiary", returns: "customer:QueryBeneficiary", body: updateBeneficiaryBody{}, handle: updateBeneficiary},
+	{method: http.MethodDelete, pattern: "/customers/{customerID}/beneficiaries/{beneficiaryID}", summary: "Delete a beneficiary", status: http.StatusNoContent,
+		transaction: "customer:RemoveBeneficiary", handle: removeBeneficiary},
+	{method: http.MethodGet, pattern: "/customers/{customerID}/payment-requests", summary: "List the payment requests a customer has yet to answer", status: http.StatusOK,
+		transaction: "payment:QueryPendingPaymentRequests", handle: listPendingPaymentRequests},
+
+	{method: http.MethodPost, pattern: "/accounts", summary: "Open an account", status: http.StatusCreated,
+		transaction: "account:CreateAccount", returns: "account:QueryAccount", body: createAccountBody{}, handle: createAccount},
+	{method: http.MethodGet, pattern: "/accounts/{accountID}", summary: "Get an account", status: http.StatusOK,
+		transaction: "account:QueryAccount", handle: getAccount},
+	{method: http.MethodGet, pattern: "/accounts/by-iban/{iban}", summary: "Find an account by its IBAN", status: http.StatusOK,
+		transaction: "account:QueryAccountByIBAN", handle: getAccountByIBAN},
+	{method: http.MethodGet, pattern: "/accounts/by-number/{routingScheme}/{routingNumber}/{accountNumber}", summary: "Find an account by its local account number", status: http.StatusOK,
+		transaction: "account:QueryAccountByNumber", handle: getAccountByNumber},
+	{method: http.MethodGet, pattern: "/accounts/{accountID}/payments", summary: "List the payments sent or received by an account", status: http.StatusOK,
+		transaction: "payment:QueryPayments", handle: listAccountPayments},
+	{method: http.MethodGet, pattern: "/accounts/{accountID}/payments/awaiting-approval", summary: "List the payments from an account that still need its holders' signatures", status: http.StatusOK,
+		transaction: "payment:QueryPaymentsAwaitingApproval", handle: listPaymentsAwaitingApproval},
+	{method: http.MethodPost, pattern: "/accounts/{accountID}/holders", summary: "Make a customer a joint holder of an account", status: http.StatusOK,
+		transaction: "account:AddAccountHolder", returns: "account:QueryAccount", body: accountHolderBody{}, handle: addAccountHolder},
+	{method: http.MethodDelete, pattern: "/accounts/{accountID}/holders/{customerID}", summary: "Take a joint holder off an account", status: http.StatusNoContent,
+		transaction: "account:RemoveAccountHolder", handle: removeAccountHolder},
+	{method: http.MethodPut, pattern: "/accounts/{accountID}/signing-rule", summary: "Set how many holders must sign an account's payments", status: http.StatusOK,
+		transaction: "account:SetSigningRule", returns: "account:QueryAccount", body: signingRuleBody{}, handle: setSigningRule},
+
+	{method: http.MethodPost, pattern: "/payments", summary: "Send a payment, at the quoted exchange rate unless one is given", status: http.StatusCreated,
+		transaction: "payment:CreatePayment", result: bank.Payment{}, body: createPaymentBody{}, handle: createPayment},
+	{method: http.MethodPost, pattern: "/payments/batches", summary: "Send payment instructions as one batch", status: http.StatusCreated,
+		transaction: "payment:CreatePaymentBatch", body: createBatchBody{}, handle: createBatch},
+	{method: http.MethodGet, pattern: "/payments/batches/{batchID}", summary: "Get the report of a batch", status: http.StatusOK,
+		transaction: "payment:QueryPaymentBatch", handle: getBatch},
+	{method: http.MethodGet, pattern: "/payments/held", summary: "List the payments held for compliance review", status: http.StatusOK,
+		transaction: "payment:QueryHeldPayments", handle: listHeldPayments},
+	{method: http.MethodPost, pattern: "/payments/held/{paymentID}/release", summary: "Settle a held payment", status: http.StatusNoContent,
+		transaction: "payment:ReleaseHeldPayment", handle: releaseHeldPayment},
+	{method: http.MethodPost, pattern: "/payments/held/{paymentID}/reject", summary: "Close a held payment without moving funds", status: http.StatusNoContent,
+		transaction: "payment:RejectHeldPayment", handle: rejectHeldPayment},
+	{method: http.MethodPost, pattern: "/payments/{paymentID}/approve", summary: "Sign a payment from a joint account, which settles once it has enough signatures", status: http.StatusOK,
+		transaction: "payment:ApprovePayment", body: paymentApprovalBody{}, handle: approvePayment},
+	{method: http.MethodPost, pattern: "/payments/{paymentID}/decline", summary: "Refuse a payment from a joint account", status: http.StatusNoContent,
+		transaction: "payment:DeclinePayment", body: paymentApprovalBody{}, handle: declinePayment},
+
+	{method: http.MethodPost, pattern: "/payment-requests", summary: "Ask a customer to pay into the caller's account", status: http.StatusCreated,
+		transaction: "payment:CreatePaymentRequest", body: createPaymentRequestBody{}, handle: createPaymentRequest},
+	{method: http.MethodGet, pattern: "/payment-requests/{requestID}", summary: "Get a payment request", status: http.StatusOK,
+		transaction: "payment:QueryPaymentRequest", handle: getPaymentRequest},
+	{method: http.MethodPost, pattern: "/payment-requests/{requestID}/accept", summary: "Pay a payment request at the banks' exchange rate", status: http.StatusCreated,
+		transaction: "payment:AcceptPaymentRequest", body: acceptPaymentRequestBody{}, handle: acceptPaymentRequest},
+	{method: http.MethodPost, pattern: "/payment-requests/{requestID}/decline", summary: "Refuse a payment request", status: http.StatusNoContent,
+		transaction: "payment:DeclinePaymentRequest", body: declinePaymentRequestBody{}, handle: declinePaymentRequest},
+
+	{method: http.MethodGet, pattern: "/operations", summary: "List the administrative operations waiting for approval", status: http.StatusOK,
+		transaction: "admin:QueryPendingOperations", handle: listPendingOperations},
+	{method: http.MethodGet, pattern: "/operations/{operationID}", summary: "Get an administrative operation and who decided it", status: http.StatusOK,
+		transaction: "admin:QueryOperation", handle: getOperation},
+	{method: http.MethodPost, pattern: "/operations/{operationID}/approve", summary: "Apply an operation proposed by another administrator", status: http.StatusOK,
+		transaction: "admin:ApproveOperation", handle: approveOperation},
+	{method: http.MethodPost, pattern: "/operations/{operationID}/reject", summary: "Close an operation without applying it", status: http.StatusNoContent,
+		transaction: "admin:RejectOperation", body: rejectOperationBody{}, handle: rejectOperation},
+
+	{method: http.MethodPost, pattern: "/quotes", summary: "Quote the exchange rate and fee of a payment between two accounts", status: http.StatusOK,
+		transaction: "bank:QueryBank", body: QuoteRequest{}, result: Quote{}, handle: createQuote},
+}
+
+// matchRoute finds the route for a request. When only the method does not
+// match it returns the allowed methods instead.
+func matchRoute(method string, path string) (*route, map[string]string, []string) {
+	var allowed []string
+	for _, rt := range routes {
+		params, ok := matchPath(rt.pattern, path)
+		if !ok {
+			continue
+		}
+		if rt.method == method {
+			return rt, params, nil
+		}
+		allowed = append(allowed, rt.method)
+	}
+	return nil, nil, allowed
+}
+
+func matchPath(pattern string, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// Passwords are stored on the ledger but never returned by the API
+
+func redactBank(b *bank.Bank) *bank.Bank {
+	b.Password = ""
+	return b
+}
+
+func redactCustomer(customer *bank.Customer) *bank.Customer {
+	customer.Password = ""
+	return customer
+}
+
+func queryBank(r *request, bankID string) (interface{}, error) {
+	b, err := r.client.QueryBank(bankID)
+	if err != nil {
+		return nil, err
+	}
+	return redactBank(b), nil
+}
+
+func createBank(r *request) (interface{}, error) {
+	var body createBankBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.CreateBank(cbpsclient.CreateBankRequest(body))
+	if err != nil {
+		return nil, err
+	}
+	return queryBank(r, body.BankID)
+}
+
+func getBank(r *request) (interface{}, error) {
+	return queryBank(r, r.params["bankID"])
+}
+
+func getBankByBIC(r *request) (interface{}, error) {
+	b, err := r.client.QueryBankByBIC(r.params["bic"])
+	if err != nil {
+		return nil, err
+	}
+	return redactBank(b), nil
+}
+
+func updateBank(r *request) (interface{}, error) {
+	var body updateBankBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.UpdateBankProfile(cbpsclient.UpdateBankProfileRequest{BankID: r.params["bankID"], Name: body.Name, Reserves: body.Reserves, Country: body.Country})
+}
+
+func updateExchangeRate(r *request) (interface{}, error) {
+	var body exchangeRateBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.UpdateExchangeRate(r.params["bankID"], body.ExchangeRate)
+	if err != nil {
+		return nil, err
+	}
+	return queryBank(r, r.params["bankID"])
+}
+
+func listBankAccounts(r *request) (interface{}, error) {
+	return r.client.QueryBankAccounts(r.params["bankID"])
+}
+
+func listBankCustomers(r *request) (interface{}, error) {
+	customers, err := r.client.QueryCustomersByBank(r.params["bankID"])
+	if err != nil {
+		return nil, err
+	}
+	for _, customer := range customers {
+		redactCustomer(customer)
+	}
+	return customers, nil
+}
+
+func listPurposeCodeRules(r *request) (interface{}, error) {
+	return r.client.QueryPurposeCodeRules(r.params["bankID"])
+}
+
+func setPurposeCodeRule(r *request) (interface{}, error) {
+	var body purposeCodeRuleBody
+	if len(r.body) > 0 {
+		err := r.decode(&body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, r.client.SetPurposeCodeRule(r.params["bankID"], r.params["country"], body.PurposeCodes)
+}
+
+func removePurposeCodeRule(r *request) (interface{}, error) {
+	return nil, r.client.RemovePurposeCodeRule(r.params["bankID"], r.params["country"])
+}
+
+func listMembershipProposals(r *request) (interface{}, error) {
+	return r.client.QueryMembershipProposals(r.params["bankID"])
+}
+
+func proposeMembershipChange(r *request) (interface{}, error) {
+	var body proposeMembershipBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.ProposeMembershipChange(r.params["bankID"], body.ProposerBankID, body.Action, body.Reason)
+}
+
+func voteOnMembership(r *request) (interface{}, error) {
+	var body membershipVoteBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.VoteOnMembership(r.params["bankID"], body.VoterBankID, body.InFavor)
+}
+
+func withdrawMembershipProposal(r *request) (interface{}, error) {
+	var body withdrawMembershipBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.WithdrawMembershipProposal(r.params["bankID"], body.Reason)
+}
+
+func listOpenMembershipProposals(r *request) (interface{}, error) {
+	return r.client.QueryOpenMembershipProposals()
+}
+
+func listMemberBanks(r *request) (interface{}, error) {
+	banks, err := r.client.QueryMemberBanks()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range banks {
+		redactBank(b)
+	}
+	return banks, nil
+}
+
+func admitFoundingBank(r *request) (interface{}, error) {
+	var body admitFoundingBankBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.AdmitFoundingBank(body.BankID)
+}
+
+func listPurposeCodes(r *request) (interface{}, error) {
+	return r.client.QueryPurposeCodes()
+}
+
+func queryCustomer(r *request, customerID string) (interface{}, error) {
+	customer, err := r.client.QueryCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+	return redactCustomer(customer), nil
+}
+
+func createCustomer(r *request) (interface{}, error) {
+	var body createCustomerBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.CreateCustomer(cbpsclient.CustomerRequest(body))
+	if err != nil {
+		return nil, err
+	}
+	return queryCustomer(r, body.CustomerID)
+}
+
+func getCustomer(r *request) (interface{}, error) {
+	return queryCustomer(r, r.params["customerID"])
+}
+
+func updateCustomer(r *request) (interface{}, error) {
+	var body updateCustomerBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.UpdateProfile(cbpsclient.CustomerRequest{CustomerID: r.params["customerID"], Password: body.Password, Name: body.Name, Surname: body.Surname})
+	if err != nil {
+		return nil, err
+	}
+	return queryCustomer(r, r.params["customerID"])
+}
+
+func listCustomerAccounts(r *request) (interface{}, error) {
+	return r.client.QueryCustomerAccounts(r.params["customerID"])
+}
+
+func addBeneficiary(r *request) (interface{}, error) {
+	var body addBeneficiaryBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.AddBeneficiary(cbpsclient.BeneficiaryRequest{
+		CustomerID:        r.params["customerID"],
+		BeneficiaryID:     body.BeneficiaryID,
+		Nickname:          body.Nickname,
+		ReceiverAccountID: body.ReceiverAccountID,
+		Name:              body.Name,
+		PaymentLimit:      body.PaymentLimit,
+		DailyLimit:        body.DailyLimit,
+	})
+}
+
+func listBeneficiaries(r *request) (interface{}, error) {
+	return r.client.QueryBeneficiaries(r.params["customerID"])
+}
+
+func getBeneficiary(r *request) (interface{}, error) {
+	return r.client.QueryBeneficiary(r.params["customerID"], r.params["beneficiaryID"])
+}
+
+func updateBeneficiary(r *request) (interface{}, error) {
+	var body updateBeneficiaryBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.UpdateBeneficiary(cbpsclient.BeneficiaryRequest{
+		CustomerID:    r.params["customerID"],
+		BeneficiaryID: r.params["beneficiaryID"],
+		Nickname:      body.Nickname,
+		PaymentLimit:  body.PaymentLimit,
+		DailyLimit:    body.DailyLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return getBeneficiary(r)
+}
+
+func removeBeneficiary(r *request) (interface{}, error) {
+	return nil, r.client.RemoveBeneficiary(r.params["customerID"], r.params["beneficiaryID"])
+}
+
+func createAccount(r *request) (interface{}, error) {
+	var body createAccountBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.CreateAccount(cbpsclient.CreateAccountRequest(body))
+	if err != nil {
+		return nil, err
+	}
+	return r.client.QueryAccount(body.AccountID)
+}
+
+func getAccount(r *request) (interface{}, error) {
+	return r.client.QueryAccount(r.params["accountID"])
+}
+
+func getAccountByIBAN(r *request) (interface{}, error) {
+	return r.client.QueryAccountByIBAN(r.params["iban"])
+}
+
+func getAccountByNumber(r *request) (interface{}, error) {
+	return r.client.QueryAccountByNumber(r.params["routingScheme"], r.params["routingNumber"], r.params["accountNumber"])
+}
+
+func listAccountPayments(r *request) (interface{}, error) {
+	return r.client.QueryPayments(r.params["accountID"])
+}
+
+func listPaymentsAwaitingApproval(r *request) (interface{}, error) {
+	return r.client.QueryPaymentsAwaitingApproval(r.params["accountID"])
+}
+
+func addAccountHolder(r *request) (interface{}, error) {
+	var body accountHolderBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.AddAccountHolder(r.params["accountID"], body.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.QueryAccount(r.params["accountID"])
+}
+
+func removeAccountHolder(r *request) (interface{}, error) {
+	return nil, r.client.RemoveAccountHolder(r.params["accountID"], r.params["customerID"])
+}
+
+func setSigningRule(r *request) (interface{}, error) {
+	var body signingRuleBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	err = r.client.SetSigningRule(r.params["accountID"], body.SigningRule, body.RequiredSignatures)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.QueryAccount(r.params["accountID"])
+}
+
+// findPayment returns a payment sent by an account, which is either in the
+// account's payments, held for review or waiting for the approval of the
+// account's holders. The contract has no query by payment ID.
+func findPayment(r *request, accountID string, paymentID string) (*bank.Payment, error) {
+	payments, err := r.client.QueryPayments(accountID)
+	if err != nil {
+		return nil, err
+	}
+	held, err := r.client.QueryHeldPayments()
+	if err != nil {
+		return nil, err
+	}
+	awaiting, err := r.client.QueryPaymentsAwaitingApproval(accountID)
+	if err != nil {
+		return nil, err
+	}
+	payments = append(payments, held...)
+	for _, payment := range append(payments, awaiting...) {
+		if payment.PaymentID == paymentID {
+			return payment, nil
+		}
+	}
+	return nil, contracterrors.New(contracterrors.NotFound, "payment %s of account %s does not exist", paymentID, accountID)
+}
+
+func createPayment(r *request) (interface{}, error) {
+	var body createPaymentBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	if body.BeneficiaryID != "" && body.ReceiverAccountID == "" {
+		// The quote needs the receiver account
+		beneficiary, err := r.client.QueryBeneficiary(body.SenderCustomerID, body.BeneficiaryID)
+		if err != nil {
+			return nil, err
+		}
+		body.ReceiverAccountID, body.ReceiverCustomerID = beneficiary.ReceiverAccountID, beneficiary.ReceiverCustomerID
+	}
+	if body.ExchangeRate == 0 {
+		quote, err := quote(r.client, QuoteRequest{SenderAccountID: body.SenderAccountID, ReceiverAccountID: body.ReceiverAccountID, Amount: body.Amount})
+		if err != nil {
+			return nil, err
+		}
+		body.ExchangeRate = quote.ExchangeRate
+	}
+	if body.Date == "" {
+		body.Date = time.Now().UTC().Format("2006-01-02")
+	}
+	err = r.client.CreatePayment(bank.PaymentInstruction(body))
+	if err != nil {
+		return nil, err
+	}
+	return findPayment(r, body.SenderAccountID, body.PaymentID)
+}
+
+func createBatch(r *request) (interface{}, error) {
+	var body createBatchBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	if body.Mode == "" {
+		body.Mode = bank.BatchModeAtomic
+	}
+	return r.client.CreatePaymentBatch(body.BatchID, body.Instructions, body.Mode)
+}
+
+func getBatch(r *request) (interface{}, error) {
+	return r.client.QueryPaymentBatch(r.params["batchID"])
+}
+
+func listHeldPayments(r *request) (interface{}, error) {
+	return r.client.QueryHeldPayments()
+}
+
+func releaseHeldPayment(r *request) (interface{}, error) {
+	return nil, r.client.ReleaseHeldPayment(r.params["paymentID"])
+}
+
+func rejectHeldPayment(r *request) (interface{}, error) {
+	return nil, r.client.RejectHeldPayment(r.params["paymentID"])
+}
+
+func approvePayment(r *request) (interface{}, error) {
+	var body paymentApprovalBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.ApprovePayment(r.params["paymentID"], body.CustomerID)
+}
+
+func declinePayment(r *request) (interface{}, error) {
+	var body paymentApprovalBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return nil, r.client.DeclinePayment(r.params["paymentID"], body.CustomerID)
+}
+
+func createPaymentRequest(r *request) (interface{}, error) {
+	var body createPaymentRequestBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.CreatePaymentRequest(cbpsclient.RequestToPay(body))
+}
+
+func getPaymentRequest(r *request) (interface{}, error) {
+	return r.client.QueryPaymentRequest(r.params["requestID"])
+}
+
+func listPendingPaymentRequests(r *request) (interface{}, error) {
+	return r.client.QueryPendingPaymentRequests(r.params["customerID"])
+}
+
+func acceptPaymentRequest(r *request) (interface{}, error) {
+	var body acceptPaymentRequestBody
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.AcceptPaymentRequest(r.params["requestID"], body.PaymentID, body.PayerAccountID)
+}
+
+func declinePaymentRequest(r *request) (interface{}, error) {
+	// The reason is optional, and so is the body
+	var body declinePaymentRequestBody
+	if len(r.body) > 0 {
+		err := r.decode(&body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, r.client.DeclinePaymentRequest(r.params["requestID"], body.Reason)
+}
+
+func listPendingOperations(r *request) (interface{}, error) {
+	return r.client.QueryPendingOperations()
+}
+
+func getOperation(r *request) (interface{}, error) {
+	return r.client.QueryOperation(r.params["operationID"])
+}
+
+func approveOperation(r *request) (interface{}, error) {
+	return r.client.ApproveOperation(r.params["operationID"])
+}
+
+func rejectOperation(r *request) (interface{}, error) {
+	// The reason is optional, and so is the body
+	var body rejectOperationBody
+	if len(r.body) > 0 {
+		err := r.decode(&body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, r.client.RejectOperation(r.params["operationID"], body.Reason)
+}
+
+func createQuote(r *request) (interface{}, error) {
+	var body QuoteRequest
+	err := r.decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	return quote(r.client, body)
+}
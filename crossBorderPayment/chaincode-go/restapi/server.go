@@ -0,0 +1,264 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package restapi is an HTTP service exposing the bank chaincode as
+// resource-oriented JSON endpoints under /banks, /customers, /accounts,
+// /payments and /quotes. Each request acts as the wallet identity its API key
+// maps to, through a transport kept per identity. Writes honour the
+// Idempotency-Key header, and /openapi.json describes the API with schemas
+// taken from the deployed contract's metadata.
+package restapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// maxBodySize limits request bodies, which are small except for batches
+const maxBodySize = 1 << 20
+
+// Codes of errors raised by the service itself rather than the contract
+const (
+	CodeUnauthenticated  contracterrors.Code = "UNAUTHENTICATED"
+	CodeMethodNotAllowed contracterrors.Code = "METHOD_NOT_ALLOWED"
+	CodeIdempotency      contracterrors.Code = "IDEMPOTENCY_CONFLICT"
+	CodeUnavailable      contracterrors.Code = "UNAVAILABLE"
+)
+
+// Options configures a Server
+type Options struct {
+	Gateway      Gateway
+	Wallet       Wallet
+	Authenticate Authenticator
+
+	// SpecIdentity is the wallet label used to read the contract metadata
+	// for /openapi.json, which is served without authentication
+	SpecIdentity string
+
+	// IdempotencyTTL is how long responses are kept for retried requests,
+	// DefaultIdempotencyTTL when zero
+	IdempotencyTTL time.Duration
+}
+
+// Server serves the REST API
+type Server struct {
+	options     Options
+	idempotency *idempotencyStore
+	spec        *specCache
+}
+
+// NewServer returns a server for options
+func NewServer(options Options) *Server {
+	return &Server{
+		options:     options,
+		idempotency: newIdempotencyStore(options.IdempotencyTTL),
+		spec:        &specCache{},
+	}
+}
+
+// apiError is the JSON body of error responses
+type apiError struct {
+	Code    contracterrors.Code `json:"code"`
+	Message string              `json:"message"`
+	Details interface{}         `json:"details,omitempty"`
+}
+
+// request is what route handlers work with
+type request struct {
+	*http.Request
+	client *cbpsclient.Client
+	params map[string]string
+	body   []byte
+}
+
+// decode reads the JSON body into v, rejecting unknown fields
+func (r *request) decode(v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(r.body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		return contracterrors.New(contracterrors.Validation, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// response is a complete response, kept for idempotent replays
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == SpecPath {
+		s.serveSpec(w, r)
+		return
+	}
+
+	rt, params, allowed := matchRoute(r.Method, r.URL.Path)
+	if rt == nil {
+		if len(allowed) == 0 {
+			writeResponse(w, errorResponse(contracterrors.New(contracterrors.NotFound, "no resource at %s", r.URL.Path)))
+			return
+		}
+		res := jsonResponse(http.StatusMethodNotAllowed, &apiError{Code: CodeMethodNotAllowed, Message: fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path)})
+		res.header.Set("Allow", strings.Join(allowed, ", "))
+		writeResponse(w, res)
+		return
+	}
+
+	label, err := s.options.Authenticate(r)
+	if err != nil {
+		res := jsonResponse(http.StatusUnauthorized, &apiError{Code: CodeUnauthenticated, Message: err.Error()})
+		res.header.Set("WWW-Authenticate", "Bearer")
+		writeResponse(w, res)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil || len(body) > maxBodySize {
+		writeResponse(w, errorResponse(contracterrors.New(contracterrors.Validation, "request body is unreadable or larger than %d bytes", maxBodySize)))
+		return
+	}
+
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" || r.Method == http.MethodGet {
+		writeResponse(w, s.run(rt, r, label, params, body))
+		return
+	}
+
+	storeKey := label + "\x00" + key
+	state, recorded := s.idempotency.begin(storeKey, fingerprint(r, body))
+	switch state {
+	case idempotencyMismatch:
+		writeResponse(w, jsonResponse(http.StatusUnprocessableEntity, &apiError{Code: CodeIdempotency, Message: "the idempotency key was used for a different request"}))
+	case idempotencyInProgress:
+		writeResponse(w, jsonResponse(http.StatusConflict, &apiError{Code: CodeIdempotency, Message: "a request with this idempotency key is still in progress"}))
+	case idempotencyReplay:
+		res := &response{status: recorded.status, header: recorded.header.Clone(), body: recorded.body}
+		res.header.Set(IdempotentReplayedHeader, "true")
+		writeResponse(w, res)
+	default:
+		// A handler that panics never reaches finish
+		defer s.idempotency.release(storeKey)
+		res := s.run(rt, r, label, params, body)
+		s.idempotency.finish(storeKey, res.status, res.header, res.body)
+		writeResponse(w, res)
+	}
+}
+
+// run connects as the caller's identity and runs the route's handler
+func (s *Server) run(rt *route, r *http.Request, label string, params map[string]string, body []byte) *response {
+	client, err := s.connect(label)
+	if err != nil {
+		return errorResponse(err)
+	}
+	result, err := rt.handle(&request{Request: r, client: client, params: params, body: body})
+	if err != nil {
+		return errorResponse(err)
+	}
+	if rt.status == http.StatusNoContent {
+		return &response{status: rt.status, header: make(http.Header)}
+	}
+	return jsonResponse(rt.status, result)
+}
+
+func (s *Server) connect(label string) (*cbpsclient.Client, error) {
+	identity, err := s.options.Wallet.Get(label)
+	if errors.Is(err, ErrUnknownIdentity) {
+		return nil, contracterrors.New(contracterrors.Forbidden, "identity %s is not in the wallet", label)
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		return nil, contracterrors.New(contracterrors.Internal, "failed to load identity %s", label)
+	}
+	transport, err := s.options.Gateway.Connect(identity)
+	if err != nil {
+		return nil, &unavailableError{err: err}
+	}
+	return cbpsclient.New(transport), nil
+}
+
+// unavailableError means the chaincode could not be reached
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return fmt.Sprintf("failed to connect to the chaincode: %v", e.err)
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// statusOf returns the HTTP status of a contract error code
+func statusOf(code contracterrors.Code) int {
+	switch code {
+	case contracterrors.NotFound:
+		return http.StatusNotFound
+	case contracterrors.AlreadyExists, contracterrors.InvalidState, contracterrors.RateStale:
+		return http.StatusConflict
+	case contracterrors.Forbidden:
+		return http.StatusForbidden
+	case contracterrors.Validation:
+		return http.StatusBadRequest
+	case contracterrors.InsufficientFunds, contracterrors.LimitExceeded:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorResponse turns an error into a JSON error response. Contract errors
+// keep their code; errors of the transport become 502 Bad Gateway.
+func errorResponse(err error) *response {
+	var clientErr *cbpsclient.Error
+	var contractErr *contracterrors.Error
+	var unavailable *unavailableError
+	switch {
+	case errors.As(err, &clientErr):
+		return jsonResponse(statusOf(clientErr.Code), &apiError{Code: clientErr.Code, Message: clientErr.Message, Details: clientErr.Details})
+	case errors.As(err, &contractErr):
+		return jsonResponse(statusOf(contractErr.Code), &apiError{Code: contractErr.Code, Message: contractErr.Message, Details: contractErr.Details})
+	case errors.As(err, &unavailable):
+		log.Printf("%v", err)
+		return jsonResponse(http.StatusServiceUnavailable, &apiError{Code: CodeUnavailable, Message: "the chaincode is unavailable"})
+	default:
+		// Transport failures and unexpected results may hold details of
+		// the network that callers should not see
+		log.Printf("request failed: %v", err)
+		return jsonResponse(http.StatusBadGateway, &apiError{Code: contracterrors.Internal, Message: "the request to the chaincode failed"})
+	}
+}
+
+func jsonResponse(status int, v interface{}) *response {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+		status = http.StatusInternalServerError
+		body = []byte(`{"code":"INTERNAL","message":"failed to encode the response"}`)
+	}
+	return &response{status: status, header: header, body: append(body, '\n')}
+}
+
+func writeResponse(w http.ResponseWriter, res *response) {
+	for name, values := range res.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(res.status)
+	_, _ = w.Write(res.body)
+}
@@ -0,0 +1,444 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/cbpsclient/cbpsclienttest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+// fakeGateway connects every identity to the same fake transport
+type fakeGateway struct {
+	transport *cbpsclienttest.Transport
+	err       error
+	labels    []string
+}
+
+func (g *fakeGateway) Connect(identity *Identity) (cbpsclient.Transport, error) {
+	g.labels = append(g.labels, identity.Label)
+	if g.err != nil {
+		return nil, g.err
+	}
+	return g.transport, nil
+}
+
+type mapWallet map[string]*Identity
+
+func (w mapWallet) Get(label string) (*Identity, error) {
+	identity, ok := w[label]
+	if !ok {
+		return nil, ErrUnknownIdentity
+	}
+	return identity, nil
+}
+
+func newTestServer() (*Server, *fakeGateway) {
+	gateway := &fakeGateway{transport: cbpsclienttest.NewTransport()}
+	server := NewServer(Options{
+		Gateway: gateway,
+		Wallet: mapWallet{
+			"bank1admin": {Label: "bank1admin", MSPID: "Org1MSP"},
+			"spec":       {Label: "spec", MSPID: "Org1MSP"},
+		},
+		Authenticate: APIKeys(map[string]string{"key1": "bank1admin", "orphan": "missing"}),
+		SpecIdentity: "spec",
+	})
+	return server, gateway
+}
+
+// do sends a request with API key key1 and extra headers as name, value pairs
+func do(server *Server, method string, path string, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer key1")
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) apiError {
+	t.Helper()
+	var body apiError
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("error body %q is not JSON: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+func TestRoutes(t *testing.T) {
+	server, gateway := newTestServer()
+	transport := gateway.transport
+	transport.Return("bank:QueryBank", &bank.Bank{BankID: "BANK1", Name: "First", Password: "secret", Currency: "EUR"})
+	transport.Return("bank:CreateBank", nil)
+	transport.Return("customer:QueryCustomer", &bank.Customer{CustomerID: "C1", Password: "secret"})
+	transport.Return("account:QueryAccount", &bank.Account{AccountID: "A1", Balance: 10})
+	transport.Return("payment:ReleaseHeldPayment", nil)
+
+	w := do(server, http.MethodPost, "/banks", `{"bankID":"BANK1","name":"First","password":"secret","country":"DE","currency":"EUR","reserves":1000,"exchangeRate":1.1}`)
+	if w.Code != http.StatusCreated || strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("POST /banks = %d %s", w.Code, w.Body.String())
+	}
+	calls := transport.Calls()
+	if calls[0].Name != "bank:CreateBank" || !calls[0].Submit || strings.Join(calls[0].Args, " ") != "BANK1  First secret DE EUR 1000 1.1 " {
+		t.Fatalf("unexpected call %+v", calls[0])
+	}
+
+	w = do(server, http.MethodGet, "/customers/C1", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "secret") {
+		t.Fatalf("GET /customers/C1 = %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(server, http.MethodGet, "/accounts/A1", "")
+	var account bank.Account
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &account) != nil || account.AccountID != "A1" {
+		t.Fatalf("GET /accounts/A1 = %d %s", w.Code, w.Body.String())
+	}
+	if call, _ := transport.LastCall(); call.Name != "account:QueryAccount" || call.Args[0] != "A1" || call.Submit {
+		t.Fatalf("unexpected call %+v", call)
+	}
+
+	w = do(server, http.MethodPost, "/payments/held/P1/release", "")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("release = %d %q", w.Code, w.Body.String())
+	}
+
+	for _, label := range gateway.labels {
+		if label != "bank1admin" {
+			t.Fatalf("connected as %s, want the API key's identity", label)
+		}
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		setup      func(g *fakeGateway)
+		wantStatus int
+		wantCode   contracterrors.Code
+	}{
+		{
+			name: "unknown path", method: http.MethodGet, path: "/nowhere",
+			wantStatus: http.StatusNotFound, wantCode: contracterrors.NotFound,
+		},
+		{
+			name: "wrong method", method: http.MethodDelete, path: "/banks/BANK1",
+			wantStatus: http.StatusMethodNotAllowed, wantCode: CodeMethodNotAllowed,
+		},
+		{
+			name: "unknown field", method: http.MethodPut, path: "/banks/BANK1/exchange-rate", body: `{"rate":1.2}`,
+			wantStatus: http.StatusBadRequest, wantCode: contracterrors.Validation,
+		},
+		{
+			name: "contract error", method: http.MethodGet, path: "/banks/BANK9",
+			setup: func(g *fakeGateway) {
+				g.transport.Fail("bank:QueryBank", contracterrors.New(contracterrors.NotFound, "bank BANK9 does not exist"))
+			},
+			wantStatus: http.StatusNotFound, wantCode: contracterrors.NotFound,
+		},
+		{
+			name: "limit exceeded", method: http.MethodPost, path: "/payments/batches", body: `{"batchID":"B1","instructions":[]}`,
+			setup: func(g *fakeGateway) {
+				g.transport.Fail("payment:CreatePaymentBatch", contracterrors.New(contracterrors.LimitExceeded, "daily limit exceeded"))
+			},
+			wantStatus: http.StatusUnprocessableEntity, wantCode: contracterrors.LimitExceeded,
+		},
+		{
+			name: "transport error", method: http.MethodGet, path: "/banks/BANK1",
+			setup: func(g *fakeGateway) {
+				g.transport.Fail("bank:QueryBank", errors.New("peer0.org1 is down"))
+			},
+			wantStatus: http.StatusBadGateway, wantCode: contracterrors.Internal,
+		},
+		{
+			name: "gateway down", method: http.MethodGet, path: "/banks/BANK1",
+			setup:      func(g *fakeGateway) { g.err = errors.New("connection refused") },
+			wantStatus: http.StatusServiceUnavailable, wantCode: CodeUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, gateway := newTestServer()
+			if tt.setup != nil {
+				tt.setup(gateway)
+			}
+			w := do(server, tt.method, tt.path, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if body := decodeError(t, w); body.Code != tt.wantCode {
+				t.Fatalf("code = %s, want %s", body.Code, tt.wantCode)
+			}
+			if strings.Contains(w.Body.String(), "peer0") {
+				t.Fatalf("response leaks the transport error: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server, gateway := newTestServer()
+	gateway.transport.Return("bank:QueryBank", &bank.Bank{BankID: "BANK1"})
+
+	for _, authorization := range []string{"", "key1", "Bearer wrong"} {
+		r := httptest.NewRequest(http.MethodGet, "/banks/BANK1", nil)
+		r.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Fatalf("Authorization %q: status = %d", authorization, w.Code)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/banks/BANK1", nil)
+	r.Header.Set("Authorization", "Bearer orphan")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("key of an identity missing from the wallet: status = %d", w.Code)
+	}
+	if len(gateway.transport.Calls()) != 0 {
+		t.Fatalf("unauthenticated requests reached the chaincode")
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	server, gateway := newTestServer()
+	transport := gateway.transport
+	transport.Return("bank:UpdateExchangeRate", nil)
+	transport.Return("bank:QueryBank", &bank.Bank{BankID: "BANK1", ExchangeRate: 1.2})
+	submits := func() int {
+		n := 0
+		for _, call := range transport.Calls() {
+			if call.Submit {
+				n++
+			}
+		}
+		return n
+	}
+
+	first := do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.2}`, IdempotencyKeyHeader, "k1")
+	retry := do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.2}`, IdempotencyKeyHeader, "k1")
+	if first.Code != http.StatusOK || retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Fatalf("retry = %d %s, first = %d %s", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("only the retry should be marked replayed")
+	}
+	if submits() != 1 {
+		t.Fatalf("retry submitted again, %d submits", submits())
+	}
+
+	w := do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.3}`, IdempotencyKeyHeader, "k1")
+	if w.Code != http.StatusUnprocessableEntity || decodeError(t, w).Code != CodeIdempotency {
+		t.Fatalf("reused key = %d %s", w.Code, w.Body.String())
+	}
+
+	// Server errors are not kept, so the retry runs again
+	transport.Fail("bank:UpdateExchangeRate", errors.New("timeout"))
+	w = do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.2}`, IdempotencyKeyHeader, "k2")
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("failed request = %d", w.Code)
+	}
+	transport.Return("bank:UpdateExchangeRate", nil)
+	w = do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.2}`, IdempotencyKeyHeader, "k2")
+	if w.Code != http.StatusOK || w.Header().Get(IdempotentReplayedHeader) != "" || submits() != 3 {
+		t.Fatalf("retry after a server error = %d, %d submits", w.Code, submits())
+	}
+
+	// A handler that panics releases its key
+	transport.Handle("bank:UpdateExchangeRate", func(args []string) ([]byte, error) { panic("gateway crashed") })
+	func() {
+		defer func() { _ = recover() }()
+		do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.2}`, IdempotencyKeyHeader, "k3")
+	}()
+	transport.Return("bank:UpdateExchangeRate", nil)
+	w = do(server, http.MethodPut, "/banks/BANK1/exchange-rate", `{"exchangeRate":1.2}`, IdempotencyKeyHeader, "k3")
+	if w.Code != http.StatusOK || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("retry after a panic = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFingerprintIncludesQuery(t *testing.T) {
+	first := httptest.NewRequest(http.MethodPost, "/payments?dryRun=true", nil)
+	second := httptest.NewRequest(http.MethodPost, "/payments?dryRun=false", nil)
+	if fingerprint(first, []byte("{}")) == fingerprint(second, []byte("{}")) {
+		t.Fatalf("requests that differ in the query have the same fingerprint")
+	}
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	store := newIdempotencyStore(time.Hour)
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	if state, _ := store.begin("k", "f"); state != idempotencyNew {
+		t.Fatalf("first use = %v", state)
+	}
+	if state, _ := store.begin("k", "f"); state != idempotencyInProgress {
+		t.Fatalf("concurrent use = %v", state)
+	}
+	store.finish("k", http.StatusCreated, http.Header{}, []byte("{}"))
+	if state, recorded := store.begin("k", "f"); state != idempotencyReplay || recorded.status != http.StatusCreated {
+		t.Fatalf("retry = %v", state)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if state, _ := store.begin("k", "other"); state != idempotencyNew {
+		t.Fatalf("expired key = %v", state)
+	}
+
+	// A claim that is never finished expires, and a released one is free
+	now = now.Add(idempotencyClaimTimeout + time.Second)
+	if state, _ := store.begin("k", "f"); state != idempotencyNew {
+		t.Fatalf("abandoned claim = %v", state)
+	}
+	store.release("k")
+	if state, _ := store.begin("k", "f"); state != idempotencyNew {
+		t.Fatalf("released claim = %v", state)
+	}
+	store.finish("k", http.StatusOK, http.Header{}, nil)
+	store.release("k")
+	if state, _ := store.begin("k", "f"); state != idempotencyReplay {
+		t.Fatalf("release dropped a finished response: %v", state)
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	server, gateway := newTestServer()
+	transport := gateway.transport
+	accounts := map[string]*bank.Account{
+		"A1": {AccountID: "A1", BankID: "BANK1", Currency: "EUR"},
+		"A2": {AccountID: "A2", BankID: "BANK2", Currency: "GBP"},
+		"A3": {AccountID: "A3", BankID: "BANK1", Currency: "EUR"},
+	}
+	transport.Handle("account:QueryAccount", func(args []string) ([]byte, error) {
+		return json.Marshal(accounts[args[0]])
+	})
+	rateDate := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+	banks := map[string]*bank.Bank{
+		"BANK1": {BankID: "BANK1", Currency: "EUR", ExchangeRate: 0.9, ExchangeRateDate: rateDate},
+		"BANK2": {BankID: "BANK2", Currency: "GBP", ExchangeRate: 0.8, ExchangeRateDate: rateDate},
+	}
+	transport.Handle("bank:QueryBank", func(args []string) ([]byte, error) {
+		return json.Marshal(banks[args[0]])
+	})
+	transport.Return("admin:GetConfig", &bank.ChaincodeConfig{RateMaxAgeSeconds: 3600, DefaultFeeRate: 0.01, DefaultFlatFee: 2})
+
+	w := do(server, http.MethodPost, "/quotes", `{"senderAccountID":"A1","receiverAccountID":"A2","amount":90}`)
+	var q Quote
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &q) != nil {
+		t.Fatalf("quote = %d %s", w.Code, w.Body.String())
+	}
+	if q.SourceCurrency != "EUR" || q.TargetCurrency != "GBP" || q.ConvertedAmount != 80 || q.Fee != 2.9 || q.TotalDebit != 92.9 || q.ValidUntil == "" {
+		t.Fatalf("unexpected quote %+v", q)
+	}
+
+	w = do(server, http.MethodPost, "/quotes", `{"senderAccountID":"A1","receiverAccountID":"A3","amount":50}`)
+	q = Quote{}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &q) != nil || q.ExchangeRate != 1 || q.ConvertedAmount != 50 || q.ValidUntil != "" {
+		t.Fatalf("same-currency quote = %d %s", w.Code, w.Body.String())
+	}
+
+	w = do(server, http.MethodPost, "/quotes", `{"senderAccountID":"A1","receiverAccountID":"A1","amount":50}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("quote to the same account = %d", w.Code)
+	}
+
+	banks["BANK2"].ExchangeRateDate = time.Now().UTC().Add(-2 * time.Hour).Format(time.RFC3339)
+	w = do(server, http.MethodPost, "/quotes", `{"senderAccountID":"A1","receiverAccountID":"A2","amount":90}`)
+	if w.Code != http.StatusConflict || decodeError(t, w).Code != contracterrors.RateStale {
+		t.Fatalf("stale quote = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreatePaymentUsesQuotedRate(t *testing.T) {
+	server, gateway := newTestServer()
+	transport := gateway.transport
+	transport.Handle("account:QueryAccount", func(args []string) ([]byte, error) {
+		return json.Marshal(&bank.Account{AccountID: args[0], BankID: "BANK" + args[0][1:], Currency: map[string]string{"A1": "EUR", "A2": "GBP"}[args[0]]})
+	})
+	transport.Handle("bank:QueryBank", func(args []string) ([]byte, error) {
+		return json.Marshal(&bank.Bank{BankID: args[0], ExchangeRate: map[string]float64{"BANK1": 0.5, "BANK2": 2}[args[0]]})
+	})
+	transport.Return("admin:GetConfig", &bank.ChaincodeConfig{})
+	transport.Return("payment:CreatePayment", nil)
+	transport.Return("payment:QueryPayments", []*bank.Payment{{PaymentID: "P1", Status: "completed"}})
+	transport.Return("payment:QueryHeldPayments", []*bank.Payment{})
+	transport.Return("payment:QueryPaymentsAwaitingApproval", []*bank.Payment{})
+
+	transport.Return("customer:QueryBeneficiary", &bank.Beneficiary{CustomerID: "C1", BeneficiaryID: "BOB", ReceiverAccountID: "A2", ReceiverCustomerID: "C2"})
+
+	bodies := []string{
+		`{"paymentID":"P1","senderCustomerID":"C1","receiverCustomerID":"C2","senderAccountID":"A1","receiverAccountID":"A2","amount":10}`,
+		`{"paymentID":"P1","senderCustomerID":"C1","senderAccountID":"A1","beneficiaryID":"BOB","amount":10}`,
+	}
+	for _, body := range bodies {
+		w := do(server, http.MethodPost, "/payments", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST /payments = %d %s", w.Code, w.Body.String())
+		}
+		var call cbpsclienttest.Call
+		for _, c := range transport.Calls() {
+			if c.Name == "payment:CreatePayment" {
+				call = c
+			}
+		}
+		if call.Name != "payment:CreatePayment" {
+			t.Fatalf("payment was not submitted")
+		}
+		if call.Args[2] != "A2" || call.Args[6] != "4" || call.Args[7] != time.Now().UTC().Format("2006-01-02") {
+			t.Fatalf("payment sent to %s with rate %s on %s", call.Args[2], call.Args[6], call.Args[7])
+		}
+	}
+}
+
+func TestAcceptPaymentRequest(t *testing.T) {
+	server, gateway := newTestServer()
+	transport := gateway.transport
+	transport.Return("payment:AcceptPaymentRequest", &bank.Payment{PaymentID: "P1", Amount: 10})
+	transport.Return("payment:DeclinePaymentRequest", nil)
+
+	// The contract uses the banks' exchange rate, callers cannot give one
+	w := do(server, http.MethodPost, "/payment-requests/R1/accept", `{"paymentID":"P1","payerAccountID":"A1","exchangeRate":1e9}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("POST /payment-requests/R1/accept with a rate = %d %s", w.Code, w.Body.String())
+	}
+	w = do(server, http.MethodPost, "/payment-requests/R1/accept", `{"paymentID":"P1","payerAccountID":"A1"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /payment-requests/R1/accept = %d %s", w.Code, w.Body.String())
+	}
+	var call cbpsclienttest.Call
+	for _, c := range transport.Calls() {
+		if c.Name == "payment:AcceptPaymentRequest" {
+			call = c
+		}
+	}
+	if !reflect.DeepEqual(call.Args, []string{"R1", "P1", "A1"}) {
+		t.Fatalf("accepted with %v", call.Args)
+	}
+
+	w = do(server, http.MethodPost, "/payment-requests/R1/decline", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("POST /payment-requests/R1/decline = %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,246 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// Account statuses
+const (
+	AccountActive  = "active"
+	AccountFrozen  = "frozen"
+	AccountDormant = "dormant"
+	AccountClosed  = "closed"
+)
+
+// accountTransitions lists the statuses each status may move to. Closed is final.
+var accountTransitions = map[string][]string{
+	AccountActive:  {AccountFrozen, AccountDormant, AccountClosed},
+	AccountDormant: {AccountActive, AccountFrozen, AccountClosed},
+	AccountFrozen:  {AccountActive},
+}
+
+// FreezeAccount blocks all payments to and from the account. The account's bank
+// administrator or a compliance officer can freeze it.
+func (s *AccountContract) FreezeAccount(ctx contractapi.TransactionContextInterface, accountID string, reason string) error {
+	account, err := getAccountForAdmin(ctx, accountID, true)
+	if err != nil {
+		return err
+	}
+	return setAccountStatus(ctx, account, AccountFrozen, reason)
+}
+
+// UnfreezeAccount makes a frozen account active again
+func (s *AccountContract) UnfreezeAccount(ctx contractapi.TransactionContextInterface, accountID string) error {
+	account, err := getAccountForAdmin(ctx, accountID, true)
+	if err != nil {
+		return err
+	}
+	if accountStatus(account) != AccountFrozen {
+		return contracterrors.New(contracterrors.InvalidState, "account %s is not frozen", accountID)
+	}
+	return setAccountStatus(ctx, account, AccountActive, "")
+}
+
+// MarkAccountDormant flags an unused account. Dormant accounts cannot send or
+// receive payments until they are reactivated.
+func (s *AccountContract) MarkAccountDormant(ctx contractapi.TransactionContextInterface, accountID string) error {
+	account, err := getAccountForAdmin(ctx, accountID, false)
+	if err != nil {
+		return err
+	}
+	return setAccountStatus(ctx, account, AccountDormant, "")
+}
+
+// ReactivateAccount makes a dormant account active again
+func (s *AccountContract) ReactivateAccount(ctx contractapi.TransactionContextInterface, accountID string) error {
+	account, err := getAccountForAdmin(ctx, accountID, false)
+	if err != nil {
+		return err
+	}
+	if accountStatus(account) != AccountDormant {
+		return contracterrors.New(contracterrors.InvalidState, "account %s is not dormant", accountID)
+	}
+	return setAccountStatus(ctx, account, AccountActive, "")
+}
+
+// CloseAccount closes an account for good. The balance must be zero unless
+// sweepAccountID names another active account of the same customer and
+// currency, in which case the remaining balance is moved there first. The sweep
+// does not count against the customer's limits. Closed accounts keep their
+// state and history and stay listed on their bank and customer.
+func (s *AccountContract) CloseAccount(ctx contractapi.TransactionContextInterface, accountID string, sweepAccountID string) error {
+	err := checkArgs(
+		validation.ID("accountID", accountID),
+		validation.OptionalID("sweepAccountID", sweepAccountID),
+	)
+	if err != nil {
+		return err
+	}
+
+	account, err := getAccountForAdmin(ctx, accountID, false)
+	if err != nil {
+		return err
+	}
+	if !contains(accountTransitions[accountStatus(account)], AccountClosed) {
+		return contracterrors.New(contracterrors.InvalidState, "account %s is %s and cannot be closed", accountID, accountStatus(account))
+	}
+
+	if account.Balance != 0 {
+		if sweepAccountID == "" {
+			return contracterrors.New(contracterrors.InvalidState, "account %s still holds %v %s, name an account to sweep it to", accountID, account.Balance, account.Currency)
+		}
+		if sweepAccountID == accountID {
+			return contracterrors.New(contracterrors.Validation, "account %s cannot be swept into itself", accountID)
+		}
+		if account.Balance < 0 {
+			return contracterrors.New(contracterrors.InvalidState, "account %s is overdrawn by %v %s", accountID, -account.Balance, account.Currency)
+		}
+
+		ledger := newPaymentLedger(ctx)
+		target, err := ledger.account(sweepAccountID)
+		if err != nil {
+			return err
+		}
+		if target.CustomerID != account.CustomerID || target.Currency != account.Currency {
+			return contracterrors.New(contracterrors.Validation, "sweep account %s must belong to customer %s and hold %s", sweepAccountID, account.CustomerID, account.Currency)
+		}
+		err = checkAccountCanTransact(target)
+		if err != nil {
+			return err
+		}
+		targetBank, err := ledger.bank(target.BankID)
+		if err != nil {
+			return err
+		}
+		err = checkBankCanTransact(targetBank)
+		if err != nil {
+			return err
+		}
+
+		date, err := getTxDate(ctx)
+		if err != nil {
+			return err
+		}
+		sweep := Payment{
+			PaymentID:          "sweep-" + ctx.GetStub().GetTxID(),
+			SenderCustomerID:   account.CustomerID,
+			ReceiverCustomerID: target.CustomerID,
+			SenderAccountID:    accountID,
+			ReceiverAccountID:  sweepAccountID,
+			Amount:             account.Balance,
+			ExchangeRate:       1,
+			Date:               date,
+			SchemaVersion:      SchemaVersion,
+		}
+		err = ledger.sweep(&sweep)
+		if err != nil {
+			return err
+		}
+		err = ledger.flush()
+		if err != nil {
+			return err
+		}
+
+		account, err = ledger.account(accountID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return setAccountStatus(ctx, account, AccountClosed, "")
+}
+
+// checkAccountCanTransact returns an error unless the account is active
+func checkAccountCanTransact(account *Account) error {
+	if status := accountStatus(account); status != AccountActive {
+		return contracterrors.New(contracterrors.InvalidState, "account %s is %s", account.AccountID, status)
+	}
+	return nil
+}
+
+// checkAccountStatus refuses the payment unless both accounts and their banks
+// are active
+func (l *paymentLedger) checkAccountStatus(payment *Payment) error {
+	for _, accountID := range []string{payment.SenderAccountID, payment.ReceiverAccountID} {
+		account, err := l.account(accountID)
+		if err != nil {
+			return err
+		}
+		err = checkAccountCanTransact(account)
+		if err != nil {
+			return err
+		}
+		bank, err := l.bank(account.BankID)
+		if err != nil {
+			return err
+		}
+		err = checkBankCanTransact(bank)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountStatus treats accounts stored before statuses existed as active
+func accountStatus(account *Account) string {
+	if account.Status == "" {
+		return AccountActive
+	}
+	return account.Status
+}
+
+func setAccountStatus(ctx contractapi.TransactionContextInterface, account *Account, status string, reason string) error {
+	current := accountStatus(account)
+	if !contains(accountTransitions[current], status) {
+		return contracterrors.New(contracterrors.InvalidState, "account %s cannot move from %s to %s", account.AccountID, current, status)
+	}
+
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	account.Status = status
+	account.StatusReason = reason
+	account.StatusDate = date
+	return putAccount(ctx, account)
+}
+
+// getAccountForAdmin loads the account after checking that the caller
+// administers the account's bank, which must be a member, or, if
+// allowCompliance is set, holds the compliance role.
+func getAccountForAdmin(ctx contractapi.TransactionContextInterface, accountID string, allowCompliance bool) (*Account, error) {
+	err := checkArgs(validation.ID("accountID", accountID))
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := getAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if allowCompliance && requireRole(ctx, RoleCompliance) == nil {
+		return account, nil
+	}
+
+	bank, err := getBank(ctx, account.BankID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireBankAdmin(ctx, bank)
+	if err != nil {
+		return nil, err
+	}
+	err = checkBankCanTransact(bank)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
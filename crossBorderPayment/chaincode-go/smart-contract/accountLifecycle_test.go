@@ -0,0 +1,185 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+)
+
+func TestAccountStatusTransitions(t *testing.T) {
+	freeze := func(f *fixture) txFunc {
+		return func(ctx contractapi.TransactionContextInterface) error {
+			return f.accounts.FreezeAccount(ctx, "A1", "fraud")
+		}
+	}
+	unfreeze := func(f *fixture) txFunc {
+		return func(ctx contractapi.TransactionContextInterface) error { return f.accounts.UnfreezeAccount(ctx, "A1") }
+	}
+	dormant := func(f *fixture) txFunc {
+		return func(ctx contractapi.TransactionContextInterface) error {
+			return f.accounts.MarkAccountDormant(ctx, "A1")
+		}
+	}
+	reactivate := func(f *fixture) txFunc {
+		return func(ctx contractapi.TransactionContextInterface) error {
+			return f.accounts.ReactivateAccount(ctx, "A1")
+		}
+	}
+
+	type step struct {
+		fn       func(f *fixture) txFunc
+		identity func(f *fixture) *chaincodetest.Identity
+		wantErr  string
+	}
+	admin := func(f *fixture) *chaincodetest.Identity { return f.bank1Admin }
+	compliance := func(f *fixture) *chaincodetest.Identity { return f.compliance }
+	otherAdmin := func(f *fixture) *chaincodetest.Identity { return f.bank2Admin }
+
+	tests := []struct {
+		name       string
+		steps      []step
+		wantStatus string
+	}{
+		{name: "admin freezes and unfreezes", steps: []step{{fn: freeze, identity: admin}, {fn: unfreeze, identity: admin}}, wantStatus: AccountActive},
+		{name: "compliance freezes", steps: []step{{fn: freeze, identity: compliance}}, wantStatus: AccountFrozen},
+		{name: "other bank cannot freeze", steps: []step{{fn: freeze, identity: otherAdmin, wantErr: "not the administrator"}}, wantStatus: AccountActive},
+		{name: "compliance cannot mark dormant", steps: []step{{fn: dormant, identity: compliance, wantErr: "not the administrator"}}, wantStatus: AccountActive},
+		{name: "dormant and reactivated", steps: []step{{fn: dormant, identity: admin}, {fn: reactivate, identity: admin}}, wantStatus: AccountActive},
+		{name: "dormant account frozen", steps: []step{{fn: dormant, identity: admin}, {fn: freeze, identity: admin}}, wantStatus: AccountFrozen},
+		{name: "frozen cannot go dormant", steps: []step{{fn: freeze, identity: admin}, {fn: dormant, identity: admin, wantErr: "cannot move from frozen to dormant"}}, wantStatus: AccountFrozen},
+		{name: "unfreeze active account", steps: []step{{fn: unfreeze, identity: admin, wantErr: "is not frozen"}}, wantStatus: AccountActive},
+		{name: "reactivate active account", steps: []step{{fn: reactivate, identity: admin, wantErr: "is not dormant"}}, wantStatus: AccountActive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			for _, s := range tt.steps {
+				checkErr(t, f.submit(s.identity(f), s.fn(f)), s.wantErr)
+			}
+			if status := f.account("A1").Status; status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPaymentsRequireActiveAccounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(f *fixture) txFunc
+		wantErr string
+	}{
+		{name: "frozen sender", setup: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.FreezeAccount(ctx, "A1", "")
+			}
+		}, wantErr: "account A1 is frozen"},
+		{name: "dormant receiver", setup: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.MarkAccountDormant(ctx, "A2")
+			}
+		}, wantErr: "account A2 is dormant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.FreezeAccount(ctx, "A2", "")
+			})
+			f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.UnfreezeAccount(ctx, "A2")
+			})
+			admin := f.bank1Admin
+			if tt.name == "dormant receiver" {
+				admin = f.bank2Admin
+			}
+			f.mustSubmit(admin, tt.setup(f))
+
+			checkErr(t, f.pay("P1", "A1", "A2", 10, 1), tt.wantErr)
+		})
+	}
+}
+
+func TestCloseAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		sweepTo   string
+		prepare   func(f *fixture)
+		wantErr   string
+		wantSweep float64
+	}{
+		{name: "balance without sweep account", wantErr: "still holds 1000 USD"},
+		{name: "sweep to own account", sweepTo: "A3", wantSweep: 1000},
+		{
+			name: "sweep to frozen account", sweepTo: "A3",
+			prepare: func(f *fixture) {
+				f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.accounts.FreezeAccount(ctx, "A3", "investigation")
+				})
+			},
+			wantErr: "account A3 is frozen",
+		},
+		{
+			name: "sweep at the daily limit", sweepTo: "A3",
+			prepare: func(f *fixture) {
+				f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.admin.SetTierLimit(ctx, DefaultTier, "USD", 0, 150, 0, 0, 0)
+				})
+				checkErr(f.t, f.pay("P0", "A1", "A2", 100, 0.9), "")
+			},
+			wantSweep: 900,
+		},
+		{name: "sweep to another customer", sweepTo: "A4", wantErr: "must belong to customer C1"},
+		{name: "sweep to other currency", sweepTo: "A5", wantErr: "and hold USD"},
+		{name: "sweep to itself", sweepTo: "A1", wantErr: "cannot be swept into itself"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			for _, account := range []struct{ id, customer, bank string }{{"A3", "C1", "BANK1"}, {"A4", "C2", "BANK1"}, {"A5", "C1", "BANK2"}} {
+				f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+					return f.accounts.CreateAccount(ctx, account.id, account.customer, account.bank, 0, "", "", "", "")
+				})
+			}
+
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+
+			err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CloseAccount(ctx, "A1", tt.sweepTo)
+			})
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+
+			closed := f.account("A1")
+			if closed.Status != AccountClosed || closed.Balance != 0 {
+				t.Fatalf("unexpected closed account %+v", closed)
+			}
+			assertFloat(t, "swept balance", f.account(tt.sweepTo).Balance, tt.wantSweep)
+			// The swept balance stays in BANK1's reserves
+			assertFloat(t, "BANK1 reserves", f.bank("BANK1").Reserves, 9000+tt.wantSweep)
+			// The sweep leaves the rest of the daily limit to the customer
+			checkErr(t, f.pay("P2", tt.sweepTo, "A2", 50, 0.9), "")
+
+			err = f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.ReactivateAccount(ctx, "A1")
+			})
+			checkErr(t, err, "is not dormant")
+			checkErr(t, f.pay("P1", "A1", "A3", 1, 1), "account A1 is closed")
+		})
+	}
+}
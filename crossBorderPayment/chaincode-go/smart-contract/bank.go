@@ -9,12 +9,10 @@ import (
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
 )
 
-type SmartContract struct {
-	contractapi.Contract
-}
-
 // Bank data
 type Bank struct {
 	Name         string   `json:"name"`
@@ -26,6 +24,19 @@ type Bank struct {
 	Reserves     float64  `json:"reserves"`
 	AccountIDs   []string `json:"accountIDs"`
 	ExchangeRate float64  `json:"exchangeRate"`
+
+	// ExchangeRateDate is when the bank last set its exchange rate
+	ExchangeRateDate string `json:"exchangeRateDate,omitempty" metadata:",optional"`
+	// BIC is the bank's 11 character business identifier code
+	BIC string `json:"bic,omitempty" metadata:",optional"`
+
+	// Status is where the bank stands in the consortium, which votes it in,
+	// suspends or expels it
+	Status       string `json:"status,omitempty" metadata:",optional"`
+	StatusReason string `json:"statusReason,omitempty" metadata:",optional"`
+	StatusDate   string `json:"statusDate,omitempty" metadata:",optional"`
+
+	SchemaVersion int `json:"schemaVersion"`
 }
 
 // Define the customer structure, with 3 properties.  Structure tags are used by encoding/json library
@@ -35,6 +46,22 @@ type Customer struct {
 	CustomerID string   `json:"customerID"`
 	Password   string   `json:"password"`
 	AccountIDs []string `json:"accountIDs"`
+	Tier       string   `json:"tier,omitempty" metadata:",optional"`
+
+	KYCStatus        string   `json:"kycStatus,omitempty" metadata:",optional"`
+	VerificationDate string   `json:"verificationDate,omitempty" metadata:",optional"`
+	VerifyingBankID  string   `json:"verifyingBankID,omitempty" metadata:",optional"`
+	KYCExpiryDate    string   `json:"kycExpiryDate,omitempty" metadata:",optional"`
+	DocumentHashes   []string `json:"documentHashes,omitempty" metadata:",optional"`
+	RiskRating       string   `json:"riskRating,omitempty" metadata:",optional"`
+
+	// ClientID is the client identity enrolled to act as the customer, in the
+	// form getSubmittingClientIdentity returns, and ClientBankID the bank that
+	// enrolled it
+	ClientID     string `json:"clientID,omitempty" metadata:",optional"`
+	ClientBankID string `json:"clientBankID,omitempty" metadata:",optional"`
+
+	SchemaVersion int `json:"schemaVersion"`
 }
 
 type Account struct {
@@ -44,24 +71,117 @@ type Account struct {
 	Balance    float64  `json:"balance"`
 	Currency   string   `json:"currency"`
 	PaymentIDs []string `json:"paymentIDs"`
+
+	Status       string `json:"status,omitempty" metadata:",optional"`
+	StatusReason string `json:"statusReason,omitempty" metadata:",optional"`
+	StatusDate   string `json:"statusDate,omitempty" metadata:",optional"`
+
+	// An account is identified outside the ledger by an IBAN or by a local
+	// account number at the branch a routing number names
+	IBAN          string `json:"iban,omitempty" metadata:",optional"`
+	RoutingScheme string `json:"routingScheme,omitempty" metadata:",optional"`
+	RoutingNumber string `json:"routingNumber,omitempty" metadata:",optional"`
+	AccountNumber string `json:"accountNumber,omitempty" metadata:",optional"`
+
+	// A joint or corporate account lists all its holders, CustomerID first,
+	// and how many of them must sign a payment. Accounts with one holder
+	// leave these empty.
+	Holders            []string `json:"holders,omitempty" metadata:",optional"`
+	SigningRule        string   `json:"signingRule,omitempty" metadata:",optional"`
+	RequiredSignatures int      `json:"requiredSignatures,omitempty" metadata:",optional"`
+
+	SchemaVersion int `json:"schemaVersion"`
 }
 
 type Payment struct {
-	PaymentID          string  `json:"paymentID"`
-	SenderCustomerID   string  `json:"senderCustomerID"`
-	ReceiverCustomerID string  `json:"receiverCustomerID"`
-	SenderAccountID    string  `json:"senderAccountID"`
-	ReceiverAccountID  string  `json:"receiverAccountID"`
-	Amount             float64 `json:"amount"`
-	ExchangeRate       float64 `json:"exchangeRate"`
-	Date               string  `json:"date"`
+	PaymentID          string   `json:"paymentID"`
+	SenderCustomerID   string   `json:"senderCustomerID"`
+	ReceiverCustomerID string   `json:"receiverCustomerID"`
+	SenderAccountID    string   `json:"senderAccountID"`
+	ReceiverAccountID  string   `json:"receiverAccountID"`
+	Amount             float64  `json:"amount"`
+	ExchangeRate       float64  `json:"exchangeRate"`
+	Date               string   `json:"date"`
+	BatchID            string   `json:"batchID,omitempty" metadata:",optional"`
+	Status             string   `json:"status,omitempty" metadata:",optional"`
+	ScreeningHits      []string `json:"screeningHits,omitempty" metadata:",optional"`
+	BeneficiaryID      string   `json:"beneficiaryID,omitempty" metadata:",optional"`
+	PaymentRequestID   string   `json:"paymentRequestID,omitempty" metadata:",optional"`
+
+	// Fee is charged to the sender on top of Amount, in the sender's
+	// currency, when the payment settles. The sending bank keeps it.
+	Fee float64 `json:"fee,omitempty" metadata:",optional"`
+
+	// Holders of a joint sender account who have signed the payment, and
+	// how many signatures it needs before it settles
+	Approvals         []string `json:"approvals,omitempty" metadata:",optional"`
+	RequiredApprovals int      `json:"requiredApprovals,omitempty" metadata:",optional"`
+
+	// Remittance information and purpose, see Remittance
+	RemittanceInfo    string   `json:"remittanceInfo,omitempty" metadata:",optional"`
+	CreditorReference string   `json:"creditorReference,omitempty" metadata:",optional"`
+	InvoiceNumbers    []string `json:"invoiceNumbers,omitempty" metadata:",optional"`
+	PurposeCode       string   `json:"purposeCode,omitempty" metadata:",optional"`
+
+	// ISO 20022 details, set for payments created from pacs.008 messages
+	EndToEndID   string `json:"endToEndID,omitempty" metadata:",optional"`
+	UETR         string `json:"uetr,omitempty" metadata:",optional"`
+	ChargeBearer string `json:"chargeBearer,omitempty" metadata:",optional"`
+
+	SchemaVersion int `json:"schemaVersion"`
 }
 
 // CreateBank creates on bank on the public channel. The identity that
-// submits the transacion becomes the seller of the bank
-func (s *SmartContract) CreateBank(ctx contractapi.TransactionContextInterface, bankid string, bankadminid string, name string, password string, country string, currency string, reserves float64, exchangeRate float64) error {
+// submits the transacion becomes the seller of the bank. bic may be left
+// empty; otherwise it must be from the bank's country and unique. The bank is
+// an application until the member banks vote it in, and cannot hold accounts
+// or take part in payments before then.
+func (s *BankContract) CreateBank(ctx contractapi.TransactionContextInterface, bankid string, bankadminid string, name string, password string, country string, currency string, reserves float64, exchangeRate float64, bic string) error {
+	if bic != "" {
+		bic = normalizeBIC(bic)
+	}
+	err := checkArgs(
+		validation.ID("bankID", bankid),
+		validation.Required("name", name),
+		validation.Country("country", country),
+		validation.Currency("currency", currency),
+		validation.NonNegativeAmount("reserves", reserves),
+		validation.PositiveAmount("exchangeRate", exchangeRate),
+	)
+	if err == nil && bic != "" {
+		err = checkArgs(validation.BIC("bic", bic))
+	}
+	if err != nil {
+		return err
+	}
+	err = checkBankIdentifiers(bic, country)
+	if err != nil {
+		return err
+	}
+	err = requireUnusedID(ctx, "bank", bankid)
+	if err != nil {
+		return err
+	}
+	if bic != "" {
+		err = putIndexedID(ctx, bankid, "BIC "+bic, bicIndexObjectType, bic)
+		if err != nil {
+			return err
+		}
+	}
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	err = checkSupportedCurrency(config, "currency", currency)
+	if err != nil {
+		return err
+	}
+	rateDate, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
 
-	bankadminid, error := s.GetSubmittingClientIdentity(ctx)
+	bankadminid, error := getSubmittingClientIdentity(ctx)
 	if error != nil {
 		return fmt.Errorf("failed to get client identity %v", error)
 	}
@@ -75,7 +195,14 @@ func (s *SmartContract) CreateBank(ctx contractapi.TransactionContextInterface,
 		Reserves:     reserves,
 		Password:     password,
 		AccountIDs:   []string{},
-		ExchangeRate: exchangeRate}
+		ExchangeRate: exchangeRate,
+
+		ExchangeRateDate: rateDate,
+		BIC:              bic,
+		Status:           BankPending,
+		StatusDate:       rateDate,
+		SchemaVersion:    SchemaVersion,
+	}
 
 	bankAsBytes, _ := json.Marshal(bank)
 
@@ -83,10 +210,31 @@ func (s *SmartContract) CreateBank(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("failed to marshal bank object: %v", error)
 	}
 
-	return ctx.GetStub().PutState(bankid, bankAsBytes)
+	err = ctx.GetStub().PutState(bankid, bankAsBytes)
+	if err != nil {
+		return err
+	}
+	_, err = openMembershipProposal(ctx, &MembershipProposal{BankID: bankid, Action: MembershipAdmit, ProposedBy: bankid})
+	if err != nil {
+		return err
+	}
+
+	return recordFunding(ctx, currency, 0, reserves)
 }
 
-func (s *SmartContract) CreateCustomer(ctx contractapi.TransactionContextInterface, custid string, password string, name string, surname string) error {
+func (s *CustomerContract) CreateCustomer(ctx contractapi.TransactionContextInterface, custid string, password string, name string, surname string) error {
+	err := checkArgs(
+		validation.ID("customerID", custid),
+		validation.Required("name", name),
+		validation.Required("surname", surname),
+	)
+	if err != nil {
+		return err
+	}
+	err = requireUnusedID(ctx, "customer", custid)
+	if err != nil {
+		return err
+	}
 
 	customer := Customer{
 		Name:       name,
@@ -94,13 +242,38 @@ func (s *SmartContract) CreateCustomer(ctx contractapi.TransactionContextInterfa
 		CustomerID: custid,
 		Password:   password,
 		AccountIDs: []string{},
+		KYCStatus:  KYCPending,
+
+		SchemaVersion: SchemaVersion,
 	}
 	userAsBytes, _ := json.Marshal(customer)
 	return ctx.GetStub().PutState(custid, userAsBytes)
 
 }
 
-func (s *SmartContract) CreateAccount(ctx contractapi.TransactionContextInterface, id string, customerID string, bankID string, balance float64) error {
+// CreateAccount opens an account for a verified customer. The account may be
+// given an IBAN, or a local account number with the scheme and number of the
+// branch's routing code, from the bank's country; all of them may be left
+// empty for accounts only used on the ledger.
+func (s *AccountContract) CreateAccount(ctx contractapi.TransactionContextInterface, id string, customerID string, bankID string, balance float64, iban string, routingScheme string, routingNumber string, accountNumber string) error {
+	iban = normalizeIBAN(iban)
+	err := checkArgs(
+		validation.ID("accountID", id),
+		validation.ID("customerID", customerID),
+		validation.ID("bankID", bankID),
+		validation.NonNegativeAmount("balance", balance),
+	)
+	if err != nil {
+		return err
+	}
+	err = accountIdentifierArgs(iban, routingScheme, routingNumber, accountNumber)
+	if err != nil {
+		return err
+	}
+	err = requireUnusedID(ctx, "account", id)
+	if err != nil {
+		return err
+	}
 
 	// Update the associated bank with the account ID
 	bankBytes, err := ctx.GetStub().GetState(bankID)
@@ -108,10 +281,18 @@ func (s *SmartContract) CreateAccount(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 	if bankBytes == nil {
-		return fmt.Errorf("Bank with ID %s does not exist", bankID)
+		return contracterrors.New(contracterrors.NotFound, "bank %s does not exist", bankID)
 	}
 	var bank Bank
-	err = json.Unmarshal(bankBytes, &bank)
+	err = unmarshalBank(bankBytes, &bank)
+	if err != nil {
+		return err
+	}
+	err = checkBankCanTransact(&bank)
+	if err != nil {
+		return err
+	}
+	err = checkAccountIdentifiers(iban, routingScheme, &bank)
 	if err != nil {
 		return err
 	}
@@ -128,10 +309,14 @@ func (s *SmartContract) CreateAccount(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 	if customerBytes == nil {
-		return fmt.Errorf("Customer with ID %s does not exist", customerID)
+		return contracterrors.New(contracterrors.NotFound, "customer %s does not exist", customerID)
 	}
 	var customer Customer
-	err = json.Unmarshal(customerBytes, &customer)
+	err = unmarshalCustomer(customerBytes, &customer)
+	if err != nil {
+		return err
+	}
+	err = requireVerifiedCustomer(ctx, &customer)
 	if err != nil {
 		return err
 	}
@@ -149,6 +334,18 @@ func (s *SmartContract) CreateAccount(ctx contractapi.TransactionContextInterfac
 		Balance:    balance,
 		Currency:   bank.Currency,
 		PaymentIDs: []string{},
+		Status:     AccountActive,
+
+		IBAN:          iban,
+		RoutingScheme: routingScheme,
+		RoutingNumber: routingNumber,
+		AccountNumber: accountNumber,
+
+		SchemaVersion: SchemaVersion,
+	}
+	err = indexAccountIdentifiers(ctx, &account)
+	if err != nil {
+		return err
 	}
 	accountAsBytes, _ := json.Marshal(account)
 	err1 := ctx.GetStub().PutState(id, accountAsBytes)
@@ -156,20 +353,25 @@ func (s *SmartContract) CreateAccount(ctx contractapi.TransactionContextInterfac
 		return err1
 	}
 
-	return nil
+	return recordFunding(ctx, bank.Currency, balance, 0)
 }
 
-func (s *SmartContract) GetAccount(ctx contractapi.TransactionContextInterface, accountID string) (*Account, error) {
+func (s *AccountContract) GetAccount(ctx contractapi.TransactionContextInterface, accountID string) (*Account, error) {
+	err := checkArgs(validation.ID("accountID", accountID))
+	if err != nil {
+		return nil, err
+	}
+
 	accountBytes, err := ctx.GetStub().GetState(accountID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read account state: %v", err)
 	}
 	if accountBytes == nil {
-		return nil, fmt.Errorf("account %s does not exist", accountID)
+		return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
 	}
 
 	var account Account
-	err = json.Unmarshal(accountBytes, &account)
+	err = unmarshalAccount(accountBytes, &account)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal account JSON: %v", err)
 	}
@@ -177,8 +379,12 @@ func (s *SmartContract) GetAccount(ctx contractapi.TransactionContextInterface,
 	return &account, nil
 }
 
-// Define the CreatePayment function
-func (s *SmartContract) CreatePayment(ctx contractapi.TransactionContextInterface, paymentID string, senderAccountID string, receiverAccountID string, senderCustomerID string, receiverCustomerID string, amount float64, exchangeRate float64, date string) error {
+// CreatePayment moves amount from the sender's account to the receiver's. A
+// payment to one of the sender's beneficiaries may leave receiverAccountID and
+// receiverCustomerID empty to take them from the beneficiary. remittance is
+// a JSON Remittance object with the references and purpose of the payment. It
+// may be empty unless a purpose code rule of either bank applies.
+func (s *PaymentContract) CreatePayment(ctx contractapi.TransactionContextInterface, paymentID string, senderAccountID string, receiverAccountID string, senderCustomerID string, receiverCustomerID string, amount float64, exchangeRate float64, date string, beneficiaryID string, remittance string) error {
 	payment := Payment{
 		PaymentID:          paymentID,
 		SenderCustomerID:   senderCustomerID,
@@ -188,109 +394,134 @@ func (s *SmartContract) CreatePayment(ctx contractapi.TransactionContextInterfac
 		Amount:             amount,
 		ExchangeRate:       exchangeRate,
 		Date:               date,
+		BeneficiaryID:      beneficiaryID,
+		SchemaVersion:      SchemaVersion,
+	}
+	if remittance != "" {
+		var r Remittance
+		err := json.Unmarshal([]byte(remittance), &r)
+		if err != nil {
+			return contracterrors.New(contracterrors.Validation, "invalid remittance JSON: %v", err)
+		}
+		r.applyTo(&payment)
 	}
+	return createPayment(ctx, &payment)
+}
 
-	// Save the payment in the world state
-	paymentJSON, err := json.Marshal(payment)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payment JSON: %v", err)
+// createPayment checks a new payment and settles it, or holds it for review
+// if it hits the watchlist. A payment from a joint account that needs more
+// than one signature waits for the other holders' approval instead.
+func createPayment(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+	ledger := newPaymentLedger(ctx)
+	if payment.BeneficiaryID != "" {
+		err := checkArgs(
+			validation.ID("senderCustomerID", payment.SenderCustomerID),
+			validation.ID("beneficiaryID", payment.BeneficiaryID),
+		)
+		if err != nil {
+			return err
+		}
+		payment.ReceiverAccountID, payment.ReceiverCustomerID, err = ledger.beneficiaryReceiver(payment.SenderCustomerID, payment.BeneficiaryID, payment.ReceiverAccountID, payment.ReceiverCustomerID)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = ctx.GetStub().PutState(paymentID, paymentJSON)
+	err := checkArgs(
+		validation.ID("paymentID", payment.PaymentID),
+		validation.ID("senderAccountID", payment.SenderAccountID),
+		validation.ID("receiverAccountID", payment.ReceiverAccountID),
+		validation.ID("senderCustomerID", payment.SenderCustomerID),
+		validation.ID("receiverCustomerID", payment.ReceiverCustomerID),
+		validation.PositiveAmount("amount", payment.Amount),
+		validation.PositiveAmount("exchangeRate", payment.ExchangeRate),
+		validation.DistinctAccounts("receiverAccountID", payment.SenderAccountID, payment.ReceiverAccountID),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to put payment state: %v", err)
+		return err
 	}
-
-	// Update sender's account balance
-	err = s.updateAccountBalance(ctx, senderAccountID, -amount, 1, paymentID)
+	err = requireUnusedID(ctx, "payment", payment.PaymentID)
 	if err != nil {
-		return fmt.Errorf("failed to update sender's account balance: %v", err)
+		return err
 	}
 
-	// Update receiver's account balance
-	err = s.updateAccountBalance(ctx, receiverAccountID, amount, exchangeRate, paymentID)
+	err = ledger.checkOwners(payment)
 	if err != nil {
-		return fmt.Errorf("failed to update receiver's account balance: %v", err)
+		return err
 	}
-
-	return nil
-}
-
-func (s *SmartContract) updateAccountBalance(ctx contractapi.TransactionContextInterface, accountID string, amount float64, exchangeRate float64, paymentID string) error {
-	accountJSON, err := ctx.GetStub().GetState(accountID)
+	err = ledger.checkRemittance(payment)
 	if err != nil {
-		return fmt.Errorf("failed to read account state: %v", err)
-	}
-	if accountJSON == nil {
-		return fmt.Errorf("account %s does not exist", accountID)
+		return err
 	}
-
-	var account Account
-	err = json.Unmarshal(accountJSON, &account)
+	err = ledger.checkAccountStatus(payment)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal account JSON: %v", err)
+		return err
 	}
-
-	// Convert the amount to the account's currency
-	convertedAmount := amount * exchangeRate
-	account.Balance += convertedAmount
-	account.PaymentIDs = append(account.PaymentIDs, paymentID)
-	err = s.UpdateBankReserves(ctx, account.BankID, convertedAmount)
+	err = ledger.checkKYC(payment)
 	if err != nil {
-		return fmt.Errorf("failed to update bank reserves: %v", err)
+		return err
 	}
-	accountJSON, err = json.Marshal(account)
+	signatures, err := ledger.requiredSignatures(payment)
 	if err != nil {
-		return fmt.Errorf("failed to marshal account JSON: %v", err)
+		return err
+	}
+	if signatures > 1 {
+		err = ledger.awaitApproval(payment, signatures)
+	} else {
+		err = settlePayment(ctx, ledger, payment)
 	}
-
-	err = ctx.GetStub().PutState(accountID, accountJSON)
 	if err != nil {
-		return fmt.Errorf("failed to put account state: %v", err)
+		return err
 	}
 
-	return nil
+	return ledger.flush()
 }
 
-func (s *SmartContract) UpdateBankReserves(ctx contractapi.TransactionContextInterface, bankID string, amount float64) error {
-	bankJSON, err := ctx.GetStub().GetState(bankID)
+// settlePayment checks the rates, the watchlist and the limits of a payment
+// whose accounts have been checked, and settles it or holds it for review
+func settlePayment(ctx contractapi.TransactionContextInterface, ledger *paymentLedger, payment *Payment) error {
+	err := ledger.checkRates(payment)
 	if err != nil {
-		return fmt.Errorf("failed to read bank state: %v", err)
-	}
-	if bankJSON == nil {
-		return fmt.Errorf("bank %s does not exist", bankID)
+		return err
 	}
-
-	var bank Bank
-	err = json.Unmarshal(bankJSON, &bank)
+	hits, err := screenPayment(ctx, ledger, payment)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal bank JSON: %v", err)
+		return err
+	}
+	if len(hits) > 0 {
+		ledger.hold(payment, hits)
+		return nil
 	}
 
-	bank.Reserves += amount
-	bankJSON, err = json.Marshal(bank)
-	if err != nil {
-		return fmt.Errorf("failed to marshal bank JSON: %v", err)
+	// The transaction fails on a breach, so there is no event to emit: the
+	// breach reaches the client as the details of the LimitExceeded error
+	err = ledger.clear(payment)
+	if breach, ok := err.(*LimitBreach); ok {
+		err = limitExceeded(breach)
 	}
+	return err
+}
 
-	err = ctx.GetStub().PutState(bankID, bankJSON)
+func (s *CustomerContract) UpdateProfile(ctx contractapi.TransactionContextInterface, custid string, name string, surname string, password string) error {
+	err := checkArgs(
+		validation.ID("customerID", custid),
+		validation.Required("name", name),
+		validation.Required("surname", surname),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to put bank state: %v", err)
+		return err
 	}
-	return nil
-}
 
-func (s *SmartContract) UpdateProfile(ctx contractapi.TransactionContextInterface, custid string, name string, surname string, password string) error {
 	customerAsBytes, err := ctx.GetStub().GetState(custid)
 	if err != nil {
 		return fmt.Errorf("Failed to get customer: %v", err)
 	}
 	if customerAsBytes == nil {
-		return fmt.Errorf("Customer with custid %s does not exist", custid)
+		return contracterrors.New(contracterrors.NotFound, "customer %s does not exist", custid)
 	}
 
 	customer := Customer{}
-	err = json.Unmarshal(customerAsBytes, &customer)
+	err = unmarshalCustomer(customerAsBytes, &customer)
 	if err != nil {
 		return fmt.Errorf("Failed to unmarshal customer: %v", err)
 	}
@@ -307,111 +538,105 @@ func (s *SmartContract) UpdateProfile(ctx contractapi.TransactionContextInterfac
 	return ctx.GetStub().PutState(custid, customerAsBytes)
 }
 
-func (s *SmartContract) UpdateBankProfile(ctx contractapi.TransactionContextInterface, bankID string, bankAdminID string, name string, reserves float64, country string) error {
-	bankAsBytes, err := ctx.GetStub().GetState(bankID)
+// UpdateBankProfile proposes a new name, reserves and country for a bank. The
+// bank's administrator or an operator proposes it, and the change only takes
+// effect once a different one approves the returned operation.
+func (s *BankContract) UpdateBankProfile(ctx contractapi.TransactionContextInterface, bankID string, bankAdminID string, name string, reserves float64, country string) (*PendingOperation, error) {
+	change := &BankProfileChange{BankID: bankID, Name: name, Reserves: reserves, Country: country}
+	bank, err := checkBankProfileChange(ctx, change)
 	if err != nil {
-		return fmt.Errorf("Failed to get customer: %v", err)
-	}
-	if bankAsBytes == nil {
-		return fmt.Errorf("Customer with custid %s does not exist", bankID)
+		return nil, err
 	}
-
-	bank := Bank{}
-	err = json.Unmarshal(bankAsBytes, &bank)
+	err = requireBankAdminOrOperator(ctx, bank)
 	if err != nil {
-		return fmt.Errorf("Failed to unmarshal customer: %v", err)
+		return nil, err
 	}
+	return proposeOperation(ctx, &PendingOperation{Type: OperationUpdateBankProfile, BankProfile: change})
+}
 
-	bank.Name = name
-	bank.Reserves = reserves
-	bank.Country = country
-	bankAsBytes, err = json.Marshal(bank)
+// checkBankProfileChange validates a bank profile change against the bank
+// and returns the bank
+func checkBankProfileChange(ctx contractapi.TransactionContextInterface, change *BankProfileChange) (*Bank, error) {
+	err := checkArgs(
+		validation.ID("bankID", change.BankID),
+		validation.Required("name", change.Name),
+		validation.NonNegativeAmount("reserves", change.Reserves),
+		validation.Country("country", change.Country),
+	)
 	if err != nil {
-		return fmt.Errorf("Failed to marshal customer: %v", err)
+		return nil, err
 	}
 
-	return ctx.GetStub().PutState(bankID, bankAsBytes)
-}
-
-func (s *SmartContract) DeleteAccount(ctx contractapi.TransactionContextInterface, accountID string) error {
-	// Silinecek hesabı bulmak için durumu alın
-	accountBytes, err := ctx.GetStub().GetState(accountID)
+	bank, err := getBank(ctx, change.BankID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if accountBytes == nil {
-		return fmt.Errorf("Account with ID %s does not exist", accountID)
+	err = checkBankCanTransact(bank)
+	if err != nil {
+		return nil, err
 	}
-
-	// Hesabı sil
-	err = ctx.GetStub().DelState(accountID)
+	err = checkBankIdentifiers(bank.BIC, change.Country)
 	if err != nil {
-		return fmt.Errorf("failed to delete account: %v", err)
+		return nil, err
 	}
+	return bank, nil
+}
 
-	// İlgili bankayı güncelle
-	account := new(Account)
-	err = json.Unmarshal(accountBytes, account)
+// updateBankProfile applies an approved bank profile change
+func updateBankProfile(ctx contractapi.TransactionContextInterface, change *BankProfileChange) error {
+	bank, err := checkBankProfileChange(ctx, change)
 	if err != nil {
 		return err
 	}
 
-	bankBytes, err := ctx.GetStub().GetState(account.BankID)
+	reservesChange := change.Reserves - bank.Reserves
+	bank.Name = change.Name
+	bank.Reserves = change.Reserves
+	bank.Country = change.Country
+	err = putBank(ctx, bank)
 	if err != nil {
 		return err
 	}
-	if bankBytes == nil {
-		return fmt.Errorf("Bank with ID %s does not exist", account.BankID)
-	}
-	var bank Bank
-	err = json.Unmarshal(bankBytes, &bank)
+	return recordFunding(ctx, bank.Currency, 0, reservesChange)
+}
+
+// UpdateExchangeRate sets the exchange rate of a bank's currency and restarts
+// its age for the staleness check. Only the administrator of a member bank may
+// set it.
+func (s *BankContract) UpdateExchangeRate(ctx contractapi.TransactionContextInterface, bankID string, exchangeRate float64) error {
+	err := checkArgs(
+		validation.ID("bankID", bankID),
+		validation.PositiveAmount("exchangeRate", exchangeRate),
+	)
 	if err != nil {
 		return err
 	}
 
-	for i, accID := range bank.AccountIDs {
-		if accID == accountID {
-			// Hesabı bankadan kaldır
-			bank.AccountIDs = append(bank.AccountIDs[:i], bank.AccountIDs[i+1:]...)
-			break
-		}
-	}
-
-	bankBytes, _ = json.Marshal(bank)
-	err = ctx.GetStub().PutState(account.BankID, bankBytes)
+	bank, err := getBank(ctx, bankID)
 	if err != nil {
 		return err
 	}
-
-	// İlgili müşteriyi güncelle
-	customerBytes, err := ctx.GetStub().GetState(account.CustomerID)
+	err = requireBankAdmin(ctx, bank)
 	if err != nil {
 		return err
 	}
-	if customerBytes == nil {
-		return fmt.Errorf("Customer with ID %s does not exist", account.CustomerID)
-	}
-	var customer Customer
-	err = json.Unmarshal(customerBytes, &customer)
+	err = checkBankCanTransact(bank)
 	if err != nil {
 		return err
 	}
 
-	for i, accID := range customer.AccountIDs {
-		if accID == accountID {
-			// Hesabı müşteriden kaldır
-			customer.AccountIDs = append(customer.AccountIDs[:i], customer.AccountIDs[i+1:]...)
-			break
-		}
-	}
-
-	customerBytes, _ = json.Marshal(customer)
-	err = ctx.GetStub().PutState(account.CustomerID, customerBytes)
+	bank.ExchangeRate = exchangeRate
+	bank.ExchangeRateDate, err = getTxDate(ctx)
 	if err != nil {
 		return err
 	}
+	return putBank(ctx, bank)
+}
 
-	return nil
+// DeleteAccount closes an empty account, which stays in the world state for
+// audit. Use CloseAccount to sweep a remaining balance elsewhere.
+func (s *AccountContract) DeleteAccount(ctx contractapi.TransactionContextInterface, accountID string) error {
+	return s.CloseAccount(ctx, accountID, "")
 }
 
 func parseExchangeRateFromJSON(jsonData []byte, targetCurrency string) (float64, error) {
@@ -430,54 +655,70 @@ func parseExchangeRateFromJSON(jsonData []byte, targetCurrency string) (float64,
 	// Hedef para birimi için döviz kurunu elde edin
 	rate, ok := response.Rates[targetCurrency]
 	if !ok {
-		return 0.0, fmt.Errorf("Exchange rate for target currency '%s' not found", targetCurrency)
+		return 0.0, contracterrors.New(contracterrors.NotFound, "Exchange rate for target currency '%s' not found", targetCurrency)
 	}
 
 	return rate, nil
 }
-func (s *SmartContract) UpdateBalance(ctx contractapi.TransactionContextInterface, accountID string, amount float64) error {
-	accountJSON, err := ctx.GetStub().GetState(accountID)
+
+// UpdateBalance proposes to credit amount to an account, or debit it when
+// negative. The administrator of the account's bank or an operator proposes
+// it, and the balance only changes once a different one approves the
+// returned operation.
+func (s *AccountContract) UpdateBalance(ctx contractapi.TransactionContextInterface, accountID string, amount float64) (*PendingOperation, error) {
+	change := &BalanceChange{AccountID: accountID, Amount: amount}
+	_, bank, err := checkBalanceChange(ctx, change)
 	if err != nil {
-		return fmt.Errorf("failed to read account state: %v", err)
+		return nil, err
 	}
-	if accountJSON == nil {
-		return fmt.Errorf("account %s does not exist", accountID)
+	err = requireBankAdminOrOperator(ctx, bank)
+	if err != nil {
+		return nil, err
 	}
+	return proposeOperation(ctx, &PendingOperation{Type: OperationUpdateBalance, Balance: change})
+}
 
-	var account Account
-	err = json.Unmarshal(accountJSON, &account)
+// checkBalanceChange validates a balance change against the account and its
+// bank and returns both
+func checkBalanceChange(ctx contractapi.TransactionContextInterface, change *BalanceChange) (*Account, *Bank, error) {
+	err := checkArgs(
+		validation.ID("accountID", change.AccountID),
+		validation.NonZeroAmount("amount", change.Amount),
+	)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal account JSON: %v", err)
+		return nil, nil, err
 	}
 
-	// Convert the amount to the account's currency
-	account.Balance += amount
+	account, err := getAccount(ctx, change.AccountID)
 	if err != nil {
-		return fmt.Errorf("failed to update bank reserves: %v", err)
+		return nil, nil, err
 	}
-	accountJSON, err = json.Marshal(account)
+	err = checkAccountCanTransact(account)
 	if err != nil {
-		return fmt.Errorf("failed to marshal account JSON: %v", err)
+		return nil, nil, err
 	}
-
-	err = ctx.GetStub().PutState(accountID, accountJSON)
+	bank, err := getBank(ctx, account.BankID)
 	if err != nil {
-		return fmt.Errorf("failed to put account state: %v", err)
+		return nil, nil, err
 	}
-
-	return nil
+	err = checkBankCanTransact(bank)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, bank, nil
 }
 
-func main() {
-
-	chaincode, err := contractapi.NewChaincode(new(SmartContract))
-
+// updateBalance applies an approved balance change
+func updateBalance(ctx contractapi.TransactionContextInterface, change *BalanceChange) error {
+	account, _, err := checkBalanceChange(ctx, change)
 	if err != nil {
-		fmt.Printf("Error create fabcar chaincode: %s", err.Error())
-		return
+		return err
 	}
 
-	if err := chaincode.Start(); err != nil {
-		fmt.Printf("Error starting fabcar chaincode: %s", err.Error())
+	account.Balance += change.Amount
+	err = putAccount(ctx, account)
+	if err != nil {
+		return err
 	}
+	return recordFunding(ctx, account.Currency, change.Amount, 0)
 }
@@ -0,0 +1,205 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// Indexes from the identifiers banks and customers use to the ledger IDs.
+// Each index entry holds the ID of the bank or account it names.
+const (
+	bicIndexObjectType          = "BIC"
+	ibanIndexObjectType         = "IBAN"
+	localAccountIndexObjectType = "LocalAccount"
+)
+
+// headOffice is the branch code of a BIC that names no particular branch
+const headOffice = "XXX"
+
+// normalizeIBAN turns an IBAN as people write it, in groups of four and maybe
+// in lower case, into its electronic format
+func normalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// normalizeBIC writes a BIC in upper case and with the head office branch
+// code, so that the 8 and 11 character forms of a BIC are the same
+func normalizeBIC(bic string) string {
+	bic = strings.ToUpper(strings.TrimSpace(bic))
+	if len(bic) == 8 {
+		bic += headOffice
+	}
+	return bic
+}
+
+// bicCountry returns the country code of a valid BIC
+func bicCountry(bic string) string {
+	return bic[4:6]
+}
+
+// checkBankIdentifiers checks the BIC of a bank against its country
+func checkBankIdentifiers(bic string, country string) error {
+	if bic != "" && bicCountry(bic) != country {
+		return contracterrors.New(contracterrors.Validation, "BIC %s is not from %s, the bank's country", bic, country)
+	}
+	return nil
+}
+
+// accountIdentifierArgs checks that an account has an IBAN, a local account
+// number with its routing scheme and number, or neither
+func accountIdentifierArgs(iban string, routingScheme string, routingNumber string, accountNumber string) error {
+	local := routingScheme != "" || routingNumber != "" || accountNumber != ""
+	switch {
+	case iban != "" && local:
+		return contracterrors.New(contracterrors.Validation, "an account has either an IBAN or a local account number, not both")
+	case iban != "":
+		return checkArgs(validation.IBAN("iban", iban))
+	case local:
+		return localAccountArgs(routingScheme, routingNumber, accountNumber)
+	}
+	return nil
+}
+
+func localAccountArgs(routingScheme string, routingNumber string, accountNumber string) error {
+	err := checkArgs(validation.OneOf("routingScheme", routingScheme, validation.RoutingABA, validation.RoutingSortCode, validation.RoutingBSB, validation.RoutingIFSC))
+	if err != nil {
+		return err
+	}
+	return checkArgs(
+		validation.RoutingNumber("routingNumber", routingScheme, routingNumber),
+		validation.AccountNumber("accountNumber", accountNumber),
+	)
+}
+
+// checkAccountIdentifiers checks that the IBAN or routing scheme of an account
+// is from the country of its bank
+func checkAccountIdentifiers(iban string, routingScheme string, bank *Bank) error {
+	if iban != "" && iban[:2] != bank.Country {
+		return contracterrors.New(contracterrors.Validation, "IBAN %s is not from %s, the country of bank %s", iban, bank.Country, bank.BankID)
+	}
+	if routingScheme != "" && validation.RoutingSchemeCountries[routingScheme] != bank.Country {
+		return contracterrors.New(contracterrors.Validation, "routing scheme %s is not used in %s, the country of bank %s", routingScheme, bank.Country, bank.BankID)
+	}
+	return nil
+}
+
+func identifierKey(ctx contractapi.TransactionContextInterface, objectType string, attributes ...string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s index key: %v", objectType, err)
+	}
+	return key, nil
+}
+
+// getIndexedID returns the ID an index entry holds, or "" if there is none
+func getIndexedID(ctx contractapi.TransactionContextInterface, objectType string, attributes ...string) (string, error) {
+	key, err := identifierKey(ctx, objectType, attributes...)
+	if err != nil {
+		return "", err
+	}
+	id, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s index: %v", objectType, err)
+	}
+	return string(id), nil
+}
+
+// putIndexedID adds an index entry, failing with AlreadyExists if the
+// identifier, described by name, already names another object
+func putIndexedID(ctx contractapi.TransactionContextInterface, id string, name string, objectType string, attributes ...string) error {
+	existing, err := getIndexedID(ctx, objectType, attributes...)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		return contracterrors.New(contracterrors.AlreadyExists, "%s is already used by %s", name, existing)
+	}
+	key, err := identifierKey(ctx, objectType, attributes...)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, []byte(id))
+}
+
+// indexAccountIdentifiers reserves the IBAN or local account number of a new
+// account. Closed accounts keep theirs, so an identifier is never reused.
+func indexAccountIdentifiers(ctx contractapi.TransactionContextInterface, account *Account) error {
+	if account.IBAN != "" {
+		return putIndexedID(ctx, account.AccountID, "IBAN "+account.IBAN, ibanIndexObjectType, account.IBAN)
+	}
+	if account.AccountNumber != "" {
+		name := fmt.Sprintf("account number %s at %s %s", account.AccountNumber, account.RoutingScheme, account.RoutingNumber)
+		return putIndexedID(ctx, account.AccountID, name, localAccountIndexObjectType, account.RoutingScheme, account.RoutingNumber, account.AccountNumber)
+	}
+	return nil
+}
+
+// QueryBankByBIC returns the bank with a BIC. A branch BIC that no bank holds
+// finds the bank holding the BIC of its head office.
+func (s *BankContract) QueryBankByBIC(ctx contractapi.TransactionContextInterface, bic string) (*Bank, error) {
+	bic = normalizeBIC(bic)
+	err := checkArgs(validation.BIC("bic", bic))
+	if err != nil {
+		return nil, err
+	}
+
+	bankID, err := getIndexedID(ctx, bicIndexObjectType, bic)
+	if err != nil {
+		return nil, err
+	}
+	if bankID == "" && !strings.HasSuffix(bic, headOffice) {
+		bankID, err = getIndexedID(ctx, bicIndexObjectType, bic[:8]+headOffice)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if bankID == "" {
+		return nil, contracterrors.New(contracterrors.NotFound, "no bank has BIC %s", bic)
+	}
+	return getBank(ctx, bankID)
+}
+
+// QueryAccountByIBAN returns the account with an IBAN, which may be written in
+// groups of four
+func (s *AccountContract) QueryAccountByIBAN(ctx contractapi.TransactionContextInterface, iban string) (*Account, error) {
+	iban = normalizeIBAN(iban)
+	err := checkArgs(validation.IBAN("iban", iban))
+	if err != nil {
+		return nil, err
+	}
+
+	accountID, err := getIndexedID(ctx, ibanIndexObjectType, iban)
+	if err != nil {
+		return nil, err
+	}
+	if accountID == "" {
+		return nil, contracterrors.New(contracterrors.NotFound, "no account has IBAN %s", iban)
+	}
+	return getAccount(ctx, accountID)
+}
+
+// QueryAccountByNumber returns the account with a local account number at the
+// branch a routing number names
+func (s *AccountContract) QueryAccountByNumber(ctx contractapi.TransactionContextInterface, routingScheme string, routingNumber string, accountNumber string) (*Account, error) {
+	err := localAccountArgs(routingScheme, routingNumber, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID, err := getIndexedID(ctx, localAccountIndexObjectType, routingScheme, routingNumber, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if accountID == "" {
+		return nil, contracterrors.New(contracterrors.NotFound, "no account has %s %s account number %s", routingScheme, routingNumber, accountNumber)
+	}
+	return getAccount(ctx, accountID)
+}
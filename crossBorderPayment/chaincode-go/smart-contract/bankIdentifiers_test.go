@@ -0,0 +1,276 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/iso20022"
+)
+
+const (
+	bank2IBAN = "DE89370400440532013000"
+	usRouting = "021000021"
+)
+
+func TestCreateBankBIC(t *testing.T) {
+	tests := []struct {
+		name     string
+		bic      string
+		country  string
+		wantBIC  string
+		wantCode contracterrors.Code
+		wantErr  string
+	}{
+		{name: "head office", bic: "bofaus3n", country: "US", wantBIC: "BOFAUS3NXXX"},
+		{name: "branch", bic: "BOFAUS3NLAX", country: "US", wantBIC: "BOFAUS3NLAX"},
+		{name: "no BIC", country: "US"},
+		{name: "format", bic: "BOFA-US3N", country: "US", wantCode: contracterrors.Validation, wantErr: "invalid bic"},
+		{name: "other country", bic: "COBADEFF", country: "US", wantCode: contracterrors.Validation, wantErr: "BIC COBADEFFXXX is not from US"},
+		{name: "taken", bic: "COBADEFFXXX", country: "DE", wantCode: contracterrors.AlreadyExists, wantErr: "BIC COBADEFFXXX is already used by BANK2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "COBADEFF")
+			})
+
+			err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", tt.country, "USD", 10000, 1, tt.bic)
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				checkErr(t, err, tt.wantErr)
+				return
+			}
+			checkErr(t, err, "")
+			if got := f.bank("BANK1").BIC; got != tt.wantBIC {
+				t.Fatalf("BIC = %q, want %q", got, tt.wantBIC)
+			}
+		})
+	}
+}
+
+func TestUpdateBankProfileKeepsBICCountry(t *testing.T) {
+	f := newFixture(t)
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "COBADEFF")
+	})
+	f.admitBank("BANK2")
+
+	err := f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.banks.UpdateBankProfile(ctx, "BANK2", "", "Second Bank", 5000, "FR")
+		return err
+	})
+	checkCode(t, err, contracterrors.Validation)
+	checkErr(t, err, "BIC COBADEFFXXX is not from FR")
+}
+
+func TestCreateAccountIdentifiers(t *testing.T) {
+	tests := []struct {
+		name          string
+		bankID        string
+		iban          string
+		routingScheme string
+		routingNumber string
+		accountNumber string
+		want          Account
+		wantCode      contracterrors.Code
+		wantErr       string
+	}{
+		{name: "IBAN", bankID: "BANK2", iban: "de89 3704 0044 0532 0130 00", want: Account{IBAN: bank2IBAN}},
+		{
+			name: "local account number", bankID: "BANK1", routingScheme: "ABA", routingNumber: usRouting, accountNumber: "31926819",
+			want: Account{RoutingScheme: "ABA", RoutingNumber: usRouting, AccountNumber: "31926819"},
+		},
+		{name: "check digits", bankID: "BANK2", iban: "DE88370400440532013000", wantCode: contracterrors.Validation, wantErr: "has wrong check digits"},
+		{name: "IBAN of another country", bankID: "BANK1", iban: bank2IBAN, wantCode: contracterrors.Validation, wantErr: "IBAN DE89370400440532013000 is not from US"},
+		{
+			name: "IBAN and local number", bankID: "BANK2", iban: bank2IBAN, routingScheme: "ABA", routingNumber: usRouting, accountNumber: "1",
+			wantCode: contracterrors.Validation, wantErr: "either an IBAN or a local account number",
+		},
+		{name: "ABA check digit", bankID: "BANK1", routingScheme: "ABA", routingNumber: "021000022", accountNumber: "1", wantCode: contracterrors.Validation, wantErr: "wrong check digit"},
+		{name: "no account number", bankID: "BANK1", routingScheme: "ABA", routingNumber: usRouting, wantCode: contracterrors.Validation, wantErr: "invalid accountNumber"},
+		{name: "unknown scheme", bankID: "BANK1", routingScheme: "FEDWIRE", routingNumber: usRouting, accountNumber: "1", wantCode: contracterrors.Validation, wantErr: "invalid routingScheme"},
+		{name: "scheme of another country", bankID: "BANK1", routingScheme: "SORTCODE", routingNumber: "200000", accountNumber: "1", wantCode: contracterrors.Validation, wantErr: "SORTCODE is not used in US"},
+		{name: "IBAN taken", bankID: "BANK3", iban: "GB82 WEST 1234 5698 7654 32", wantCode: contracterrors.AlreadyExists, wantErr: "IBAN GB82WEST12345698765432 is already used by A9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.banks.CreateBank(ctx, "BANK3", "", "Third Bank", "pw", "GB", "GBP", 0, 0.8, "")
+			})
+			f.admitBank("BANK3")
+			f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CreateAccount(ctx, "A9", "C1", "BANK3", 0, "GB82WEST12345698765432", "", "", "")
+			})
+
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CreateAccount(ctx, "A3", "C1", tt.bankID, 0, tt.iban, tt.routingScheme, tt.routingNumber, tt.accountNumber)
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				checkErr(t, err, tt.wantErr)
+				if f.stub.Committed("A3") != nil {
+					t.Fatalf("rejected account was stored")
+				}
+				return
+			}
+			checkErr(t, err, "")
+			account := f.account("A3")
+			if account.IBAN != tt.want.IBAN || account.RoutingScheme != tt.want.RoutingScheme || account.RoutingNumber != tt.want.RoutingNumber || account.AccountNumber != tt.want.AccountNumber {
+				t.Fatalf("unexpected account %+v", account)
+			}
+		})
+	}
+}
+
+func TestQueryByIdentifiers(t *testing.T) {
+	f := newFixture(t)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", "US", "USD", 10000, 1, "BOFAUS3N")
+	})
+	f.admitBank("BANK1")
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "COBADEFFXXX")
+	})
+	f.admitBank("BANK2")
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C1", "Alice", "Smith")
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A1", "C1", "BANK1", 0, "", "ABA", usRouting, "31926819")
+	})
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A2", "C1", "BANK2", 0, bank2IBAN, "", "", "")
+	})
+
+	tests := []struct {
+		name     string
+		query    func(ctx contractapi.TransactionContextInterface) (string, error)
+		want     string
+		wantCode contracterrors.Code
+	}{
+		{name: "BIC8", want: "BANK1", query: bankByBIC(f, "BOFAUS3N")},
+		{name: "BIC11", want: "BANK2", query: bankByBIC(f, "cobadeffxxx")},
+		{name: "branch BIC", want: "BANK2", query: bankByBIC(f, "COBADEFF300")},
+		{name: "unknown BIC", wantCode: contracterrors.NotFound, query: bankByBIC(f, "DEUTDEFF")},
+		{name: "invalid BIC", wantCode: contracterrors.Validation, query: bankByBIC(f, "DEUT")},
+		{name: "IBAN", want: "A2", query: accountByIBAN(f, "DE89 3704 0044 0532 0130 00")},
+		{name: "unknown IBAN", wantCode: contracterrors.NotFound, query: accountByIBAN(f, "GB82WEST12345698765432")},
+		{name: "invalid IBAN", wantCode: contracterrors.Validation, query: accountByIBAN(f, "DE00370400440532013000")},
+		{name: "account number", want: "A1", query: func(ctx contractapi.TransactionContextInterface) (string, error) {
+			account, err := f.accounts.QueryAccountByNumber(ctx, "ABA", usRouting, "31926819")
+			if err != nil {
+				return "", err
+			}
+			return account.AccountID, nil
+		}},
+		{name: "unknown account number", wantCode: contracterrors.NotFound, query: func(ctx contractapi.TransactionContextInterface) (string, error) {
+			_, err := f.accounts.QueryAccountByNumber(ctx, "ABA", usRouting, "31926818")
+			return "", err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				got, err = tt.query(ctx)
+				return err
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				return
+			}
+			checkErr(t, err, "")
+			if got != tt.want {
+				t.Fatalf("found %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func bankByBIC(f *fixture, bic string) func(ctx contractapi.TransactionContextInterface) (string, error) {
+	return func(ctx contractapi.TransactionContextInterface) (string, error) {
+		bank, err := f.banks.QueryBankByBIC(ctx, bic)
+		if err != nil {
+			return "", err
+		}
+		return bank.BankID, nil
+	}
+}
+
+func accountByIBAN(f *fixture, iban string) func(ctx contractapi.TransactionContextInterface) (string, error) {
+	return func(ctx contractapi.TransactionContextInterface) (string, error) {
+		account, err := f.accounts.QueryAccountByIBAN(ctx, iban)
+		if err != nil {
+			return "", err
+		}
+		return account.AccountID, nil
+	}
+}
+
+func TestPacs008AccountsByIBAN(t *testing.T) {
+	f := newFixture(t)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", "US", "USD", 10000, 1, "")
+	})
+	f.admitBank("BANK1")
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "COBADEFF")
+	})
+	f.admitBank("BANK2")
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C1", "Alice", "Smith")
+	f.addVerifiedCustomer("BANK2", f.bank2Admin, "C2", "Bob", "Jones")
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A1", "C1", "BANK1", 1000, "", "", "", "")
+	})
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A2", "C2", "BANK2", 500, bank2IBAN, "", "", "")
+	})
+
+	message := strings.Replace(pacs008Message, "<CdtrAcct><Id><Othr><Id>A2</Id></Othr></Id></CdtrAcct>", "<CdtrAcct><Id><IBAN>"+bank2IBAN+"</IBAN></Id></CdtrAcct>", 1)
+	var payment *Payment
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		payment, err = f.payments.CreatePaymentFromPacs008(ctx, message)
+		return err
+	})
+	checkErr(t, err, "")
+	if payment.ReceiverAccountID != "A2" || payment.ReceiverCustomerID != "C2" {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+
+	var exported string
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		exported, err = f.payments.ExportPaymentPacs008(ctx, "P1")
+		return err
+	})
+	checkErr(t, err, "")
+	doc, err := iso20022.ParsePacs008([]byte(exported))
+	if err != nil {
+		t.Fatalf("exported message is invalid: %v", err)
+	}
+	tx := doc.CreditTransfer.Transactions[0]
+	if tx.CreditorAccount.ID.IBAN != bank2IBAN || tx.CreditorAccount.ID.Other != nil || tx.DebtorAccount.ID.Other.ID != "A1" {
+		t.Fatalf("unexpected accounts %+v %+v", tx.DebtorAccount, tx.CreditorAccount)
+	}
+	if tx.CreditorAgent.FinancialInstitution.BICFI != "COBADEFFXXX" || tx.DebtorAgent.FinancialInstitution.BICFI != "" {
+		t.Fatalf("unexpected agents %+v %+v", tx.DebtorAgent, tx.CreditorAgent)
+	}
+
+	unknown := strings.Replace(message, bank2IBAN, "GB82WEST12345698765432", 1)
+	err = f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentFromPacs008(ctx, strings.Replace(unknown, "<InstrId>P1", "<InstrId>P2", 1))
+		return err
+	})
+	checkCode(t, err, contracterrors.NotFound)
+}
@@ -5,25 +5,30 @@ SPDX-License-Identifier: Apache-2.0
 package bank
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
 )
 
 // QueryBank allows all members of the channel to read a public bank
-func (s *SmartContract) QueryBank(ctx contractapi.TransactionContextInterface, bankID string) (*Bank, error) {
+func (s *BankContract) QueryBank(ctx contractapi.TransactionContextInterface, bankID string) (*Bank, error) {
+	err := checkArgs(validation.ID("bankID", bankID))
+	if err != nil {
+		return nil, err
+	}
 
 	bankJSON, err := ctx.GetStub().GetState(bankID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bank object %v: %v", bankID, err)
 	}
 	if bankJSON == nil {
-		return nil, fmt.Errorf("bank does not exist")
+		return nil, contracterrors.New(contracterrors.NotFound, "bank %s does not exist", bankID)
 	}
 
-	var bank *Bank
-	err = json.Unmarshal(bankJSON, &bank)
+	bank := new(Bank)
+	err = unmarshalBank(bankJSON, bank)
 	if err != nil {
 		return nil, err
 	}
@@ -31,67 +36,85 @@ func (s *SmartContract) QueryBank(ctx contractapi.TransactionContextInterface, b
 	return bank, nil
 }
 
-func (s *SmartContract) QueryCustomer(ctx contractapi.TransactionContextInterface, custId string) (*Customer, error) {
+func (s *CustomerContract) QueryCustomer(ctx contractapi.TransactionContextInterface, custId string) (*Customer, error) {
+	err := checkArgs(validation.ID("customerID", custId))
+	if err != nil {
+		return nil, err
+	}
+
 	userAsBytes, err := ctx.GetStub().GetState(custId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bank object %v: %v", custId, err)
 	}
 	if userAsBytes == nil {
-		return nil, fmt.Errorf("%s does not exist", custId)
+		return nil, contracterrors.New(contracterrors.NotFound, "customer %s does not exist", custId)
 	}
 
 	customer := new(Customer)
-	_ = json.Unmarshal(userAsBytes, customer)
+	err = unmarshalCustomer(userAsBytes, customer)
+	if err != nil {
+		return nil, err
+	}
 
 	return customer, nil
 }
 
-func (s *SmartContract) QueryAccount(ctx contractapi.TransactionContextInterface, accountID string) (*Account, error) {
+func (s *AccountContract) QueryAccount(ctx contractapi.TransactionContextInterface, accountID string) (*Account, error) {
+	err := checkArgs(validation.ID("accountID", accountID))
+	if err != nil {
+		return nil, err
+	}
+
 	accountBytes, err := ctx.GetStub().GetState(accountID)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to read account from world state: %v", err)
 	}
 	if accountBytes == nil {
-		return nil, fmt.Errorf("Account does not exist: %s", accountID)
+		return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
 	}
 
 	account := new(Account)
-	err = json.Unmarshal(accountBytes, account)
+	err = unmarshalAccount(accountBytes, account)
 	if err != nil {
 		return nil, err
 	}
 
 	return account, nil
 }
-func (s *SmartContract) QueryPayments(ctx contractapi.TransactionContextInterface, accountID string) ([]*Payment, error) {
+func (s *PaymentContract) QueryPayments(ctx contractapi.TransactionContextInterface, accountID string) ([]*Payment, error) {
+	err := checkArgs(validation.ID("accountID", accountID))
+	if err != nil {
+		return nil, err
+	}
+
 	accountBytes, err := ctx.GetStub().GetState(accountID)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read customer state from ledger: %v", err)
+		return nil, fmt.Errorf("Failed to read account state from ledger: %v", err)
 	}
 	if accountBytes == nil {
-		return nil, fmt.Errorf("Customer with ID %s does not exist", accountID)
+		return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
 	}
 
 	var account Account
-	err = json.Unmarshal(accountBytes, &account)
+	err = unmarshalAccount(accountBytes, &account)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal customer state: %v", err)
+		return nil, fmt.Errorf("Failed to unmarshal account state: %v", err)
 	}
 
 	payments := []*Payment{}
 	for _, paymentID := range account.PaymentIDs {
 		paymentBytes, err := ctx.GetStub().GetState(paymentID)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to read account state from ledger: %v", err)
+			return nil, fmt.Errorf("Failed to read payment state from ledger: %v", err)
 		}
 		if paymentBytes == nil {
-			return nil, fmt.Errorf("Account with ID %s does not exist", accountID)
+			return nil, contracterrors.New(contracterrors.NotFound, "payment %s of account %s does not exist", paymentID, accountID)
 		}
 
 		var payment Payment
-		err = json.Unmarshal(paymentBytes, &payment)
+		err = unmarshalPayment(paymentBytes, &payment)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to unmarshal account state: %v", err)
+			return nil, fmt.Errorf("Failed to unmarshal payment state: %v", err)
 		}
 
 		payments = append(payments, &payment)
@@ -100,17 +123,22 @@ func (s *SmartContract) QueryPayments(ctx contractapi.TransactionContextInterfac
 	return payments, nil
 }
 
-func (s *SmartContract) QueryCustomerAccounts(ctx contractapi.TransactionContextInterface, customerID string) ([]*Account, error) {
+func (s *CustomerContract) QueryCustomerAccounts(ctx contractapi.TransactionContextInterface, customerID string) ([]*Account, error) {
+	err := checkArgs(validation.ID("customerID", customerID))
+	if err != nil {
+		return nil, err
+	}
+
 	customerBytes, err := ctx.GetStub().GetState(customerID)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to read customer state from ledger: %v", err)
 	}
 	if customerBytes == nil {
-		return nil, fmt.Errorf("Customer with ID %s does not exist", customerID)
+		return nil, contracterrors.New(contracterrors.NotFound, "customer %s does not exist", customerID)
 	}
 
 	var customer Customer
-	err = json.Unmarshal(customerBytes, &customer)
+	err = unmarshalCustomer(customerBytes, &customer)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to unmarshal customer state: %v", err)
 	}
@@ -122,11 +150,11 @@ func (s *SmartContract) QueryCustomerAccounts(ctx contractapi.TransactionContext
 			return nil, fmt.Errorf("Failed to read account state from ledger: %v", err)
 		}
 		if accountBytes == nil {
-			return nil, fmt.Errorf("Account with ID %s does not exist", accountID)
+			return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
 		}
 
 		var account Account
-		err = json.Unmarshal(accountBytes, &account)
+		err = unmarshalAccount(accountBytes, &account)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to unmarshal account state: %v", err)
 		}
@@ -137,19 +165,24 @@ func (s *SmartContract) QueryCustomerAccounts(ctx contractapi.TransactionContext
 	return accounts, nil
 }
 
-func (s *SmartContract) QueryBankAccounts(ctx contractapi.TransactionContextInterface, bankID string) ([]*Account, error) {
+func (s *BankContract) QueryBankAccounts(ctx contractapi.TransactionContextInterface, bankID string) ([]*Account, error) {
+	err := checkArgs(validation.ID("bankID", bankID))
+	if err != nil {
+		return nil, err
+	}
+
 	bankBytes, err := ctx.GetStub().GetState(bankID)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read customer state from ledger: %v", err)
+		return nil, fmt.Errorf("Failed to read bank state from ledger: %v", err)
 	}
 	if bankBytes == nil {
-		return nil, fmt.Errorf("Customer with ID %s does not exist", bankID)
+		return nil, contracterrors.New(contracterrors.NotFound, "bank %s does not exist", bankID)
 	}
 
 	var bank Bank
-	err = json.Unmarshal(bankBytes, &bank)
+	err = unmarshalBank(bankBytes, &bank)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to unmarshal customer state: %v", err)
+		return nil, fmt.Errorf("Failed to unmarshal bank state: %v", err)
 	}
 
 	accounts := []*Account{}
@@ -159,11 +192,11 @@ func (s *SmartContract) QueryBankAccounts(ctx contractapi.TransactionContextInte
 			return nil, fmt.Errorf("Failed to read account state from ledger: %v", err)
 		}
 		if accountBytes == nil {
-			return nil, fmt.Errorf("Account with ID %s does not exist", accountID)
+			return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
 		}
 
 		var account Account
-		err = json.Unmarshal(accountBytes, &account)
+		err = unmarshalAccount(accountBytes, &account)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to unmarshal account state: %v", err)
 		}
@@ -174,17 +207,22 @@ func (s *SmartContract) QueryBankAccounts(ctx contractapi.TransactionContextInte
 	return accounts, nil
 }
 
-func (s *SmartContract) QueryCustomerPassword(ctx contractapi.TransactionContextInterface, custid string) (string, error) {
+func (s *CustomerContract) QueryCustomerPassword(ctx contractapi.TransactionContextInterface, custid string) (string, error) {
+	err := checkArgs(validation.ID("customerID", custid))
+	if err != nil {
+		return "", err
+	}
+
 	customerBytes, err := ctx.GetStub().GetState(custid)
 	if err != nil {
 		return "", fmt.Errorf("Failed to read customer state from the ledger: %v", err)
 	}
 	if customerBytes == nil {
-		return "", fmt.Errorf("Customer with ID %s does not exist", custid)
+		return "", contracterrors.New(contracterrors.NotFound, "customer %s does not exist", custid)
 	}
 
 	customer := new(Customer)
-	err = json.Unmarshal(customerBytes, customer)
+	err = unmarshalCustomer(customerBytes, customer)
 	if err != nil {
 		return "", fmt.Errorf("Failed to unmarshal customer data: %v", err)
 	}
@@ -192,43 +230,39 @@ func (s *SmartContract) QueryCustomerPassword(ctx contractapi.TransactionContext
 	return customer.Password, nil
 }
 
-func (s *SmartContract) QueryCustomersByBank(ctx contractapi.TransactionContextInterface, bankID string) ([]*Customer, error) {
-	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey("Account", []string{bankID})
+// QueryCustomersByBank returns every customer holding an account at the bank,
+// joint holders included, once each, in the order they first held an account
+func (s *BankContract) QueryCustomersByBank(ctx contractapi.TransactionContextInterface, bankID string) ([]*Customer, error) {
+	accounts, err := s.QueryBankAccounts(ctx, bankID)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get accounts for bank %s: %v", bankID, err)
+		return nil, err
 	}
-	defer iterator.Close()
 
 	customers := []*Customer{}
-	for iterator.HasNext() {
-		queryResponse, err := iterator.Next()
-		if err != nil {
-			return nil, fmt.Errorf("Failed to iterate over account data for bank %s: %v", bankID, err)
+	seen := map[string]bool{}
+	for _, account := range accounts {
+		for _, customerID := range accountHolders(account) {
+			if seen[customerID] {
+				continue
+			}
+			seen[customerID] = true
+
+			customerBytes, err := ctx.GetStub().GetState(customerID)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to get customer data for account %s: %v", account.AccountID, err)
+			}
+			if customerBytes == nil {
+				return nil, contracterrors.New(contracterrors.NotFound, "customer %s of account %s does not exist", customerID, account.AccountID)
+			}
+
+			var customer Customer
+			err = unmarshalCustomer(customerBytes, &customer)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to unmarshal customer data for account %s: %v", account.AccountID, err)
+			}
+
+			customers = append(customers, &customer)
 		}
-
-		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to split composite key: %v", err)
-		}
-
-		accountID := compositeKeyParts[0]
-		customerID := compositeKeyParts[1]
-
-		customerBytes, err := ctx.GetStub().GetState(customerID)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to get customer data for account %s: %v", accountID, err)
-		}
-		if customerBytes == nil {
-			return nil, fmt.Errorf("Customer with ID %s does not exist for account %s", customerID, accountID)
-		}
-
-		var customer Customer
-		err = json.Unmarshal(customerBytes, &customer)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to unmarshal customer data for account %s: %v", accountID, err)
-		}
-
-		customers = append(customers, &customer)
 	}
 
 	return customers, nil
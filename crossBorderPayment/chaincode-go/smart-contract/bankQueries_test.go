@@ -0,0 +1,190 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func TestQueryBank(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	if bank := f.bank("BANK2"); bank.Name != "Second Bank" || len(bank.AccountIDs) != 1 {
+		t.Fatalf("unexpected bank %+v", bank)
+	}
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.banks.QueryBank(ctx, "NOBANK")
+		return err
+	})
+	checkErr(t, err, "bank NOBANK does not exist")
+}
+
+func TestQueryCustomer(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	if customer := f.customer("C2"); customer.Name != "Bob" || customer.AccountIDs[0] != "A2" {
+		t.Fatalf("unexpected customer %+v", customer)
+	}
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.QueryCustomer(ctx, "NOCUST")
+		return err
+	})
+	checkErr(t, err, "NOCUST does not exist")
+}
+
+func TestQueryAccount(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	var account *Account
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		account, err = f.accounts.QueryAccount(ctx, "A2")
+		return err
+	})
+	checkErr(t, err, "")
+	if account.Currency != "EUR" {
+		t.Fatalf("unexpected account %+v", account)
+	}
+
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.accounts.QueryAccount(ctx, "NOACCOUNT")
+		return err
+	})
+	checkErr(t, err, "account NOACCOUNT does not exist")
+}
+
+func TestQueryPayments(t *testing.T) {
+	tests := []struct {
+		name      string
+		accountID string
+		want      []string
+		wantErr   string
+	}{
+		{name: "sender", accountID: "A1", want: []string{"P1", "P2"}},
+		{name: "receiver", accountID: "A2", want: []string{"P1", "P2"}},
+		{name: "missing account", accountID: "NOACCOUNT", wantErr: "does not exist"},
+	}
+
+	f := newFixture(t)
+	f.seed()
+	checkErr(t, f.pay("P1", "A1", "A2", 10, 0.9), "")
+	checkErr(t, f.pay("P2", "A1", "A2", 20, 0.9), "")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payments []*Payment
+			err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				payments, err = f.payments.QueryPayments(ctx, tt.accountID)
+				return err
+			})
+			checkErr(t, err, tt.wantErr)
+			if len(payments) != len(tt.want) {
+				t.Fatalf("got %d payments, want %d", len(payments), len(tt.want))
+			}
+			for i, payment := range payments {
+				if payment.PaymentID != tt.want[i] {
+					t.Fatalf("payment %d = %s, want %s", i, payment.PaymentID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueryCustomerAccounts(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A3", "C1", "BANK2", 0, "", "", "", "")
+	})
+
+	var accounts []*Account
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		accounts, err = f.customers.QueryCustomerAccounts(ctx, "C1")
+		return err
+	})
+	checkErr(t, err, "")
+	if len(accounts) != 2 || accounts[0].AccountID != "A1" || accounts[1].AccountID != "A3" {
+		t.Fatalf("unexpected accounts %+v", accounts)
+	}
+
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.QueryCustomerAccounts(ctx, "NOCUST")
+		return err
+	})
+	checkErr(t, err, "does not exist")
+}
+
+func TestQueryBankAccounts(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	var accounts []*Account
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		accounts, err = f.banks.QueryBankAccounts(ctx, "BANK1")
+		return err
+	})
+	checkErr(t, err, "")
+	if len(accounts) != 1 || accounts[0].AccountID != "A1" {
+		t.Fatalf("unexpected accounts %+v", accounts)
+	}
+
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.banks.QueryBankAccounts(ctx, "NOBANK")
+		return err
+	})
+	checkErr(t, err, "does not exist")
+}
+
+func TestQueryCustomerPassword(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	var password string
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		password, err = f.customers.QueryCustomerPassword(ctx, "C1")
+		return err
+	})
+	checkErr(t, err, "")
+	if password != "pw" {
+		t.Fatalf("password = %q, want %q", password, "pw")
+	}
+
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.QueryCustomerPassword(ctx, "NOCUST")
+		return err
+	})
+	checkErr(t, err, "does not exist")
+}
+
+func TestQueryCustomersByBank(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A3", "C2", "BANK1", 0, "", "", "", "")
+	})
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A4", "C1", "BANK1", 0, "", "", "", "")
+	})
+
+	var customers []*Customer
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		customers, err = f.banks.QueryCustomersByBank(ctx, "BANK1")
+		return err
+	})
+	checkErr(t, err, "")
+	if len(customers) != 2 || customers[0].CustomerID != "C1" || customers[1].CustomerID != "C2" {
+		t.Fatalf("unexpected customers %+v", customers)
+	}
+
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.banks.QueryCustomersByBank(ctx, "NOBANK")
+		return err
+	})
+	checkErr(t, err, "does not exist")
+}
@@ -0,0 +1,471 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-contract-api-go/metadata"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// invokeStub calls a single function through the chaincode's Invoke
+type invokeStub struct {
+	*chaincodetest.Stub
+	function string
+}
+
+func (s *invokeStub) GetFunctionAndParameters() (string, []string) {
+	return s.function, nil
+}
+
+func (s *invokeStub) GetCreator() ([]byte, error) {
+	return nil, errors.New("no creator")
+}
+
+func TestNewChaincode(t *testing.T) {
+	chaincode, err := NewChaincode()
+	if err != nil {
+		t.Fatalf("contract metadata is invalid: %v", err)
+	}
+
+	response := chaincode.Invoke(&invokeStub{Stub: chaincodetest.NewStub(), function: "org.hyperledger.fabric:GetMetadata"})
+	if response.Status != shim.OK {
+		t.Fatalf("GetMetadata failed: %s", response.Message)
+	}
+	var chaincodeMetadata metadata.ContractChaincodeMetadata
+	err = json.Unmarshal(response.Payload, &chaincodeMetadata)
+	if err != nil {
+		t.Fatalf("failed to unmarshal metadata: %v", err)
+	}
+
+	wantTransactions := map[string]string{
+		BankContractName:     "CreateBank",
+		CustomerContractName: "VerifyCustomer",
+		AccountContractName:  "CloseAccount",
+		PaymentContractName:  "CreatePayment",
+		AdminContractName:    "VerifyInvariants",
+	}
+	for name, transaction := range wantTransactions {
+		if !hasTransaction(chaincodeMetadata.Contracts[name], transaction) {
+			t.Fatalf("contract %q does not expose %s", name, transaction)
+		}
+	}
+	for name, contract := range chaincodeMetadata.Contracts {
+		for _, helper := range []string{"UpdateBankReserves", "GetSubmittingClientIdentity"} {
+			if hasTransaction(contract, helper) {
+				t.Fatalf("contract %q exposes helper %s", name, helper)
+			}
+		}
+	}
+
+	response = chaincode.Invoke(&invokeStub{Stub: chaincodetest.NewStub(), function: "bank:UpdateBankReserves"})
+	if response.Status == shim.OK {
+		t.Fatalf("UpdateBankReserves is callable as a transaction")
+	}
+}
+
+func hasTransaction(contract metadata.ContractMetadata, name string) bool {
+	for _, transaction := range contract.Transactions {
+		if transaction.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGetSubmittingClientIdentity(t *testing.T) {
+	f := newFixture(t)
+	var id string
+	err := f.evaluate(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) (err error) {
+		id, err = getSubmittingClientIdentity(ctx)
+		return err
+	})
+	checkErr(t, err, "")
+	if id != f.bank1Admin.ID() {
+		t.Fatalf("client identity = %q, want %q", id, f.bank1Admin.ID())
+	}
+}
+
+func TestCreateBank(t *testing.T) {
+	f := newFixture(t)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "ignored", "First Bank", "pw", "US", "USD", 10000, 1, "")
+	})
+
+	bank := f.bank("BANK1")
+	if bank.BankAdminID != f.bank1Admin.ID() {
+		t.Fatalf("bank admin = %q, want the submitting identity %q", bank.BankAdminID, f.bank1Admin.ID())
+	}
+	if bank.Currency != "USD" || bank.Country != "US" || len(bank.AccountIDs) != 0 {
+		t.Fatalf("unexpected bank %+v", bank)
+	}
+	assertFloat(t, "reserves", bank.Reserves, 10000)
+}
+
+func TestCreateBankValidation(t *testing.T) {
+	f := newFixture(t)
+	err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK 1", "", "", "pw", "XX", "usd", -1, 0, "")
+	})
+	checkCode(t, err, contracterrors.Validation)
+	errs := contracterrors.Parse(err.Error()).Details.([]interface{})
+
+	rules := map[string]string{}
+	for _, e := range errs {
+		failure := e.(map[string]interface{})
+		rules[failure["field"].(string)] = failure["rule"].(string)
+	}
+	want := map[string]string{
+		"bankID":       validation.RuleIDFormat,
+		"name":         validation.RuleRequired,
+		"country":      validation.RuleCountry,
+		"currency":     validation.RuleCurrency,
+		"reserves":     validation.RuleNonNegative,
+		"exchangeRate": validation.RulePositive,
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Fatalf("failed rules = %v, want %v", rules, want)
+	}
+	if f.stub.Committed("BANK 1") != nil {
+		t.Fatalf("invalid bank was stored")
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity func(f *fixture) *chaincodetest.Identity
+		fn       func(f *fixture) txFunc
+		want     contracterrors.Code
+	}{
+		{name: "missing account", fn: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				_, err := f.accounts.UpdateBalance(ctx, "NOACCOUNT", 1)
+				return err
+			}
+		}, want: contracterrors.NotFound},
+		{name: "bank ID taken", fn: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				return f.banks.CreateBank(ctx, "BANK1", "", "Copy", "pw", "US", "USD", 0, 1, "")
+			}
+		}, want: contracterrors.AlreadyExists},
+		{name: "payment ID taken by an account", fn: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.CreatePayment(ctx, "A2", "A1", "A2", "C1", "C2", 1, 1, "2024-01-01", "", "")
+			}
+		}, want: contracterrors.AlreadyExists},
+		{name: "not the bank administrator", identity: func(f *fixture) *chaincodetest.Identity { return f.bank2Admin }, fn: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.MarkAccountDormant(ctx, "A1")
+			}
+		}, want: contracterrors.Forbidden},
+		{name: "invalid argument", fn: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error {
+				_, err := f.accounts.UpdateBalance(ctx, "A1", 0)
+				return err
+			}
+		}, want: contracterrors.Validation},
+		{name: "account not frozen", fn: func(f *fixture) txFunc {
+			return func(ctx contractapi.TransactionContextInterface) error { return f.accounts.UnfreezeAccount(ctx, "A1") }
+		}, want: contracterrors.InvalidState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			identity := f.bank1Admin
+			if tt.identity != nil {
+				identity = tt.identity(f)
+			}
+
+			err := f.submit(identity, tt.fn(f))
+			checkCode(t, err, tt.want)
+			if parsed := contracterrors.Parse("chaincode response 500, " + err.Error()); parsed.Code != tt.want {
+				t.Fatalf("parsed code = %s, want %s", parsed.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateCustomer(t *testing.T) {
+	f := newFixture(t)
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.CreateCustomer(ctx, "C1", "pw", "Alice", "Smith")
+	})
+
+	customer := f.customer("C1")
+	if customer.Name != "Alice" || customer.Surname != "Smith" || customer.KYCStatus != KYCPending {
+		t.Fatalf("unexpected customer %+v", customer)
+	}
+}
+
+func TestCreateAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		accountID  string
+		customerID string
+		bankID     string
+		wantErr    string
+	}{
+		{name: "second account", accountID: "A3", customerID: "C1", bankID: "BANK2"},
+		{name: "missing bank", accountID: "A3", customerID: "C1", bankID: "NOBANK", wantErr: "bank NOBANK does not exist"},
+		{name: "missing customer", accountID: "A3", customerID: "NOCUST", bankID: "BANK1", wantErr: "customer NOCUST does not exist"},
+		{name: "unverified customer", accountID: "A3", customerID: "C3", bankID: "BANK1", wantErr: "not KYC verified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.customers.CreateCustomer(ctx, "C3", "pw", "Carol", "White")
+			})
+
+			err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CreateAccount(ctx, tt.accountID, tt.customerID, tt.bankID, 250, "", "", "", "")
+			})
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+
+			account := f.account(tt.accountID)
+			if account.Currency != f.bank(tt.bankID).Currency || account.Status != AccountActive {
+				t.Fatalf("unexpected account %+v", account)
+			}
+			if !contains(f.bank(tt.bankID).AccountIDs, tt.accountID) || !contains(f.customer(tt.customerID).AccountIDs, tt.accountID) {
+				t.Fatalf("account %s is not listed on its bank and customer", tt.accountID)
+			}
+		})
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	if account := f.account("A1"); account.CustomerID != "C1" || account.BankID != "BANK1" {
+		t.Fatalf("unexpected account %+v", account)
+	}
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.accounts.GetAccount(ctx, "NOACCOUNT")
+		return err
+	})
+	checkErr(t, err, "account NOACCOUNT does not exist")
+}
+
+func TestCreatePayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		amount   float64
+		rate     float64
+		wantErr  string
+		balances map[string]float64
+		reserves map[string]float64
+	}{
+		{
+			name: "cross currency", from: "A1", to: "A2", amount: 100, rate: 0.9,
+			balances: map[string]float64{"A1": 900, "A2": 590},
+			reserves: map[string]float64{"BANK1": 9900, "BANK2": 5090},
+		},
+		{
+			name: "same bank", from: "A1", to: "A3", amount: 50, rate: 1,
+			balances: map[string]float64{"A1": 950, "A3": 50},
+			reserves: map[string]float64{"BANK1": 10000},
+		},
+		{name: "missing sender account", from: "NOACCOUNT", to: "A2", amount: 10, rate: 1, wantErr: "account NOACCOUNT does not exist"},
+		{name: "missing receiver account", from: "A1", to: "NOACCOUNT", amount: 10, rate: 1, wantErr: "account NOACCOUNT does not exist"},
+		{name: "zero amount", from: "A1", to: "A2", amount: 0, rate: 1, wantErr: `invalid amount "0": must be greater than zero`},
+		{name: "negative exchange rate", from: "A1", to: "A2", amount: 10, rate: -1, wantErr: `invalid exchangeRate "-1"`},
+		{name: "self transfer", from: "A1", to: "A1", amount: 10, rate: 1, wantErr: "must differ from the sender account"},
+		{name: "overdraft", from: "A1", to: "A2", amount: 1000.01, rate: 0.9, wantErr: "insufficient funds in account A1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CreateAccount(ctx, "A3", "C2", "BANK1", 0, "", "", "", "")
+			})
+
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.CreatePayment(ctx, "P1", tt.from, tt.to, "C1", "C2", tt.amount, tt.rate, "2024-01-01", "", "")
+			})
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				if f.stub.Committed("P1") != nil {
+					t.Fatalf("failed payment was stored")
+				}
+				return
+			}
+
+			for accountID, want := range tt.balances {
+				account := f.account(accountID)
+				assertFloat(t, accountID+" balance", account.Balance, want)
+				if !contains(account.PaymentIDs, "P1") {
+					t.Fatalf("payment not recorded on account %s", accountID)
+				}
+			}
+			for bankID, want := range tt.reserves {
+				assertFloat(t, bankID+" reserves", f.bank(bankID).Reserves, want)
+			}
+		})
+	}
+}
+
+func TestUpdateAccountBalanceMissingAccount(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	tests := []struct {
+		name    string
+		payment Payment
+		wantErr string
+	}{
+		{name: "sender", payment: Payment{PaymentID: "P1", SenderAccountID: "NOACCOUNT", ReceiverAccountID: "A2", Amount: 1, ExchangeRate: 1}, wantErr: "failed to update sender's account balance"},
+		{name: "receiver", payment: Payment{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "NOACCOUNT", Amount: 1, ExchangeRate: 1}, wantErr: "failed to update receiver's account balance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				ledger := newPaymentLedger(ctx)
+				err := ledger.apply(&tt.payment)
+				if len(ledger.payments) != 0 {
+					t.Fatalf("payment queued despite error")
+				}
+				return err
+			})
+			checkErr(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.UpdateProfile(ctx, "C1", "Alicia", "Smyth", "newpw")
+	})
+	customer := f.customer("C1")
+	if customer.Name != "Alicia" || customer.Surname != "Smyth" || customer.Password != "newpw" {
+		t.Fatalf("profile not updated: %+v", customer)
+	}
+	if customer.KYCStatus != KYCVerified || len(customer.AccountIDs) != 1 {
+		t.Fatalf("profile update lost other fields: %+v", customer)
+	}
+
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.UpdateProfile(ctx, "NOCUST", "a", "b", "c")
+	})
+	checkErr(t, err, "does not exist")
+}
+
+func TestUpdateBankProfile(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	checkErr(t, f.updateBankProfile("BANK1", "Renamed Bank", 12000, "CA"), "")
+	bank := f.bank("BANK1")
+	if bank.Name != "Renamed Bank" || bank.Country != "CA" {
+		t.Fatalf("bank profile not updated: %+v", bank)
+	}
+	assertFloat(t, "reserves", bank.Reserves, 12000)
+
+	checkErr(t, f.updateBankProfile("NOBANK", "x", 1, "US"), "bank NOBANK does not exist")
+}
+
+func TestDeleteAccount(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "EMPTY", "C1", "BANK1", 0, "", "", "", "")
+	})
+
+	err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.DeleteAccount(ctx, "A1")
+	})
+	checkErr(t, err, "still holds")
+
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.DeleteAccount(ctx, "EMPTY")
+	})
+	if account := f.account("EMPTY"); account.Status != AccountClosed {
+		t.Fatalf("account status = %s, want %s", account.Status, AccountClosed)
+	}
+	if !contains(f.customer("C1").AccountIDs, "EMPTY") {
+		t.Fatalf("closed account was removed from its customer")
+	}
+
+	err = f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.DeleteAccount(ctx, "NOACCOUNT")
+	})
+	checkErr(t, err, "does not exist")
+}
+
+func TestUpdateBalance(t *testing.T) {
+	tests := []struct {
+		name      string
+		accountID string
+		freeze    bool
+		wantErr   string
+	}{
+		{name: "credit", accountID: "A1"},
+		{name: "missing account", accountID: "NOACCOUNT", wantErr: "account NOACCOUNT does not exist"},
+		{name: "frozen account", accountID: "A1", freeze: true, wantErr: "account A1 is frozen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			if tt.freeze {
+				f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.accounts.FreezeAccount(ctx, tt.accountID, "investigation")
+				})
+			}
+
+			checkErr(t, f.updateBalance(tt.accountID, 25), tt.wantErr)
+			if tt.wantErr == "" {
+				assertFloat(t, "balance", f.account(tt.accountID).Balance, 1025)
+			}
+		})
+	}
+}
+
+func TestParseExchangeRateFromJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		currency string
+		want     float64
+		wantErr  string
+	}{
+		{name: "known currency", json: `{"rates":{"EUR":0.9,"TRY":30.5}}`, currency: "TRY", want: 30.5},
+		{name: "unknown currency", json: `{"rates":{"EUR":0.9}}`, currency: "GBP", wantErr: "'GBP' not found"},
+		{name: "malformed JSON", json: `{"rates":`, currency: "EUR", wantErr: "Failed to unmarshal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := parseExchangeRateFromJSON([]byte(tt.json), tt.currency)
+			checkErr(t, err, tt.wantErr)
+			assertFloat(t, "rate", rate, tt.want)
+		})
+	}
+}
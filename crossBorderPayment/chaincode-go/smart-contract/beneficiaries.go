@@ -0,0 +1,385 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const beneficiaryObjectType = "Beneficiary"
+
+// Results of checking the name a customer gives for a beneficiary against the
+// holder of the beneficiary's account
+const (
+	NameMatch      = "match"
+	NameCloseMatch = "close_match"
+	NameNoMatch    = "no_match"
+)
+
+// Beneficiary is an account a customer has saved to pay by nickname. Limits
+// are in the currency of the beneficiary's account, and zero leaves them
+// unlimited.
+type Beneficiary struct {
+	CustomerID         string  `json:"customerID"`
+	BeneficiaryID      string  `json:"beneficiaryID"`
+	Nickname           string  `json:"nickname"`
+	Name               string  `json:"name"`
+	NameCheck          string  `json:"nameCheck"`
+	ReceiverAccountID  string  `json:"receiverAccountID"`
+	ReceiverCustomerID string  `json:"receiverCustomerID"`
+	Currency           string  `json:"currency"`
+	PaymentLimit       float64 `json:"paymentLimit"`
+	DailyLimit         float64 `json:"dailyLimit"`
+	Date               string  `json:"date"`
+
+	// DailyTotal is what the customer has paid the beneficiary on DailyDate
+	DailyTotal float64 `json:"dailyTotal"`
+	DailyDate  string  `json:"dailyDate,omitempty" metadata:",optional"`
+}
+
+// AddBeneficiary saves an account to pay under a nickname, through the client
+// enrolled for the customer. name is the account holder's name as the
+// customer knows it; the beneficiary is refused if it does not match any
+// holder, and saved with a close_match name check if it only nearly does. The
+// holder whose name matched best is the one payments to it are made out to.
+func (s *CustomerContract) AddBeneficiary(ctx contractapi.TransactionContextInterface, customerID string, beneficiaryID string, nickname string, receiverAccountID string, name string, paymentLimit float64, dailyLimit float64) (*Beneficiary, error) {
+	err := checkArgs(
+		validation.ID("customerID", customerID),
+		validation.ID("beneficiaryID", beneficiaryID),
+		validation.Required("nickname", nickname),
+		validation.ID("receiverAccountID", receiverAccountID),
+		validation.Required("name", name),
+		validation.NonNegativeAmount("paymentLimit", paymentLimit),
+		validation.NonNegativeAmount("dailyLimit", dailyLimit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	_, err = getCustomerForClient(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := beneficiaryKey(ctx, customerID, beneficiaryID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read beneficiary state: %v", err)
+	}
+	if existing != nil {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "customer %s already has a beneficiary %s", customerID, beneficiaryID)
+	}
+
+	account, err := getAccount(ctx, receiverAccountID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAccountCanTransact(account)
+	if err != nil {
+		return nil, err
+	}
+	nameCheck, receiverCustomerID := NameNoMatch, ""
+	for _, holderID := range accountHolders(account) {
+		holder, err := getCustomer(ctx, holderID)
+		if err != nil {
+			return nil, err
+		}
+		switch matchName(name, holder) {
+		case NameMatch:
+			if nameCheck != NameMatch {
+				nameCheck, receiverCustomerID = NameMatch, holderID
+			}
+		case NameCloseMatch:
+			if nameCheck == NameNoMatch {
+				nameCheck, receiverCustomerID = NameCloseMatch, holderID
+			}
+		}
+	}
+	if nameCheck == NameNoMatch {
+		return nil, contracterrors.New(contracterrors.Validation, "%s is not the name of the holder of account %s", name, receiverAccountID)
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	beneficiary := &Beneficiary{
+		CustomerID:         customerID,
+		BeneficiaryID:      beneficiaryID,
+		Nickname:           nickname,
+		Name:               name,
+		NameCheck:          nameCheck,
+		ReceiverAccountID:  account.AccountID,
+		ReceiverCustomerID: receiverCustomerID,
+		Currency:           account.Currency,
+		PaymentLimit:       paymentLimit,
+		DailyLimit:         dailyLimit,
+		Date:               date,
+	}
+	return beneficiary, putBeneficiary(ctx, beneficiary)
+}
+
+// UpdateBeneficiary changes the nickname and limits of a beneficiary, through
+// the client enrolled for the customer
+func (s *CustomerContract) UpdateBeneficiary(ctx contractapi.TransactionContextInterface, customerID string, beneficiaryID string, nickname string, paymentLimit float64, dailyLimit float64) error {
+	err := checkArgs(
+		validation.Required("nickname", nickname),
+		validation.NonNegativeAmount("paymentLimit", paymentLimit),
+		validation.NonNegativeAmount("dailyLimit", dailyLimit),
+	)
+	if err != nil {
+		return err
+	}
+	beneficiary, err := getBeneficiary(ctx, customerID, beneficiaryID)
+	if err != nil {
+		return err
+	}
+	_, err = getCustomerForClient(ctx, customerID)
+	if err != nil {
+		return err
+	}
+
+	beneficiary.Nickname = nickname
+	beneficiary.PaymentLimit = paymentLimit
+	beneficiary.DailyLimit = dailyLimit
+	return putBeneficiary(ctx, beneficiary)
+}
+
+// RemoveBeneficiary deletes a saved beneficiary, through the client enrolled
+// for the customer. Payments already made to it keep its ID.
+func (s *CustomerContract) RemoveBeneficiary(ctx contractapi.TransactionContextInterface, customerID string, beneficiaryID string) error {
+	beneficiary, err := getBeneficiary(ctx, customerID, beneficiaryID)
+	if err != nil {
+		return err
+	}
+	_, err = getCustomerForClient(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	key, err := beneficiaryKey(ctx, beneficiary.CustomerID, beneficiary.BeneficiaryID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// QueryBeneficiary returns one of a customer's beneficiaries
+func (s *CustomerContract) QueryBeneficiary(ctx contractapi.TransactionContextInterface, customerID string, beneficiaryID string) (*Beneficiary, error) {
+	return getBeneficiary(ctx, customerID, beneficiaryID)
+}
+
+// QueryBeneficiaries returns a customer's beneficiaries
+func (s *CustomerContract) QueryBeneficiaries(ctx contractapi.TransactionContextInterface, customerID string) ([]*Beneficiary, error) {
+	err := checkArgs(validation.ID("customerID", customerID))
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(beneficiaryObjectType, []string{customerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read beneficiaries: %v", err)
+	}
+	defer iterator.Close()
+
+	beneficiaries := []*Beneficiary{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over beneficiaries: %v", err)
+		}
+
+		var beneficiary Beneficiary
+		err = json.Unmarshal(queryResponse.Value, &beneficiary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal beneficiary JSON: %v", err)
+		}
+		beneficiaries = append(beneficiaries, &beneficiary)
+	}
+
+	return beneficiaries, nil
+}
+
+// matchName compares a name with that of a customer, ignoring case,
+// punctuation and extra spaces. The same words in another order, or the
+// customer's surname with only the initial of the first name, are a close
+// match.
+func matchName(name string, customer *Customer) string {
+	given := nameWords(name)
+	holder := nameWords(customer.Name + " " + customer.Surname)
+	if len(given) == 0 || len(holder) == 0 {
+		return NameNoMatch
+	}
+	if strings.Join(given, " ") == strings.Join(holder, " ") {
+		return NameMatch
+	}
+
+	initial := []rune(given[0])
+	if given[len(given)-1] == holder[len(holder)-1] && len(initial) == 1 && initial[0] == []rune(holder[0])[0] {
+		return NameCloseMatch
+	}
+	sort.Strings(given)
+	sort.Strings(holder)
+	if strings.Join(given, " ") == strings.Join(holder, " ") {
+		return NameCloseMatch
+	}
+	return NameNoMatch
+}
+
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+}
+
+func beneficiaryKey(ctx contractapi.TransactionContextInterface, customerID string, beneficiaryID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(beneficiaryObjectType, []string{customerID, beneficiaryID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create beneficiary key: %v", err)
+	}
+	return key, nil
+}
+
+func getBeneficiary(ctx contractapi.TransactionContextInterface, customerID string, beneficiaryID string) (*Beneficiary, error) {
+	err := checkArgs(
+		validation.ID("customerID", customerID),
+		validation.ID("beneficiaryID", beneficiaryID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	key, err := beneficiaryKey(ctx, customerID, beneficiaryID)
+	if err != nil {
+		return nil, err
+	}
+	beneficiaryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read beneficiary state: %v", err)
+	}
+	if beneficiaryJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "customer %s has no beneficiary %s", customerID, beneficiaryID)
+	}
+
+	var beneficiary Beneficiary
+	err = json.Unmarshal(beneficiaryJSON, &beneficiary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal beneficiary JSON: %v", err)
+	}
+	return &beneficiary, nil
+}
+
+func putBeneficiary(ctx contractapi.TransactionContextInterface, beneficiary *Beneficiary) error {
+	key, err := beneficiaryKey(ctx, beneficiary.CustomerID, beneficiary.BeneficiaryID)
+	if err != nil {
+		return err
+	}
+	beneficiaryJSON, err := json.Marshal(beneficiary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal beneficiary JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(key, beneficiaryJSON)
+}
+
+// beneficiary returns the buffered beneficiary of a sender, loading it on
+// first use
+func (l *paymentLedger) beneficiary(customerID string, beneficiaryID string) (*Beneficiary, error) {
+	key, err := beneficiaryKey(l.ctx, customerID, beneficiaryID)
+	if err != nil {
+		return nil, err
+	}
+	if beneficiary, ok := l.beneficiaries[key]; ok {
+		return beneficiary, nil
+	}
+	beneficiary, err := getBeneficiary(l.ctx, customerID, beneficiaryID)
+	if err != nil {
+		return nil, err
+	}
+	l.beneficiaries[key] = beneficiary
+	return beneficiary, nil
+}
+
+// beneficiaryReceiver returns the receiver account and customer of a payment
+// to one of the sender's beneficiaries. Receiver IDs the caller gave anyway
+// must be the beneficiary's.
+func (l *paymentLedger) beneficiaryReceiver(senderCustomerID string, beneficiaryID string, receiverAccountID string, receiverCustomerID string) (string, string, error) {
+	beneficiary, err := l.beneficiary(senderCustomerID, beneficiaryID)
+	if err != nil {
+		return "", "", err
+	}
+	if (receiverAccountID != "" && receiverAccountID != beneficiary.ReceiverAccountID) ||
+		(receiverCustomerID != "" && receiverCustomerID != beneficiary.ReceiverCustomerID) {
+		return "", "", contracterrors.New(contracterrors.Validation, "beneficiary %s is account %s of customer %s", beneficiaryID, beneficiary.ReceiverAccountID, beneficiary.ReceiverCustomerID)
+	}
+	return beneficiary.ReceiverAccountID, beneficiary.ReceiverCustomerID, nil
+}
+
+// checkBeneficiaryLimits refuses a payment to a beneficiary if the amount
+// credited would be above its payment limit or take the day's total above
+// its daily limit
+func (l *paymentLedger) checkBeneficiaryLimits(payment *Payment) error {
+	if payment.BeneficiaryID == "" {
+		return nil
+	}
+	beneficiary, err := l.beneficiary(payment.SenderCustomerID, payment.BeneficiaryID)
+	if err != nil {
+		return err
+	}
+	today, err := l.today()
+	if err != nil {
+		return err
+	}
+
+	amount := payment.Amount * payment.ExchangeRate
+	dailyTotal := amount
+	if beneficiary.DailyDate == today {
+		dailyTotal += beneficiary.DailyTotal
+	}
+	switch {
+	case beneficiary.PaymentLimit > 0 && amount > beneficiary.PaymentLimit:
+		return contracterrors.New(contracterrors.LimitExceeded, "payment %s of %v %s is above the limit of %v for beneficiary %s",
+			payment.PaymentID, amount, beneficiary.Currency, beneficiary.PaymentLimit, beneficiary.BeneficiaryID)
+	case beneficiary.DailyLimit > 0 && dailyTotal > beneficiary.DailyLimit:
+		return contracterrors.New(contracterrors.LimitExceeded, "payment %s would take today's total for beneficiary %s to %v %s, limit is %v",
+			payment.PaymentID, beneficiary.BeneficiaryID, dailyTotal, beneficiary.Currency, beneficiary.DailyLimit)
+	}
+	return nil
+}
+
+// recordBeneficiaryUsage adds a settled payment to its beneficiary's daily
+// total
+func (l *paymentLedger) recordBeneficiaryUsage(payment *Payment) error {
+	if payment.BeneficiaryID == "" {
+		return nil
+	}
+	beneficiary, err := l.beneficiary(payment.SenderCustomerID, payment.BeneficiaryID)
+	if err != nil {
+		return err
+	}
+	today, err := l.today()
+	if err != nil {
+		return err
+	}
+	if beneficiary.DailyDate != today {
+		beneficiary.DailyDate, beneficiary.DailyTotal = today, 0
+	}
+	beneficiary.DailyTotal += payment.Amount * payment.ExchangeRate
+	return nil
+}
+
+func (l *paymentLedger) today() (string, error) {
+	now, err := getTxTime(l.ctx)
+	if err != nil {
+		return "", err
+	}
+	return now.Format("2006-01-02"), nil
+}
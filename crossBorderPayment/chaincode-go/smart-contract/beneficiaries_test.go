@@ -0,0 +1,251 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+func TestMatchName(t *testing.T) {
+	bob := &Customer{Name: "Bob", Surname: "Jones"}
+	tests := []struct {
+		name   string
+		holder *Customer
+		want   string
+	}{
+		{name: "Bob Jones", want: NameMatch},
+		{name: "  bob   JONES ", want: NameMatch},
+		{name: "B. Jones", want: NameCloseMatch},
+		{name: "Jones, Bob", want: NameCloseMatch},
+		{name: "Robert Jones", want: NameNoMatch},
+		{name: "Bob Smith", want: NameNoMatch},
+		{name: "...", want: NameNoMatch},
+		{name: "Barbara Jones", want: NameNoMatch},
+		{name: "Jane Smith", holder: &Customer{Name: "John", Surname: "Smith"}, want: NameNoMatch},
+		{name: "É. Zola", holder: &Customer{Name: "Émile", Surname: "Zola"}, want: NameCloseMatch},
+	}
+	for _, tt := range tests {
+		holder := tt.holder
+		if holder == nil {
+			holder = bob
+		}
+		if got := matchName(tt.name, holder); got != tt.want {
+			t.Errorf("matchName(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAddBeneficiary(t *testing.T) {
+	tests := []struct {
+		name          string
+		customerID    string
+		beneficiaryID string
+		accountID     string
+		holderName    string
+		wantCheck     string
+		wantCode      contracterrors.Code
+		wantErr       string
+	}{
+		{name: "match", customerID: "C1", beneficiaryID: "BOB", accountID: "A2", holderName: "Bob Jones", wantCheck: NameMatch},
+		{name: "close match", customerID: "C1", beneficiaryID: "BOB", accountID: "A2", holderName: "B Jones", wantCheck: NameCloseMatch},
+		{name: "no match", customerID: "C1", beneficiaryID: "BOB", accountID: "A2", holderName: "Alice Smith", wantCode: contracterrors.Validation, wantErr: "not the name of the holder of account A2"},
+		{name: "taken", customerID: "C1", beneficiaryID: "SAVED", accountID: "A2", holderName: "Bob Jones", wantCode: contracterrors.AlreadyExists, wantErr: "already has a beneficiary SAVED"},
+		{name: "unknown account", customerID: "C1", beneficiaryID: "BOB", accountID: "A9", holderName: "Bob Jones", wantCode: contracterrors.NotFound},
+		{name: "unknown customer", customerID: "C9", beneficiaryID: "BOB", accountID: "A2", holderName: "Bob Jones", wantCode: contracterrors.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+				_, err := f.customers.AddBeneficiary(ctx, "C1", "SAVED", "Bob", "A2", "Bob Jones", 0, 0)
+				return err
+			})
+
+			var beneficiary *Beneficiary
+			err := f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) (err error) {
+				beneficiary, err = f.customers.AddBeneficiary(ctx, tt.customerID, tt.beneficiaryID, "Bob", tt.accountID, tt.holderName, 100, 0)
+				return err
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				if tt.wantErr != "" {
+					checkErr(t, err, tt.wantErr)
+				}
+				return
+			}
+			checkErr(t, err, "")
+			if beneficiary.NameCheck != tt.wantCheck || beneficiary.ReceiverCustomerID != "C2" || beneficiary.Currency != "EUR" {
+				t.Fatalf("unexpected beneficiary %+v", beneficiary)
+			}
+
+			var saved []*Beneficiary
+			err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				saved, err = f.customers.QueryBeneficiaries(ctx, "C1")
+				return err
+			})
+			checkErr(t, err, "")
+			if len(saved) != 2 || saved[0].BeneficiaryID != "BOB" || saved[0].PaymentLimit != 100 {
+				t.Fatalf("unexpected beneficiaries %+v", saved)
+			}
+		})
+	}
+}
+
+func TestUpdateAndRemoveBeneficiary(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.AddBeneficiary(ctx, "C1", "BOB", "Bob", "A2", "Bob Jones", 0, 0)
+		return err
+	})
+
+	// Only C1's client can change C1's beneficiaries
+	for _, identity := range []*chaincodetest.Identity{f.anyone, f.clients["C2"]} {
+		err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := f.customers.AddBeneficiary(ctx, "C1", "BOB2", "Bob", "A2", "Bob Jones", 0, 0)
+			return err
+		})
+		checkCode(t, err, contracterrors.Forbidden)
+		err = f.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.UpdateBeneficiary(ctx, "C1", "BOB", "Bob", 1, 1)
+		})
+		checkCode(t, err, contracterrors.Forbidden)
+		err = f.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.RemoveBeneficiary(ctx, "C1", "BOB")
+		})
+		checkCode(t, err, contracterrors.Forbidden)
+	}
+
+	f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.UpdateBeneficiary(ctx, "C1", "BOB", "Landlord", 500, 1000)
+	})
+	var beneficiary *Beneficiary
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		beneficiary, err = f.customers.QueryBeneficiary(ctx, "C1", "BOB")
+		return err
+	})
+	checkErr(t, err, "")
+	if beneficiary.Nickname != "Landlord" || beneficiary.PaymentLimit != 500 || beneficiary.DailyLimit != 1000 {
+		t.Fatalf("unexpected beneficiary %+v", beneficiary)
+	}
+
+	err = f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.UpdateBeneficiary(ctx, "C1", "BOB", "Landlord", -1, 0)
+	})
+	checkCode(t, err, contracterrors.Validation)
+
+	f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.RemoveBeneficiary(ctx, "C1", "BOB")
+	})
+	err = f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.RemoveBeneficiary(ctx, "C1", "BOB")
+	})
+	checkCode(t, err, contracterrors.NotFound)
+}
+
+func TestPayBeneficiary(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	// Limits are in euros, the currency of A2
+	f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.AddBeneficiary(ctx, "C1", "BOB", "Bob", "A2", "Bob Jones", 100, 150)
+		return err
+	})
+
+	tests := []struct {
+		name               string
+		paymentID          string
+		receiverAccountID  string
+		receiverCustomerID string
+		beneficiaryID      string
+		amount             float64
+		wantCode           contracterrors.Code
+		wantErr            string
+	}{
+		{name: "by beneficiary", paymentID: "P1", beneficiaryID: "BOB", amount: 100},
+		{name: "above payment limit", paymentID: "P2", beneficiaryID: "BOB", amount: 120, wantCode: contracterrors.LimitExceeded, wantErr: "above the limit of 100 for beneficiary BOB"},
+		{name: "above daily limit", paymentID: "P2", beneficiaryID: "BOB", amount: 80, wantCode: contracterrors.LimitExceeded, wantErr: "today's total for beneficiary BOB to 162 EUR"},
+		{name: "matching receiver", paymentID: "P2", receiverAccountID: "A2", receiverCustomerID: "C2", beneficiaryID: "BOB", amount: 50},
+		{name: "other receiver", paymentID: "P3", receiverAccountID: "A1", beneficiaryID: "BOB", amount: 10, wantCode: contracterrors.Validation, wantErr: "beneficiary BOB is account A2 of customer C2"},
+		{name: "unknown beneficiary", paymentID: "P3", beneficiaryID: "EVE", amount: 10, wantCode: contracterrors.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.CreatePayment(ctx, tt.paymentID, "A1", tt.receiverAccountID, "C1", tt.receiverCustomerID, tt.amount, 0.9, "2024-01-01", tt.beneficiaryID, "")
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				if tt.wantErr != "" {
+					checkErr(t, err, tt.wantErr)
+				}
+				return
+			}
+			checkErr(t, err, "")
+		})
+	}
+
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 635)
+	var beneficiary *Beneficiary
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		beneficiary, err = f.customers.QueryBeneficiary(ctx, "C1", "BOB")
+		return err
+	})
+	checkErr(t, err, "")
+	assertFloat(t, "daily total", beneficiary.DailyTotal, 135)
+
+	payment, err := getCommittedPayment(f, "P1")
+	checkErr(t, err, "")
+	if payment.ReceiverAccountID != "A2" || payment.ReceiverCustomerID != "C2" || payment.BeneficiaryID != "BOB" {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+}
+
+func TestCreatePaymentChecksOwners(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.CreatePayment(ctx, "P1", "A1", "A2", "C1", "C1", 10, 0.9, "2024-01-01", "", "")
+	})
+	checkCode(t, err, contracterrors.Validation)
+	checkErr(t, err, "account A2 does not belong to customer C1")
+
+	err = f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.CreatePayment(ctx, "P1", "A1", "A2", "C2", "C2", 10, 0.9, "2024-01-01", "", "")
+	})
+	checkErr(t, err, "account A1 does not belong to customer C2")
+}
+
+func TestBatchPaysBeneficiary(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.AddBeneficiary(ctx, "C1", "BOB", "Bob", "A2", "Bob Jones", 0, 100)
+		return err
+	})
+
+	instructions := batchJSON(t,
+		PaymentInstruction{PaymentID: "P1", SenderAccountID: "A1", SenderCustomerID: "C1", BeneficiaryID: "BOB", Amount: 100, ExchangeRate: 0.9},
+		PaymentInstruction{PaymentID: "P2", SenderAccountID: "A1", SenderCustomerID: "C1", BeneficiaryID: "BOB", Amount: 100, ExchangeRate: 0.9},
+	)
+	var batch *PaymentBatch
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		batch, err = f.payments.CreatePaymentBatch(ctx, "B1", instructions, BatchModeBestEffort)
+		return err
+	})
+	checkErr(t, err, "")
+	if batch.Settled != 1 || batch.Results[1].Code != string(contracterrors.LimitExceeded) {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+}
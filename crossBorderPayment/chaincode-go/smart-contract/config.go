@@ -0,0 +1,233 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const configObjectType = "ChaincodeConfig"
+
+// maxRateAge bounds RateMaxAgeSeconds at one year
+const maxRateAge = 365 * 24 * 60 * 60
+
+// ChaincodeConfig holds the operator settings of the network. Version counts
+// the changes made to it: InitLedger stores version 1 and every Configure
+// increments it. Before InitLedger runs the defaults apply, which is the
+// version 0 config with every setting empty.
+type ChaincodeConfig struct {
+	Version int `json:"version"`
+
+	// SupportedCurrencies lists the currencies banks may hold. An empty list
+	// accepts every ISO 4217 currency.
+	SupportedCurrencies []string `json:"supportedCurrencies"`
+
+	// RateMaxAgeSeconds is how long an exchange rate stays usable after its
+	// bank set it. Payments between currencies are refused with RateStale when
+	// either bank's rate is older. Zero disables the check.
+	RateMaxAgeSeconds int `json:"rateMaxAgeSeconds"`
+
+	// DefaultFeeRate and DefaultFlatFee are the fee charged to the sender of a
+	// payment, as a fraction of the amount plus a fixed amount in the sender's
+	// currency. See Fee.
+	DefaultFeeRate float64 `json:"defaultFeeRate"`
+	DefaultFlatFee float64 `json:"defaultFlatFee"`
+
+	// AdminMSPs lists the organizations whose clients may act in the operator
+	// and compliance roles. An empty list accepts the roles from any
+	// organization.
+	AdminMSPs []string `json:"adminMSPs"`
+
+	// OperationExpirySeconds is how long a proposed administrative operation
+	// waits for its second approval before it expires. Zero means a day.
+	OperationExpirySeconds int `json:"operationExpirySeconds,omitempty" metadata:",optional"`
+
+	// MembershipQuorumPercent is the share of the member banks, other than
+	// the bank concerned, that must vote for a membership proposal. Zero means
+	// more than half of them.
+	MembershipQuorumPercent int `json:"membershipQuorumPercent,omitempty" metadata:",optional"`
+
+	UpdatedAt string `json:"updatedAt,omitempty" metadata:",optional"`
+	UpdatedBy string `json:"updatedBy,omitempty" metadata:",optional"`
+}
+
+// InitLedger stores the first version of the chaincode config. It fails if the
+// ledger was already initialized; use Configure to change the config. Only
+// operators may run it.
+func (s *AdminContract) InitLedger(ctx contractapi.TransactionContextInterface, config ChaincodeConfig) (*ChaincodeConfig, error) {
+	err := checkConfig(&config)
+	if err != nil {
+		return nil, err
+	}
+	err = requireRole(ctx, RoleOperator)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != 0 {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "the ledger is already initialized, config version %d", current.Version)
+	}
+
+	config.Version = 1
+	err = putConfig(ctx, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Configure replaces the chaincode config. config.Version must be the version
+// being replaced, as returned by GetConfig, so that concurrent changes are not
+// silently lost. Only operators may run it.
+func (s *AdminContract) Configure(ctx contractapi.TransactionContextInterface, config ChaincodeConfig) (*ChaincodeConfig, error) {
+	err := checkConfig(&config)
+	if err != nil {
+		return nil, err
+	}
+	err = requireRole(ctx, RoleOperator)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version == 0 {
+		return nil, contracterrors.New(contracterrors.InvalidState, "the ledger is not initialized, run InitLedger first")
+	}
+	if config.Version != current.Version {
+		return nil, contracterrors.New(contracterrors.InvalidState, "config version %d is not the current version %d", config.Version, current.Version)
+	}
+
+	config.Version = current.Version + 1
+	err = putConfig(ctx, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetConfig returns the chaincode config, or the defaults if InitLedger has
+// not run
+func (s *AdminContract) GetConfig(ctx contractapi.TransactionContextInterface) (*ChaincodeConfig, error) {
+	return getConfig(ctx)
+}
+
+// checkConfig validates the settings of config and replaces missing lists
+// with empty ones
+func checkConfig(config *ChaincodeConfig) error {
+	if config.SupportedCurrencies == nil {
+		config.SupportedCurrencies = []string{}
+	}
+	if config.AdminMSPs == nil {
+		config.AdminMSPs = []string{}
+	}
+
+	results := []error{
+		validation.IntRange("rateMaxAgeSeconds", config.RateMaxAgeSeconds, 0, maxRateAge),
+		validation.IntRange("operationExpirySeconds", config.OperationExpirySeconds, 0, maxOperationExpiry),
+		validation.IntRange("membershipQuorumPercent", config.MembershipQuorumPercent, 0, 100),
+		validation.NonNegativeAmount("defaultFeeRate", config.DefaultFeeRate),
+		validation.NonNegativeAmount("defaultFlatFee", config.DefaultFlatFee),
+	}
+	for _, currency := range config.SupportedCurrencies {
+		results = append(results, validation.Currency("supportedCurrencies", currency))
+	}
+	for _, mspID := range config.AdminMSPs {
+		results = append(results, validation.ID("adminMSPs", mspID))
+	}
+	return checkArgs(results...)
+}
+
+func getConfig(ctx contractapi.TransactionContextInterface) (*ChaincodeConfig, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config key: %v", err)
+	}
+	configJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+
+	config := &ChaincodeConfig{}
+	if configJSON != nil {
+		err = json.Unmarshal(configJSON, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal config: %v", err)
+		}
+	}
+	err = checkConfig(config)
+	if err != nil {
+		return nil, contracterrors.Wrap(err, "stored config is invalid")
+	}
+	return config, nil
+}
+
+func putConfig(ctx contractapi.TransactionContextInterface, config *ChaincodeConfig) error {
+	var err error
+	config.UpdatedAt, err = getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	config.UpdatedBy, err = getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(configObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create config key: %v", err)
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %v", err)
+	}
+	return ctx.GetStub().PutState(key, configJSON)
+}
+
+// checkSupportedCurrency returns a Validation error if the config restricts the
+// currencies banks may hold and currency is not one of them
+func checkSupportedCurrency(config *ChaincodeConfig, field string, currency string) error {
+	if len(config.SupportedCurrencies) == 0 {
+		return nil
+	}
+	return checkArgs(validation.OneOf(field, currency, config.SupportedCurrencies...))
+}
+
+// Fee returns the fee of a payment of amount, rounded to cents
+func (c *ChaincodeConfig) Fee(amount float64) float64 {
+	return math.Round((amount*c.DefaultFeeRate+c.DefaultFlatFee)*100) / 100
+}
+
+// checkRateAge returns a RateStale error if the exchange rate of bank is older
+// than the config allows at time now
+func checkRateAge(config *ChaincodeConfig, bank *Bank, now time.Time) error {
+	if config.RateMaxAgeSeconds == 0 {
+		return nil
+	}
+	if bank.ExchangeRateDate == "" {
+		return contracterrors.New(contracterrors.RateStale, "bank %s has not set its exchange rate", bank.BankID)
+	}
+	rateDate, err := time.Parse(time.RFC3339, bank.ExchangeRateDate)
+	if err != nil {
+		return fmt.Errorf("failed to parse exchange rate date of bank %s: %v", bank.BankID, err)
+	}
+	if now.Sub(rateDate) > time.Duration(config.RateMaxAgeSeconds)*time.Second {
+		return contracterrors.New(contracterrors.RateStale, "exchange rate of bank %s was set at %s, more than %d seconds ago", bank.BankID, bank.ExchangeRateDate, config.RateMaxAgeSeconds)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+func (f *fixture) config() *ChaincodeConfig {
+	f.t.Helper()
+	var config *ChaincodeConfig
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		config, err = f.admin.GetConfig(ctx)
+		return err
+	})
+	if err != nil {
+		f.t.Fatalf("failed to read config: %v", err)
+	}
+	return config
+}
+
+// configure stores config as the next version of the chaincode config
+func (f *fixture) configure(config ChaincodeConfig) {
+	f.t.Helper()
+	config.Version = f.config().Version
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		if config.Version == 0 {
+			_, err := f.admin.InitLedger(ctx, config)
+			return err
+		}
+		_, err := f.admin.Configure(ctx, config)
+		return err
+	})
+}
+
+func TestInitLedgerAndConfigure(t *testing.T) {
+	f := newFixture(t)
+
+	config := f.config()
+	if config.Version != 0 || config.SupportedCurrencies == nil || config.AdminMSPs == nil {
+		t.Fatalf("unexpected default config %+v", config)
+	}
+
+	settings := ChaincodeConfig{SupportedCurrencies: []string{"USD", "EUR"}, RateMaxAgeSeconds: 3600, DefaultFeeRate: 0.001}
+	err := f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.InitLedger(ctx, settings)
+		return err
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.InitLedger(ctx, settings)
+		return err
+	})
+	config = f.config()
+	if config.Version != 1 || config.RateMaxAgeSeconds != 3600 || config.UpdatedBy != f.operator.ID() || config.UpdatedAt == "" {
+		t.Fatalf("unexpected initial config %+v", config)
+	}
+
+	err = f.submit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.InitLedger(ctx, settings)
+		return err
+	})
+	checkCode(t, err, contracterrors.AlreadyExists)
+
+	tests := []struct {
+		name     string
+		config   ChaincodeConfig
+		wantCode contracterrors.Code
+	}{
+		{name: "stale version", config: ChaincodeConfig{Version: 0}, wantCode: contracterrors.InvalidState},
+		{name: "unknown currency", config: ChaincodeConfig{Version: 1, SupportedCurrencies: []string{"XYZ"}}, wantCode: contracterrors.Validation},
+		{name: "negative fee", config: ChaincodeConfig{Version: 1, DefaultFlatFee: -1}, wantCode: contracterrors.Validation},
+		{name: "negative rate age", config: ChaincodeConfig{Version: 1, RateMaxAgeSeconds: -1}, wantCode: contracterrors.Validation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.submit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+				_, err := f.admin.Configure(ctx, tt.config)
+				return err
+			})
+			checkCode(t, err, tt.wantCode)
+		})
+	}
+
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.Configure(ctx, ChaincodeConfig{Version: 1, DefaultFlatFee: 2})
+		return err
+	})
+	config = f.config()
+	if config.Version != 2 || config.DefaultFlatFee != 2 || len(config.SupportedCurrencies) != 0 {
+		t.Fatalf("unexpected config after Configure %+v", config)
+	}
+}
+
+func TestSupportedCurrencies(t *testing.T) {
+	f := newFixture(t)
+	f.configure(ChaincodeConfig{SupportedCurrencies: []string{"USD"}})
+
+	err := f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "")
+	})
+	checkCode(t, err, contracterrors.Validation)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", "US", "USD", 10000, 1, "")
+	})
+
+	err = f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetTierLimit(ctx, DefaultTier, "EUR", 100, 0, 0, 0, 0)
+	})
+	checkCode(t, err, contracterrors.Validation)
+}
+
+func TestAdminMSPs(t *testing.T) {
+	f := newFixture(t)
+	f.configure(ChaincodeConfig{AdminMSPs: []string{"Org1MSP"}})
+	outsider := chaincodetest.NewIdentity("Org2MSP", "officer2", map[string]string{RoleAttribute: RoleCompliance})
+
+	err := f.submit(outsider, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddWatchlistEntry(ctx, WatchlistCountry, "KP", "sanctioned")
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddWatchlistEntry(ctx, WatchlistCountry, "KP", "sanctioned")
+	})
+}
+
+func TestRateStaleness(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.configure(ChaincodeConfig{RateMaxAgeSeconds: 3600})
+
+	checkErr(t, f.pay("P1", "A1", "A2", 10, 0.9), "")
+
+	f.stub.Now = f.stub.Now.Add(2 * time.Hour)
+	err := f.pay("P2", "A1", "A2", 10, 0.9)
+	checkCode(t, err, contracterrors.RateStale)
+	checkErr(t, err, "exchange rate of bank BANK1")
+
+	err = f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.UpdateExchangeRate(ctx, "BANK1", 1)
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.UpdateExchangeRate(ctx, "BANK1", 1)
+	})
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.UpdateExchangeRate(ctx, "BANK2", 0.92)
+	})
+	checkErr(t, f.pay("P2", "A1", "A2", 10, 0.92), "")
+	assertFloat(t, "BANK2 exchange rate", f.bank("BANK2").ExchangeRate, 0.92)
+}
+
+func TestPaymentFees(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.configure(ChaincodeConfig{DefaultFeeRate: 0.01, DefaultFlatFee: 2})
+
+	checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+	payment, err := getCommittedPayment(f, "P1")
+	checkErr(t, err, "")
+	assertFloat(t, "fee", payment.Fee, 3)
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 897)
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+	// The sending bank keeps the fee
+	assertFloat(t, "BANK1 reserves", f.bank("BANK1").Reserves, 9900)
+
+	// The balance must cover the fee as well as the amount
+	err = f.pay("P2", "A1", "A2", 890, 0.9)
+	checkCode(t, err, contracterrors.InsufficientFunds)
+	checkErr(t, err, "fee 10.9")
+
+	if report := f.invariants(); !report.Holds {
+		t.Fatalf("invariants do not hold: %v", report.Violations)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Contract names. Clients call a transaction by prefixing its name with the
+// contract name, for example "payment:CreatePayment".
+const (
+	BankContractName     = "bank"
+	CustomerContractName = "customer"
+	AccountContractName  = "account"
+	PaymentContractName  = "payment"
+	AdminContractName    = "admin"
+)
+
+// BankContract registers banks and maintains their profiles
+type BankContract struct {
+	contractapi.Contract
+}
+
+// CustomerContract registers customers and runs their KYC lifecycle
+type CustomerContract struct {
+	contractapi.Contract
+}
+
+// AccountContract opens, queries and changes the status of accounts
+type AccountContract struct {
+	contractapi.Contract
+}
+
+// PaymentContract submits payments and batches and handles payments held by
+// screening
+type PaymentContract struct {
+	contractapi.Contract
+}
+
+// AdminContract holds the network administration transactions: the
+// watchlist, tier limits and the ledger invariants
+type AdminContract struct {
+	contractapi.Contract
+}
+
+// NewChaincode returns the chaincode with every contract registered under its
+// name
+func NewChaincode() (*contractapi.ContractChaincode, error) {
+	banks := new(BankContract)
+	banks.Name = BankContractName
+	customers := new(CustomerContract)
+	customers.Name = CustomerContractName
+	accounts := new(AccountContract)
+	accounts.Name = AccountContractName
+	payments := new(PaymentContract)
+	payments.Name = PaymentContractName
+	admin := new(AdminContract)
+	admin.Name = AdminContractName
+
+	return contractapi.NewChaincode(banks, customers, accounts, payments, admin)
+}
@@ -0,0 +1,571 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const (
+	memberBankObjectType         = "MemberBank"
+	membershipProposalObjectType = "MembershipProposal"
+	openProposalObjectType       = "OpenMembershipProposal"
+)
+
+// governanceSchemaVersion is the first schema version with consortium
+// governance. Banks stored before it join the member index when MigrateState
+// rewrites them, so votes wait until a migration to it has completed.
+const governanceSchemaVersion = 2
+
+// Bank statuses. Only active banks are members of the consortium: they hold
+// accounts, take part in payments and vote on proposals. Expelled and
+// rejected are final.
+const (
+	BankPending   = "pending"
+	BankActive    = "active"
+	BankSuspended = "suspended"
+	BankExpelled  = "expelled"
+	BankRejected  = "rejected"
+)
+
+// Membership proposals the member banks vote on, and the status each moves
+// its bank from and to
+const (
+	MembershipAdmit     = "admit"
+	MembershipSuspend   = "suspend"
+	MembershipReinstate = "reinstate"
+	MembershipExpel     = "expel"
+)
+
+var membershipTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	MembershipAdmit:     {from: []string{BankPending}, to: BankActive},
+	MembershipSuspend:   {from: []string{BankActive}, to: BankSuspended},
+	MembershipReinstate: {from: []string{BankSuspended}, to: BankActive},
+	MembershipExpel:     {from: []string{BankActive, BankSuspended}, to: BankExpelled},
+}
+
+// Membership proposal statuses
+const (
+	ProposalOpen      = "open"
+	ProposalAccepted  = "accepted"
+	ProposalRejected  = "rejected"
+	ProposalWithdrawn = "withdrawn"
+)
+
+// MembershipProposal is a vote of the member banks on admitting, suspending,
+// reinstating or expelling a bank. A bank has at most one open proposal. The
+// proposal passes once the banks in VotesFor reach the quorum of the members
+// other than the bank itself, and fails once too many have voted against for
+// it to pass, or when it is withdrawn. Decided proposals stay on the ledger as
+// the bank's history.
+type MembershipProposal struct {
+	ProposalID   string   `json:"proposalID"`
+	BankID       string   `json:"bankID"`
+	Action       string   `json:"action"`
+	Status       string   `json:"status"`
+	ProposedBy   string   `json:"proposedBy"`
+	VotesFor     []string `json:"votesFor"`
+	VotesAgainst []string `json:"votesAgainst"`
+	Date         string   `json:"date"`
+
+	Reason        string `json:"reason,omitempty" metadata:",optional"`
+	RequiredVotes int    `json:"requiredVotes,omitempty" metadata:",optional"`
+	DecidedAt     string `json:"decidedAt,omitempty" metadata:",optional"`
+
+	WithdrawalReason string `json:"withdrawalReason,omitempty" metadata:",optional"`
+}
+
+// ProposeMembershipChange asks the member banks to suspend, reinstate or expel
+// a bank. The administrator of proposerBankID, which must be a member, submits
+// it, and the proposal counts as that bank's vote in favor. It fails if the
+// bank already has an open proposal.
+func (s *BankContract) ProposeMembershipChange(ctx contractapi.TransactionContextInterface, bankID string, proposerBankID string, action string, reason string) (*MembershipProposal, error) {
+	err := checkArgs(
+		validation.ID("bankID", bankID),
+		validation.OneOf("action", action, MembershipSuspend, MembershipReinstate, MembershipExpel),
+		validation.Required("reason", reason),
+	)
+	if err != nil {
+		return nil, err
+	}
+	bank, err := getBank(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(membershipTransitions[action].from, bank.Status) {
+		return nil, contracterrors.New(contracterrors.InvalidState, "bank %s is %s and cannot be proposed to %s", bankID, bank.Status, action)
+	}
+	err = requireVotingMember(ctx, bankID, proposerBankID)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal, err := openMembershipProposal(ctx, &MembershipProposal{BankID: bankID, Action: action, ProposedBy: proposerBankID, Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+	return proposal, castMembershipVote(ctx, proposal, proposerBankID, true)
+}
+
+// VoteOnMembership records the vote of a member bank on the open proposal
+// about bankID and returns the proposal, which is decided and applied as soon
+// as the vote settles it. Only the administrator of voterBankID may vote for
+// it, each member votes once, and a bank never votes on itself.
+func (s *BankContract) VoteOnMembership(ctx contractapi.TransactionContextInterface, bankID string, voterBankID string, inFavor bool) (*MembershipProposal, error) {
+	err := checkArgs(validation.ID("bankID", bankID))
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := getOpenMembershipProposal(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireVotingMember(ctx, bankID, voterBankID)
+	if err != nil {
+		return nil, err
+	}
+	if contains(proposal.VotesFor, voterBankID) || contains(proposal.VotesAgainst, voterBankID) {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "bank %s already voted on proposal %s", voterBankID, proposal.ProposalID)
+	}
+
+	return proposal, castMembershipVote(ctx, proposal, voterBankID, inFavor)
+}
+
+// WithdrawMembershipProposal closes the open proposal about bankID without a
+// decision, for example when too few members are left to settle it. The
+// administrator of the bank that made the proposal, or an operator, may
+// withdraw it. A withdrawn application rejects the applicant.
+func (s *BankContract) WithdrawMembershipProposal(ctx contractapi.TransactionContextInterface, bankID string, reason string) (*MembershipProposal, error) {
+	err := checkArgs(
+		validation.ID("bankID", bankID),
+		validation.Required("reason", reason),
+	)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := getOpenMembershipProposal(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	proposer, err := getBank(ctx, proposal.ProposedBy)
+	if err != nil {
+		return nil, err
+	}
+	err = requireBankAdminOrOperator(ctx, proposer)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal.WithdrawalReason = reason
+	return proposal, decideMembershipProposal(ctx, proposal, ProposalWithdrawn)
+}
+
+// AdmitFoundingBank admits an applicant while the consortium has no members,
+// when there is nobody to vote. Only operators may run it, once the state
+// migration has indexed any banks stored before governance.
+func (s *AdminContract) AdmitFoundingBank(ctx contractapi.TransactionContextInterface, bankID string) (*MembershipProposal, error) {
+	err := checkArgs(validation.ID("bankID", bankID))
+	if err != nil {
+		return nil, err
+	}
+	err = requireRole(ctx, RoleOperator)
+	if err != nil {
+		return nil, err
+	}
+	err = requireMemberIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := memberBankIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) > 0 {
+		return nil, contracterrors.New(contracterrors.InvalidState, "the consortium has %d members, which vote on bank %s", len(members), bankID)
+	}
+	proposal, err := getOpenMembershipProposal(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	return proposal, decideMembershipProposal(ctx, proposal, ProposalAccepted)
+}
+
+// QueryMemberBanks returns the banks that are members of the consortium
+func (s *BankContract) QueryMemberBanks(ctx contractapi.TransactionContextInterface) ([]*Bank, error) {
+	members, err := memberBankIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	banks := []*Bank{}
+	for _, bankID := range members {
+		bank, err := getBank(ctx, bankID)
+		if err != nil {
+			return nil, err
+		}
+		banks = append(banks, bank)
+	}
+	return banks, nil
+}
+
+// QueryMembershipProposals returns the proposals about a bank, open and
+// decided, in the order they were made
+func (s *BankContract) QueryMembershipProposals(ctx contractapi.TransactionContextInterface, bankID string) ([]*MembershipProposal, error) {
+	err := checkArgs(validation.ID("bankID", bankID))
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(membershipProposalObjectType, []string{bankID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership proposals: %v", err)
+	}
+	defer iterator.Close()
+
+	proposals := []*MembershipProposal{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over membership proposals: %v", err)
+		}
+		var proposal MembershipProposal
+		err = json.Unmarshal(queryResponse.Value, &proposal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal membership proposal: %v", err)
+		}
+		proposals = append(proposals, &proposal)
+	}
+	sortProposals(proposals)
+	return proposals, nil
+}
+
+// QueryOpenMembershipProposals returns the proposals the members have yet to
+// decide
+func (s *BankContract) QueryOpenMembershipProposals(ctx contractapi.TransactionContextInterface) ([]*MembershipProposal, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(openProposalObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open membership proposals: %v", err)
+	}
+	defer iterator.Close()
+
+	proposals := []*MembershipProposal{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over open membership proposals: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		proposal, err := getMembershipProposal(ctx, keyParts[0], string(queryResponse.Value))
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, proposal)
+	}
+	return proposals, nil
+}
+
+// checkBankCanTransact returns an error unless the bank is a member
+func checkBankCanTransact(bank *Bank) error {
+	if bank.Status != BankActive {
+		return contracterrors.New(contracterrors.InvalidState, "bank %s is %s", bank.BankID, bank.Status)
+	}
+	return nil
+}
+
+// requireMemberIndex returns an error until a migration to a schema version
+// with governance has completed. Until then banks stored before governance
+// are members missing from the index, and votes would be counted without
+// them.
+func requireMemberIndex(ctx contractapi.TransactionContextInterface) error {
+	progress, err := getMigrationProgress(ctx)
+	if err != nil {
+		return err
+	}
+	if !progress.Complete || progress.TargetVersion < governanceSchemaVersion {
+		return contracterrors.New(contracterrors.InvalidState, "membership votes wait until MigrateState has completed")
+	}
+	return nil
+}
+
+// requireVotingMember returns an error unless the member index is complete
+// and the submitting client administers voterBankID, a member other than
+// bankID
+func requireVotingMember(ctx contractapi.TransactionContextInterface, bankID string, voterBankID string) error {
+	err := checkArgs(validation.ID("voterBankID", voterBankID))
+	if err != nil {
+		return err
+	}
+	err = requireMemberIndex(ctx)
+	if err != nil {
+		return err
+	}
+	voter, err := getBank(ctx, voterBankID)
+	if err != nil {
+		return err
+	}
+	err = requireBankAdmin(ctx, voter)
+	if err != nil {
+		return err
+	}
+	if voterBankID == bankID {
+		return contracterrors.New(contracterrors.Forbidden, "bank %s cannot vote on itself", bankID)
+	}
+	if voter.Status != BankActive {
+		return contracterrors.New(contracterrors.Forbidden, "bank %s is %s and cannot vote", voterBankID, voter.Status)
+	}
+	return nil
+}
+
+// requiredVotes is the number of votes in favor a proposal about bankID needs
+// under the config's quorum, out of the members other than the bank
+func requiredVotes(ctx contractapi.TransactionContextInterface, bankID string) (int, int, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	members, err := memberBankIDs(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	voters := len(removeString(members, bankID))
+
+	if config.MembershipQuorumPercent == 0 {
+		return voters/2 + 1, voters, nil
+	}
+	required := (voters*config.MembershipQuorumPercent + 99) / 100
+	if required < 1 {
+		required = 1
+	}
+	return required, voters, nil
+}
+
+// castMembershipVote adds a vote to the proposal and decides it if the votes
+// now settle it
+func castMembershipVote(ctx contractapi.TransactionContextInterface, proposal *MembershipProposal, voterBankID string, inFavor bool) error {
+	if inFavor {
+		proposal.VotesFor = append(proposal.VotesFor, voterBankID)
+	} else {
+		proposal.VotesAgainst = append(proposal.VotesAgainst, voterBankID)
+	}
+
+	required, voters, err := requiredVotes(ctx, proposal.BankID)
+	if err != nil {
+		return err
+	}
+	proposal.RequiredVotes = required
+	switch {
+	case len(proposal.VotesFor) >= required:
+		return decideMembershipProposal(ctx, proposal, ProposalAccepted)
+	case voters-len(proposal.VotesAgainst) < required:
+		return decideMembershipProposal(ctx, proposal, ProposalRejected)
+	}
+	return putMembershipProposal(ctx, proposal)
+}
+
+// decideMembershipProposal closes the proposal and, if it was accepted, moves
+// its bank to the new status. A rejected or withdrawn application rejects the
+// bank.
+func decideMembershipProposal(ctx contractapi.TransactionContextInterface, proposal *MembershipProposal, status string) error {
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	proposal.Status = status
+	proposal.DecidedAt = date
+	err = putMembershipProposal(ctx, proposal)
+	if err != nil {
+		return err
+	}
+	openKey, err := openProposalKey(ctx, proposal.BankID)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(openKey)
+	if err != nil {
+		return err
+	}
+
+	bankStatus, reason := membershipTransitions[proposal.Action].to, proposal.Reason
+	if status == ProposalWithdrawn {
+		reason = proposal.WithdrawalReason
+	}
+	if status != ProposalAccepted {
+		if proposal.Action != MembershipAdmit {
+			return nil
+		}
+		bankStatus = BankRejected
+	}
+	bank, err := getBank(ctx, proposal.BankID)
+	if err != nil {
+		return err
+	}
+	bank.Status = bankStatus
+	bank.StatusReason = reason
+	bank.StatusDate = date
+	err = putBank(ctx, bank)
+	if err != nil {
+		return err
+	}
+	return putMemberIndex(ctx, bank)
+}
+
+// openMembershipProposal stores proposal as the open proposal about its bank,
+// under the ID of the running transaction
+func openMembershipProposal(ctx contractapi.TransactionContextInterface, proposal *MembershipProposal) (*MembershipProposal, error) {
+	openKey, err := openProposalKey(ctx, proposal.BankID)
+	if err != nil {
+		return nil, err
+	}
+	openID, err := ctx.GetStub().GetState(openKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open membership proposal: %v", err)
+	}
+	if openID != nil {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "bank %s already has open proposal %s", proposal.BankID, openID)
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal.ProposalID = ctx.GetStub().GetTxID()
+	proposal.Status = ProposalOpen
+	proposal.VotesFor = []string{}
+	proposal.VotesAgainst = []string{}
+	proposal.Date = date
+	err = putMembershipProposal(ctx, proposal)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(openKey, []byte(proposal.ProposalID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to put open membership proposal index: %v", err)
+	}
+	return proposal, nil
+}
+
+// getOpenMembershipProposal loads the open proposal about a bank
+func getOpenMembershipProposal(ctx contractapi.TransactionContextInterface, bankID string) (*MembershipProposal, error) {
+	openKey, err := openProposalKey(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	openID, err := ctx.GetStub().GetState(openKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open membership proposal: %v", err)
+	}
+	if openID == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "bank %s has no open membership proposal", bankID)
+	}
+	return getMembershipProposal(ctx, bankID, string(openID))
+}
+
+// memberBankIDs returns the IDs of the member banks in order
+func memberBankIDs(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(memberBankObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read member banks: %v", err)
+	}
+	defer iterator.Close()
+
+	members := []string{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over member banks: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+		members = append(members, keyParts[0])
+	}
+	return members, nil
+}
+
+// putMemberIndex lists the bank among the members if it is active and takes it
+// off the list otherwise
+func putMemberIndex(ctx contractapi.TransactionContextInterface, bank *Bank) error {
+	key, err := ctx.GetStub().CreateCompositeKey(memberBankObjectType, []string{bank.BankID})
+	if err != nil {
+		return fmt.Errorf("failed to create member bank key: %v", err)
+	}
+	if bank.Status != BankActive {
+		return ctx.GetStub().DelState(key)
+	}
+	err = ctx.GetStub().PutState(key, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put member bank index: %v", err)
+	}
+	return nil
+}
+
+// sortProposals orders proposals by date, as their transaction IDs do not
+// sort in the order they were made
+func sortProposals(proposals []*MembershipProposal) {
+	sort.SliceStable(proposals, func(i, j int) bool {
+		return proposals[i].Date < proposals[j].Date
+	})
+}
+
+func membershipProposalKey(ctx contractapi.TransactionContextInterface, bankID string, proposalID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(membershipProposalObjectType, []string{bankID, proposalID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create membership proposal key: %v", err)
+	}
+	return key, nil
+}
+
+func openProposalKey(ctx contractapi.TransactionContextInterface, bankID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(openProposalObjectType, []string{bankID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create open membership proposal key: %v", err)
+	}
+	return key, nil
+}
+
+func getMembershipProposal(ctx contractapi.TransactionContextInterface, bankID string, proposalID string) (*MembershipProposal, error) {
+	key, err := membershipProposalKey(ctx, bankID, proposalID)
+	if err != nil {
+		return nil, err
+	}
+	proposalJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership proposal state: %v", err)
+	}
+	if proposalJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "membership proposal %s does not exist", proposalID)
+	}
+
+	var proposal MembershipProposal
+	err = json.Unmarshal(proposalJSON, &proposal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal membership proposal JSON: %v", err)
+	}
+	return &proposal, nil
+}
+
+func putMembershipProposal(ctx contractapi.TransactionContextInterface, proposal *MembershipProposal) error {
+	key, err := membershipProposalKey(ctx, proposal.BankID, proposal.ProposalID)
+	if err != nil {
+		return err
+	}
+	proposalJSON, err := json.Marshal(proposal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal membership proposal JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(key, proposalJSON)
+}
@@ -0,0 +1,356 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// admitBank makes an applicant a member: an operator migrates the state and
+// admits the founding bank, and BANK1 and BANK2, whichever are members, vote
+// for the ones after it
+func (f *fixture) admitBank(bankID string) {
+	f.t.Helper()
+	members := f.memberBankIDs()
+	if len(members) == 0 {
+		f.migrate()
+		f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := f.admin.AdmitFoundingBank(ctx, bankID)
+			return err
+		})
+		return
+	}
+	for _, voter := range []struct {
+		bankID string
+		admin  *chaincodetest.Identity
+	}{{"BANK1", f.bank1Admin}, {"BANK2", f.bank2Admin}} {
+		if !contains(members, voter.bankID) || f.bank(bankID).Status == BankActive {
+			continue
+		}
+		_, err := f.voteOnMembership(voter.admin, bankID, voter.bankID, true)
+		checkErr(f.t, err, "")
+	}
+	if status := f.bank(bankID).Status; status != BankActive {
+		f.t.Fatalf("bank %s is %s after the members voted", bankID, status)
+	}
+}
+
+// migrate runs MigrateState until it completes
+func (f *fixture) migrate() {
+	f.t.Helper()
+	for complete := false; !complete; {
+		f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+			progress, err := f.admin.MigrateState(ctx, maxMigrationPageSize)
+			if err == nil {
+				complete = progress.Complete
+			}
+			return err
+		})
+	}
+}
+
+func (f *fixture) voteOnMembership(identity *chaincodetest.Identity, bankID string, voterBankID string, inFavor bool) (*MembershipProposal, error) {
+	f.t.Helper()
+	var proposal *MembershipProposal
+	err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+		proposal, err = f.banks.VoteOnMembership(ctx, bankID, voterBankID, inFavor)
+		return err
+	})
+	return proposal, err
+}
+
+func (f *fixture) proposeMembershipChange(identity *chaincodetest.Identity, bankID string, proposerBankID string, action string) (*MembershipProposal, error) {
+	f.t.Helper()
+	var proposal *MembershipProposal
+	err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+		proposal, err = f.banks.ProposeMembershipChange(ctx, bankID, proposerBankID, action, "audit findings")
+		return err
+	})
+	return proposal, err
+}
+
+func (f *fixture) memberBankIDs() []string {
+	f.t.Helper()
+	var banks []*Bank
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		banks, err = f.banks.QueryMemberBanks(ctx)
+		return err
+	})
+	checkErr(f.t, err, "")
+	ids := []string{}
+	for _, bank := range banks {
+		ids = append(ids, bank.BankID)
+	}
+	return ids
+}
+
+// addThirdBank applies for BANK3, administered by anyone, and has BANK1 and
+// BANK2 vote it in
+func (f *fixture) addThirdBank() {
+	f.t.Helper()
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK3", "", "Third Bank", "pw", "GB", "GBP", 0, 0.8, "")
+	})
+	f.admitBank("BANK3")
+}
+
+func TestAdmitBank(t *testing.T) {
+	f := newFixture(t)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", "US", "USD", 10000, 1, "")
+	})
+	if status := f.bank("BANK1").Status; status != BankPending {
+		t.Fatalf("new bank is %s", status)
+	}
+
+	err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.AdmitFoundingBank(ctx, "BANK1")
+		return err
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	f.admitBank("BANK1")
+
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "")
+	})
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C2", "Bob", "Jones")
+	err = f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A2", "C2", "BANK2", 500, "", "", "", "")
+	})
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "bank BANK2 is pending")
+
+	err = f.submit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.AdmitFoundingBank(ctx, "BANK2")
+		return err
+	})
+	checkCode(t, err, contracterrors.InvalidState)
+	_, err = f.voteOnMembership(f.bank2Admin, "BANK2", "BANK2", true)
+	checkCode(t, err, contracterrors.Forbidden)
+	checkErr(t, err, "cannot vote on itself")
+	_, err = f.voteOnMembership(f.bank2Admin, "BANK2", "BANK1", true)
+	checkCode(t, err, contracterrors.Forbidden)
+
+	proposal, err := f.voteOnMembership(f.bank1Admin, "BANK2", "BANK1", true)
+	checkErr(t, err, "")
+	if proposal.Status != ProposalAccepted || proposal.RequiredVotes != 1 || proposal.DecidedAt == "" {
+		t.Fatalf("unexpected proposal %+v", proposal)
+	}
+	if members := f.memberBankIDs(); len(members) != 2 {
+		t.Fatalf("unexpected members %v", members)
+	}
+	_, err = f.voteOnMembership(f.bank1Admin, "BANK2", "BANK1", true)
+	checkCode(t, err, contracterrors.NotFound)
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A2", "C2", "BANK2", 500, "", "", "", "")
+	})
+}
+
+func TestMembershipQuorum(t *testing.T) {
+	tests := []struct {
+		name       string
+		quorum     int
+		votes      []bool
+		wantStatus string
+	}{
+		{name: "majority needs both members", votes: []bool{true}, wantStatus: BankPending},
+		{name: "majority accepted", votes: []bool{true, true}, wantStatus: BankActive},
+		{name: "one against rejects", votes: []bool{true, false}, wantStatus: BankRejected},
+		{name: "half quorum", quorum: 50, votes: []bool{true}, wantStatus: BankActive},
+		{name: "half quorum against", quorum: 50, votes: []bool{false}, wantStatus: BankPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			if tt.quorum != 0 {
+				f.configure(ChaincodeConfig{MembershipQuorumPercent: tt.quorum})
+			}
+			f.seed()
+			f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.banks.CreateBank(ctx, "BANK3", "", "Third Bank", "pw", "GB", "GBP", 0, 0.8, "")
+			})
+
+			voters := []struct {
+				bankID string
+				admin  *chaincodetest.Identity
+			}{{"BANK1", f.bank1Admin}, {"BANK2", f.bank2Admin}}
+			for i, inFavor := range tt.votes {
+				_, err := f.voteOnMembership(voters[i].admin, "BANK3", voters[i].bankID, inFavor)
+				checkErr(t, err, "")
+			}
+			if status := f.bank("BANK3").Status; status != tt.wantStatus {
+				t.Fatalf("bank is %s, want %s", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSuspendAndExpelBank(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.addThirdBank()
+
+	_, err := f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK2", MembershipSuspend)
+	checkCode(t, err, contracterrors.Forbidden)
+	_, err = f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipReinstate)
+	checkCode(t, err, contracterrors.InvalidState)
+
+	proposal, err := f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipSuspend)
+	checkErr(t, err, "")
+	if proposal.Status != ProposalOpen || proposal.RequiredVotes != 2 || len(proposal.VotesFor) != 1 {
+		t.Fatalf("unexpected proposal %+v", proposal)
+	}
+	_, err = f.proposeMembershipChange(f.anyone, "BANK2", "BANK3", MembershipExpel)
+	checkCode(t, err, contracterrors.AlreadyExists)
+	_, err = f.voteOnMembership(f.bank2Admin, "BANK2", "BANK2", false)
+	checkCode(t, err, contracterrors.Forbidden)
+	_, err = f.voteOnMembership(f.anyone, "BANK2", "BANK3", true)
+	checkErr(t, err, "")
+
+	bank := f.bank("BANK2")
+	if bank.Status != BankSuspended || bank.StatusReason != "audit findings" {
+		t.Fatalf("unexpected bank %+v", bank)
+	}
+	checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "bank BANK2 is suspended")
+	checkErr(t, f.pay("P1", "A2", "A1", 100, 1/0.9), "bank BANK2 is suspended")
+	// A suspended bank keeps its accounts and cannot vote
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 500)
+	_, err = f.proposeMembershipChange(f.bank2Admin, "BANK3", "BANK2", MembershipSuspend)
+	checkCode(t, err, contracterrors.Forbidden)
+
+	_, err = f.proposeMembershipChange(f.anyone, "BANK2", "BANK3", MembershipReinstate)
+	checkErr(t, err, "")
+	_, err = f.voteOnMembership(f.bank1Admin, "BANK2", "BANK1", true)
+	checkErr(t, err, "")
+	checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+
+	_, err = f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipExpel)
+	checkErr(t, err, "")
+	_, err = f.voteOnMembership(f.anyone, "BANK2", "BANK3", true)
+	checkErr(t, err, "")
+	if status := f.bank("BANK2").Status; status != BankExpelled {
+		t.Fatalf("bank is %s after it was expelled", status)
+	}
+	if members := f.memberBankIDs(); len(members) != 2 || contains(members, "BANK2") {
+		t.Fatalf("unexpected members %v", members)
+	}
+	_, err = f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipReinstate)
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, f.pay("P2", "A1", "A2", 100, 0.9), "bank BANK2 is expelled")
+
+	var proposals []*MembershipProposal
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		proposals, err = f.banks.QueryMembershipProposals(ctx, "BANK2")
+		return err
+	})
+	checkErr(t, err, "")
+	var actions []string
+	for _, p := range proposals {
+		actions = append(actions, p.Action+":"+p.Status)
+	}
+	if len(actions) != 4 || actions[0] != "admit:accepted" || actions[1] != "suspend:accepted" || actions[3] != "expel:accepted" {
+		t.Fatalf("unexpected history %v", actions)
+	}
+	if report := f.invariants(); !report.Holds {
+		t.Fatalf("invariants do not hold: %v", report.Violations)
+	}
+}
+
+func TestSuspendedBankCannotActForItself(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	// Proposed while the bank was a member, approved after it was suspended
+	operation, err := f.propose(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, "A2", 100)
+	})
+	checkErr(t, err, "")
+	_, err = f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipSuspend)
+	checkErr(t, err, "")
+
+	for name, fn := range map[string]txFunc{
+		"set purpose code rule": func(ctx contractapi.TransactionContextInterface) error {
+			return f.banks.SetPurposeCodeRule(ctx, "BANK2", "US", nil)
+		},
+		"remove purpose code rule": func(ctx contractapi.TransactionContextInterface) error {
+			return f.banks.RemovePurposeCodeRule(ctx, "BANK2", "US")
+		},
+		"update exchange rate": func(ctx contractapi.TransactionContextInterface) error {
+			return f.banks.UpdateExchangeRate(ctx, "BANK2", 0.95)
+		},
+		"propose bank profile": func(ctx contractapi.TransactionContextInterface) error {
+			_, err := f.banks.UpdateBankProfile(ctx, "BANK2", "", "Second Bank", 6000, "DE")
+			return err
+		},
+		"propose balance": func(ctx contractapi.TransactionContextInterface) error {
+			_, err := f.accounts.UpdateBalance(ctx, "A2", 100)
+			return err
+		},
+		"freeze account": func(ctx contractapi.TransactionContextInterface) error {
+			return f.accounts.FreezeAccount(ctx, "A2", "audit")
+		},
+	} {
+		err := f.submit(f.bank2Admin, fn)
+		if contracterrors.CodeOf(err) != contracterrors.InvalidState || !strings.Contains(contracterrors.Message(err), "bank BANK2 is suspended") {
+			t.Fatalf("%s: error = %v, want bank BANK2 is suspended", name, err)
+		}
+	}
+
+	_, err = f.approveOperation(f.operator, operation.OperationID)
+	checkCode(t, err, contracterrors.InvalidState)
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 500)
+	// Compliance officers still act on the bank's accounts
+	checkErr(t, f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.FreezeAccount(ctx, "A2", "audit")
+	}), "")
+}
+
+func TestWithdrawMembershipProposal(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.addThirdBank()
+	withdraw := func(identity *chaincodetest.Identity, bankID string) (*MembershipProposal, error) {
+		var proposal *MembershipProposal
+		err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+			proposal, err = f.banks.WithdrawMembershipProposal(ctx, bankID, "settled privately")
+			return err
+		})
+		return proposal, err
+	}
+
+	_, err := f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipSuspend)
+	checkErr(t, err, "")
+	for _, identity := range []*chaincodetest.Identity{f.anyone, f.bank2Admin} {
+		_, err = withdraw(identity, "BANK2")
+		checkCode(t, err, contracterrors.Forbidden)
+	}
+	proposal, err := withdraw(f.bank1Admin, "BANK2")
+	checkErr(t, err, "")
+	if proposal.Status != ProposalWithdrawn || proposal.WithdrawalReason != "settled privately" || proposal.DecidedAt == "" {
+		t.Fatalf("unexpected proposal %+v", proposal)
+	}
+	if status := f.bank("BANK2").Status; status != BankActive {
+		t.Fatalf("bank is %s after the proposal was withdrawn", status)
+	}
+	_, err = withdraw(f.bank1Admin, "BANK2")
+	checkCode(t, err, contracterrors.NotFound)
+	_, err = f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipExpel)
+	checkErr(t, err, "")
+
+	// An operator closes an application nobody will decide
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK4", "", "Fourth Bank", "pw", "FR", "EUR", 0, 0.9, "")
+	})
+	_, err = withdraw(f.operator, "BANK4")
+	checkErr(t, err, "")
+	if bank := f.bank("BANK4"); bank.Status != BankRejected || bank.StatusReason != "settled privately" {
+		t.Fatalf("unexpected bank %+v", bank)
+	}
+}
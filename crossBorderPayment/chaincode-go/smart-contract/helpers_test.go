@@ -0,0 +1,196 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+type txFunc func(ctx contractapi.TransactionContextInterface) error
+
+// fixture is the contracts over an in-memory ledger with a few well-known
+// identities. seed adds two banks in different currencies, two verified
+// customers and an account for each. Every verified customer is enrolled with
+// a client identity of its own, found in clients.
+type fixture struct {
+	t          *testing.T
+	stub       *chaincodetest.Stub
+	banks      *BankContract
+	customers  *CustomerContract
+	accounts   *AccountContract
+	payments   *PaymentContract
+	admin      *AdminContract
+	bank1Admin *chaincodetest.Identity
+	bank2Admin *chaincodetest.Identity
+	compliance *chaincodetest.Identity
+	operator   *chaincodetest.Identity
+	checker    *chaincodetest.Identity
+	anyone     *chaincodetest.Identity
+	clients    map[string]*chaincodetest.Identity
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	return &fixture{
+		t:          t,
+		stub:       chaincodetest.NewStub(),
+		banks:      &BankContract{},
+		customers:  &CustomerContract{},
+		accounts:   &AccountContract{},
+		payments:   &PaymentContract{},
+		admin:      &AdminContract{},
+		bank1Admin: chaincodetest.NewIdentity("Org1MSP", "bank1admin", nil),
+		bank2Admin: chaincodetest.NewIdentity("Org2MSP", "bank2admin", nil),
+		compliance: chaincodetest.NewIdentity("Org1MSP", "officer", map[string]string{RoleAttribute: RoleCompliance}),
+		operator:   chaincodetest.NewIdentity("Org1MSP", "operator", map[string]string{RoleAttribute: RoleOperator}),
+		checker:    chaincodetest.NewIdentity("Org2MSP", "checker", map[string]string{RoleAttribute: RoleOperator}),
+		anyone:     chaincodetest.NewIdentity("Org2MSP", "someone", nil),
+		clients:    map[string]*chaincodetest.Identity{},
+	}
+}
+
+// submit runs fn as a transaction of identity and commits it if fn succeeds
+func (f *fixture) submit(identity *chaincodetest.Identity, fn txFunc) error {
+	f.t.Helper()
+	return f.stub.Transact(func() error {
+		return fn(chaincodetest.NewContext(f.stub, identity))
+	})
+}
+
+func (f *fixture) mustSubmit(identity *chaincodetest.Identity, fn txFunc) {
+	f.t.Helper()
+	if err := f.submit(identity, fn); err != nil {
+		f.t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// evaluate runs fn as a transaction of identity and always discards it
+func (f *fixture) evaluate(identity *chaincodetest.Identity, fn txFunc) error {
+	f.t.Helper()
+	f.stub.Begin()
+	defer f.stub.Rollback()
+	return fn(chaincodetest.NewContext(f.stub, identity))
+}
+
+func (f *fixture) seed() {
+	f.t.Helper()
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", "US", "USD", 10000, 1, "")
+	})
+	f.admitBank("BANK1")
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK2", "", "Second Bank", "pw", "DE", "EUR", 5000, 0.9, "")
+	})
+	f.admitBank("BANK2")
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C1", "Alice", "Smith")
+	f.addVerifiedCustomer("BANK2", f.bank2Admin, "C2", "Bob", "Jones")
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A1", "C1", "BANK1", 1000, "", "", "", "")
+	})
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A2", "C2", "BANK2", 500, "", "", "", "")
+	})
+}
+
+func (f *fixture) addVerifiedCustomer(bankID string, admin *chaincodetest.Identity, customerID string, name string, surname string) {
+	f.t.Helper()
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.CreateCustomer(ctx, customerID, "pw", name, surname)
+	})
+	f.mustSubmit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.AddKYCDocument(ctx, bankID, customerID, "hash-"+customerID)
+	})
+	f.mustSubmit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.VerifyCustomer(ctx, bankID, customerID, RiskLow, "2030-01-01")
+	})
+	client := chaincodetest.NewIdentity(admin.MSPID, strings.ToLower(customerID), nil)
+	f.mustSubmit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.EnrollCustomerClient(ctx, bankID, customerID, client.ID())
+	})
+	f.clients[customerID] = client
+}
+
+func (f *fixture) account(accountID string) *Account {
+	f.t.Helper()
+	var account *Account
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		account, err = f.accounts.GetAccount(ctx, accountID)
+		return err
+	})
+	if err != nil {
+		f.t.Fatalf("failed to read account %s: %v", accountID, err)
+	}
+	return account
+}
+
+func (f *fixture) bank(bankID string) *Bank {
+	f.t.Helper()
+	var bank *Bank
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		bank, err = f.banks.QueryBank(ctx, bankID)
+		return err
+	})
+	if err != nil {
+		f.t.Fatalf("failed to read bank %s: %v", bankID, err)
+	}
+	return bank
+}
+
+func (f *fixture) customer(customerID string) *Customer {
+	f.t.Helper()
+	var customer *Customer
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		customer, err = f.customers.QueryCustomer(ctx, customerID)
+		return err
+	})
+	if err != nil {
+		f.t.Fatalf("failed to read customer %s: %v", customerID, err)
+	}
+	return customer
+}
+
+func (f *fixture) pay(paymentID string, from string, to string, amount float64, rate float64) error {
+	f.t.Helper()
+	sender := f.account(from)
+	receiver := f.account(to)
+	return f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.CreatePayment(ctx, paymentID, from, to, sender.CustomerID, receiver.CustomerID, amount, rate, "2024-01-01", "", "")
+	})
+}
+
+// checkErr fails the test unless err matches wantErr: nil when wantErr is
+// empty, otherwise an error whose message contains wantErr.
+func checkErr(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	switch {
+	case wantErr == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case wantErr != "" && err == nil:
+		t.Fatalf("expected error containing %q, got nil", wantErr)
+	case wantErr != "" && !strings.Contains(contracterrors.Message(err), wantErr):
+		t.Fatalf("expected error containing %q, got %q", wantErr, contracterrors.Message(err))
+	}
+}
+
+// checkCode fails the test unless err carries code
+func checkCode(t *testing.T, err error, code contracterrors.Code) {
+	t.Helper()
+	if got := contracterrors.CodeOf(err); got != code {
+		t.Fatalf("error code = %q, want %s (error %v)", got, code, err)
+	}
+}
+
+func assertFloat(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+	const epsilon = 1e-9
+	if got-want > epsilon || want-got > epsilon {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+}
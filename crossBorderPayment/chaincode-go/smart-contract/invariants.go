@@ -0,0 +1,302 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const currencyFundingObjectType = "CurrencyFunding"
+
+// invariantTolerance is the relative difference allowed between two totals
+// before they count as unequal, to absorb float64 rounding
+const invariantTolerance = 1e-9
+
+// CurrencyFunding records the money put into the network from outside in one
+// currency: opening account balances and deposits, and bank reserves set or
+// adjusted by their banks. Payments only move money that is already there, so
+// every currency total can be derived from its funding and the settled
+// payments.
+type CurrencyFunding struct {
+	Currency string  `json:"currency"`
+	Accounts float64 `json:"accounts"`
+	Reserves float64 `json:"reserves"`
+}
+
+// CurrencyTotals is the value held in one currency, as stored in the world
+// state and as expected from funding and payments
+type CurrencyTotals struct {
+	Currency         string  `json:"currency"`
+	AccountBalances  float64 `json:"accountBalances"`
+	BankReserves     float64 `json:"bankReserves"`
+	FundedAccounts   float64 `json:"fundedAccounts"`
+	FundedReserves   float64 `json:"fundedReserves"`
+	PaymentsIn       float64 `json:"paymentsIn"`
+	PaymentsOut      float64 `json:"paymentsOut"`
+	Fees             float64 `json:"fees"`
+	ExpectedBalances float64 `json:"expectedBalances"`
+	ExpectedReserves float64 `json:"expectedReserves"`
+}
+
+// InvariantReport is the result of VerifyInvariants. Holds is true when no
+// violation was found.
+type InvariantReport struct {
+	Holds      bool              `json:"holds"`
+	Totals     []*CurrencyTotals `json:"totals"`
+	Violations []string          `json:"violations"`
+}
+
+// ledgerSnapshot is every bank, account, payment and funding record on the
+// ledger, keyed by ID and currency
+type ledgerSnapshot struct {
+	banks     map[string]*Bank
+	customers map[string]*Customer
+	accounts  map[string]*Account
+	payments  map[string]*Payment
+	funding   map[string]*CurrencyFunding
+}
+
+// VerifyInvariants checks that no money was created or destroyed. For every
+// currency the account balances and the bank reserves must equal their funding
+// plus the settled payments received minus those sent, and the account
+// balances must also be short of the fees charged. It also checks that
+// accounts, banks, customers and payments reference each other consistently.
+func (s *AdminContract) VerifyInvariants(ctx contractapi.TransactionContextInterface) (*InvariantReport, error) {
+	snapshot, err := loadLedgerSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return checkInvariants(snapshot), nil
+}
+
+// checkInvariants computes the currency totals of a snapshot and lists every
+// violation in a stable order
+func checkInvariants(snapshot *ledgerSnapshot) *InvariantReport {
+	totals := map[string]*CurrencyTotals{}
+	currencyTotals := func(currency string) *CurrencyTotals {
+		if _, ok := totals[currency]; !ok {
+			totals[currency] = &CurrencyTotals{Currency: currency}
+		}
+		return totals[currency]
+	}
+	violations := []string{}
+
+	for _, funding := range snapshot.funding {
+		currencyTotals(funding.Currency).FundedAccounts += funding.Accounts
+		currencyTotals(funding.Currency).FundedReserves += funding.Reserves
+	}
+	bankIDs := make([]string, 0, len(snapshot.banks))
+	for bankID := range snapshot.banks {
+		bankIDs = append(bankIDs, bankID)
+	}
+	sort.Strings(bankIDs)
+	for _, bankID := range bankIDs {
+		bank := snapshot.banks[bankID]
+		currencyTotals(bank.Currency).BankReserves += bank.Reserves
+		for _, accountID := range bank.AccountIDs {
+			if _, ok := snapshot.accounts[accountID]; !ok {
+				violations = append(violations, fmt.Sprintf("bank %s lists missing account %s", bankID, accountID))
+			}
+		}
+	}
+
+	accountIDs := make([]string, 0, len(snapshot.accounts))
+	for accountID := range snapshot.accounts {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Strings(accountIDs)
+	for _, accountID := range accountIDs {
+		account := snapshot.accounts[accountID]
+		currencyTotals(account.Currency).AccountBalances += account.Balance
+
+		bank, ok := snapshot.banks[account.BankID]
+		switch {
+		case !ok:
+			violations = append(violations, fmt.Sprintf("account %s belongs to missing bank %s", accountID, account.BankID))
+		case bank.Currency != account.Currency:
+			violations = append(violations, fmt.Sprintf("account %s holds %s but bank %s holds %s", accountID, account.Currency, bank.BankID, bank.Currency))
+		case !contains(bank.AccountIDs, accountID):
+			violations = append(violations, fmt.Sprintf("bank %s does not list account %s", bank.BankID, accountID))
+		}
+		for _, customerID := range accountHolders(account) {
+			customer, ok := snapshot.customers[customerID]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("account %s belongs to missing customer %s", accountID, customerID))
+			} else if !contains(customer.AccountIDs, accountID) {
+				violations = append(violations, fmt.Sprintf("customer %s does not list account %s", customer.CustomerID, accountID))
+			}
+		}
+	}
+
+	paymentIDs := make([]string, 0, len(snapshot.payments))
+	for paymentID := range snapshot.payments {
+		paymentIDs = append(paymentIDs, paymentID)
+	}
+	sort.Strings(paymentIDs)
+	for _, paymentID := range paymentIDs {
+		payment := snapshot.payments[paymentID]
+		sender, senderOK := snapshot.accounts[payment.SenderAccountID]
+		receiver, receiverOK := snapshot.accounts[payment.ReceiverAccountID]
+		if !senderOK || !receiverOK {
+			violations = append(violations, fmt.Sprintf("payment %s references a missing account", paymentID))
+			continue
+		}
+
+		listed := contains(sender.PaymentIDs, paymentID) && contains(receiver.PaymentIDs, paymentID)
+		if !paymentSettled(payment) {
+			if listed {
+				violations = append(violations, fmt.Sprintf("%s payment %s is listed on its accounts", payment.Status, paymentID))
+			}
+			continue
+		}
+		if !listed {
+			violations = append(violations, fmt.Sprintf("settled payment %s is not listed on both accounts", paymentID))
+		}
+		currencyTotals(sender.Currency).PaymentsOut += payment.Amount
+		currencyTotals(sender.Currency).Fees += payment.Fee
+		currencyTotals(receiver.Currency).PaymentsIn += payment.Amount * payment.ExchangeRate
+	}
+
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	report := &InvariantReport{Totals: []*CurrencyTotals{}}
+	for _, currency := range currencies {
+		t := totals[currency]
+		t.ExpectedBalances = t.FundedAccounts + t.PaymentsIn - t.PaymentsOut - t.Fees
+		t.ExpectedReserves = t.FundedReserves + t.PaymentsIn - t.PaymentsOut
+		if !amountsEqual(t.AccountBalances, t.ExpectedBalances) {
+			violations = append(violations, fmt.Sprintf("%s account balances total %v, expected %v", currency, t.AccountBalances, t.ExpectedBalances))
+		}
+		if !amountsEqual(t.BankReserves, t.ExpectedReserves) {
+			violations = append(violations, fmt.Sprintf("%s bank reserves total %v, expected %v", currency, t.BankReserves, t.ExpectedReserves))
+		}
+		report.Totals = append(report.Totals, t)
+	}
+
+	report.Violations = violations
+	report.Holds = len(violations) == 0
+	return report
+}
+
+// paymentSettled reports whether the payment moved money. Payments stored
+// before screening was introduced have no status and were all settled.
+func paymentSettled(payment *Payment) bool {
+	return payment.Status == "" || payment.Status == PaymentStatusSettled
+}
+
+func amountsEqual(a float64, b float64) bool {
+	return math.Abs(a-b) <= invariantTolerance*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+// loadLedgerSnapshot reads every bank, customer, account and payment, upgraded
+// to the current schema version, and every funding record
+func loadLedgerSnapshot(ctx contractapi.TransactionContextInterface) (*ledgerSnapshot, error) {
+	snapshot := &ledgerSnapshot{
+		banks:     map[string]*Bank{},
+		customers: map[string]*Customer{},
+		accounts:  map[string]*Account{},
+		payments:  map[string]*Payment{},
+		funding:   map[string]*CurrencyFunding{},
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		key := queryResponse.Key
+		switch storedObjectType(queryResponse.Value) {
+		case bankObjectType:
+			bank := &Bank{}
+			snapshot.banks[key] = bank
+			err = unmarshalBank(queryResponse.Value, bank)
+		case paymentObjectType:
+			payment := &Payment{}
+			snapshot.payments[key] = payment
+			err = unmarshalPayment(queryResponse.Value, payment)
+		case accountObjectType:
+			account := &Account{}
+			snapshot.accounts[key] = account
+			err = unmarshalAccount(queryResponse.Value, account)
+		case customerObjectType:
+			customer := &Customer{}
+			snapshot.customers[key] = customer
+			err = unmarshalCustomer(queryResponse.Value, customer)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %v", key, err)
+		}
+	}
+
+	fundingIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(currencyFundingObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read currency funding: %v", err)
+	}
+	defer fundingIterator.Close()
+
+	for fundingIterator.HasNext() {
+		queryResponse, err := fundingIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var funding CurrencyFunding
+		err = json.Unmarshal(queryResponse.Value, &funding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal currency funding: %v", err)
+		}
+		snapshot.funding[funding.Currency] = &funding
+	}
+
+	return snapshot, nil
+}
+
+// recordFunding adds money put into accounts or bank reserves from outside the
+// network to the funding record of currency. Call it at most once per currency
+// in a transaction, as a second call would not see the first one's write.
+func recordFunding(ctx contractapi.TransactionContextInterface, currency string, accounts float64, reserves float64) error {
+	if accounts == 0 && reserves == 0 {
+		return nil
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(currencyFundingObjectType, []string{currency})
+	if err != nil {
+		return fmt.Errorf("failed to create currency funding key: %v", err)
+	}
+	fundingJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read currency funding: %v", err)
+	}
+
+	funding := CurrencyFunding{Currency: currency}
+	if fundingJSON != nil {
+		err = json.Unmarshal(fundingJSON, &funding)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal currency funding: %v", err)
+		}
+	}
+	funding.Accounts += accounts
+	funding.Reserves += reserves
+
+	fundingJSON, err = json.Marshal(funding)
+	if err != nil {
+		return fmt.Errorf("failed to marshal currency funding: %v", err)
+	}
+	return ctx.GetStub().PutState(key, fundingJSON)
+}
@@ -0,0 +1,308 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+func (f *fixture) invariants() *InvariantReport {
+	f.t.Helper()
+	var report *InvariantReport
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		report, err = f.admin.VerifyInvariants(ctx)
+		return err
+	})
+	if err != nil {
+		f.t.Fatalf("VerifyInvariants: %v", err)
+	}
+	return report
+}
+
+func TestVerifyInvariants(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+	checkErr(t, f.updateBalance("A1", 50), "")
+
+	report := f.invariants()
+	if !report.Holds || len(report.Totals) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	eur, usd := report.Totals[0], report.Totals[1]
+	assertFloat(t, "EUR balances", eur.AccountBalances, 590)
+	assertFloat(t, "EUR payments in", eur.PaymentsIn, 90)
+	assertFloat(t, "USD funded accounts", usd.FundedAccounts, 1050)
+	assertFloat(t, "USD payments out", usd.PaymentsOut, 100)
+	assertFloat(t, "USD reserves", usd.BankReserves, 9900)
+}
+
+func TestVerifyInvariantsReportsViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(f *fixture)
+		want    string
+	}{
+		{name: "balance changed outside the contract", corrupt: func(f *fixture) {
+			account := f.account("A1")
+			account.Balance += 1
+			f.stub.Seed("A1", mustJSON(t, account))
+		}, want: "USD account balances total 1001, expected 1000"},
+		{name: "reserves changed outside the contract", corrupt: func(f *fixture) {
+			bank := f.bank("BANK2")
+			bank.Reserves = 0
+			f.stub.Seed("BANK2", mustJSON(t, bank))
+		}, want: "EUR bank reserves total 0, expected 5000"},
+		{name: "account missing from its bank", corrupt: func(f *fixture) {
+			bank := f.bank("BANK1")
+			bank.AccountIDs = []string{}
+			f.stub.Seed("BANK1", mustJSON(t, bank))
+		}, want: "bank BANK1 does not list account A1"},
+		{name: "settled payment missing from account", corrupt: func(f *fixture) {
+			checkErr(t, f.pay("P1", "A1", "A2", 10, 1), "")
+			account := f.account("A2")
+			account.PaymentIDs = []string{}
+			f.stub.Seed("A2", mustJSON(t, account))
+		}, want: "settled payment P1 is not listed on both accounts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			tt.corrupt(f)
+
+			report := f.invariants()
+			if report.Holds || len(report.Violations) != 1 || !strings.Contains(report.Violations[0], tt.want) {
+				t.Fatalf("violations = %q, want one containing %q", report.Violations, tt.want)
+			}
+		})
+	}
+}
+
+// TestConservationProperty runs random sequences of payments, batches, deposits,
+// reserve changes, freezes, reviews and sweeps, and checks after every step that
+// the invariants hold and that each currency's balances match a model kept by
+// the test.
+func TestConservationProperty(t *testing.T) {
+	property := func(seed int64) bool {
+		f := newFixture(t)
+		f.seed()
+		f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+			return f.banks.CreateBank(ctx, "BANK3", "", "Third Bank", "pw", "GB", "GBP", 2000, 0.8, "")
+		})
+		f.admitBank("BANK3")
+		f.addVerifiedCustomer("BANK2", f.bank2Admin, "C3", "Eve", "Black")
+		for _, account := range []struct{ id, customer, bank string }{{"A3", "C1", "BANK1"}, {"A4", "C2", "BANK3"}, {"A5", "C3", "BANK2"}} {
+			f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CreateAccount(ctx, account.id, account.customer, account.bank, 100, "", "", "", "")
+			})
+		}
+		f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+			return f.admin.AddWatchlistEntry(ctx, WatchlistName, "Eve Black", "test")
+		})
+
+		model := newConservationModel(f)
+		r := rand.New(rand.NewSource(seed))
+		for step := 0; step < 60; step++ {
+			description := model.randomStep(r, step)
+			report := f.invariants()
+			if !report.Holds {
+				t.Logf("seed %d, step %d (%s): %q", seed, step, description, report.Violations)
+				return false
+			}
+			for _, totals := range report.Totals {
+				if !amountsEqual(totals.AccountBalances, model.balances[totals.Currency]) {
+					t.Logf("seed %d, step %d (%s): %s balances %v, model %v", seed, step, description, totals.Currency, totals.AccountBalances, model.balances[totals.Currency])
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 25}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// conservationModel tracks the expected total balance of each currency
+type conservationModel struct {
+	f          *fixture
+	accountIDs []string
+	currencies map[string]string
+	balances   map[string]float64
+	held       []*Payment
+	closed     map[string]bool
+}
+
+func newConservationModel(f *fixture) *conservationModel {
+	m := &conservationModel{
+		f:          f,
+		accountIDs: []string{"A1", "A2", "A3", "A4", "A5"},
+		currencies: map[string]string{},
+		balances:   map[string]float64{},
+		closed:     map[string]bool{},
+	}
+	for _, accountID := range m.accountIDs {
+		account := f.account(accountID)
+		m.currencies[accountID] = account.Currency
+		m.balances[account.Currency] += account.Balance
+	}
+	return m
+}
+
+func (m *conservationModel) settle(senderAccountID string, receiverAccountID string, amount float64, rate float64) {
+	m.balances[m.currencies[senderAccountID]] -= amount
+	m.balances[m.currencies[receiverAccountID]] += amount * rate
+}
+
+func (m *conservationModel) randomAccount(r *rand.Rand) string {
+	return m.accountIDs[r.Intn(len(m.accountIDs))]
+}
+
+func (m *conservationModel) randomInstruction(r *rand.Rand, paymentID string) PaymentInstruction {
+	sender := m.f.account(m.randomAccount(r))
+	receiver := m.f.account(m.randomAccount(r))
+	return PaymentInstruction{
+		PaymentID:          paymentID,
+		SenderAccountID:    sender.AccountID,
+		ReceiverAccountID:  receiver.AccountID,
+		SenderCustomerID:   sender.CustomerID,
+		ReceiverCustomerID: receiver.CustomerID,
+		Amount:             float64(r.Intn(30000)+1) / 100,
+		ExchangeRate:       0.5 + r.Float64(),
+	}
+}
+
+// randomStep submits one random operation, updates the model for whatever the
+// ledger accepted, and describes the operation
+func (m *conservationModel) randomStep(r *rand.Rand, step int) string {
+	f := m.f
+	paymentID := fmt.Sprintf("P%d", step)
+
+	switch r.Intn(8) {
+	case 0, 1:
+		line := m.randomInstruction(r, paymentID)
+		err := f.pay(paymentID, line.SenderAccountID, line.ReceiverAccountID, line.Amount, line.ExchangeRate)
+		if err == nil {
+			payment, _ := getCommittedPayment(f, paymentID)
+			if payment.Status == PaymentStatusHeld {
+				m.held = append(m.held, payment)
+			} else {
+				m.settle(line.SenderAccountID, line.ReceiverAccountID, line.Amount, line.ExchangeRate)
+			}
+		}
+		return fmt.Sprintf("payment %+v: %v", line, err)
+
+	case 2:
+		lines := []PaymentInstruction{}
+		for i := 0; i < 1+r.Intn(3); i++ {
+			lines = append(lines, m.randomInstruction(r, fmt.Sprintf("%s-%d", paymentID, i)))
+		}
+		mode := BatchModeAtomic
+		if r.Intn(2) == 0 {
+			mode = BatchModeBestEffort
+		}
+		var batch *PaymentBatch
+		err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+			batch, err = f.payments.CreatePaymentBatch(ctx, "B"+paymentID, batchJSON(f.t, lines...), mode)
+			return err
+		})
+		if err == nil {
+			for i, result := range batch.Results {
+				switch result.Status {
+				case BatchLineSettled:
+					m.settle(lines[i].SenderAccountID, lines[i].ReceiverAccountID, lines[i].Amount, lines[i].ExchangeRate)
+				case BatchLineHeld:
+					payment, _ := getCommittedPayment(f, result.PaymentID)
+					m.held = append(m.held, payment)
+				}
+			}
+		}
+		return fmt.Sprintf("%s batch of %d: %v", mode, len(lines), err)
+
+	case 3:
+		accountID := m.randomAccount(r)
+		amount := float64(r.Intn(20000)-5000) / 100
+		err := f.updateBalance(accountID, amount)
+		if err == nil {
+			m.balances[m.currencies[accountID]] += amount
+		}
+		return fmt.Sprintf("deposit %v to %s: %v", amount, accountID, err)
+
+	case 4:
+		bankID := []string{"BANK1", "BANK2", "BANK3"}[r.Intn(3)]
+		amount := float64(r.Intn(100000)-50000) / 100
+		bank := f.bank(bankID)
+		err := f.updateBankProfile(bankID, bank.Name, bank.Reserves+amount, bank.Country)
+		return fmt.Sprintf("set %s reserves to %v: %v", bankID, bank.Reserves+amount, err)
+
+	case 5:
+		accountID := m.randomAccount(r)
+		frozen := accountStatus(f.account(accountID)) == AccountFrozen
+		err := f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+			if frozen {
+				return f.accounts.UnfreezeAccount(ctx, accountID)
+			}
+			return f.accounts.FreezeAccount(ctx, accountID, "test")
+		})
+		return fmt.Sprintf("toggle freeze of %s: %v", accountID, err)
+
+	case 6:
+		if len(m.held) == 0 {
+			return "no held payment to review"
+		}
+		payment := m.held[0]
+		m.held = m.held[1:]
+		if r.Intn(2) == 0 {
+			err := f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.RejectHeldPayment(ctx, payment.PaymentID)
+			})
+			return fmt.Sprintf("reject %s: %v", payment.PaymentID, err)
+		}
+		err := f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+			return f.payments.ReleaseHeldPayment(ctx, payment.PaymentID)
+		})
+		if err == nil {
+			m.settle(payment.SenderAccountID, payment.ReceiverAccountID, payment.Amount, payment.ExchangeRate)
+		}
+		return fmt.Sprintf("release %s: %v", payment.PaymentID, err)
+
+	default:
+		// A1 and A3 belong to the same customer and currency, so either can
+		// be swept into the other. The swept amount stays in USD.
+		from, to := "A1", "A3"
+		if r.Intn(2) == 0 {
+			from, to = to, from
+		}
+		if m.closed[from] || m.closed[to] {
+			return "sweep account already closed"
+		}
+		err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+			return f.accounts.CloseAccount(ctx, from, to)
+		})
+		if err == nil {
+			m.closed[from] = true
+		}
+		return fmt.Sprintf("close %s into %s: %v", from, to, err)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal %T: %v", v, err)
+	}
+	return data
+}
@@ -0,0 +1,371 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const paymentApprovalObjectType = "PaymentApproval"
+
+// Signing rules of accounts with several holders. An account without a rule
+// lets any one holder sign.
+const (
+	SigningAnyOne = "any_one"
+	SigningAll    = "all"
+	SigningNOfM   = "n_of_m"
+)
+
+// AddAccountHolder makes a verified customer a holder of an account, which
+// then appears among the customer's accounts. Only the administrator of the
+// account's bank may change its holders.
+func (s *AccountContract) AddAccountHolder(ctx contractapi.TransactionContextInterface, accountID string, customerID string) error {
+	err := checkArgs(validation.ID("customerID", customerID))
+	if err != nil {
+		return err
+	}
+	account, err := getAccountForAdmin(ctx, accountID, false)
+	if err != nil {
+		return err
+	}
+	err = checkAccountCanTransact(account)
+	if err != nil {
+		return err
+	}
+	holders := accountHolders(account)
+	if contains(holders, customerID) {
+		return contracterrors.New(contracterrors.AlreadyExists, "customer %s already holds account %s", customerID, accountID)
+	}
+
+	customer, err := getCustomer(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	err = requireVerifiedCustomer(ctx, customer)
+	if err != nil {
+		return err
+	}
+	customer.AccountIDs = append(customer.AccountIDs, accountID)
+	err = putCustomer(ctx, customer)
+	if err != nil {
+		return err
+	}
+
+	account.Holders = append(holders, customerID)
+	return putAccount(ctx, account)
+}
+
+// RemoveAccountHolder takes a customer off an account. The primary holder
+// named by the account's CustomerID stays, and an n-of-m rule must be lowered
+// before the account has fewer holders than signatures it requires.
+func (s *AccountContract) RemoveAccountHolder(ctx contractapi.TransactionContextInterface, accountID string, customerID string) error {
+	err := checkArgs(validation.ID("customerID", customerID))
+	if err != nil {
+		return err
+	}
+	account, err := getAccountForAdmin(ctx, accountID, false)
+	if err != nil {
+		return err
+	}
+	if customerID == account.CustomerID {
+		return contracterrors.New(contracterrors.Validation, "customer %s is the primary holder of account %s", customerID, accountID)
+	}
+	holders := accountHolders(account)
+	if !contains(holders, customerID) {
+		return contracterrors.New(contracterrors.NotFound, "customer %s does not hold account %s", customerID, accountID)
+	}
+	remaining := removeString(holders, customerID)
+	if account.SigningRule == SigningNOfM && account.RequiredSignatures > len(remaining) {
+		return contracterrors.New(contracterrors.Validation, "account %s requires %d signatures, lower its signing rule before removing a holder", accountID, account.RequiredSignatures)
+	}
+
+	customer, err := getCustomer(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	customer.AccountIDs = removeString(customer.AccountIDs, accountID)
+	err = putCustomer(ctx, customer)
+	if err != nil {
+		return err
+	}
+
+	account.Holders = remaining
+	return putAccount(ctx, account)
+}
+
+// SetSigningRule sets how many holders must sign the account's payments:
+// SigningAnyOne, SigningAll, or SigningNOfM with requiredSignatures of them.
+// The other rules ignore requiredSignatures. Payments already waiting for
+// approval are settled under the new rule at their next signature.
+func (s *AccountContract) SetSigningRule(ctx contractapi.TransactionContextInterface, accountID string, rule string, requiredSignatures int) error {
+	err := checkArgs(validation.OneOf("signingRule", rule, SigningAnyOne, SigningAll, SigningNOfM))
+	if err != nil {
+		return err
+	}
+	account, err := getAccountForAdmin(ctx, accountID, false)
+	if err != nil {
+		return err
+	}
+	if rule == SigningNOfM {
+		err = checkArgs(validation.IntRange("requiredSignatures", requiredSignatures, 1, len(accountHolders(account))))
+		if err != nil {
+			return err
+		}
+	} else {
+		requiredSignatures = 0
+	}
+
+	account.SigningRule = rule
+	account.RequiredSignatures = requiredSignatures
+	return putAccount(ctx, account)
+}
+
+// ApprovePayment adds a holder's signature to a payment waiting for approval
+// and returns the payment. Only the client enrolled for the holder can sign
+// for them. Once it has as many signatures as the sender account's signing
+// rule requires, it is checked again and settles, or is held by screening.
+func (s *PaymentContract) ApprovePayment(ctx contractapi.TransactionContextInterface, paymentID string, customerID string) (*Payment, error) {
+	payment, account, err := getPaymentAwaitingApproval(ctx, paymentID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := getCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireCustomerClient(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	if contains(payment.Approvals, customerID) {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "customer %s already approved payment %s", customerID, paymentID)
+	}
+	err = requireVerifiedCustomer(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.Approvals = append(payment.Approvals, customerID)
+	payment.RequiredApprovals = signaturesRequired(account)
+	if len(payment.Approvals) < payment.RequiredApprovals {
+		return payment, putPayment(ctx, payment)
+	}
+
+	ledger := newPaymentLedger(ctx)
+	err = ledger.checkAccountStatus(payment)
+	if err != nil {
+		return nil, err
+	}
+	err = ledger.checkKYC(payment)
+	if err != nil {
+		return nil, err
+	}
+	err = settlePayment(ctx, ledger, payment)
+	if err != nil {
+		return nil, err
+	}
+	err = ledger.flush()
+	if err != nil {
+		return nil, err
+	}
+	err = finishPaymentRequest(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, deletePaymentApprovalIndex(ctx, payment)
+}
+
+// DeclinePayment lets a holder refuse a payment waiting for approval, through
+// the client enrolled for them. The payment is closed as rejected without
+// moving any funds.
+func (s *PaymentContract) DeclinePayment(ctx contractapi.TransactionContextInterface, paymentID string, customerID string) error {
+	payment, _, err := getPaymentAwaitingApproval(ctx, paymentID, customerID)
+	if err != nil {
+		return err
+	}
+	customer, err := getCustomer(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	err = requireCustomerClient(ctx, customer)
+	if err != nil {
+		return err
+	}
+
+	payment.Status = PaymentStatusRejected
+	err = putPayment(ctx, payment)
+	if err != nil {
+		return err
+	}
+	err = finishPaymentRequest(ctx, payment)
+	if err != nil {
+		return err
+	}
+	return deletePaymentApprovalIndex(ctx, payment)
+}
+
+// QueryPaymentsAwaitingApproval returns the payments from an account that
+// still need signatures from its holders
+func (s *PaymentContract) QueryPaymentsAwaitingApproval(ctx contractapi.TransactionContextInterface, accountID string) ([]*Payment, error) {
+	err := checkArgs(validation.ID("accountID", accountID))
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(paymentApprovalObjectType, []string{accountID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payments awaiting approval: %v", err)
+	}
+	defer iterator.Close()
+
+	payments := []*Payment{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over payments awaiting approval: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+
+		payment, err := getPayment(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// requiredSignatures returns how many holders of the sender account must sign
+// the payment
+func (l *paymentLedger) requiredSignatures(payment *Payment) (int, error) {
+	account, err := l.account(payment.SenderAccountID)
+	if err != nil {
+		return 0, err
+	}
+	return signaturesRequired(account), nil
+}
+
+// awaitApproval queues the payment without moving any funds. It counts as
+// signed by the customer who sent it only if it was submitted by the client
+// enrolled for them; otherwise they sign with ApprovePayment like the others.
+func (l *paymentLedger) awaitApproval(payment *Payment, signatures int) error {
+	sender, err := getCustomer(l.ctx, payment.SenderCustomerID)
+	if err != nil {
+		return err
+	}
+	payment.Approvals = []string{}
+	err = requireCustomerClient(l.ctx, sender)
+	if err == nil {
+		payment.Approvals = append(payment.Approvals, payment.SenderCustomerID)
+	} else if contracterrors.CodeOf(err) != contracterrors.Forbidden {
+		return err
+	}
+
+	payment.Status = PaymentStatusPendingApproval
+	payment.RequiredApprovals = signatures
+	l.payments = append(l.payments, payment)
+	return nil
+}
+
+// accountHolders returns every holder of an account, the primary one first
+func accountHolders(account *Account) []string {
+	if len(account.Holders) == 0 {
+		return []string{account.CustomerID}
+	}
+	return account.Holders
+}
+
+func isAccountHolder(account *Account, customerID string) bool {
+	return contains(accountHolders(account), customerID)
+}
+
+// signaturesRequired applies the account's signing rule to its holders
+func signaturesRequired(account *Account) int {
+	switch account.SigningRule {
+	case SigningAll:
+		return len(accountHolders(account))
+	case SigningNOfM:
+		return account.RequiredSignatures
+	default:
+		return 1
+	}
+}
+
+// getPaymentAwaitingApproval loads a payment waiting for approval and its
+// sender account, after checking that customerID holds the account
+func getPaymentAwaitingApproval(ctx contractapi.TransactionContextInterface, paymentID string, customerID string) (*Payment, *Account, error) {
+	err := checkArgs(
+		validation.ID("paymentID", paymentID),
+		validation.ID("customerID", customerID),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment, err := getPayment(ctx, paymentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payment.Status != PaymentStatusPendingApproval {
+		return nil, nil, contracterrors.New(contracterrors.InvalidState, "payment %s is not waiting for approval", paymentID)
+	}
+	account, err := getAccount(ctx, payment.SenderAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isAccountHolder(account, customerID) {
+		return nil, nil, contracterrors.New(contracterrors.Forbidden, "customer %s does not hold account %s", customerID, account.AccountID)
+	}
+	return payment, account, nil
+}
+
+func putPayment(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment JSON: %v", err)
+	}
+	err = ctx.GetStub().PutState(payment.PaymentID, paymentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put payment state: %v", err)
+	}
+	return nil
+}
+
+func paymentApprovalKey(ctx contractapi.TransactionContextInterface, accountID string, paymentID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(paymentApprovalObjectType, []string{accountID, paymentID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create payment approval key: %v", err)
+	}
+	return key, nil
+}
+
+func deletePaymentApprovalIndex(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+	key, err := paymentApprovalKey(ctx, payment.SenderAccountID, payment.PaymentID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// removeString returns list without value
+func removeString(list []string, value string) []string {
+	result := []string{}
+	for _, item := range list {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
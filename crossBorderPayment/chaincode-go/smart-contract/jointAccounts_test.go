@@ -0,0 +1,301 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// jointAccount makes C3 and C4, customers of BANK1, joint holders of A1 with
+// C1 under rule
+func (f *fixture) jointAccount(rule string, requiredSignatures int) {
+	f.t.Helper()
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C3", "Carol", "White")
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C4", "Dan", "Brown")
+	for _, customerID := range []string{"C3", "C4"} {
+		customerID := customerID
+		f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+			return f.accounts.AddAccountHolder(ctx, "A1", customerID)
+		})
+	}
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.SetSigningRule(ctx, "A1", rule, requiredSignatures)
+	})
+}
+
+func (f *fixture) payFromJointAccount(paymentID string, customerID string, amount float64) error {
+	f.t.Helper()
+	return f.submit(f.clients[customerID], func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.CreatePayment(ctx, paymentID, "A1", "A2", customerID, "C2", amount, 0.9, "2024-01-01", "", "")
+	})
+}
+
+func (f *fixture) approvePayment(paymentID string, customerID string) (*Payment, error) {
+	f.t.Helper()
+	return f.approvePaymentAs(f.clients[customerID], paymentID, customerID)
+}
+
+func (f *fixture) approvePaymentAs(identity *chaincodetest.Identity, paymentID string, customerID string) (*Payment, error) {
+	f.t.Helper()
+	var payment *Payment
+	err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+		payment, err = f.payments.ApprovePayment(ctx, paymentID, customerID)
+		return err
+	})
+	return payment, err
+}
+
+func (f *fixture) paymentsAwaitingApproval(accountID string) []*Payment {
+	f.t.Helper()
+	var payments []*Payment
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		payments, err = f.payments.QueryPaymentsAwaitingApproval(ctx, accountID)
+		return err
+	})
+	checkErr(f.t, err, "")
+	return payments
+}
+
+func TestAccountHolders(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.addVerifiedCustomer("BANK1", f.bank1Admin, "C3", "Carol", "White")
+
+	err := f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.AddAccountHolder(ctx, "A1", "C3")
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.AddAccountHolder(ctx, "A1", "C3")
+	})
+	err = f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.AddAccountHolder(ctx, "A1", "C3")
+	})
+	checkCode(t, err, contracterrors.AlreadyExists)
+
+	if holders := f.account("A1").Holders; !reflect.DeepEqual(holders, []string{"C1", "C3"}) {
+		t.Fatalf("holders = %v", holders)
+	}
+	var accounts []*Account
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		accounts, err = f.customers.QueryCustomerAccounts(ctx, "C3")
+		return err
+	})
+	checkErr(t, err, "")
+	if len(accounts) != 1 || accounts[0].AccountID != "A1" {
+		t.Fatalf("unexpected accounts of C3 %+v", accounts)
+	}
+	var customers []*Customer
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		customers, err = f.banks.QueryCustomersByBank(ctx, "BANK1")
+		return err
+	})
+	checkErr(t, err, "")
+	if len(customers) != 2 || customers[1].CustomerID != "C3" {
+		t.Fatalf("unexpected customers of BANK1 %+v", customers)
+	}
+
+	// The name of any holder passes the beneficiary name check, and payments
+	// to the beneficiary are made out to that holder
+	var beneficiary *Beneficiary
+	f.mustSubmit(f.clients["C2"], func(ctx contractapi.TransactionContextInterface) (err error) {
+		beneficiary, err = f.customers.AddBeneficiary(ctx, "C2", "B1", "Carol", "A1", "Carol White", 0, 0)
+		return err
+	})
+	if beneficiary.ReceiverCustomerID != "C3" {
+		t.Fatalf("beneficiary is made out to %s, want the holder named, C3", beneficiary.ReceiverCustomerID)
+	}
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.CreatePayment(ctx, "PB", "A2", "", "C2", "C3", 10, 1/0.9, "2024-01-01", "B1", "")
+	})
+	checkErr(t, err, "")
+
+	// Without a signing rule any holder can pay on their own
+	checkErr(t, f.payFromJointAccount("P1", "C3", 100), "")
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 900)
+
+	err = f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.RemoveAccountHolder(ctx, "A1", "C1")
+	})
+	checkErr(t, err, "primary holder")
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.SetSigningRule(ctx, "A1", SigningNOfM, 2)
+	})
+	err = f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.RemoveAccountHolder(ctx, "A1", "C3")
+	})
+	checkErr(t, err, "lower its signing rule")
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.SetSigningRule(ctx, "A1", SigningAnyOne, 0)
+	})
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.RemoveAccountHolder(ctx, "A1", "C3")
+	})
+	if ids := f.customer("C3").AccountIDs; len(ids) != 0 {
+		t.Fatalf("C3 still lists accounts %v", ids)
+	}
+	checkErr(t, f.payFromJointAccount("P2", "C3", 100), "account A1 does not belong to customer C3")
+
+	if report := f.invariants(); !report.Holds {
+		t.Fatalf("invariants do not hold: %v", report.Violations)
+	}
+}
+
+func TestSetSigningRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		required int
+		wantErr  string
+	}{
+		{name: "all", rule: SigningAll},
+		{name: "two of three", rule: SigningNOfM, required: 2},
+		{name: "more than the holders", rule: SigningNOfM, required: 4, wantErr: "requiredSignatures"},
+		{name: "none", rule: SigningNOfM, required: 0, wantErr: "requiredSignatures"},
+		{name: "unknown rule", rule: "majority", wantErr: "signingRule"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.jointAccount(SigningAnyOne, 0)
+
+			err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.SetSigningRule(ctx, "A1", tt.rule, tt.required)
+			})
+			if tt.wantErr != "" {
+				checkCode(t, err, contracterrors.Validation)
+				checkErr(t, err, tt.wantErr)
+				return
+			}
+			checkErr(t, err, "")
+			if account := f.account("A1"); account.SigningRule != tt.rule || account.RequiredSignatures != tt.required {
+				t.Fatalf("unexpected account %+v", account)
+			}
+		})
+	}
+}
+
+func TestJointAccountApprovals(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.jointAccount(SigningNOfM, 2)
+
+	checkErr(t, f.payFromJointAccount("P1", "C1", 100), "")
+	payment, err := getCommittedPayment(f, "P1")
+	checkErr(t, err, "")
+	if payment.Status != PaymentStatusPendingApproval || payment.RequiredApprovals != 2 || !reflect.DeepEqual(payment.Approvals, []string{"C1"}) {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+	if awaiting := f.paymentsAwaitingApproval("A1"); len(awaiting) != 1 || awaiting[0].PaymentID != "P1" {
+		t.Fatalf("unexpected payments awaiting approval %+v", awaiting)
+	}
+
+	_, err = f.approvePayment("P1", "C1")
+	checkCode(t, err, contracterrors.AlreadyExists)
+	_, err = f.approvePayment("P1", "C2")
+	checkCode(t, err, contracterrors.Forbidden)
+
+	payment, err = f.approvePayment("P1", "C3")
+	checkErr(t, err, "")
+	if payment.Status != PaymentStatusSettled {
+		t.Fatalf("payment is %s after the second signature", payment.Status)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 900)
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+	if awaiting := f.paymentsAwaitingApproval("A1"); len(awaiting) != 0 {
+		t.Fatalf("unexpected payments awaiting approval %+v", awaiting)
+	}
+	_, err = f.approvePayment("P1", "C4")
+	checkCode(t, err, contracterrors.InvalidState)
+
+	// Every holder must sign, and one refusal closes the payment
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.SetSigningRule(ctx, "A1", SigningAll, 0)
+	})
+	checkErr(t, f.payFromJointAccount("P2", "C3", 50), "")
+	payment, err = f.approvePayment("P2", "C1")
+	checkErr(t, err, "")
+	if payment.Status != PaymentStatusPendingApproval || payment.RequiredApprovals != 3 {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+	err = f.submit(f.clients["C3"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.DeclinePayment(ctx, "P2", "C4")
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	f.mustSubmit(f.clients["C4"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.DeclinePayment(ctx, "P2", "C4")
+	})
+	payment, err = getCommittedPayment(f, "P2")
+	checkErr(t, err, "")
+	if payment.Status != PaymentStatusRejected {
+		t.Fatalf("payment is %s after it was declined", payment.Status)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 900)
+
+	instructions := batchJSON(t, PaymentInstruction{PaymentID: "P3", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 10, ExchangeRate: 0.9})
+	var batch *PaymentBatch
+	err = f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		batch, err = f.payments.CreatePaymentBatch(ctx, "B1", instructions, BatchModeAtomic)
+		return err
+	})
+	checkErr(t, err, "")
+	if batch.Status != BatchStatusRejected || batch.Results[0].Error != "account A1 requires 3 signatures, send its payments with CreatePayment" {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+
+	if report := f.invariants(); !report.Holds {
+		t.Fatalf("invariants do not hold: %v", report.Violations)
+	}
+}
+
+func TestApprovalsNeedTheHoldersClient(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.jointAccount(SigningAll, 0)
+
+	// Sent by a client that is not C1's, the payment starts without C1's
+	// signature
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.CreatePayment(ctx, "P1", "A1", "A2", "C1", "C2", 100, 0.9, "2024-01-01", "", "")
+	})
+	checkErr(t, err, "")
+	payment, err := getCommittedPayment(f, "P1")
+	checkErr(t, err, "")
+	if len(payment.Approvals) != 0 {
+		t.Fatalf("payment sent by another client has approvals %v", payment.Approvals)
+	}
+
+	// One client cannot sign for several holders
+	_, err = f.approvePaymentAs(f.clients["C3"], "P1", "C3")
+	checkErr(t, err, "")
+	for _, customerID := range []string{"C1", "C4"} {
+		_, err = f.approvePaymentAs(f.clients["C3"], "P1", customerID)
+		checkCode(t, err, contracterrors.Forbidden)
+		checkErr(t, err, "client is not the enrolled identity of customer "+customerID)
+	}
+	_, err = f.approvePaymentAs(f.anyone, "P1", "C1")
+	checkCode(t, err, contracterrors.Forbidden)
+
+	payment, err = getCommittedPayment(f, "P1")
+	checkErr(t, err, "")
+	if payment.Status != PaymentStatusPendingApproval || !reflect.DeepEqual(payment.Approvals, []string{"C3"}) {
+		t.Fatalf("unexpected payment %+v", payment)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+
+	for _, customerID := range []string{"C1", "C4"} {
+		_, err = f.approvePayment("P1", customerID)
+		checkErr(t, err, "")
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 900)
+}
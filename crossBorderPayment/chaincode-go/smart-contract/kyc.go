@@ -0,0 +1,291 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// KYC statuses
+const (
+	KYCPending  = "pending"
+	KYCVerified = "verified"
+	KYCRejected = "rejected"
+	KYCExpired  = "expired"
+)
+
+// Risk ratings
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// kycDateLayout is the layout of KYC expiry dates
+const kycDateLayout = "2006-01-02"
+
+// customerClientObjectType indexes customers by their enrolled client
+// identity, so that one identity acts as one customer only
+const customerClientObjectType = "CustomerClient"
+
+// AddKYCDocument records the hash of an identity document a bank has collected
+// for the customer. The customer stays pending until the bank verifies them.
+func (s *CustomerContract) AddKYCDocument(ctx contractapi.TransactionContextInterface, bankID string, customerID string, documentHash string) error {
+	err := checkArgs(validation.Required("documentHash", documentHash))
+	if err != nil {
+		return err
+	}
+
+	customer, err := getCustomerForKYC(ctx, bankID, customerID)
+	if err != nil {
+		return err
+	}
+	if contains(customer.DocumentHashes, documentHash) {
+		return contracterrors.New(contracterrors.AlreadyExists, "document %s is already recorded for customer %s", documentHash, customerID)
+	}
+
+	customer.DocumentHashes = append(customer.DocumentHashes, documentHash)
+	return putCustomer(ctx, customer)
+}
+
+// VerifyCustomer marks the customer as verified by bankID until validUntil
+// (YYYY-MM-DD) with the given risk rating.
+func (s *CustomerContract) VerifyCustomer(ctx contractapi.TransactionContextInterface, bankID string, customerID string, riskRating string, validUntil string) error {
+	err := checkArgs(validation.OneOf("riskRating", riskRating, RiskLow, RiskMedium, RiskHigh))
+	if err != nil {
+		return err
+	}
+	if _, err := time.Parse(kycDateLayout, validUntil); err != nil {
+		return contracterrors.New(contracterrors.Validation, "invalid KYC expiry date %q, expected YYYY-MM-DD", validUntil)
+	}
+
+	customer, err := getCustomerForKYC(ctx, bankID, customerID)
+	if err != nil {
+		return err
+	}
+	if len(customer.DocumentHashes) == 0 {
+		return contracterrors.New(contracterrors.InvalidState, "customer %s has no KYC documents", customerID)
+	}
+
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	if validUntil <= date[:len(kycDateLayout)] {
+		return contracterrors.New(contracterrors.Validation, "KYC expiry date %s is not in the future", validUntil)
+	}
+
+	customer.KYCStatus = KYCVerified
+	customer.VerificationDate = date
+	customer.VerifyingBankID = bankID
+	customer.KYCExpiryDate = validUntil
+	customer.RiskRating = riskRating
+	return putCustomer(ctx, customer)
+}
+
+// EnrollCustomerClient records clientID as the client identity that acts as
+// the customer. Only the bank that verified the customer may enroll them, an
+// identity can be enrolled for one customer only, and only the bank that
+// enrolled the current identity may replace it.
+func (s *CustomerContract) EnrollCustomerClient(ctx contractapi.TransactionContextInterface, bankID string, customerID string, clientID string) error {
+	err := checkArgs(validation.Required("clientID", clientID))
+	if err != nil {
+		return err
+	}
+
+	customer, err := getCustomerForKYC(ctx, bankID, customerID)
+	if err != nil {
+		return err
+	}
+	if customer.KYCStatus != KYCVerified || customer.VerifyingBankID != bankID {
+		return contracterrors.New(contracterrors.InvalidState, "customer %s is not verified by bank %s", customerID, bankID)
+	}
+
+	key, err := customerClientKey(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	enrolled, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read customer client index: %v", err)
+	}
+	if enrolled != nil && string(enrolled) != customerID {
+		return contracterrors.New(contracterrors.AlreadyExists, "client %s is already enrolled for another customer", clientID)
+	}
+	if customer.ClientID != "" && customer.ClientID != clientID {
+		if customer.ClientBankID != bankID {
+			return contracterrors.New(contracterrors.Forbidden, "the client of customer %s was enrolled by bank %s", customerID, customer.ClientBankID)
+		}
+		previousKey, err := customerClientKey(ctx, customer.ClientID)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().DelState(previousKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete customer client index: %v", err)
+		}
+	}
+	err = ctx.GetStub().PutState(key, []byte(customerID))
+	if err != nil {
+		return fmt.Errorf("failed to put customer client index: %v", err)
+	}
+
+	customer.ClientID = clientID
+	customer.ClientBankID = bankID
+	return putCustomer(ctx, customer)
+}
+
+// RejectCustomer marks the customer's KYC as rejected by bankID
+func (s *CustomerContract) RejectCustomer(ctx contractapi.TransactionContextInterface, bankID string, customerID string) error {
+	customer, err := getCustomerForKYC(ctx, bankID, customerID)
+	if err != nil {
+		return err
+	}
+
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	customer.KYCStatus = KYCRejected
+	customer.VerificationDate = date
+	customer.VerifyingBankID = bankID
+	return putCustomer(ctx, customer)
+}
+
+// ExpireCustomerKYC marks a verified customer as expired ahead of their expiry
+// date, for example when a document is revoked.
+func (s *CustomerContract) ExpireCustomerKYC(ctx contractapi.TransactionContextInterface, bankID string, customerID string) error {
+	customer, err := getCustomerForKYC(ctx, bankID, customerID)
+	if err != nil {
+		return err
+	}
+	if customer.KYCStatus != KYCVerified {
+		return contracterrors.New(contracterrors.InvalidState, "customer %s is not verified", customerID)
+	}
+
+	customer.KYCStatus = KYCExpired
+	return putCustomer(ctx, customer)
+}
+
+// RequestKYCRenewal moves a rejected or expired customer back to pending so a
+// bank can verify them again.
+func (s *CustomerContract) RequestKYCRenewal(ctx contractapi.TransactionContextInterface, bankID string, customerID string) error {
+	customer, err := getCustomerForKYC(ctx, bankID, customerID)
+	if err != nil {
+		return err
+	}
+	if customer.KYCStatus != KYCRejected && customer.KYCStatus != KYCExpired {
+		return contracterrors.New(contracterrors.InvalidState, "customer %s is %s, only rejected or expired customers can be renewed", customerID, customer.KYCStatus)
+	}
+
+	customer.KYCStatus = KYCPending
+	return putCustomer(ctx, customer)
+}
+
+// requireVerifiedCustomer returns an error unless the customer's KYC is verified
+// and has not passed its expiry date.
+func requireVerifiedCustomer(ctx contractapi.TransactionContextInterface, customer *Customer) error {
+	if customer.KYCStatus != KYCVerified {
+		status := customer.KYCStatus
+		if status == "" {
+			status = KYCPending
+		}
+		return contracterrors.New(contracterrors.InvalidState, "customer %s is not KYC verified, status is %s", customer.CustomerID, status)
+	}
+
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	if customer.KYCExpiryDate <= date[:len(kycDateLayout)] {
+		return contracterrors.New(contracterrors.InvalidState, "KYC of customer %s expired on %s", customer.CustomerID, customer.KYCExpiryDate)
+	}
+
+	return nil
+}
+
+// checkKYC refuses the payment unless the customers it names for both
+// accounts are verified
+func (l *paymentLedger) checkKYC(payment *Payment) error {
+	for _, customerID := range []string{payment.SenderCustomerID, payment.ReceiverCustomerID} {
+		customer, err := getCustomer(l.ctx, customerID)
+		if err != nil {
+			return err
+		}
+		err = requireVerifiedCustomer(l.ctx, customer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requireCustomerClient returns an error unless the submitting client is the
+// identity enrolled for the customer
+func requireCustomerClient(ctx contractapi.TransactionContextInterface, customer *Customer) error {
+	clientID, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	if customer.ClientID == "" || clientID != customer.ClientID {
+		return contracterrors.New(contracterrors.Forbidden, "client is not the enrolled identity of customer %s", customer.CustomerID)
+	}
+	return nil
+}
+
+// getCustomerForClient loads the customer after checking that the caller is
+// the client enrolled for them
+func getCustomerForClient(ctx contractapi.TransactionContextInterface, customerID string) (*Customer, error) {
+	customer, err := getCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireCustomerClient(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+func customerClientKey(ctx contractapi.TransactionContextInterface, clientID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(customerClientObjectType, []string{clientID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create customer client key: %v", err)
+	}
+	return key, nil
+}
+
+// getCustomerForKYC loads the customer after checking that the caller
+// administers bankID and that the bank is a member.
+func getCustomerForKYC(ctx contractapi.TransactionContextInterface, bankID string, customerID string) (*Customer, error) {
+	err := checkArgs(
+		validation.ID("bankID", bankID),
+		validation.ID("customerID", customerID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bank, err := getBank(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireBankAdmin(ctx, bank)
+	if err != nil {
+		return nil, err
+	}
+	err = checkBankCanTransact(bank)
+	if err != nil {
+		return nil, err
+	}
+
+	return getCustomer(ctx, customerID)
+}
@@ -0,0 +1,219 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+func TestKYCLifecycle(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.CreateCustomer(ctx, "C3", "pw", "Carol", "White")
+	})
+
+	steps := []struct {
+		name       string
+		otherBank  bool
+		fn         func(ctx contractapi.TransactionContextInterface) error
+		wantErr    string
+		wantStatus string
+	}{
+		{name: "verify without documents", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.VerifyCustomer(ctx, "BANK1", "C3", RiskLow, "2030-01-01")
+		}, wantErr: "has no KYC documents"},
+		{name: "other bank's admin", otherBank: true, fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.AddKYCDocument(ctx, "BANK1", "C3", "doc1")
+		}, wantErr: "not the administrator of bank BANK1"},
+		{name: "add document", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.AddKYCDocument(ctx, "BANK1", "C3", "doc1")
+		}, wantStatus: KYCPending},
+		{name: "add same document", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.AddKYCDocument(ctx, "BANK1", "C3", "doc1")
+		}, wantErr: "already recorded"},
+		{name: "bad risk rating", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.VerifyCustomer(ctx, "BANK1", "C3", "extreme", "2030-01-01")
+		}, wantErr: `invalid riskRating "extreme"`},
+		{name: "expiry in the past", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.VerifyCustomer(ctx, "BANK1", "C3", RiskLow, "2020-01-01")
+		}, wantErr: "not in the future"},
+		{name: "renew pending customer", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.RequestKYCRenewal(ctx, "BANK1", "C3")
+		}, wantErr: "only rejected or expired"},
+		{name: "verify", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.VerifyCustomer(ctx, "BANK1", "C3", RiskMedium, "2030-01-01")
+		}, wantStatus: KYCVerified},
+		{name: "expire", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.ExpireCustomerKYC(ctx, "BANK1", "C3")
+		}, wantStatus: KYCExpired},
+		{name: "expire again", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.ExpireCustomerKYC(ctx, "BANK1", "C3")
+		}, wantErr: "is not verified"},
+		{name: "renew", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.RequestKYCRenewal(ctx, "BANK1", "C3")
+		}, wantStatus: KYCPending},
+		{name: "reject", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.RejectCustomer(ctx, "BANK1", "C3")
+		}, wantStatus: KYCRejected},
+		{name: "missing customer", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.RejectCustomer(ctx, "BANK1", "NOCUST")
+		}, wantErr: "customer NOCUST does not exist"},
+		{name: "missing bank", fn: func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.RejectCustomer(ctx, "NOBANK", "C3")
+		}, wantErr: "bank NOBANK does not exist"},
+	}
+
+	// The steps run in order against the same ledger
+	for _, step := range steps {
+		identity := f.bank1Admin
+		if step.otherBank {
+			identity = f.bank2Admin
+		}
+		err := f.submit(identity, step.fn)
+		if (err != nil) != (step.wantErr != "") || err != nil && !strings.Contains(contracterrors.Message(err), step.wantErr) {
+			t.Fatalf("%s: error = %v, want %q", step.name, err, step.wantErr)
+		}
+		if step.wantStatus != "" {
+			if status := f.customer("C3").KYCStatus; status != step.wantStatus {
+				t.Fatalf("%s: status = %s, want %s", step.name, status, step.wantStatus)
+			}
+		}
+	}
+
+	customer := f.customer("C3")
+	if customer.VerifyingBankID != "BANK1" || customer.RiskRating != RiskMedium || customer.DocumentHashes[0] != "doc1" {
+		t.Fatalf("unexpected customer %+v", customer)
+	}
+}
+
+func TestCreatePaymentRequiresVerifiedCustomers(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(f *fixture)
+		wantErr string
+	}{
+		{name: "both verified"},
+		{name: "receiver expired early", setup: func(f *fixture) {
+			f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.customers.ExpireCustomerKYC(ctx, "BANK2", "C2")
+			})
+		}, wantErr: "customer C2 is not KYC verified, status is expired"},
+		{name: "sender past expiry date", setup: func(f *fixture) {
+			f.stub.Now = time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+		}, wantErr: "KYC of customer C1 expired on 2030-01-01"},
+		{name: "sender rejected", setup: func(f *fixture) {
+			f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.customers.RejectCustomer(ctx, "BANK1", "C1")
+			})
+		}, wantErr: "customer C1 is not KYC verified, status is rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			if tt.setup != nil {
+				tt.setup(f)
+			}
+			checkErr(t, f.pay("P1", "A1", "A2", 10, 1), tt.wantErr)
+		})
+	}
+}
+
+func TestEnrollCustomerClient(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	enroll := func(identity *chaincodetest.Identity, bankID string, customerID string, clientID string) error {
+		return f.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			return f.customers.EnrollCustomerClient(ctx, bankID, customerID, clientID)
+		})
+	}
+
+	err := enroll(f.bank2Admin, "BANK2", "C1", "x509::CN=new")
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "customer C1 is not verified by bank BANK2")
+	err = enroll(f.bank2Admin, "BANK1", "C1", "x509::CN=new")
+	checkCode(t, err, contracterrors.Forbidden)
+	err = enroll(f.bank1Admin, "BANK1", "C1", f.clients["C2"].ID())
+	checkCode(t, err, contracterrors.AlreadyExists)
+
+	// A new identity replaces the old one, which is then free to enroll again
+	old := f.clients["C1"].ID()
+	checkErr(t, enroll(f.bank1Admin, "BANK1", "C1", "x509::CN=new"), "")
+	if clientID := f.customer("C1").ClientID; clientID != "x509::CN=new" {
+		t.Fatalf("C1 is enrolled as %q", clientID)
+	}
+	checkErr(t, enroll(f.bank2Admin, "BANK2", "C2", old), "")
+
+	// Verifying the customer again does not hand their client to another bank
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.AddKYCDocument(ctx, "BANK2", "C1", "doc2")
+	})
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.VerifyCustomer(ctx, "BANK2", "C1", RiskLow, "2030-01-01")
+	})
+	err = enroll(f.bank2Admin, "BANK2", "C1", "x509::CN=other")
+	checkCode(t, err, contracterrors.Forbidden)
+	checkErr(t, err, "the client of customer C1 was enrolled by bank BANK1")
+	if clientID := f.customer("C1").ClientID; clientID != "x509::CN=new" {
+		t.Fatalf("C1 is enrolled as %q", clientID)
+	}
+}
+
+func TestKYCNeedsAMemberBank(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(f *fixture) (string, *chaincodetest.Identity)
+		wantErr string
+	}{
+		{name: "pending", setup: func(f *fixture) (string, *chaincodetest.Identity) {
+			f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.banks.CreateBank(ctx, "BANK3", "", "Third Bank", "pw", "GB", "GBP", 0, 0.8, "")
+			})
+			return "BANK3", f.anyone
+		}, wantErr: "bank BANK3 is pending"},
+		{name: "suspended", setup: func(f *fixture) (string, *chaincodetest.Identity) {
+			_, err := f.proposeMembershipChange(f.bank1Admin, "BANK2", "BANK1", MembershipSuspend)
+			checkErr(f.t, err, "")
+			return "BANK2", f.bank2Admin
+		}, wantErr: "bank BANK2 is suspended"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			bankID, admin := tt.setup(f)
+			for _, fn := range []txFunc{
+				func(ctx contractapi.TransactionContextInterface) error {
+					return f.customers.AddKYCDocument(ctx, bankID, "C1", "doc2")
+				},
+				func(ctx contractapi.TransactionContextInterface) error {
+					return f.customers.VerifyCustomer(ctx, bankID, "C1", RiskLow, "2030-01-01")
+				},
+				func(ctx contractapi.TransactionContextInterface) error {
+					return f.customers.EnrollCustomerClient(ctx, bankID, "C1", admin.ID())
+				},
+				func(ctx contractapi.TransactionContextInterface) error {
+					return f.customers.RejectCustomer(ctx, bankID, "C2")
+				},
+			} {
+				err := f.submit(admin, fn)
+				checkCode(t, err, contracterrors.InvalidState)
+				checkErr(t, err, tt.wantErr)
+			}
+			if customer := f.customer("C2"); customer.KYCStatus != KYCVerified {
+				t.Fatalf("C2 is %s", customer.KYCStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const (
+	tierLimitObjectType    = "TierLimit"
+	limitCounterObjectType = "LimitCounter"
+
+	// DefaultTier applies to customers that have not been given a tier
+	DefaultTier = "standard"
+
+	// LimitBreachEvent is the chaincode event CreatePaymentBatch emits for the
+	// lines it refused because they would exceed the sender's limits. A single
+	// payment that breaches them fails instead, and Fabric delivers no events
+	// of failed transactions.
+	LimitBreachEvent = "LimitBreach"
+)
+
+// TierLimit holds the outbound limits of a KYC tier in one currency. A zero
+// value leaves that dimension unlimited.
+type TierLimit struct {
+	Tier               string  `json:"tier"`
+	Currency           string  `json:"currency"`
+	PerTransactionMax  float64 `json:"perTransactionMax"`
+	DailyLimit         float64 `json:"dailyLimit"`
+	MonthlyLimit       float64 `json:"monthlyLimit"`
+	MaxDailyPayments   int     `json:"maxDailyPayments"`
+	MaxMonthlyPayments int     `json:"maxMonthlyPayments"`
+}
+
+// LimitCounter is a customer's outbound total in one currency for one period.
+// Periods are D<yyyymmdd> for days and M<yyyymm> for months.
+type LimitCounter struct {
+	CustomerID string  `json:"customerID"`
+	Currency   string  `json:"currency"`
+	Period     string  `json:"period"`
+	Amount     float64 `json:"amount"`
+	Count      int     `json:"count"`
+}
+
+// LimitBreach is the payload of a LimitBreachEvent and the details of a
+// LimitExceeded error
+type LimitBreach struct {
+	PaymentID  string  `json:"paymentID"`
+	CustomerID string  `json:"customerID"`
+	Tier       string  `json:"tier"`
+	Currency   string  `json:"currency"`
+	Amount     float64 `json:"amount"`
+	Reason     string  `json:"reason"`
+}
+
+func (b *LimitBreach) Error() string {
+	return fmt.Sprintf("payment %s exceeds the %s tier limits of customer %s: %s", b.PaymentID, b.Tier, b.CustomerID, b.Reason)
+}
+
+// limitExceeded is the error returned to the client for a breach
+func limitExceeded(b *LimitBreach) error {
+	return contracterrors.New(contracterrors.LimitExceeded, "%s", b.Error()).WithDetails(b)
+}
+
+// SetTierLimit creates or replaces the limits of a tier in one currency
+func (s *AdminContract) SetTierLimit(ctx contractapi.TransactionContextInterface, tier string, currency string, perTransactionMax float64, dailyLimit float64, monthlyLimit float64, maxDailyPayments int, maxMonthlyPayments int) error {
+	err := requireRole(ctx, RoleCompliance)
+	if err != nil {
+		return err
+	}
+	err = checkArgs(
+		validation.Required("tier", tier),
+		validation.Currency("currency", currency),
+		validation.NonNegativeAmount("perTransactionMax", perTransactionMax),
+		validation.NonNegativeAmount("dailyLimit", dailyLimit),
+		validation.NonNegativeAmount("monthlyLimit", monthlyLimit),
+		validation.NonNegativeAmount("maxDailyPayments", float64(maxDailyPayments)),
+		validation.NonNegativeAmount("maxMonthlyPayments", float64(maxMonthlyPayments)),
+	)
+	if err != nil {
+		return err
+	}
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	err = checkSupportedCurrency(config, "currency", currency)
+	if err != nil {
+		return err
+	}
+
+	limit := TierLimit{
+		Tier:               tier,
+		Currency:           currency,
+		PerTransactionMax:  perTransactionMax,
+		DailyLimit:         dailyLimit,
+		MonthlyLimit:       monthlyLimit,
+		MaxDailyPayments:   maxDailyPayments,
+		MaxMonthlyPayments: maxMonthlyPayments,
+	}
+	limitJSON, err := json.Marshal(limit)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tier limit: %v", err)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(tierLimitObjectType, []string{tier, currency})
+	if err != nil {
+		return fmt.Errorf("failed to create tier limit key: %v", err)
+	}
+	return ctx.GetStub().PutState(key, limitJSON)
+}
+
+// QueryTierLimits returns the limits of a tier in every configured currency
+func (s *AdminContract) QueryTierLimits(ctx contractapi.TransactionContextInterface, tier string) ([]*TierLimit, error) {
+	err := checkArgs(validation.Required("tier", tier))
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(tierLimitObjectType, []string{tier})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tier limits: %v", err)
+	}
+	defer iterator.Close()
+
+	limits := []*TierLimit{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over tier limits: %v", err)
+		}
+
+		var limit TierLimit
+		err = json.Unmarshal(queryResponse.Value, &limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tier limit: %v", err)
+		}
+		limits = append(limits, &limit)
+	}
+
+	return limits, nil
+}
+
+// SetCustomerTier moves a customer to another KYC tier
+func (s *AdminContract) SetCustomerTier(ctx contractapi.TransactionContextInterface, customerID string, tier string) error {
+	err := requireRole(ctx, RoleCompliance)
+	if err != nil {
+		return err
+	}
+	err = checkArgs(
+		validation.ID("customerID", customerID),
+		validation.Required("tier", tier),
+	)
+	if err != nil {
+		return err
+	}
+
+	customerBytes, err := ctx.GetStub().GetState(customerID)
+	if err != nil {
+		return fmt.Errorf("failed to read customer state: %v", err)
+	}
+	if customerBytes == nil {
+		return contracterrors.New(contracterrors.NotFound, "customer %s does not exist", customerID)
+	}
+
+	var customer Customer
+	err = unmarshalCustomer(customerBytes, &customer)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal customer JSON: %v", err)
+	}
+
+	customer.Tier = tier
+	customerBytes, err = json.Marshal(customer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal customer JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(customerID, customerBytes)
+}
+
+// QueryLimitUsage returns a customer's current daily and monthly counters in
+// one currency
+func (s *AdminContract) QueryLimitUsage(ctx contractapi.TransactionContextInterface, customerID string, currency string) ([]*LimitCounter, error) {
+	err := checkArgs(
+		validation.ID("customerID", customerID),
+		validation.Currency("currency", currency),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := newPaymentLedger(ctx)
+	daily, monthly, err := ledger.limitCounters(customerID, currency)
+	if err != nil {
+		return nil, err
+	}
+	return []*LimitCounter{daily, monthly}, nil
+}
+
+// checkLimits refuses the payment if it would take the sender over the limits
+// of their tier, counting the payments already applied to the ledger.
+func (l *paymentLedger) checkLimits(payment *Payment) error {
+	sender, err := l.account(payment.SenderAccountID)
+	if err != nil {
+		return err
+	}
+
+	tier := DefaultTier
+	customerBytes, err := l.ctx.GetStub().GetState(sender.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to read customer state: %v", err)
+	}
+	if customerBytes != nil {
+		var customer Customer
+		err = unmarshalCustomer(customerBytes, &customer)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal customer JSON: %v", err)
+		}
+		if customer.Tier != "" {
+			tier = customer.Tier
+		}
+	}
+
+	key, err := l.ctx.GetStub().CreateCompositeKey(tierLimitObjectType, []string{tier, sender.Currency})
+	if err != nil {
+		return fmt.Errorf("failed to create tier limit key: %v", err)
+	}
+	limitJSON, err := l.ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read tier limit: %v", err)
+	}
+	if limitJSON == nil {
+		return nil
+	}
+	var limit TierLimit
+	err = json.Unmarshal(limitJSON, &limit)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal tier limit: %v", err)
+	}
+
+	daily, monthly, err := l.limitCounters(sender.CustomerID, sender.Currency)
+	if err != nil {
+		return err
+	}
+
+	reason := ""
+	switch {
+	case limit.PerTransactionMax > 0 && payment.Amount > limit.PerTransactionMax:
+		reason = fmt.Sprintf("amount %v is above the per-transaction maximum of %v", payment.Amount, limit.PerTransactionMax)
+	case limit.DailyLimit > 0 && daily.Amount+payment.Amount > limit.DailyLimit:
+		reason = fmt.Sprintf("daily total would be %v, limit is %v", daily.Amount+payment.Amount, limit.DailyLimit)
+	case limit.MonthlyLimit > 0 && monthly.Amount+payment.Amount > limit.MonthlyLimit:
+		reason = fmt.Sprintf("monthly total would be %v, limit is %v", monthly.Amount+payment.Amount, limit.MonthlyLimit)
+	case limit.MaxDailyPayments > 0 && daily.Count >= limit.MaxDailyPayments:
+		reason = fmt.Sprintf("daily payment count limit of %d reached", limit.MaxDailyPayments)
+	case limit.MaxMonthlyPayments > 0 && monthly.Count >= limit.MaxMonthlyPayments:
+		reason = fmt.Sprintf("monthly payment count limit of %d reached", limit.MaxMonthlyPayments)
+	default:
+		return nil
+	}
+
+	return &LimitBreach{
+		PaymentID:  payment.PaymentID,
+		CustomerID: sender.CustomerID,
+		Tier:       tier,
+		Currency:   sender.Currency,
+		Amount:     payment.Amount,
+		Reason:     reason,
+	}
+}
+
+// limitCounters returns the buffered daily and monthly counters of a customer
+// for the transaction's date, loading them from the world state on first use.
+func (l *paymentLedger) limitCounters(customerID string, currency string) (*LimitCounter, *LimitCounter, error) {
+	timestamp, err := l.ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.AsTime().UTC()
+
+	counters := []*LimitCounter{}
+	for _, period := range []string{now.Format("D20060102"), now.Format("M200601")} {
+		key, err := l.ctx.GetStub().CreateCompositeKey(limitCounterObjectType, []string{customerID, currency, period})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create limit counter key: %v", err)
+		}
+
+		counter, ok := l.counters[key]
+		if !ok {
+			counter = &LimitCounter{CustomerID: customerID, Currency: currency, Period: period}
+			counterJSON, err := l.ctx.GetStub().GetState(key)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read limit counter: %v", err)
+			}
+			if counterJSON != nil {
+				err = json.Unmarshal(counterJSON, counter)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to unmarshal limit counter: %v", err)
+				}
+			}
+			l.counters[key] = counter
+		}
+		counters = append(counters, counter)
+	}
+
+	return counters[0], counters[1], nil
+}
+
+// recordLimitUsage adds a settled payment to the sender's counters
+func (l *paymentLedger) recordLimitUsage(sender *Account, amount float64) error {
+	daily, monthly, err := l.limitCounters(sender.CustomerID, sender.Currency)
+	if err != nil {
+		return err
+	}
+	for _, counter := range []*LimitCounter{daily, monthly} {
+		counter.Amount += amount
+		counter.Count++
+	}
+	return nil
+}
+
+// setLimitBreachEvent emits the breaches as a LimitBreachEvent
+func setLimitBreachEvent(ctx contractapi.TransactionContextInterface, breaches []*LimitBreach) error {
+	if len(breaches) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(breaches)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limit breach event: %v", err)
+	}
+	return ctx.GetStub().SetEvent(LimitBreachEvent, payload)
+}
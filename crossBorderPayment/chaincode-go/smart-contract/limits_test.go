@@ -0,0 +1,145 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+func TestSetTierLimit(t *testing.T) {
+	f := newFixture(t)
+
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetTierLimit(ctx, DefaultTier, "USD", 100, 0, 0, 0, 0)
+	})
+	checkErr(t, err, "not authorized")
+	err = f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetTierLimit(ctx, DefaultTier, "USD", -1, 0, 0, 0, 0)
+	})
+	checkErr(t, err, "must not be negative")
+
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetTierLimit(ctx, DefaultTier, "USD", 100, 500, 2000, 3, 50)
+	})
+	var limits []*TierLimit
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		limits, err = f.admin.QueryTierLimits(ctx, DefaultTier)
+		return err
+	})
+	checkErr(t, err, "")
+	if len(limits) != 1 || limits[0].DailyLimit != 500 || limits[0].MaxDailyPayments != 3 {
+		t.Fatalf("unexpected limits %+v", limits)
+	}
+}
+
+func TestSetCustomerTier(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	checkErr(t, f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetCustomerTier(ctx, "C1", "premium")
+	}), "not authorized")
+	checkErr(t, f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetCustomerTier(ctx, "NOCUST", "premium")
+	}), "does not exist")
+
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetCustomerTier(ctx, "C1", "premium")
+	})
+	if tier := f.customer("C1").Tier; tier != "premium" {
+		t.Fatalf("tier = %s, want premium", tier)
+	}
+}
+
+func TestCreatePaymentEnforcesLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    TierLimit
+		amounts  []float64
+		nextDay  bool
+		wantErrs []string
+	}{
+		{name: "per transaction", limit: TierLimit{PerTransactionMax: 100}, amounts: []float64{100, 101}, wantErrs: []string{"", "per-transaction maximum"}},
+		{name: "daily amount", limit: TierLimit{DailyLimit: 150}, amounts: []float64{100, 60}, wantErrs: []string{"", "daily total"}},
+		{name: "daily amount resets", limit: TierLimit{DailyLimit: 150}, amounts: []float64{100, 60}, nextDay: true, wantErrs: []string{"", ""}},
+		{name: "monthly amount", limit: TierLimit{MonthlyLimit: 150}, amounts: []float64{100, 60}, nextDay: true, wantErrs: []string{"", "monthly total"}},
+		{name: "daily count", limit: TierLimit{MaxDailyPayments: 1}, amounts: []float64{1, 1}, wantErrs: []string{"", "daily payment count"}},
+		{name: "monthly count", limit: TierLimit{MaxMonthlyPayments: 1}, amounts: []float64{1, 1}, nextDay: true, wantErrs: []string{"", "monthly payment count"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				l := tt.limit
+				return f.admin.SetTierLimit(ctx, DefaultTier, "USD", l.PerTransactionMax, l.DailyLimit, l.MonthlyLimit, l.MaxDailyPayments, l.MaxMonthlyPayments)
+			})
+
+			for i, amount := range tt.amounts {
+				if i > 0 && tt.nextDay {
+					f.stub.Now = f.stub.Now.Add(24 * time.Hour)
+				}
+				err := f.pay("P"+string(rune('1'+i)), "A1", "A2", amount, 1)
+				checkErr(t, err, tt.wantErrs[i])
+				if tt.wantErrs[i] == "" {
+					continue
+				}
+				details := err.(*contracterrors.Error).Details
+				if breach, ok := details.(*LimitBreach); !ok || breach.CustomerID != "C1" || breach.Amount != amount {
+					t.Fatalf("unexpected breach details %+v", details)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentBatchReportsLimitBreaches(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.SetTierLimit(ctx, DefaultTier, "USD", 0, 150, 0, 0, 0)
+	})
+
+	lines := []PaymentInstruction{
+		{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 100, ExchangeRate: 1},
+		{PaymentID: "P2", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 100, ExchangeRate: 1},
+	}
+	var batch *PaymentBatch
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		batch, err = f.payments.CreatePaymentBatch(ctx, "B1", batchJSON(t, lines...), BatchModeBestEffort)
+		return err
+	})
+	if batch.Settled != 1 || batch.Failed != 1 || batch.Results[1].Code != string(contracterrors.LimitExceeded) {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+
+	events := f.stub.Events()
+	if len(events) != 1 || events[0].Name != LimitBreachEvent {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	var breaches []LimitBreach
+	checkErr(t, json.Unmarshal(events[0].Payload, &breaches), "")
+	if len(breaches) != 1 || breaches[0].PaymentID != "P2" || breaches[0].CustomerID != "C1" {
+		t.Fatalf("unexpected breaches %+v", breaches)
+	}
+
+	var usage []*LimitCounter
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		usage, err = f.admin.QueryLimitUsage(ctx, "C1", "USD")
+		return err
+	})
+	checkErr(t, err, "")
+	for _, counter := range usage {
+		if counter.Count != 1 || counter.Amount != 100 {
+			t.Fatalf("unexpected counter %+v", counter)
+		}
+	}
+}
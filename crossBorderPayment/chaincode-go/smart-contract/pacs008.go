@@ -0,0 +1,461 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/iso20022"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// maxRemittanceLine is the length of one Ustrd element of a pacs.008 message
+const maxRemittanceLine = 140
+
+// amountTolerance is how far the settlement amount of a pacs.008 message may
+// be from its instructed amount times its exchange rate, to allow for rounding
+const amountTolerance = 0.01
+
+// pacs008Payment is a payment read from a pacs.008 message, with the
+// currencies its amounts were given in
+type pacs008Payment struct {
+	payment          *Payment
+	senderCurrency   string
+	receiverCurrency string
+	senderIBAN       string
+	receiverIBAN     string
+}
+
+// CreatePaymentFromPacs008 creates a payment from a pacs.008.001.08 message
+// with a single transaction, as CBPR+ requires. The message is checked
+// against the schema first. Accounts are identified by their IBANs or by
+// their ledger IDs in Othr/Id elements, parties by their ledger IDs in Othr/Id
+// elements, and the payment ID is the instruction ID,
+// or the transaction ID if the message has none. The debtor and creditor IDs
+// may be left out, in which case the accounts' owners are used.
+func (s *PaymentContract) CreatePaymentFromPacs008(ctx contractapi.TransactionContextInterface, message string) (*Payment, error) {
+	doc, err := iso20022.ParsePacs008([]byte(message))
+	if err != nil {
+		return nil, contracterrors.From(err)
+	}
+	parsed, err := paymentFromPacs008(doc)
+	if err != nil {
+		return nil, err
+	}
+	payment := parsed.payment
+	for _, account := range []struct {
+		id   *string
+		iban string
+	}{
+		{id: &payment.SenderAccountID, iban: parsed.senderIBAN},
+		{id: &payment.ReceiverAccountID, iban: parsed.receiverIBAN},
+	} {
+		if account.iban == "" {
+			continue
+		}
+		*account.id, err = getIndexedID(ctx, ibanIndexObjectType, account.iban)
+		if err != nil {
+			return nil, err
+		}
+		if *account.id == "" {
+			return nil, contracterrors.New(contracterrors.NotFound, "no account has IBAN %s", account.iban)
+		}
+	}
+	err = checkArgs(
+		validation.ID("paymentID", payment.PaymentID),
+		validation.ID("senderAccountID", payment.SenderAccountID),
+		validation.ID("receiverAccountID", payment.ReceiverAccountID),
+		validation.OptionalID("senderCustomerID", payment.SenderCustomerID),
+		validation.OptionalID("receiverCustomerID", payment.ReceiverCustomerID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := getAccount(ctx, payment.SenderAccountID)
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := getAccount(ctx, payment.ReceiverAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if sender.Currency != parsed.senderCurrency {
+		return nil, contracterrors.New(contracterrors.Validation, "the amount is in %s but account %s holds %s", parsed.senderCurrency, sender.AccountID, sender.Currency)
+	}
+	if receiver.Currency != parsed.receiverCurrency {
+		return nil, contracterrors.New(contracterrors.Validation, "the settlement amount is in %s but account %s holds %s", parsed.receiverCurrency, receiver.AccountID, receiver.Currency)
+	}
+	for _, party := range []struct {
+		customerID *string
+		account    *Account
+	}{
+		{customerID: &payment.SenderCustomerID, account: sender},
+		{customerID: &payment.ReceiverCustomerID, account: receiver},
+	} {
+		if *party.customerID == "" {
+			*party.customerID = party.account.CustomerID
+		} else if !isAccountHolder(party.account, *party.customerID) {
+			return nil, contracterrors.New(contracterrors.Validation, "account %s does not belong to customer %s", party.account.AccountID, *party.customerID)
+		}
+	}
+
+	err = createPayment(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// paymentFromPacs008 maps the transaction of a valid pacs.008 message onto a
+// payment. The amount is the instructed amount, in the debtor's currency, or
+// the settlement amount if the message has no instructed amount.
+func paymentFromPacs008(doc *iso20022.Pacs008) (*pacs008Payment, error) {
+	if len(doc.CreditTransfer.Transactions) != 1 {
+		return nil, contracterrors.New(contracterrors.Validation, "a pacs.008 message must hold exactly one transaction, not %d", len(doc.CreditTransfer.Transactions))
+	}
+	tx := &doc.CreditTransfer.Transactions[0]
+
+	var failed validation.Errors
+	fail := func(field string, value string, format string, args ...interface{}) {
+		failed = append(failed, &validation.Error{Field: "CdtTrfTxInf[0]/" + field, Rule: validation.RuleRequired, Value: value, Message: fmt.Sprintf(format, args...)})
+	}
+
+	paymentID := tx.PaymentID.InstructionID
+	if paymentID == "" {
+		paymentID = tx.PaymentID.TransactionID
+	}
+	if paymentID == "" {
+		fail("PmtId/InstrId", "", "or TxId is required as the payment ID")
+	}
+	senderAccountID, senderIBAN := accountIdentification(tx.DebtorAccount)
+	if senderAccountID == "" && senderIBAN == "" {
+		fail("DbtrAcct/Id", "", "must hold an IBAN or the sender account ID in Othr/Id")
+	}
+	receiverAccountID, receiverIBAN := accountIdentification(tx.CreditorAccount)
+	if receiverAccountID == "" && receiverIBAN == "" {
+		fail("CdtrAcct/Id", "", "must hold an IBAN or the receiver account ID in Othr/Id")
+	}
+
+	settlement, _ := iso20022.ParseAmount(tx.InterbankSettlementAmount.Value)
+	amount, senderCurrency, rate := settlement, tx.InterbankSettlementAmount.Currency, 1.0
+	if tx.InstructedAmount != nil {
+		amount, _ = iso20022.ParseAmount(tx.InstructedAmount.Value)
+		senderCurrency = tx.InstructedAmount.Currency
+		if amount > 0 {
+			rate = settlement / amount
+		}
+	}
+	if tx.ExchangeRate != "" {
+		if tx.InstructedAmount == nil {
+			fail("InstdAmt", "", "is required with XchgRate")
+		}
+		rate, _ = iso20022.ParseAmount(tx.ExchangeRate)
+	}
+
+	date := tx.InterbankSettlementDate
+	if date == "" {
+		created, _ := iso20022.ParseDateTime(doc.CreditTransfer.GroupHeader.CreationDateTime)
+		date = created.UTC().Format("2006-01-02")
+	}
+	if len(failed) > 0 {
+		return nil, contracterrors.From(failed)
+	}
+	if math.Abs(amount*rate-settlement) > amountTolerance {
+		return nil, contracterrors.New(contracterrors.Validation, "exchange rate %s does not convert the instructed amount %s into the settlement amount %s", tx.ExchangeRate, tx.InstructedAmount.Value, tx.InterbankSettlementAmount.Value)
+	}
+
+	payment := &Payment{
+		PaymentID:          paymentID,
+		SenderCustomerID:   partyID(&tx.Debtor),
+		ReceiverCustomerID: partyID(&tx.Creditor),
+		SenderAccountID:    senderAccountID,
+		ReceiverAccountID:  receiverAccountID,
+		Amount:             amount,
+		ExchangeRate:       rate,
+		Date:               date,
+		EndToEndID:         tx.PaymentID.EndToEndID,
+		UETR:               tx.PaymentID.UETR,
+		ChargeBearer:       tx.ChargeBearer,
+		SchemaVersion:      SchemaVersion,
+	}
+	pacs008Remittance(tx).applyTo(payment)
+	return &pacs008Payment{
+		payment:          payment,
+		senderCurrency:   senderCurrency,
+		receiverCurrency: tx.InterbankSettlementAmount.Currency,
+		senderIBAN:       senderIBAN,
+		receiverIBAN:     receiverIBAN,
+	}, nil
+}
+
+// accountIdentification returns the ledger ID in Othr/Id or the IBAN of an
+// account
+func accountIdentification(account *iso20022.CashAccount) (string, string) {
+	switch {
+	case account == nil:
+		return "", ""
+	case account.ID.Other != nil:
+		return account.ID.Other.ID, ""
+	}
+	return "", account.ID.IBAN
+}
+
+// partyID returns the first identifier of a party, or "" if it has none
+func partyID(party *iso20022.Party) string {
+	if party.ID == nil {
+		return ""
+	}
+	for _, ids := range []*iso20022.GenericIDs{party.ID.Private, party.ID.Organisation} {
+		if ids != nil && len(ids.Other) > 0 {
+			return ids.Other[0].ID
+		}
+	}
+	return ""
+}
+
+// ExportPaymentPacs008 returns a payment as a pacs.008.001.08 message for
+// other rails. The agents are the two banks and the parties their customers,
+// identified by their ledger IDs. Payments created without a UETR get one
+// derived from the payment ID, so exporting a payment twice gives the same
+// message apart from its creation time.
+func (s *PaymentContract) ExportPaymentPacs008(ctx contractapi.TransactionContextInterface, paymentID string) (string, error) {
+	err := checkArgs(validation.ID("paymentID", paymentID))
+	if err != nil {
+		return "", err
+	}
+	payment, err := getPayment(ctx, paymentID)
+	if err != nil {
+		return "", err
+	}
+	parties, err := getPacs008Parties(ctx, payment)
+	if err != nil {
+		return "", err
+	}
+	created, err := getTxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	doc := paymentToPacs008(payment, parties, created)
+	err = doc.Validate()
+	if err != nil {
+		return "", contracterrors.Wrap(err, "payment %s cannot be written as pacs.008", paymentID)
+	}
+	message, err := doc.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return string(message), nil
+}
+
+// pacs008Parties are the ledger objects a pacs.008 message describes besides
+// the payment itself
+type pacs008Parties struct {
+	senderAccount, receiverAccount   *Account
+	senderCustomer, receiverCustomer *Customer
+	senderBank, receiverBank         *Bank
+}
+
+func getPacs008Parties(ctx contractapi.TransactionContextInterface, payment *Payment) (*pacs008Parties, error) {
+	var parties pacs008Parties
+	var err error
+	parties.senderAccount, err = getAccount(ctx, payment.SenderAccountID)
+	if err != nil {
+		return nil, err
+	}
+	parties.receiverAccount, err = getAccount(ctx, payment.ReceiverAccountID)
+	if err != nil {
+		return nil, err
+	}
+	parties.senderCustomer, err = getCustomer(ctx, payment.SenderCustomerID)
+	if err != nil {
+		return nil, err
+	}
+	parties.receiverCustomer, err = getCustomer(ctx, payment.ReceiverCustomerID)
+	if err != nil {
+		return nil, err
+	}
+	parties.senderBank, err = getBank(ctx, parties.senderAccount.BankID)
+	if err != nil {
+		return nil, err
+	}
+	parties.receiverBank, err = getBank(ctx, parties.receiverAccount.BankID)
+	if err != nil {
+		return nil, err
+	}
+	return &parties, nil
+}
+
+// paymentToPacs008 writes a payment as a pacs.008 message created at created.
+// The ledger is the clearing system, so the message settles by clearing.
+func paymentToPacs008(payment *Payment, parties *pacs008Parties, created time.Time) *iso20022.Pacs008 {
+	tx := iso20022.CreditTransferTransaction{
+		PaymentID: iso20022.PaymentIdentification{
+			InstructionID: payment.PaymentID,
+			EndToEndID:    payment.EndToEndID,
+			TransactionID: payment.PaymentID,
+			UETR:          payment.UETR,
+		},
+		InterbankSettlementAmount: iso20022.Amount{
+			Currency: parties.receiverAccount.Currency,
+			Value:    iso20022.FormatAmount(payment.Amount * payment.ExchangeRate),
+		},
+		ChargeBearer:    payment.ChargeBearer,
+		Debtor:          customerParty(parties.senderCustomer),
+		DebtorAccount:   ledgerAccount(parties.senderAccount),
+		DebtorAgent:     bankAgent(parties.senderBank),
+		CreditorAgent:   bankAgent(parties.receiverBank),
+		Creditor:        customerParty(parties.receiverCustomer),
+		CreditorAccount: ledgerAccount(parties.receiverAccount),
+	}
+	if tx.PaymentID.EndToEndID == "" {
+		tx.PaymentID.EndToEndID = iso20022.NotProvided
+	}
+	if tx.PaymentID.UETR == "" {
+		tx.PaymentID.UETR = derivedUETR(payment.PaymentID)
+	}
+	if tx.ChargeBearer == "" {
+		tx.ChargeBearer = iso20022.ChargeBearerShared
+	}
+	if date, err := time.Parse("2006-01-02", payment.Date); err == nil {
+		tx.InterbankSettlementDate = date.Format("2006-01-02")
+	} else if date, err := time.Parse(time.RFC3339, payment.Date); err == nil {
+		tx.InterbankSettlementDate = date.UTC().Format("2006-01-02")
+	}
+	if parties.senderAccount.Currency != parties.receiverAccount.Currency {
+		tx.InstructedAmount = &iso20022.Amount{Currency: parties.senderAccount.Currency, Value: iso20022.FormatAmount(payment.Amount)}
+		tx.ExchangeRate = iso20022.FormatRate(payment.ExchangeRate)
+	}
+	if payment.PurposeCode != "" {
+		tx.Purpose = &iso20022.Purpose{Code: payment.PurposeCode}
+	}
+	tx.RemittanceInfo = remittanceInfo(payment)
+
+	return &iso20022.Pacs008{
+		XMLName: xml.Name{Space: iso20022.Pacs008Namespace, Local: "Document"},
+		CreditTransfer: iso20022.FIToFICustomerTransfer{
+			GroupHeader: iso20022.GroupHeader{
+				MessageID:            payment.PaymentID,
+				CreationDateTime:     created.UTC().Format(time.RFC3339),
+				NumberOfTransactions: "1",
+				SettlementInfo:       iso20022.SettlementInfo{Method: iso20022.SettlementClearing},
+			},
+			Transactions: []iso20022.CreditTransferTransaction{tx},
+		},
+	}
+}
+
+func customerParty(customer *Customer) iso20022.Party {
+	return iso20022.Party{
+		Name: strings.TrimSpace(customer.Name + " " + customer.Surname),
+		ID:   &iso20022.PartyID{Private: &iso20022.GenericIDs{Other: []iso20022.GenericID{{ID: customer.CustomerID}}}},
+	}
+}
+
+// ledgerAccount identifies an account by its IBAN if it has one, and by its
+// ledger ID otherwise
+func ledgerAccount(account *Account) *iso20022.CashAccount {
+	id := iso20022.AccountID{IBAN: account.IBAN}
+	if account.IBAN == "" {
+		id.Other = &iso20022.GenericID{ID: account.AccountID}
+	}
+	return &iso20022.CashAccount{ID: id, Currency: account.Currency}
+}
+
+func bankAgent(bank *Bank) iso20022.Agent {
+	return iso20022.Agent{FinancialInstitution: iso20022.FinancialInstitution{
+		BICFI: bank.BIC,
+		Name:  bank.Name,
+		Other: &iso20022.GenericID{ID: bank.BankID},
+	}}
+}
+
+// remittanceLines splits remittance information into Ustrd elements, one per
+// line and none longer than the schema allows
+func remittanceLines(info string) []string {
+	var lines []string
+	for _, line := range strings.Split(info, "\n") {
+		runes := []rune(line)
+		for len(runes) > maxRemittanceLine {
+			lines = append(lines, string(runes[:maxRemittanceLine]))
+			runes = runes[maxRemittanceLine:]
+		}
+		if len(runes) > 0 {
+			lines = append(lines, string(runes))
+		}
+	}
+	return lines
+}
+
+// pacs008Remittance reads the remittance information of a transaction. The
+// numbers of referred documents that are commercial invoices, or have no type,
+// become invoice numbers, and the first SCOR creditor reference becomes the
+// creditor reference. Other structured references are not kept.
+func pacs008Remittance(tx *iso20022.CreditTransferTransaction) Remittance {
+	var remittance Remittance
+	if tx.Purpose != nil {
+		remittance.PurposeCode = tx.Purpose.Code
+	}
+	if tx.RemittanceInfo == nil {
+		return remittance
+	}
+	remittance.RemittanceInfo = strings.Join(tx.RemittanceInfo.Unstructured, "\n")
+	for _, structured := range tx.RemittanceInfo.Structured {
+		for _, document := range structured.ReferredDocuments {
+			if document.Number != "" && (document.Type == nil || document.Type.Code == iso20022.DocumentCommercialInvoice) {
+				remittance.InvoiceNumbers = append(remittance.InvoiceNumbers, document.Number)
+			}
+		}
+		reference := structured.CreditorReference
+		if remittance.CreditorReference == "" && reference != nil && reference.Type != nil && reference.Type.Code == iso20022.ReferenceStructured {
+			remittance.CreditorReference = reference.Reference
+		}
+	}
+	return remittance
+}
+
+// remittanceInfo writes the remittance information of a payment, nil if it
+// has none. Invoice numbers and the creditor reference go into one Strd
+// element.
+func remittanceInfo(payment *Payment) *iso20022.RemittanceInfo {
+	info := &iso20022.RemittanceInfo{Unstructured: remittanceLines(payment.RemittanceInfo)}
+	if payment.CreditorReference != "" || len(payment.InvoiceNumbers) > 0 {
+		var structured iso20022.StructuredRemittance
+		for _, number := range payment.InvoiceNumbers {
+			structured.ReferredDocuments = append(structured.ReferredDocuments, iso20022.ReferredDocument{
+				Type:   &iso20022.DocumentType{Code: iso20022.DocumentCommercialInvoice},
+				Number: number,
+			})
+		}
+		if payment.CreditorReference != "" {
+			structured.CreditorReference = &iso20022.CreditorReference{
+				Type:      &iso20022.DocumentType{Code: iso20022.ReferenceStructured, Issuer: "ISO"},
+				Reference: payment.CreditorReference,
+			}
+		}
+		info.Structured = []iso20022.StructuredRemittance{structured}
+	}
+	if len(info.Unstructured) == 0 && len(info.Structured) == 0 {
+		return nil
+	}
+	return info
+}
+
+// derivedUETR returns a version 4 UUID made from the hash of a payment ID, so
+// every peer derives the same one
+func derivedUETR(paymentID string) string {
+	sum := sha256.Sum256([]byte("uetr:" + paymentID))
+	sum[6] = sum[6]&0x0f | 0x40
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
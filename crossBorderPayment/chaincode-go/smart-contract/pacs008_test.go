@@ -0,0 +1,186 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/iso20022"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// pacs008Message is a payment of 100 USD from A1 to A2, settled as 90 EUR
+const pacs008Message = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pacs.008.001.08">
+  <FIToFICstmrCdtTrf>
+    <GrpHdr>
+      <MsgId>MSG-1</MsgId>
+      <CreDtTm>2024-03-01T10:00:00Z</CreDtTm>
+      <NbOfTxs>1</NbOfTxs>
+      <SttlmInf><SttlmMtd>INDA</SttlmMtd></SttlmInf>
+    </GrpHdr>
+    <CdtTrfTxInf>
+      <PmtId>
+        <InstrId>P1</InstrId>
+        <EndToEndId>INV-2024-17</EndToEndId>
+        <UETR>eb6305c9-1f7f-49de-aed0-16487c27b42d</UETR>
+      </PmtId>
+      <IntrBkSttlmAmt Ccy="EUR">90</IntrBkSttlmAmt>
+      <IntrBkSttlmDt>2024-03-01</IntrBkSttlmDt>
+      <InstdAmt Ccy="USD">100.00</InstdAmt>
+      <XchgRate>0.9</XchgRate>
+      <ChrgBr>SHAR</ChrgBr>
+      <Dbtr><Nm>Alice Smith</Nm><Id><PrvtId><Othr><Id>C1</Id></Othr></PrvtId></Id></Dbtr>
+      <DbtrAcct><Id><Othr><Id>A1</Id></Othr></Id></DbtrAcct>
+      <DbtrAgt><FinInstnId><Othr><Id>BANK1</Id></Othr></FinInstnId></DbtrAgt>
+      <CdtrAgt><FinInstnId><Othr><Id>BANK2</Id></Othr></FinInstnId></CdtrAgt>
+      <Cdtr><Nm>Bob Jones</Nm></Cdtr>
+      <CdtrAcct><Id><Othr><Id>A2</Id></Othr></Id></CdtrAcct>
+      <RmtInf><Ustrd>Invoice 2024-17</Ustrd><Ustrd>Thank you</Ustrd></RmtInf>
+    </CdtTrfTxInf>
+  </FIToFICstmrCdtTrf>
+</Document>`
+
+func TestCreatePaymentFromPacs008(t *testing.T) {
+	tests := []struct {
+		name     string
+		replace  []string
+		wantCode contracterrors.Code
+		wantErr  string
+	}{
+		{name: "cross-currency"},
+		{
+			name:     "schema violation",
+			replace:  []string{"<ChrgBr>SHAR</ChrgBr>", "<ChrgBr>OUR</ChrgBr>", "eb6305c9", "EB6305C9"},
+			wantCode: contracterrors.Validation, wantErr: "CdtTrfTxInf[0]/PmtId/UETR",
+		},
+		{
+			name:     "wrong namespace",
+			replace:  []string{"pacs.008.001.08", "pacs.008.001.02"},
+			wantCode: contracterrors.Validation, wantErr: "Document/@xmlns",
+		},
+		{
+			name:     "no account ID",
+			replace:  []string{"<DbtrAcct><Id><Othr><Id>A1</Id></Othr></Id></DbtrAcct>", ""},
+			wantCode: contracterrors.Validation, wantErr: "DbtrAcct/Id",
+		},
+		{
+			name:     "rate does not match the amounts",
+			replace:  []string{"<XchgRate>0.9</XchgRate>", "<XchgRate>0.8</XchgRate>"},
+			wantCode: contracterrors.Validation, wantErr: "does not convert the instructed amount",
+		},
+		{
+			name:     "currency of another account",
+			replace:  []string{`Ccy="USD"`, `Ccy="GBP"`},
+			wantCode: contracterrors.Validation, wantErr: "the amount is in GBP but account A1 holds USD",
+		},
+		{
+			name:     "debtor does not own the account",
+			replace:  []string{"<Id>C1</Id>", "<Id>C2</Id>"},
+			wantCode: contracterrors.Validation, wantErr: "account A1 does not belong to customer C2",
+		},
+		{
+			name:     "not XML",
+			replace:  []string{"</Document>", ""},
+			wantCode: contracterrors.Validation, wantErr: "is not well-formed XML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			message := strings.NewReplacer(tt.replace...).Replace(pacs008Message)
+
+			var payment *Payment
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				payment, err = f.payments.CreatePaymentFromPacs008(ctx, message)
+				return err
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				checkErr(t, err, tt.wantErr)
+				assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+				return
+			}
+			checkErr(t, err, "")
+
+			if payment.PaymentID != "P1" || payment.SenderCustomerID != "C1" || payment.ReceiverCustomerID != "C2" || payment.Date != "2024-03-01" || payment.Status != PaymentStatusSettled {
+				t.Fatalf("unexpected payment %+v", payment)
+			}
+			if payment.EndToEndID != "INV-2024-17" || payment.UETR != "eb6305c9-1f7f-49de-aed0-16487c27b42d" || payment.ChargeBearer != "SHAR" || payment.RemittanceInfo != "Invoice 2024-17\nThank you" {
+				t.Fatalf("ISO 20022 details were not kept: %+v", payment)
+			}
+			assertFloat(t, "amount", payment.Amount, 100)
+			assertFloat(t, "exchange rate", payment.ExchangeRate, 0.9)
+			assertFloat(t, "A1 balance", f.account("A1").Balance, 900)
+			assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+		})
+	}
+}
+
+func TestSchemaViolationDetails(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	message := strings.NewReplacer("<ChrgBr>SHAR</ChrgBr>", "<ChrgBr>OUR</ChrgBr>", "<MsgId>MSG-1</MsgId>", "").Replace(pacs008Message)
+
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentFromPacs008(ctx, message)
+		return err
+	})
+	failed, ok := contracterrors.From(err).(*contracterrors.Error).Details.(validation.Errors)
+	if !ok || len(failed) != 2 {
+		t.Fatalf("details = %#v, want both failures", contracterrors.From(err).(*contracterrors.Error).Details)
+	}
+	if failed[0].Field != "GrpHdr/MsgId" || failed[1].Field != "CdtTrfTxInf[0]/ChrgBr" || failed[1].Rule != iso20022.RuleSchema {
+		t.Fatalf("unexpected failures %v", failed)
+	}
+}
+
+func TestExportPaymentPacs008(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+
+	var message string
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		message, err = f.payments.ExportPaymentPacs008(ctx, "P1")
+		return err
+	})
+	checkErr(t, err, "")
+
+	doc, err := iso20022.ParsePacs008([]byte(message))
+	if err != nil {
+		t.Fatalf("exported message is invalid: %v\n%s", err, message)
+	}
+	tx := doc.CreditTransfer.Transactions[0]
+	if tx.PaymentID.InstructionID != "P1" || tx.PaymentID.EndToEndID != iso20022.NotProvided || tx.PaymentID.UETR != derivedUETR("P1") {
+		t.Fatalf("unexpected references %+v", tx.PaymentID)
+	}
+	if tx.InstructedAmount == nil || tx.InstructedAmount.Currency != "USD" || tx.InstructedAmount.Value != "100" ||
+		tx.InterbankSettlementAmount.Currency != "EUR" || tx.InterbankSettlementAmount.Value != "90" || tx.ExchangeRate != "0.9" {
+		t.Fatalf("unexpected amounts %+v %+v %s", tx.InstructedAmount, tx.InterbankSettlementAmount, tx.ExchangeRate)
+	}
+	if tx.Debtor.Name != "Alice Smith" || tx.CreditorAgent.FinancialInstitution.Other.ID != "BANK2" || tx.InterbankSettlementDate != "2024-01-01" {
+		t.Fatalf("unexpected parties %+v", tx)
+	}
+
+	// A message for a payment already on the ledger is a duplicate
+	err = f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentFromPacs008(ctx, message)
+		return err
+	})
+	checkCode(t, err, contracterrors.AlreadyExists)
+}
+
+func TestRemittanceLines(t *testing.T) {
+	lines := remittanceLines("short\n" + strings.Repeat("x", 150))
+	if len(lines) != 3 || lines[0] != "short" || len(lines[1]) != maxRemittanceLine || len(lines[2]) != 10 {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
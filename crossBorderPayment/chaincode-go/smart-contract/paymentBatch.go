@@ -0,0 +1,337 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const paymentBatchObjectType = "PaymentBatch"
+
+// Batch execution modes
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best-effort"
+)
+
+// Batch and batch line statuses
+const (
+	BatchStatusCompleted = "completed"
+	BatchStatusPartial   = "partial"
+	BatchStatusRejected  = "rejected"
+
+	BatchLineSettled = "settled"
+	BatchLineHeld    = "held"
+	BatchLineFailed  = "failed"
+	BatchLineSkipped = "skipped"
+)
+
+// PaymentInstruction is one line of a payment batch
+type PaymentInstruction struct {
+	PaymentID          string  `json:"paymentID"`
+	SenderCustomerID   string  `json:"senderCustomerID"`
+	ReceiverCustomerID string  `json:"receiverCustomerID"`
+	SenderAccountID    string  `json:"senderAccountID"`
+	ReceiverAccountID  string  `json:"receiverAccountID"`
+	Amount             float64 `json:"amount"`
+	ExchangeRate       float64 `json:"exchangeRate"`
+	Date               string  `json:"date"`
+	BeneficiaryID      string  `json:"beneficiaryID,omitempty" metadata:",optional"`
+
+	RemittanceInfo    string   `json:"remittanceInfo,omitempty" metadata:",optional"`
+	CreditorReference string   `json:"creditorReference,omitempty" metadata:",optional"`
+	InvoiceNumbers    []string `json:"invoiceNumbers,omitempty" metadata:",optional"`
+	PurposeCode       string   `json:"purposeCode,omitempty" metadata:",optional"`
+}
+
+// remittance returns the remittance information of the instruction
+func (line *PaymentInstruction) remittance() Remittance {
+	return Remittance{
+		RemittanceInfo:    line.RemittanceInfo,
+		CreditorReference: line.CreditorReference,
+		InvoiceNumbers:    line.InvoiceNumbers,
+		PurposeCode:       line.PurposeCode,
+	}
+}
+
+// BatchLineResult reports what happened to one instruction of a batch
+type BatchLineResult struct {
+	Line      int    `json:"line"`
+	PaymentID string `json:"paymentID"`
+	Status    string `json:"status"`
+	Code      string `json:"code,omitempty" metadata:",optional"`
+	Error     string `json:"error,omitempty" metadata:",optional"`
+}
+
+// PaymentBatch is the stored report of a CreatePaymentBatch call
+type PaymentBatch struct {
+	BatchID     string            `json:"batchID"`
+	Mode        string            `json:"mode"`
+	Status      string            `json:"status"`
+	SubmittedBy string            `json:"submittedBy"`
+	Date        string            `json:"date"`
+	Settled     int               `json:"settled"`
+	Held        int               `json:"held"`
+	Failed      int               `json:"failed"`
+	Results     []BatchLineResult `json:"results"`
+}
+
+// CreatePaymentBatch executes a JSON array of payment instructions in one
+// transaction. In atomic mode nothing settles unless every line is valid; in
+// best-effort mode the valid lines settle and the others are reported as failed.
+// Lines that hit the watchlist are held for review rather than settled. The batch
+// report is stored under batchID either way.
+func (s *PaymentContract) CreatePaymentBatch(ctx contractapi.TransactionContextInterface, batchID string, instructions string, mode string) (*PaymentBatch, error) {
+	err := checkArgs(
+		validation.ID("batchID", batchID),
+		validation.OneOf("mode", mode, BatchModeAtomic, BatchModeBestEffort),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	batchKey, err := ctx.GetStub().CreateCompositeKey(paymentBatchObjectType, []string{batchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch key: %v", err)
+	}
+	existing, err := ctx.GetStub().GetState(batchKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %v", err)
+	}
+	if existing != nil {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "payment batch %s already exists", batchID)
+	}
+
+	var lines []PaymentInstruction
+	err = json.Unmarshal([]byte(instructions), &lines)
+	if err != nil {
+		return nil, contracterrors.New(contracterrors.Validation, "failed to unmarshal payment instructions: %v", err)
+	}
+	if len(lines) == 0 {
+		return nil, contracterrors.New(contracterrors.Validation, "payment batch %s has no instructions", batchID)
+	}
+
+	submittedBy, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := PaymentBatch{
+		BatchID:     batchID,
+		Mode:        mode,
+		SubmittedBy: submittedBy,
+		Date:        date,
+		Results:     []BatchLineResult{},
+	}
+
+	ledger := newPaymentLedger(ctx)
+	seen := map[string]bool{}
+	breaches := []*LimitBreach{}
+	for i, line := range lines {
+		result := BatchLineResult{Line: i + 1, PaymentID: line.PaymentID, Status: BatchLineSettled}
+
+		err = validatePaymentInstruction(ctx, ledger, seen, &line)
+		if err == nil {
+			payment := Payment{
+				PaymentID:          line.PaymentID,
+				SenderCustomerID:   line.SenderCustomerID,
+				ReceiverCustomerID: line.ReceiverCustomerID,
+				SenderAccountID:    line.SenderAccountID,
+				ReceiverAccountID:  line.ReceiverAccountID,
+				Amount:             line.Amount,
+				ExchangeRate:       line.ExchangeRate,
+				Date:               line.Date,
+				BatchID:            batchID,
+				BeneficiaryID:      line.BeneficiaryID,
+				SchemaVersion:      SchemaVersion,
+			}
+			line.remittance().applyTo(&payment)
+			if payment.Date == "" {
+				payment.Date = date
+			}
+
+			var hits []string
+			hits, err = screenPayment(ctx, ledger, &payment)
+			if err == nil && len(hits) > 0 {
+				ledger.hold(&payment, hits)
+				result.Status = BatchLineHeld
+			} else if err == nil {
+				err = ledger.clear(&payment)
+				if breach, ok := err.(*LimitBreach); ok {
+					breaches = append(breaches, breach)
+					err = limitExceeded(breach)
+				}
+			}
+		}
+		switch {
+		case err != nil:
+			result.Status = BatchLineFailed
+			result.Code = string(contracterrors.CodeOf(err))
+			result.Error = contracterrors.Message(err)
+			batch.Failed++
+		case result.Status == BatchLineHeld:
+			seen[line.PaymentID] = true
+			batch.Held++
+		default:
+			seen[line.PaymentID] = true
+			batch.Settled++
+		}
+
+		batch.Results = append(batch.Results, result)
+	}
+
+	switch {
+	case batch.Failed == 0:
+		batch.Status = BatchStatusCompleted
+	case mode == BatchModeAtomic || batch.Settled+batch.Held == 0:
+		batch.Status = BatchStatusRejected
+	default:
+		batch.Status = BatchStatusPartial
+	}
+
+	if batch.Status == BatchStatusRejected {
+		// Lines that passed validation are reported as skipped, not settled
+		for i := range batch.Results {
+			if batch.Results[i].Status != BatchLineFailed {
+				batch.Results[i].Status = BatchLineSkipped
+			}
+		}
+		batch.Settled = 0
+		batch.Held = 0
+	} else {
+		err = ledger.flush()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = setLimitBreachEvent(ctx, breaches)
+	if err != nil {
+		return nil, err
+	}
+
+	batchJSON, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch JSON: %v", err)
+	}
+	err = ctx.GetStub().PutState(batchKey, batchJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put batch state: %v", err)
+	}
+
+	return &batch, nil
+}
+
+// QueryPaymentBatch returns the report stored by CreatePaymentBatch
+func (s *PaymentContract) QueryPaymentBatch(ctx contractapi.TransactionContextInterface, batchID string) (*PaymentBatch, error) {
+	err := checkArgs(validation.ID("batchID", batchID))
+	if err != nil {
+		return nil, err
+	}
+
+	batchKey, err := ctx.GetStub().CreateCompositeKey(paymentBatchObjectType, []string{batchID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch key: %v", err)
+	}
+	batchJSON, err := ctx.GetStub().GetState(batchKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %v", err)
+	}
+	if batchJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "payment batch %s does not exist", batchID)
+	}
+
+	var batch PaymentBatch
+	err = json.Unmarshal(batchJSON, &batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch JSON: %v", err)
+	}
+
+	return &batch, nil
+}
+
+// validatePaymentInstruction checks a batch line against the ledger as already
+// changed by the earlier lines of the same batch. A line paying a beneficiary
+// gets the receiver IDs filled in from it.
+func validatePaymentInstruction(ctx contractapi.TransactionContextInterface, ledger *paymentLedger, seen map[string]bool, line *PaymentInstruction) error {
+	if line.BeneficiaryID != "" {
+		err := checkArgs(
+			validation.ID("senderCustomerID", line.SenderCustomerID),
+			validation.ID("beneficiaryID", line.BeneficiaryID),
+		)
+		if err != nil {
+			return err
+		}
+		line.ReceiverAccountID, line.ReceiverCustomerID, err = ledger.beneficiaryReceiver(line.SenderCustomerID, line.BeneficiaryID, line.ReceiverAccountID, line.ReceiverCustomerID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := checkArgs(
+		validation.ID("paymentID", line.PaymentID),
+		validation.ID("senderAccountID", line.SenderAccountID),
+		validation.ID("receiverAccountID", line.ReceiverAccountID),
+		validation.ID("senderCustomerID", line.SenderCustomerID),
+		validation.ID("receiverCustomerID", line.ReceiverCustomerID),
+		validation.PositiveAmount("amount", line.Amount),
+		validation.PositiveAmount("exchangeRate", line.ExchangeRate),
+		validation.DistinctAccounts("receiverAccountID", line.SenderAccountID, line.ReceiverAccountID),
+	)
+	if err != nil {
+		return err
+	}
+	if seen[line.PaymentID] {
+		return contracterrors.New(contracterrors.Validation, "payment %s appears more than once in the batch", line.PaymentID)
+	}
+	paymentJSON, err := ctx.GetStub().GetState(line.PaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to read payment state: %v", err)
+	}
+	if paymentJSON != nil {
+		return contracterrors.New(contracterrors.AlreadyExists, "payment %s already exists", line.PaymentID)
+	}
+
+	accounts := &Payment{
+		SenderAccountID:    line.SenderAccountID,
+		ReceiverAccountID:  line.ReceiverAccountID,
+		SenderCustomerID:   line.SenderCustomerID,
+		ReceiverCustomerID: line.ReceiverCustomerID,
+	}
+	line.remittance().applyTo(accounts)
+	err = ledger.checkOwners(accounts)
+	if err != nil {
+		return err
+	}
+	signatures, err := ledger.requiredSignatures(accounts)
+	if err != nil {
+		return err
+	}
+	if signatures > 1 {
+		return contracterrors.New(contracterrors.Validation, "account %s requires %d signatures, send its payments with CreatePayment", line.SenderAccountID, signatures)
+	}
+	err = ledger.checkRemittance(accounts)
+	if err != nil {
+		return err
+	}
+	err = ledger.checkAccountStatus(accounts)
+	if err != nil {
+		return err
+	}
+	err = ledger.checkKYC(accounts)
+	if err != nil {
+		return err
+	}
+	return ledger.checkRates(accounts)
+}
@@ -0,0 +1,143 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+func batchJSON(t *testing.T, lines ...PaymentInstruction) string {
+	t.Helper()
+	instructions, err := json.Marshal(lines)
+	if err != nil {
+		t.Fatalf("failed to marshal instructions: %v", err)
+	}
+	return string(instructions)
+}
+
+func TestCreatePaymentBatch(t *testing.T) {
+	valid := PaymentInstruction{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 600, ExchangeRate: 0.5}
+	overdraft := valid
+	overdraft.PaymentID = "P3"
+	wrongOwner := valid
+	wrongOwner.PaymentID = "P4"
+	wrongOwner.SenderCustomerID = "C2"
+
+	tests := []struct {
+		name        string
+		mode        string
+		lines       []PaymentInstruction
+		raw         string
+		wantStatus  string
+		wantLines   []string
+		wantBalance float64
+		wantCode    contracterrors.Code
+		wantErr     string
+	}{
+		{name: "atomic, all valid", mode: BatchModeAtomic, lines: []PaymentInstruction{valid}, wantStatus: BatchStatusCompleted, wantLines: []string{BatchLineSettled}, wantBalance: 400},
+		{name: "atomic, one overdraws", mode: BatchModeAtomic, lines: []PaymentInstruction{valid, overdraft}, wantStatus: BatchStatusRejected, wantLines: []string{BatchLineSkipped, BatchLineFailed}, wantBalance: 1000, wantCode: contracterrors.InsufficientFunds},
+		{name: "best effort, one overdraws", mode: BatchModeBestEffort, lines: []PaymentInstruction{valid, overdraft}, wantStatus: BatchStatusPartial, wantLines: []string{BatchLineSettled, BatchLineFailed}, wantBalance: 400},
+		{name: "best effort, nothing valid", mode: BatchModeBestEffort, lines: []PaymentInstruction{wrongOwner}, wantStatus: BatchStatusRejected, wantLines: []string{BatchLineFailed}, wantBalance: 1000, wantCode: contracterrors.Validation},
+		{name: "duplicate payment ID", mode: BatchModeBestEffort, lines: []PaymentInstruction{valid, valid}, wantStatus: BatchStatusPartial, wantLines: []string{BatchLineSettled, BatchLineFailed}, wantBalance: 400},
+		{name: "unknown mode", mode: "sometimes", lines: []PaymentInstruction{valid}, wantErr: `invalid mode "sometimes"`},
+		{name: "empty batch", mode: BatchModeAtomic, lines: []PaymentInstruction{}, wantErr: "has no instructions"},
+		{name: "malformed instructions", mode: BatchModeAtomic, raw: `[{"paymentID": 1}]`, wantErr: "failed to unmarshal payment instructions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+
+			instructions := tt.raw
+			if instructions == "" {
+				instructions = batchJSON(t, tt.lines...)
+			}
+			var batch *PaymentBatch
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				batch, err = f.payments.CreatePaymentBatch(ctx, "B1", instructions, tt.mode)
+				return err
+			})
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				checkCode(t, err, contracterrors.Validation)
+				return
+			}
+
+			if batch.Status != tt.wantStatus {
+				t.Fatalf("batch status = %s, want %s", batch.Status, tt.wantStatus)
+			}
+			for i, want := range tt.wantLines {
+				if batch.Results[i].Status != want {
+					t.Fatalf("line %d status = %s (%s), want %s", i+1, batch.Results[i].Status, batch.Results[i].Error, want)
+				}
+				if failed := want == BatchLineFailed; failed != (batch.Results[i].Code != "") || failed && tt.wantCode != "" && batch.Results[i].Code != string(tt.wantCode) {
+					t.Fatalf("line %d code = %q, want %q", i+1, batch.Results[i].Code, tt.wantCode)
+				}
+			}
+			assertFloat(t, "A1 balance", f.account("A1").Balance, tt.wantBalance)
+
+			var stored *PaymentBatch
+			err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				stored, err = f.payments.QueryPaymentBatch(ctx, "B1")
+				return err
+			})
+			checkErr(t, err, "")
+			if stored.Status != batch.Status || len(stored.Results) != len(batch.Results) {
+				t.Fatalf("stored batch %+v does not match returned batch %+v", stored, batch)
+			}
+		})
+	}
+}
+
+func TestCreatePaymentBatchAppliesLinesInOrder(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	lines := []PaymentInstruction{
+		{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 300, ExchangeRate: 0.9},
+		{PaymentID: "P2", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 300, ExchangeRate: 0.9},
+		{PaymentID: "P3", SenderAccountID: "A2", ReceiverAccountID: "A1", SenderCustomerID: "C2", ReceiverCustomerID: "C1", Amount: 100, ExchangeRate: 1.1},
+	}
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentBatch(ctx, "B1", batchJSON(t, lines...), BatchModeAtomic)
+		return err
+	})
+
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000-600+110)
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 500+540-100)
+	assertFloat(t, "BANK1 reserves", f.bank("BANK1").Reserves, 10000-600+110)
+	assertFloat(t, "BANK2 reserves", f.bank("BANK2").Reserves, 5000+540-100)
+	if payments := f.account("A1").PaymentIDs; len(payments) != 3 {
+		t.Fatalf("account A1 lists %d payments, want 3", len(payments))
+	}
+}
+
+func TestCreatePaymentBatchDuplicateBatchID(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	line := PaymentInstruction{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 1, ExchangeRate: 1}
+
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentBatch(ctx, "B1", batchJSON(t, line), BatchModeAtomic)
+		return err
+	})
+	line.PaymentID = "P2"
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentBatch(ctx, "B1", batchJSON(t, line), BatchModeAtomic)
+		return err
+	})
+	checkErr(t, err, "payment batch B1 already exists")
+
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.QueryPaymentBatch(ctx, "NOBATCH")
+		return err
+	})
+	checkErr(t, err, "does not exist")
+}
@@ -0,0 +1,360 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// paymentLedger buffers the accounts and banks touched by one or more payments
+// so they can be applied within a single transaction. GetState does not return
+// the transaction's own pending writes, so every payment in a transaction has to
+// work on the same in-memory copy and write it back once.
+type paymentLedger struct {
+	ctx           contractapi.TransactionContextInterface
+	accounts      map[string]*Account
+	banks         map[string]*Bank
+	counters      map[string]*LimitCounter
+	beneficiaries map[string]*Beneficiary
+	payments      []*Payment
+	config        *ChaincodeConfig
+}
+
+func newPaymentLedger(ctx contractapi.TransactionContextInterface) *paymentLedger {
+	return &paymentLedger{
+		ctx:           ctx,
+		accounts:      map[string]*Account{},
+		banks:         map[string]*Bank{},
+		counters:      map[string]*LimitCounter{},
+		beneficiaries: map[string]*Beneficiary{},
+	}
+}
+
+func (l *paymentLedger) account(accountID string) (*Account, error) {
+	if account, ok := l.accounts[accountID]; ok {
+		return account, nil
+	}
+
+	accountJSON, err := l.ctx.GetStub().GetState(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account state: %v", err)
+	}
+	if accountJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
+	}
+
+	var account Account
+	err = unmarshalAccount(accountJSON, &account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal account JSON: %v", err)
+	}
+
+	l.accounts[accountID] = &account
+	return &account, nil
+}
+
+func (l *paymentLedger) bank(bankID string) (*Bank, error) {
+	if bank, ok := l.banks[bankID]; ok {
+		return bank, nil
+	}
+
+	bankJSON, err := l.ctx.GetStub().GetState(bankID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bank state: %v", err)
+	}
+	if bankJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "bank %s does not exist", bankID)
+	}
+
+	var bank Bank
+	err = unmarshalBank(bankJSON, &bank)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bank JSON: %v", err)
+	}
+
+	l.banks[bankID] = &bank
+	return &bank, nil
+}
+
+// chaincodeConfig reads the config once per transaction
+func (l *paymentLedger) chaincodeConfig() (*ChaincodeConfig, error) {
+	if l.config == nil {
+		config, err := getConfig(l.ctx)
+		if err != nil {
+			return nil, err
+		}
+		l.config = config
+	}
+	return l.config, nil
+}
+
+// checkOwners refuses the payment unless the customer the payment names for
+// each account is one of its holders
+func (l *paymentLedger) checkOwners(payment *Payment) error {
+	owners := [][2]string{
+		{payment.SenderAccountID, payment.SenderCustomerID},
+		{payment.ReceiverAccountID, payment.ReceiverCustomerID},
+	}
+	for _, owner := range owners {
+		account, err := l.account(owner[0])
+		if err != nil {
+			return err
+		}
+		if !isAccountHolder(account, owner[1]) {
+			return contracterrors.New(contracterrors.Validation, "account %s does not belong to customer %s", account.AccountID, owner[1])
+		}
+	}
+	return nil
+}
+
+// checkRates returns a RateStale error if the payment converts between
+// currencies and the exchange rate of either bank is older than the config
+// allows
+func (l *paymentLedger) checkRates(payment *Payment) error {
+	sender, err := l.account(payment.SenderAccountID)
+	if err != nil {
+		return err
+	}
+	receiver, err := l.account(payment.ReceiverAccountID)
+	if err != nil {
+		return err
+	}
+	if sender.Currency == receiver.Currency {
+		return nil
+	}
+
+	config, err := l.chaincodeConfig()
+	if err != nil {
+		return err
+	}
+	now, err := getTxTime(l.ctx)
+	if err != nil {
+		return err
+	}
+	for _, bankID := range []string{sender.BankID, receiver.BankID} {
+		bank, err := l.bank(bankID)
+		if err != nil {
+			return err
+		}
+		err = checkRateAge(config, bank, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply queues the payment and moves its amount between the two accounts and
+// their banks' reserves, charges the sender the fee and counts the amount
+// against the sender's limits. Nothing is changed unless both accounts and both
+// banks can be loaded and the sender can cover the amount and the fee, and
+// nothing reaches the world state until flush is called.
+func (l *paymentLedger) apply(payment *Payment) error {
+	config, err := l.chaincodeConfig()
+	if err != nil {
+		return err
+	}
+	payment.Fee = config.Fee(payment.Amount)
+	sender, receiver, err := l.fundedAccounts(payment)
+	if err != nil {
+		return err
+	}
+	err = l.recordLimitUsage(sender, payment.Amount)
+	if err != nil {
+		return err
+	}
+	err = l.recordBeneficiaryUsage(payment)
+	if err != nil {
+		return err
+	}
+	l.move(payment, sender, receiver)
+	return nil
+}
+
+// sweep moves money between two accounts like apply, but without a fee and
+// without counting it against the sender's limits: a sweep is the bank
+// emptying an account it closes, not the customer paying.
+func (l *paymentLedger) sweep(payment *Payment) error {
+	sender, receiver, err := l.fundedAccounts(payment)
+	if err != nil {
+		return err
+	}
+	l.move(payment, sender, receiver)
+	return nil
+}
+
+// fundedAccounts loads the accounts of a payment and their banks, and checks
+// that the sender can cover the amount and the fee
+func (l *paymentLedger) fundedAccounts(payment *Payment) (*Account, *Account, error) {
+	sender, err := l.account(payment.SenderAccountID)
+	if err != nil {
+		return nil, nil, contracterrors.Wrap(err, "failed to update sender's account balance")
+	}
+	receiver, err := l.account(payment.ReceiverAccountID)
+	if err != nil {
+		return nil, nil, contracterrors.Wrap(err, "failed to update receiver's account balance")
+	}
+	if _, err = l.bank(sender.BankID); err != nil {
+		return nil, nil, contracterrors.Wrap(err, "failed to update bank reserves")
+	}
+	if _, err = l.bank(receiver.BankID); err != nil {
+		return nil, nil, contracterrors.Wrap(err, "failed to update bank reserves")
+	}
+	if sender.Balance < payment.Amount+payment.Fee {
+		if payment.Fee != 0 {
+			return nil, nil, contracterrors.New(contracterrors.InsufficientFunds, "insufficient funds in account %s: balance %v, amount %v, fee %v", sender.AccountID, sender.Balance, payment.Amount, payment.Fee)
+		}
+		return nil, nil, contracterrors.New(contracterrors.InsufficientFunds, "insufficient funds in account %s: balance %v, amount %v", sender.AccountID, sender.Balance, payment.Amount)
+	}
+	return sender, receiver, nil
+}
+
+// move queues the payment as settled and updates the balances and reserves.
+// The fee leaves the sender's account but stays in its bank's reserves.
+func (l *paymentLedger) move(payment *Payment, sender *Account, receiver *Account) {
+	payment.Status = PaymentStatusSettled
+	l.payments = append(l.payments, payment)
+	l.updateAccountBalance(sender, -payment.Amount, 1, payment.PaymentID)
+	l.updateAccountBalance(receiver, payment.Amount, payment.ExchangeRate, payment.PaymentID)
+	sender.Balance -= payment.Fee
+}
+
+// clear checks a payment that passed screening against the sender's tier
+// limits and beneficiary limits and applies it. A breach of the tier limits is
+// returned as the *LimitBreach itself so the caller can report it.
+func (l *paymentLedger) clear(payment *Payment) error {
+	err := l.checkLimits(payment)
+	if err != nil {
+		return err
+	}
+	err = l.checkBeneficiaryLimits(payment)
+	if err != nil {
+		return err
+	}
+	return l.apply(payment)
+}
+
+// hold queues the payment for compliance review without moving any funds
+func (l *paymentLedger) hold(payment *Payment, hits []string) {
+	payment.Status = PaymentStatusHeld
+	payment.ScreeningHits = hits
+	l.payments = append(l.payments, payment)
+}
+
+func (l *paymentLedger) updateAccountBalance(account *Account, amount float64, exchangeRate float64, paymentID string) {
+	// Convert the amount to the account's currency
+	convertedAmount := amount * exchangeRate
+	account.Balance += convertedAmount
+	account.PaymentIDs = append(account.PaymentIDs, paymentID)
+	l.banks[account.BankID].Reserves += convertedAmount
+}
+
+// flush writes the queued payments and every buffered account, limit counter,
+// beneficiary and bank to the world state. Keys are written in sorted order so
+// the outcome never depends on map iteration.
+func (l *paymentLedger) flush() error {
+	for _, payment := range l.payments {
+		paymentJSON, err := json.Marshal(payment)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payment JSON: %v", err)
+		}
+		err = l.ctx.GetStub().PutState(payment.PaymentID, paymentJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put payment state: %v", err)
+		}
+
+		if payment.Status == PaymentStatusHeld {
+			heldKey, err := heldPaymentKey(l.ctx, payment.PaymentID)
+			if err != nil {
+				return err
+			}
+			err = l.ctx.GetStub().PutState(heldKey, []byte{0x00})
+			if err != nil {
+				return fmt.Errorf("failed to put held payment index: %v", err)
+			}
+		}
+		if payment.Status == PaymentStatusPendingApproval {
+			approvalKey, err := paymentApprovalKey(l.ctx, payment.SenderAccountID, payment.PaymentID)
+			if err != nil {
+				return err
+			}
+			err = l.ctx.GetStub().PutState(approvalKey, []byte{0x00})
+			if err != nil {
+				return fmt.Errorf("failed to put payment approval index: %v", err)
+			}
+		}
+	}
+
+	accountIDs := make([]string, 0, len(l.accounts))
+	for accountID := range l.accounts {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Strings(accountIDs)
+	for _, accountID := range accountIDs {
+		accountJSON, err := json.Marshal(l.accounts[accountID])
+		if err != nil {
+			return fmt.Errorf("failed to marshal account JSON: %v", err)
+		}
+		err = l.ctx.GetStub().PutState(accountID, accountJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put account state: %v", err)
+		}
+	}
+
+	counterKeys := make([]string, 0, len(l.counters))
+	for key := range l.counters {
+		counterKeys = append(counterKeys, key)
+	}
+	sort.Strings(counterKeys)
+	for _, key := range counterKeys {
+		counterJSON, err := json.Marshal(l.counters[key])
+		if err != nil {
+			return fmt.Errorf("failed to marshal limit counter: %v", err)
+		}
+		err = l.ctx.GetStub().PutState(key, counterJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put limit counter: %v", err)
+		}
+	}
+
+	beneficiaryKeys := make([]string, 0, len(l.beneficiaries))
+	for key := range l.beneficiaries {
+		beneficiaryKeys = append(beneficiaryKeys, key)
+	}
+	sort.Strings(beneficiaryKeys)
+	for _, key := range beneficiaryKeys {
+		beneficiaryJSON, err := json.Marshal(l.beneficiaries[key])
+		if err != nil {
+			return fmt.Errorf("failed to marshal beneficiary JSON: %v", err)
+		}
+		err = l.ctx.GetStub().PutState(key, beneficiaryJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put beneficiary state: %v", err)
+		}
+	}
+
+	bankIDs := make([]string, 0, len(l.banks))
+	for bankID := range l.banks {
+		bankIDs = append(bankIDs, bankID)
+	}
+	sort.Strings(bankIDs)
+	for _, bankID := range bankIDs {
+		bankJSON, err := json.Marshal(l.banks[bankID])
+		if err != nil {
+			return fmt.Errorf("failed to marshal bank JSON: %v", err)
+		}
+		err = l.ctx.GetStub().PutState(bankID, bankJSON)
+		if err != nil {
+			return fmt.Errorf("failed to put bank state: %v", err)
+		}
+	}
+
+	return nil
+}
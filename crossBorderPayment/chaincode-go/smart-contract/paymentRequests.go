@@ -0,0 +1,476 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const (
+	paymentRequestObjectType        = "PaymentRequest"
+	pendingPaymentRequestObjectType = "PendingPaymentRequest"
+)
+
+// Payment request statuses. A pending request past its expiry date is
+// expired; it is never accepted, so the status is worked out when it is read.
+// A request is processing while the payment made for it is held by screening
+// or waits for the payer's joint holders to approve it.
+const (
+	PaymentRequestPending    = "pending"
+	PaymentRequestProcessing = "processing"
+	PaymentRequestAccepted   = "accepted"
+	PaymentRequestDeclined   = "declined"
+	PaymentRequestExpired    = "expired"
+)
+
+// paymentRequestDateLayout is the layout of due and expiry dates
+const paymentRequestDateLayout = "2006-01-02"
+
+// PaymentRequest asks a payer customer to pay an amount into the payee's
+// account. The amount is in the currency of that account.
+type PaymentRequest struct {
+	RequestID       string  `json:"requestID"`
+	PayeeCustomerID string  `json:"payeeCustomerID"`
+	PayeeAccountID  string  `json:"payeeAccountID"`
+	PayerCustomerID string  `json:"payerCustomerID"`
+	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency"`
+	DueDate         string  `json:"dueDate"`
+	ExpiryDate      string  `json:"expiryDate"`
+	Reference       string  `json:"reference"`
+	Status          string  `json:"status"`
+	Date            string  `json:"date"`
+
+	PurposeCode   string `json:"purposeCode,omitempty" metadata:",optional"`
+	StatusDate    string `json:"statusDate,omitempty" metadata:",optional"`
+	PaymentID     string `json:"paymentID,omitempty" metadata:",optional"`
+	DeclineReason string `json:"declineReason,omitempty" metadata:",optional"`
+}
+
+// CreatePaymentRequest asks payerCustomerID to pay amount into payeeAccountID
+// by dueDate. The request can be accepted until expiryDate, which defaults to
+// the due date. Dates are YYYY-MM-DD and currency must be that of the account.
+// The payment made for the request carries reference as its remittance
+// information and purposeCode, which may be empty, as its purpose. Only the
+// client enrolled for the account's holder, the payee, may request payment.
+func (s *PaymentContract) CreatePaymentRequest(ctx contractapi.TransactionContextInterface, requestID string, payeeAccountID string, payerCustomerID string, amount float64, currency string, dueDate string, expiryDate string, reference string, purposeCode string) (*PaymentRequest, error) {
+	if expiryDate == "" {
+		expiryDate = dueDate
+	}
+	results := []error{
+		validation.ID("requestID", requestID),
+		validation.ID("payeeAccountID", payeeAccountID),
+		validation.ID("payerCustomerID", payerCustomerID),
+		validation.PositiveAmount("amount", amount),
+		validation.Currency("currency", currency),
+		validation.Required("reference", reference),
+		validation.MaxLength("reference", reference, maxRemittanceInfo),
+	}
+	if purposeCode != "" {
+		results = append(results, validation.PurposeCode("purposeCode", purposeCode))
+	}
+	err := checkArgs(results...)
+	if err != nil {
+		return nil, err
+	}
+	for _, date := range []string{dueDate, expiryDate} {
+		if _, err := time.Parse(paymentRequestDateLayout, date); err != nil {
+			return nil, contracterrors.New(contracterrors.Validation, "invalid payment request date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if expiryDate < dueDate {
+		return nil, contracterrors.New(contracterrors.Validation, "expiry date %s is before due date %s", expiryDate, dueDate)
+	}
+	now, err := getTxDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if expiryDate < now[:len(paymentRequestDateLayout)] {
+		return nil, contracterrors.New(contracterrors.Validation, "expiry date %s is in the past", expiryDate)
+	}
+
+	key, err := paymentRequestKey(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment request state: %v", err)
+	}
+	if existing != nil {
+		return nil, contracterrors.New(contracterrors.AlreadyExists, "payment request %s already exists", requestID)
+	}
+
+	account, err := getAccount(ctx, payeeAccountID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = getCustomerForClient(ctx, account.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAccountCanTransact(account)
+	if err != nil {
+		return nil, err
+	}
+	if currency != account.Currency {
+		return nil, contracterrors.New(contracterrors.Validation, "account %s is in %s, not %s", payeeAccountID, account.Currency, currency)
+	}
+	if isAccountHolder(account, payerCustomerID) {
+		return nil, contracterrors.New(contracterrors.Validation, "customer %s cannot request a payment from themselves", payerCustomerID)
+	}
+	_, err = getCustomer(ctx, payerCustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if purposeCode != "" {
+		err = checkPurposeCodeListed(ctx, "purposeCode", purposeCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	request := &PaymentRequest{
+		RequestID:       requestID,
+		PayeeCustomerID: account.CustomerID,
+		PayeeAccountID:  payeeAccountID,
+		PayerCustomerID: payerCustomerID,
+		Amount:          amount,
+		Currency:        currency,
+		DueDate:         dueDate,
+		ExpiryDate:      expiryDate,
+		Reference:       reference,
+		PurposeCode:     purposeCode,
+		Status:          PaymentRequestPending,
+		Date:            now,
+	}
+	err = putPaymentRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	pendingKey, err := pendingPaymentRequestKey(ctx, payerCustomerID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pendingKey, []byte{0x00})
+	if err != nil {
+		return nil, fmt.Errorf("failed to put pending payment request index: %v", err)
+	}
+	return request, nil
+}
+
+// AcceptPaymentRequest pays a pending request from one of the payer's
+// accounts as payment paymentID, through the client enrolled for the payer.
+// The exchange rate is that of the two accounts' banks, and the payer's
+// account is debited the requested amount divided by it, so that the payee
+// receives the amount requested. The payment goes through the same checks as
+// CreatePayment. The request is accepted once the payment settles; until then
+// it is processing, and it is pending again if the payment is rejected.
+func (s *PaymentContract) AcceptPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string, paymentID string, payerAccountID string) (*Payment, error) {
+	err := checkArgs(
+		validation.ID("paymentID", paymentID),
+		validation.ID("payerAccountID", payerAccountID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	request, err := getPendingPaymentRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = getCustomerForClient(ctx, request.PayerCustomerID)
+	if err != nil {
+		return nil, err
+	}
+	exchangeRate, err := bankExchangeRate(ctx, payerAccountID, request.PayeeAccountID)
+	if err != nil {
+		return nil, err
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payment := &Payment{
+		PaymentID:          paymentID,
+		SenderCustomerID:   request.PayerCustomerID,
+		ReceiverCustomerID: request.PayeeCustomerID,
+		SenderAccountID:    payerAccountID,
+		ReceiverAccountID:  request.PayeeAccountID,
+		Amount:             request.Amount / exchangeRate,
+		ExchangeRate:       exchangeRate,
+		Date:               date,
+		RemittanceInfo:     request.Reference,
+		PurposeCode:        request.PurposeCode,
+		PaymentRequestID:   request.RequestID,
+		SchemaVersion:      SchemaVersion,
+	}
+	err = createPayment(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = PaymentRequestAccepted
+	if payment.Status != PaymentStatusSettled {
+		request.Status = PaymentRequestProcessing
+	}
+	request.StatusDate = date
+	request.PaymentID = paymentID
+	err = closePaymentRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// DeclinePaymentRequest refuses a pending request, through the client
+// enrolled for the payer
+func (s *PaymentContract) DeclinePaymentRequest(ctx contractapi.TransactionContextInterface, requestID string, reason string) error {
+	request, err := getPendingPaymentRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	_, err = getCustomerForClient(ctx, request.PayerCustomerID)
+	if err != nil {
+		return err
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	request.Status = PaymentRequestDeclined
+	request.StatusDate = date
+	request.DeclineReason = reason
+	return closePaymentRequest(ctx, request)
+}
+
+// finishPaymentRequest accepts the processing request a payment was made for
+// once the payment has settled, and makes it pending again if the payment was
+// rejected, so that the payer can answer it anew
+func finishPaymentRequest(ctx contractapi.TransactionContextInterface, payment *Payment) error {
+	if payment.PaymentRequestID == "" {
+		return nil
+	}
+	request, err := getPaymentRequest(ctx, payment.PaymentRequestID)
+	if err != nil {
+		return err
+	}
+	if request.Status != PaymentRequestProcessing || request.PaymentID != payment.PaymentID {
+		return nil
+	}
+	request.StatusDate, err = getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch payment.Status {
+	case PaymentStatusSettled:
+		request.Status = PaymentRequestAccepted
+		return putPaymentRequest(ctx, request)
+	case PaymentStatusRejected:
+		request.Status = PaymentRequestPending
+		request.PaymentID = ""
+		err = putPaymentRequest(ctx, request)
+		if err != nil {
+			return err
+		}
+		pendingKey, err := pendingPaymentRequestKey(ctx, request.PayerCustomerID, request.RequestID)
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(pendingKey, []byte{0x00})
+		if err != nil {
+			return fmt.Errorf("failed to put pending payment request index: %v", err)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// bankExchangeRate converts from the currency of the sender account to that of
+// the receiver account with the exchange rates their banks have set
+func bankExchangeRate(ctx contractapi.TransactionContextInterface, senderAccountID string, receiverAccountID string) (float64, error) {
+	sender, err := getAccount(ctx, senderAccountID)
+	if err != nil {
+		return 0, err
+	}
+	receiver, err := getAccount(ctx, receiverAccountID)
+	if err != nil {
+		return 0, err
+	}
+	if sender.Currency == receiver.Currency {
+		return 1, nil
+	}
+
+	rates := []float64{}
+	for _, bankID := range []string{sender.BankID, receiver.BankID} {
+		bank, err := getBank(ctx, bankID)
+		if err != nil {
+			return 0, err
+		}
+		if bank.ExchangeRate <= 0 {
+			return 0, contracterrors.New(contracterrors.InvalidState, "bank %s has no exchange rate", bankID)
+		}
+		rates = append(rates, bank.ExchangeRate)
+	}
+	return rates[1] / rates[0], nil
+}
+
+// QueryPaymentRequest returns a payment request
+func (s *PaymentContract) QueryPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string) (*PaymentRequest, error) {
+	request, err := getPaymentRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return request, setExpiredStatus(ctx, request)
+}
+
+// QueryPendingPaymentRequests returns the requests a customer has yet to
+// accept or decline, leaving out expired ones
+func (s *PaymentContract) QueryPendingPaymentRequests(ctx contractapi.TransactionContextInterface, customerID string) ([]*PaymentRequest, error) {
+	err := checkArgs(validation.ID("customerID", customerID))
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(pendingPaymentRequestObjectType, []string{customerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending payment requests: %v", err)
+	}
+	defer iterator.Close()
+
+	requests := []*PaymentRequest{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over pending payment requests: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split pending payment request key: %v", err)
+		}
+
+		request, err := getPaymentRequest(ctx, keyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		err = setExpiredStatus(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		if request.Status == PaymentRequestPending {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests, nil
+}
+
+// setExpiredStatus reports a pending request past its expiry date as expired
+func setExpiredStatus(ctx contractapi.TransactionContextInterface, request *PaymentRequest) error {
+	if request.Status != PaymentRequestPending {
+		return nil
+	}
+	now, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	if request.ExpiryDate < now[:len(paymentRequestDateLayout)] {
+		request.Status = PaymentRequestExpired
+	}
+	return nil
+}
+
+// getPendingPaymentRequest loads a request that can still be accepted or
+// declined
+func getPendingPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string) (*PaymentRequest, error) {
+	request, err := getPaymentRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	err = setExpiredStatus(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != PaymentRequestPending {
+		return nil, contracterrors.New(contracterrors.InvalidState, "payment request %s is %s", requestID, request.Status)
+	}
+	return request, nil
+}
+
+// closePaymentRequest stores a request that is no longer pending and removes
+// it from its payer's pending requests
+func closePaymentRequest(ctx contractapi.TransactionContextInterface, request *PaymentRequest) error {
+	err := putPaymentRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+	pendingKey, err := pendingPaymentRequestKey(ctx, request.PayerCustomerID, request.RequestID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(pendingKey)
+}
+
+func paymentRequestKey(ctx contractapi.TransactionContextInterface, requestID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(paymentRequestObjectType, []string{requestID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create payment request key: %v", err)
+	}
+	return key, nil
+}
+
+func pendingPaymentRequestKey(ctx contractapi.TransactionContextInterface, customerID string, requestID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingPaymentRequestObjectType, []string{customerID, requestID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create pending payment request key: %v", err)
+	}
+	return key, nil
+}
+
+func getPaymentRequest(ctx contractapi.TransactionContextInterface, requestID string) (*PaymentRequest, error) {
+	err := checkArgs(validation.ID("requestID", requestID))
+	if err != nil {
+		return nil, err
+	}
+	key, err := paymentRequestKey(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	requestJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment request state: %v", err)
+	}
+	if requestJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "payment request %s does not exist", requestID)
+	}
+
+	var request PaymentRequest
+	err = json.Unmarshal(requestJSON, &request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment request JSON: %v", err)
+	}
+	return &request, nil
+}
+
+func putPaymentRequest(ctx contractapi.TransactionContextInterface, request *PaymentRequest) error {
+	key, err := paymentRequestKey(ctx, request.RequestID)
+	if err != nil {
+		return err
+	}
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment request JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(key, requestJSON)
+}
@@ -0,0 +1,324 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// requestPayment has C2 ask C1 for amount euros into A2
+func (f *fixture) requestPayment(requestID string, amount float64, dueDate string, expiryDate string) error {
+	f.t.Helper()
+	return f.submit(f.clients["C2"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.CreatePaymentRequest(ctx, requestID, "A2", "C1", amount, "EUR", dueDate, expiryDate, "INV-1", "")
+		return err
+	})
+}
+
+func (f *fixture) pendingPaymentRequests(customerID string) []*PaymentRequest {
+	f.t.Helper()
+	var requests []*PaymentRequest
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		requests, err = f.payments.QueryPendingPaymentRequests(ctx, customerID)
+		return err
+	})
+	checkErr(f.t, err, "")
+	return requests
+}
+
+func TestCreatePaymentRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		requestID       string
+		accountID       string
+		payerCustomerID string
+		currency        string
+		dueDate         string
+		expiryDate      string
+		stranger        bool
+		wantCode        contracterrors.Code
+		wantErr         string
+	}{
+		{name: "valid", requestID: "R1", accountID: "A2", payerCustomerID: "C1", currency: "EUR", dueDate: "2024-01-10"},
+		{name: "taken", requestID: "SAVED", accountID: "A2", payerCustomerID: "C1", currency: "EUR", dueDate: "2024-01-10", wantCode: contracterrors.AlreadyExists},
+		{name: "wrong currency", requestID: "R1", accountID: "A2", payerCustomerID: "C1", currency: "USD", dueDate: "2024-01-10", wantCode: contracterrors.Validation, wantErr: "account A2 is in EUR, not USD"},
+		{name: "own account", requestID: "R1", accountID: "A2", payerCustomerID: "C2", currency: "EUR", dueDate: "2024-01-10", wantCode: contracterrors.Validation},
+		{name: "bad date", requestID: "R1", accountID: "A2", payerCustomerID: "C1", currency: "EUR", dueDate: "10/01/2024", wantCode: contracterrors.Validation, wantErr: "expected YYYY-MM-DD"},
+		{name: "expires before due", requestID: "R1", accountID: "A2", payerCustomerID: "C1", currency: "EUR", dueDate: "2024-01-10", expiryDate: "2024-01-05", wantCode: contracterrors.Validation, wantErr: "before due date"},
+		{name: "expired", requestID: "R1", accountID: "A2", payerCustomerID: "C1", currency: "EUR", dueDate: "2023-12-01", wantCode: contracterrors.Validation, wantErr: "in the past"},
+		{name: "unknown payer", requestID: "R1", accountID: "A2", payerCustomerID: "C9", currency: "EUR", dueDate: "2024-01-10", wantCode: contracterrors.NotFound},
+		{name: "unknown account", requestID: "R1", accountID: "A9", payerCustomerID: "C1", currency: "EUR", dueDate: "2024-01-10", wantCode: contracterrors.NotFound},
+		{name: "not the payee", requestID: "R1", accountID: "A2", payerCustomerID: "C1", currency: "EUR", dueDate: "2024-01-10", stranger: true, wantCode: contracterrors.Forbidden, wantErr: "client is not the enrolled identity of customer C2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			checkErr(t, f.requestPayment("SAVED", 10, "2024-01-10", ""), "")
+
+			payee := f.clients["C2"]
+			if tt.stranger {
+				payee = f.anyone
+			}
+			var request *PaymentRequest
+			err := f.submit(payee, func(ctx contractapi.TransactionContextInterface) (err error) {
+				request, err = f.payments.CreatePaymentRequest(ctx, tt.requestID, tt.accountID, tt.payerCustomerID, 90, tt.currency, tt.dueDate, tt.expiryDate, "INV-1", "")
+				return err
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				if tt.wantErr != "" {
+					checkErr(t, err, tt.wantErr)
+				}
+				return
+			}
+			checkErr(t, err, "")
+			if request.Status != PaymentRequestPending || request.PayeeCustomerID != "C2" || request.ExpiryDate != "2024-01-10" {
+				t.Fatalf("unexpected payment request %+v", request)
+			}
+			if pending := f.pendingPaymentRequests("C1"); len(pending) != 2 {
+				t.Fatalf("unexpected pending requests %+v", pending)
+			}
+		})
+	}
+}
+
+func TestAcceptPaymentRequest(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.CreateAccount(ctx, "A3", "C2", "BANK1", 100, "", "", "", "")
+	})
+	checkErr(t, f.requestPayment("R1", 90, "2024-01-10", ""), "")
+
+	err := f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.AcceptPaymentRequest(ctx, "R1", "P1", "A3")
+		return err
+	})
+	checkCode(t, err, contracterrors.Validation)
+	checkErr(t, err, "account A3 does not belong to customer C1")
+
+	var payment *Payment
+	err = f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) (err error) {
+		payment, err = f.payments.AcceptPaymentRequest(ctx, "R1", "P1", "A1")
+		return err
+	})
+	checkErr(t, err, "")
+	assertFloat(t, "amount", payment.Amount, 100)
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 900)
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+
+	committed, err := getCommittedPayment(f, "P1")
+	checkErr(t, err, "")
+	if committed.PaymentRequestID != "R1" || committed.RemittanceInfo != "INV-1" || committed.Status != PaymentStatusSettled {
+		t.Fatalf("unexpected payment %+v", committed)
+	}
+
+	var request *PaymentRequest
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		request, err = f.payments.QueryPaymentRequest(ctx, "R1")
+		return err
+	})
+	checkErr(t, err, "")
+	if request.Status != PaymentRequestAccepted || request.PaymentID != "P1" {
+		t.Fatalf("unexpected payment request %+v", request)
+	}
+	if pending := f.pendingPaymentRequests("C1"); len(pending) != 0 {
+		t.Fatalf("unexpected pending requests %+v", pending)
+	}
+
+	err = f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.AcceptPaymentRequest(ctx, "R1", "P2", "A1")
+		return err
+	})
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "payment request R1 is accepted")
+}
+
+func (f *fixture) paymentRequest(requestID string) *PaymentRequest {
+	f.t.Helper()
+	var request *PaymentRequest
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		request, err = f.payments.QueryPaymentRequest(ctx, requestID)
+		return err
+	})
+	checkErr(f.t, err, "")
+	return request
+}
+
+func TestPaymentRequestWaitsForItsPayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(f *fixture)
+		reject  func(f *fixture, paymentID string) error
+		release func(f *fixture, paymentID string) error
+	}{
+		{
+			name: "held by screening",
+			setup: func(f *fixture) {
+				f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.admin.AddWatchlistEntry(ctx, WatchlistCountry, "DE", "test")
+				})
+			},
+			reject: func(f *fixture, paymentID string) error {
+				return f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.payments.RejectHeldPayment(ctx, paymentID)
+				})
+			},
+			release: func(f *fixture, paymentID string) error {
+				return f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.payments.ReleaseHeldPayment(ctx, paymentID)
+				})
+			},
+		},
+		{
+			name: "awaiting joint approval",
+			setup: func(f *fixture) {
+				f.jointAccount(SigningAll, 0)
+			},
+			reject: func(f *fixture, paymentID string) error {
+				return f.submit(f.clients["C3"], func(ctx contractapi.TransactionContextInterface) error {
+					return f.payments.DeclinePayment(ctx, paymentID, "C3")
+				})
+			},
+			release: func(f *fixture, paymentID string) error {
+				for _, customerID := range []string{"C3", "C4"} {
+					if _, err := f.approvePayment(paymentID, customerID); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			tt.setup(f)
+			checkErr(t, f.requestPayment("R1", 90, "2024-01-10", ""), "")
+			accept := func(paymentID string) {
+				t.Helper()
+				f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+					_, err := f.payments.AcceptPaymentRequest(ctx, "R1", paymentID, "A1")
+					return err
+				})
+			}
+
+			accept("P1")
+			if request := f.paymentRequest("R1"); request.Status != PaymentRequestProcessing || request.PaymentID != "P1" {
+				t.Fatalf("unexpected payment request %+v", request)
+			}
+			if pending := f.pendingPaymentRequests("C1"); len(pending) != 0 {
+				t.Fatalf("unexpected pending requests %+v", pending)
+			}
+
+			// A rejected payment leaves the request to be answered again
+			checkErr(t, tt.reject(f, "P1"), "")
+			if request := f.paymentRequest("R1"); request.Status != PaymentRequestPending || request.PaymentID != "" {
+				t.Fatalf("unexpected payment request %+v", request)
+			}
+			if pending := f.pendingPaymentRequests("C1"); len(pending) != 1 {
+				t.Fatalf("unexpected pending requests %+v", pending)
+			}
+
+			accept("P2")
+			checkErr(t, tt.release(f, "P2"), "")
+			if request := f.paymentRequest("R1"); request.Status != PaymentRequestAccepted || request.PaymentID != "P2" {
+				t.Fatalf("unexpected payment request %+v", request)
+			}
+			assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+		})
+	}
+}
+
+func TestPaymentRequestNeedsThePayer(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	checkErr(t, f.requestPayment("R1", 90, "2024-01-10", ""), "")
+
+	// Neither the payee nor anyone else can answer for C1
+	for _, identity := range []*chaincodetest.Identity{f.clients["C2"], f.anyone} {
+		err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := f.payments.AcceptPaymentRequest(ctx, "R1", "P1", "A1")
+			return err
+		})
+		checkCode(t, err, contracterrors.Forbidden)
+		err = f.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+			return f.payments.DeclinePaymentRequest(ctx, "R1", "")
+		})
+		checkCode(t, err, contracterrors.Forbidden)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+
+	// The rate is the banks' own, so the payee gets what was requested
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.UpdateExchangeRate(ctx, "BANK2", 0.8)
+	})
+	var payment *Payment
+	err := f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) (err error) {
+		payment, err = f.payments.AcceptPaymentRequest(ctx, "R1", "P1", "A1")
+		return err
+	})
+	checkErr(t, err, "")
+	assertFloat(t, "rate", payment.ExchangeRate, 0.8)
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000-112.5)
+	assertFloat(t, "A2 balance", f.account("A2").Balance, 590)
+	if report := f.invariants(); !report.Holds {
+		t.Fatalf("invariants do not hold: %v", report.Violations)
+	}
+}
+
+func TestDeclinePaymentRequest(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	checkErr(t, f.requestPayment("R1", 90, "2024-01-10", ""), "")
+
+	f.mustSubmit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.DeclinePaymentRequest(ctx, "R1", "not ordered")
+	})
+	var request *PaymentRequest
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		request, err = f.payments.QueryPaymentRequest(ctx, "R1")
+		return err
+	})
+	checkErr(t, err, "")
+	if request.Status != PaymentRequestDeclined || request.DeclineReason != "not ordered" {
+		t.Fatalf("unexpected payment request %+v", request)
+	}
+
+	err = f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.payments.AcceptPaymentRequest(ctx, "R1", "P1", "A1")
+		return err
+	})
+	checkCode(t, err, contracterrors.InvalidState)
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+}
+
+func TestPaymentRequestExpires(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	checkErr(t, f.requestPayment("R1", 90, "2024-01-05", "2024-01-10"), "")
+	checkErr(t, f.requestPayment("R2", 90, "2024-01-20", ""), "")
+
+	f.stub.Now = time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+	pending := f.pendingPaymentRequests("C1")
+	if len(pending) != 1 || pending[0].RequestID != "R2" {
+		t.Fatalf("unexpected pending requests %+v", pending)
+	}
+
+	err := f.submit(f.clients["C1"], func(ctx contractapi.TransactionContextInterface) error {
+		return f.payments.DeclinePaymentRequest(ctx, "R1", "")
+	})
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "payment request R1 is expired")
+}
@@ -0,0 +1,355 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const (
+	operationObjectType        = "Operation"
+	pendingOperationObjectType = "PendingOperation"
+)
+
+// defaultOperationExpiry is how long an operation waits for approval when the
+// config does not set OperationExpirySeconds, and maxOperationExpiry bounds
+// that setting at thirty days
+const (
+	defaultOperationExpiry = 24 * 60 * 60
+	maxOperationExpiry     = 30 * 24 * 60 * 60
+)
+
+// Types of the administrative operations that need a second approval
+const (
+	OperationUpdateBankProfile = "update_bank_profile"
+	OperationUpdateBalance     = "update_balance"
+)
+
+// Operation statuses. A pending operation past its expiry time is expired; it
+// is never applied, so the status is worked out when it is read.
+const (
+	OperationPending  = "pending"
+	OperationApproved = "approved"
+	OperationRejected = "rejected"
+	OperationExpired  = "expired"
+)
+
+// BankProfileChange is the new profile of a bank proposed by UpdateBankProfile
+type BankProfileChange struct {
+	BankID   string  `json:"bankID"`
+	Name     string  `json:"name"`
+	Reserves float64 `json:"reserves"`
+	Country  string  `json:"country"`
+}
+
+// BalanceChange is the amount UpdateBalance proposes to credit to an account,
+// or debit when negative
+type BalanceChange struct {
+	AccountID string  `json:"accountID"`
+	Amount    float64 `json:"amount"`
+}
+
+// PendingOperation is a sensitive change proposed by one client that takes
+// effect only once a different client approves it. It stays on the ledger
+// after it is decided as the record of who proposed and who approved or
+// rejected it. The change is in BankProfile or Balance, according to Type.
+type PendingOperation struct {
+	OperationID string `json:"operationID"`
+	Type        string `json:"type"`
+	Status      string `json:"status"`
+	ProposedBy  string `json:"proposedBy"`
+	ProposedAt  string `json:"proposedAt"`
+	ExpiresAt   string `json:"expiresAt"`
+
+	BankProfile *BankProfileChange `json:"bankProfile,omitempty" metadata:",optional"`
+	Balance     *BalanceChange     `json:"balance,omitempty" metadata:",optional"`
+	DecidedBy   string             `json:"decidedBy,omitempty" metadata:",optional"`
+	DecidedAt   string             `json:"decidedAt,omitempty" metadata:",optional"`
+	Reason      string             `json:"reason,omitempty" metadata:",optional"`
+}
+
+// ApproveOperation applies a pending operation and returns it. The approver
+// must be allowed to propose the operation and must not be the client who
+// proposed it. The change is checked again against the current state, so an
+// operation that no longer applies fails and stays pending.
+func (s *AdminContract) ApproveOperation(ctx contractapi.TransactionContextInterface, operationID string) (*PendingOperation, error) {
+	operation, err := getPendingOperation(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	err = requireOperationAuthority(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	clientID, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if clientID == operation.ProposedBy {
+		return nil, contracterrors.New(contracterrors.Forbidden, "operation %s must be approved by a different client than the one who proposed it", operationID)
+	}
+
+	switch operation.Type {
+	case OperationUpdateBankProfile:
+		err = updateBankProfile(ctx, operation.BankProfile)
+	case OperationUpdateBalance:
+		err = updateBalance(ctx, operation.Balance)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = closeOperation(ctx, operation, OperationApproved, clientID, "")
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// RejectOperation closes a pending operation without applying it. The client
+// who proposed it may withdraw it, and so may any client allowed to approve
+// it.
+func (s *AdminContract) RejectOperation(ctx contractapi.TransactionContextInterface, operationID string, reason string) error {
+	operation, err := getPendingOperation(ctx, operationID)
+	if err != nil {
+		return err
+	}
+	clientID, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	if clientID != operation.ProposedBy {
+		err = requireOperationAuthority(ctx, operation)
+		if err != nil {
+			return err
+		}
+	}
+	return closeOperation(ctx, operation, OperationRejected, clientID, reason)
+}
+
+// QueryOperation returns an operation, pending or decided
+func (s *AdminContract) QueryOperation(ctx contractapi.TransactionContextInterface, operationID string) (*PendingOperation, error) {
+	operation, err := getOperation(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	return operation, setOperationExpiredStatus(ctx, operation)
+}
+
+// QueryPendingOperations returns the operations waiting for approval, leaving
+// out expired ones
+func (s *AdminContract) QueryPendingOperations(ctx contractapi.TransactionContextInterface) ([]*PendingOperation, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(pendingOperationObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending operations: %v", err)
+	}
+	defer iterator.Close()
+
+	operations := []*PendingOperation{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over pending operations: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+
+		operation, err := getOperation(ctx, keyParts[0])
+		if err != nil {
+			return nil, err
+		}
+		err = setOperationExpiredStatus(ctx, operation)
+		if err != nil {
+			return nil, err
+		}
+		if operation.Status == OperationPending {
+			operations = append(operations, operation)
+		}
+	}
+
+	return operations, nil
+}
+
+// proposeOperation stores operation as pending under the ID of the running
+// transaction, proposed by the submitting client
+func proposeOperation(ctx contractapi.TransactionContextInterface, operation *PendingOperation) (*PendingOperation, error) {
+	config, err := getConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expiry := config.OperationExpirySeconds
+	if expiry == 0 {
+		expiry = defaultOperationExpiry
+	}
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	operation.ProposedBy, err = getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	operation.OperationID = ctx.GetStub().GetTxID()
+	operation.Status = OperationPending
+	operation.ProposedAt = now.Format(time.RFC3339)
+	operation.ExpiresAt = now.Add(time.Duration(expiry) * time.Second).Format(time.RFC3339)
+	err = putOperation(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingKey, err := pendingOperationKey(ctx, operation.OperationID)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(pendingKey, []byte{0x00})
+	if err != nil {
+		return nil, fmt.Errorf("failed to put pending operation index: %v", err)
+	}
+	return operation, nil
+}
+
+// requireOperationAuthority returns an error unless the submitting client is
+// allowed to propose operation: the administrator of the bank it changes, or
+// an operator
+func requireOperationAuthority(ctx contractapi.TransactionContextInterface, operation *PendingOperation) error {
+	var bankID string
+	switch operation.Type {
+	case OperationUpdateBankProfile:
+		bankID = operation.BankProfile.BankID
+	case OperationUpdateBalance:
+		account, err := getAccount(ctx, operation.Balance.AccountID)
+		if err != nil {
+			return err
+		}
+		bankID = account.BankID
+	default:
+		return contracterrors.New(contracterrors.InvalidState, "operation %s has unknown type %s", operation.OperationID, operation.Type)
+	}
+
+	bank, err := getBank(ctx, bankID)
+	if err != nil {
+		return err
+	}
+	return requireBankAdminOrOperator(ctx, bank)
+}
+
+// setOperationExpiredStatus reports a pending operation past its expiry time
+// as expired
+func setOperationExpiredStatus(ctx contractapi.TransactionContextInterface, operation *PendingOperation) error {
+	if operation.Status != OperationPending {
+		return nil
+	}
+	now, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	if operation.ExpiresAt <= now {
+		operation.Status = OperationExpired
+	}
+	return nil
+}
+
+// getPendingOperation loads an operation that can still be approved or
+// rejected
+func getPendingOperation(ctx contractapi.TransactionContextInterface, operationID string) (*PendingOperation, error) {
+	operation, err := getOperation(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	err = setOperationExpiredStatus(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	if operation.Status != OperationPending {
+		return nil, contracterrors.New(contracterrors.InvalidState, "operation %s is %s", operationID, operation.Status)
+	}
+	return operation, nil
+}
+
+// closeOperation records the decision on a pending operation and removes it
+// from the pending operations
+func closeOperation(ctx contractapi.TransactionContextInterface, operation *PendingOperation, status string, clientID string, reason string) error {
+	now, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	operation.Status = status
+	operation.DecidedBy = clientID
+	operation.DecidedAt = now
+	operation.Reason = reason
+	err = putOperation(ctx, operation)
+	if err != nil {
+		return err
+	}
+
+	pendingKey, err := pendingOperationKey(ctx, operation.OperationID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(pendingKey)
+}
+
+func operationKey(ctx contractapi.TransactionContextInterface, operationID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(operationObjectType, []string{operationID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create operation key: %v", err)
+	}
+	return key, nil
+}
+
+func pendingOperationKey(ctx contractapi.TransactionContextInterface, operationID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(pendingOperationObjectType, []string{operationID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create pending operation key: %v", err)
+	}
+	return key, nil
+}
+
+func getOperation(ctx contractapi.TransactionContextInterface, operationID string) (*PendingOperation, error) {
+	err := checkArgs(validation.ID("operationID", operationID))
+	if err != nil {
+		return nil, err
+	}
+	key, err := operationKey(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	operationJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation state: %v", err)
+	}
+	if operationJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "operation %s does not exist", operationID)
+	}
+
+	var operation PendingOperation
+	err = json.Unmarshal(operationJSON, &operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal operation JSON: %v", err)
+	}
+	return &operation, nil
+}
+
+func putOperation(ctx contractapi.TransactionContextInterface, operation *PendingOperation) error {
+	key, err := operationKey(ctx, operation.OperationID)
+	if err != nil {
+		return err
+	}
+	operationJSON, err := json.Marshal(operation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(key, operationJSON)
+}
@@ -0,0 +1,222 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+type proposeFunc func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error)
+
+// propose submits an operation as identity and returns it
+func (f *fixture) propose(identity *chaincodetest.Identity, fn proposeFunc) (*PendingOperation, error) {
+	f.t.Helper()
+	var operation *PendingOperation
+	err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+		operation, err = fn(ctx)
+		return err
+	})
+	return operation, err
+}
+
+func (f *fixture) approveOperation(identity *chaincodetest.Identity, operationID string) (*PendingOperation, error) {
+	f.t.Helper()
+	var operation *PendingOperation
+	err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+		operation, err = f.admin.ApproveOperation(ctx, operationID)
+		return err
+	})
+	return operation, err
+}
+
+// proposeAndApprove has the operator propose an operation and the checker
+// approve it
+func (f *fixture) proposeAndApprove(fn proposeFunc) error {
+	f.t.Helper()
+	operation, err := f.propose(f.operator, fn)
+	if err != nil {
+		return err
+	}
+	_, err = f.approveOperation(f.checker, operation.OperationID)
+	return err
+}
+
+func (f *fixture) updateBalance(accountID string, amount float64) error {
+	f.t.Helper()
+	return f.proposeAndApprove(func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, accountID, amount)
+	})
+}
+
+func (f *fixture) updateBankProfile(bankID string, name string, reserves float64, country string) error {
+	f.t.Helper()
+	return f.proposeAndApprove(func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.banks.UpdateBankProfile(ctx, bankID, "", name, reserves, country)
+	})
+}
+
+func (f *fixture) pendingOperations() []*PendingOperation {
+	f.t.Helper()
+	var operations []*PendingOperation
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		operations, err = f.admin.QueryPendingOperations(ctx)
+		return err
+	})
+	checkErr(f.t, err, "")
+	return operations
+}
+
+func TestApproveOperation(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	_, err := f.propose(f.anyone, func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, "A1", 500)
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	_, err = f.propose(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, "A1", 500)
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+
+	f.stub.Now = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	operation, err := f.propose(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, "A1", 500)
+	})
+	checkErr(t, err, "")
+	if operation.Status != OperationPending || operation.Type != OperationUpdateBalance || operation.ExpiresAt != "2024-01-02T10:00:00Z" {
+		t.Fatalf("unexpected operation %+v", operation)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+	if pending := f.pendingOperations(); len(pending) != 1 || pending[0].OperationID != operation.OperationID {
+		t.Fatalf("unexpected pending operations %+v", pending)
+	}
+
+	_, err = f.approveOperation(f.bank1Admin, operation.OperationID)
+	checkCode(t, err, contracterrors.Forbidden)
+	checkErr(t, err, "a different client")
+	_, err = f.approveOperation(f.bank2Admin, operation.OperationID)
+	checkCode(t, err, contracterrors.Forbidden)
+
+	approved, err := f.approveOperation(f.operator, operation.OperationID)
+	checkErr(t, err, "")
+	if approved.Status != OperationApproved || approved.DecidedBy == "" || approved.DecidedBy == approved.ProposedBy || approved.DecidedAt == "" {
+		t.Fatalf("unexpected operation %+v", approved)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1500)
+	if pending := f.pendingOperations(); len(pending) != 0 {
+		t.Fatalf("unexpected pending operations %+v", pending)
+	}
+	_, err = f.approveOperation(f.checker, operation.OperationID)
+	checkCode(t, err, contracterrors.InvalidState)
+
+	if report := f.invariants(); !report.Holds {
+		t.Fatalf("invariants do not hold: %v", report.Violations)
+	}
+}
+
+func TestApproveOperationChecksAgain(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	operation, err := f.propose(f.operator, func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, "A1", 25)
+	})
+	checkErr(t, err, "")
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.accounts.FreezeAccount(ctx, "A1", "investigation")
+	})
+
+	_, err = f.approveOperation(f.checker, operation.OperationID)
+	checkErr(t, err, "account A1 is frozen")
+	if pending := f.pendingOperations(); len(pending) != 1 {
+		t.Fatalf("failed approval closed the operation: %+v", pending)
+	}
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+}
+
+func TestApproveOperationOfUnknownType(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		return putOperation(ctx, &PendingOperation{OperationID: "OP1", Type: "renameCustomer", Status: OperationPending, ExpiresAt: "2099-01-01T00:00:00Z"})
+	})
+
+	_, err := f.approveOperation(f.checker, "OP1")
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "operation OP1 has unknown type renameCustomer")
+}
+
+func TestRejectOperation(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+
+	propose := func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.banks.UpdateBankProfile(ctx, "BANK1", "", "Renamed Bank", 99999, "US")
+	}
+	first, err := f.propose(f.bank1Admin, propose)
+	checkErr(t, err, "")
+	second, err := f.propose(f.bank1Admin, propose)
+	checkErr(t, err, "")
+
+	err = f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RejectOperation(ctx, first.OperationID, "")
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RejectOperation(ctx, first.OperationID, "reserves do not match the statement")
+	})
+	// The proposer may withdraw an operation
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RejectOperation(ctx, second.OperationID, "")
+	})
+
+	var rejected *PendingOperation
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		rejected, err = f.admin.QueryOperation(ctx, first.OperationID)
+		return err
+	})
+	checkErr(t, err, "")
+	if rejected.Status != OperationRejected || rejected.Reason != "reserves do not match the statement" || rejected.DecidedBy == "" {
+		t.Fatalf("unexpected operation %+v", rejected)
+	}
+	_, err = f.approveOperation(f.operator, second.OperationID)
+	checkCode(t, err, contracterrors.InvalidState)
+	if bank := f.bank("BANK1"); bank.Name != "First Bank" {
+		t.Fatalf("rejected change was applied: %+v", bank)
+	}
+}
+
+func TestOperationExpires(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.admin.InitLedger(ctx, ChaincodeConfig{OperationExpirySeconds: 3600})
+		return err
+	})
+
+	f.stub.Now = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	operation, err := f.propose(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) (*PendingOperation, error) {
+		return f.accounts.UpdateBalance(ctx, "A1", 25)
+	})
+	checkErr(t, err, "")
+	if operation.ExpiresAt != "2024-01-01T11:00:00Z" {
+		t.Fatalf("operation expires at %s", operation.ExpiresAt)
+	}
+
+	f.stub.Now = time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	if pending := f.pendingOperations(); len(pending) != 0 {
+		t.Fatalf("unexpected pending operations %+v", pending)
+	}
+	_, err = f.approveOperation(f.operator, operation.OperationID)
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "is expired")
+	assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+}
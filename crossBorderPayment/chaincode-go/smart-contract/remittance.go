@@ -0,0 +1,402 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const (
+	purposeCodeObjectType     = "PurposeCode"
+	purposeCodeRuleObjectType = "PurposeCodeRule"
+)
+
+// Bounds on remittance information. 140 characters fit both one pacs.008
+// Ustrd element and the four lines of an MT103 field 70; invoice numbers are
+// Max35Text in ISO 20022.
+const (
+	maxRemittanceInfo    = 140
+	maxInvoiceNumbers    = 10
+	maxInvoiceNumberSize = 35
+)
+
+// Remittance is the information a payment carries for the receiver to
+// reconcile it, and the purpose regulators classify it by. Every field is
+// optional unless a purpose code rule of one of the banks asks for the code.
+type Remittance struct {
+	RemittanceInfo    string   `json:"remittanceInfo,omitempty" metadata:",optional"`
+	CreditorReference string   `json:"creditorReference,omitempty" metadata:",optional"`
+	InvoiceNumbers    []string `json:"invoiceNumbers,omitempty" metadata:",optional"`
+	PurposeCode       string   `json:"purposeCode,omitempty" metadata:",optional"`
+}
+
+// PurposeCode is an entry of the purpose code list. Codes follow the ISO
+// 20022 external purpose code list; operators add the ones the network uses.
+type PurposeCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	AddedBy     string `json:"addedBy"`
+	Date        string `json:"date"`
+}
+
+// PurposeCodeRule makes a purpose code mandatory for the payments a bank
+// sends to or receives from banks in Country. When PurposeCodes is not empty
+// the code must also be one of them.
+type PurposeCodeRule struct {
+	BankID       string   `json:"bankID"`
+	Country      string   `json:"country"`
+	PurposeCodes []string `json:"purposeCodes"`
+	SetBy        string   `json:"setBy"`
+	Date         string   `json:"date"`
+}
+
+// applyTo copies the remittance information onto a payment
+func (r Remittance) applyTo(payment *Payment) {
+	payment.RemittanceInfo = r.RemittanceInfo
+	payment.CreditorReference = r.CreditorReference
+	payment.InvoiceNumbers = r.InvoiceNumbers
+	payment.PurposeCode = r.PurposeCode
+}
+
+// AddPurposeCode adds a code to the purpose code list, or changes the
+// description of a listed one. Only operators may maintain the list.
+func (s *AdminContract) AddPurposeCode(ctx contractapi.TransactionContextInterface, code string, description string) error {
+	err := checkArgs(
+		validation.PurposeCode("code", code),
+		validation.Required("description", description),
+	)
+	if err != nil {
+		return err
+	}
+	err = requireRole(ctx, RoleOperator)
+	if err != nil {
+		return err
+	}
+
+	addedBy, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := purposeCodeKey(ctx, code)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := json.Marshal(PurposeCode{Code: code, Description: description, AddedBy: addedBy, Date: date})
+	if err != nil {
+		return fmt.Errorf("failed to marshal purpose code: %v", err)
+	}
+	return ctx.GetStub().PutState(key, entryJSON)
+}
+
+// RemovePurposeCode takes a code off the purpose code list. Payments already
+// made with it keep it, and so do the rules that name it, but new payments can
+// no longer use it.
+func (s *AdminContract) RemovePurposeCode(ctx contractapi.TransactionContextInterface, code string) error {
+	err := checkArgs(validation.PurposeCode("code", code))
+	if err != nil {
+		return err
+	}
+	err = requireRole(ctx, RoleOperator)
+	if err != nil {
+		return err
+	}
+
+	key, err := purposeCodeKey(ctx, code)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read purpose code: %v", err)
+	}
+	if entryJSON == nil {
+		return contracterrors.New(contracterrors.NotFound, "purpose code %s is not on the list", code)
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// QueryPurposeCodes returns the purpose code list
+func (s *AdminContract) QueryPurposeCodes(ctx contractapi.TransactionContextInterface) ([]*PurposeCode, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(purposeCodeObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purpose codes: %v", err)
+	}
+	defer iterator.Close()
+
+	codes := []*PurposeCode{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over purpose codes: %v", err)
+		}
+		var code PurposeCode
+		err = json.Unmarshal(queryResponse.Value, &code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal purpose code: %v", err)
+		}
+		codes = append(codes, &code)
+	}
+	return codes, nil
+}
+
+// SetPurposeCodeRule makes a purpose code mandatory for the payments a bank
+// sends to or receives from banks in country, replacing any rule the bank had
+// for it. purposeCodes limits the codes accepted, which must be on the list;
+// leave it empty to accept any listed code. Only the bank's administrator may
+// set its rules.
+func (s *BankContract) SetPurposeCodeRule(ctx contractapi.TransactionContextInterface, bankID string, country string, purposeCodes []string) error {
+	if purposeCodes == nil {
+		purposeCodes = []string{}
+	}
+	results := []error{
+		validation.ID("bankID", bankID),
+		validation.Country("country", country),
+	}
+	for _, code := range purposeCodes {
+		results = append(results, validation.PurposeCode("purposeCodes", code))
+	}
+	err := checkArgs(results...)
+	if err != nil {
+		return err
+	}
+
+	bank, err := getBank(ctx, bankID)
+	if err != nil {
+		return err
+	}
+	err = requireBankAdmin(ctx, bank)
+	if err != nil {
+		return err
+	}
+	err = checkBankCanTransact(bank)
+	if err != nil {
+		return err
+	}
+	for _, code := range purposeCodes {
+		err = checkPurposeCodeListed(ctx, "purposeCodes", code)
+		if err != nil {
+			return err
+		}
+	}
+
+	setBy, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := purposeCodeRuleKey(ctx, bankID, country)
+	if err != nil {
+		return err
+	}
+	rule := PurposeCodeRule{BankID: bankID, Country: country, PurposeCodes: purposeCodes, SetBy: setBy, Date: date}
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal purpose code rule: %v", err)
+	}
+	return ctx.GetStub().PutState(key, ruleJSON)
+}
+
+// RemovePurposeCodeRule makes the purpose code optional again for a bank's
+// payments with country
+func (s *BankContract) RemovePurposeCodeRule(ctx contractapi.TransactionContextInterface, bankID string, country string) error {
+	err := checkArgs(
+		validation.ID("bankID", bankID),
+		validation.Country("country", country),
+	)
+	if err != nil {
+		return err
+	}
+
+	bank, err := getBank(ctx, bankID)
+	if err != nil {
+		return err
+	}
+	err = requireBankAdmin(ctx, bank)
+	if err != nil {
+		return err
+	}
+	err = checkBankCanTransact(bank)
+	if err != nil {
+		return err
+	}
+
+	rule, err := getPurposeCodeRule(ctx, bankID, country)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return contracterrors.New(contracterrors.NotFound, "bank %s has no purpose code rule for %s", bankID, country)
+	}
+	key, err := purposeCodeRuleKey(ctx, bankID, country)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// QueryPurposeCodeRules returns a bank's purpose code rules
+func (s *BankContract) QueryPurposeCodeRules(ctx contractapi.TransactionContextInterface, bankID string) ([]*PurposeCodeRule, error) {
+	err := checkArgs(validation.ID("bankID", bankID))
+	if err != nil {
+		return nil, err
+	}
+	_, err = getBank(ctx, bankID)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(purposeCodeRuleObjectType, []string{bankID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purpose code rules: %v", err)
+	}
+	defer iterator.Close()
+
+	rules := []*PurposeCodeRule{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over purpose code rules: %v", err)
+		}
+		var rule PurposeCodeRule
+		err = json.Unmarshal(queryResponse.Value, &rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal purpose code rule: %v", err)
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, nil
+}
+
+// checkRemittance validates the remittance information of a payment and
+// applies the purpose code rules of both banks to it
+func (l *paymentLedger) checkRemittance(payment *Payment) error {
+	results := []error{
+		validation.MaxLength("remittanceInfo", payment.RemittanceInfo, maxRemittanceInfo),
+		validation.IntRange("invoiceNumbers", len(payment.InvoiceNumbers), 0, maxInvoiceNumbers),
+	}
+	if payment.CreditorReference != "" {
+		results = append(results, validation.CreditorReference("creditorReference", payment.CreditorReference))
+	}
+	for _, number := range payment.InvoiceNumbers {
+		results = append(results,
+			validation.Required("invoiceNumbers", number),
+			validation.MaxLength("invoiceNumbers", number, maxInvoiceNumberSize),
+		)
+	}
+	if payment.PurposeCode != "" {
+		results = append(results, validation.PurposeCode("purposeCode", payment.PurposeCode))
+	}
+	err := checkArgs(results...)
+	if err != nil {
+		return err
+	}
+	if payment.PurposeCode != "" {
+		err = checkPurposeCodeListed(l.ctx, "purposeCode", payment.PurposeCode)
+		if err != nil {
+			return err
+		}
+	}
+
+	sender, err := l.account(payment.SenderAccountID)
+	if err != nil {
+		return err
+	}
+	receiver, err := l.account(payment.ReceiverAccountID)
+	if err != nil {
+		return err
+	}
+	senderBank, err := l.bank(sender.BankID)
+	if err != nil {
+		return err
+	}
+	receiverBank, err := l.bank(receiver.BankID)
+	if err != nil {
+		return err
+	}
+	corridors := [][2]*Bank{{senderBank, receiverBank}, {receiverBank, senderBank}}
+	for _, corridor := range corridors {
+		rule, err := getPurposeCodeRule(l.ctx, corridor[0].BankID, corridor[1].Country)
+		if err != nil {
+			return err
+		}
+		if rule == nil {
+			continue
+		}
+		if payment.PurposeCode == "" {
+			return contracterrors.New(contracterrors.Validation, "bank %s requires a purpose code for payments with %s", rule.BankID, rule.Country)
+		}
+		if len(rule.PurposeCodes) > 0 && !contains(rule.PurposeCodes, payment.PurposeCode) {
+			return contracterrors.New(contracterrors.Validation, "bank %s only accepts purpose codes %s for payments with %s", rule.BankID, strings.Join(rule.PurposeCodes, ", "), rule.Country)
+		}
+	}
+	return nil
+}
+
+// checkPurposeCodeListed returns a Validation error unless code is on the
+// purpose code list
+func checkPurposeCodeListed(ctx contractapi.TransactionContextInterface, field string, code string) error {
+	key, err := purposeCodeKey(ctx, code)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read purpose code: %v", err)
+	}
+	if entryJSON == nil {
+		return contracterrors.New(contracterrors.Validation, "%s %s is not on the purpose code list", field, code)
+	}
+	return nil
+}
+
+// getPurposeCodeRule returns a bank's rule for a country, nil if it has none
+func getPurposeCodeRule(ctx contractapi.TransactionContextInterface, bankID string, country string) (*PurposeCodeRule, error) {
+	key, err := purposeCodeRuleKey(ctx, bankID, country)
+	if err != nil {
+		return nil, err
+	}
+	ruleJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purpose code rule: %v", err)
+	}
+	if ruleJSON == nil {
+		return nil, nil
+	}
+	var rule PurposeCodeRule
+	err = json.Unmarshal(ruleJSON, &rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal purpose code rule: %v", err)
+	}
+	return &rule, nil
+}
+
+func purposeCodeKey(ctx contractapi.TransactionContextInterface, code string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(purposeCodeObjectType, []string{code})
+	if err != nil {
+		return "", fmt.Errorf("failed to create purpose code key: %v", err)
+	}
+	return key, nil
+}
+
+func purposeCodeRuleKey(ctx contractapi.TransactionContextInterface, bankID string, country string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(purposeCodeRuleObjectType, []string{bankID, country})
+	if err != nil {
+		return "", fmt.Errorf("failed to create purpose code rule key: %v", err)
+	}
+	return key, nil
+}
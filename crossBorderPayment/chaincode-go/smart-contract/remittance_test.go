@@ -0,0 +1,219 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/iso20022"
+)
+
+// listPurposeCodes puts codes on the purpose code list
+func (f *fixture) listPurposeCodes(codes ...string) {
+	f.t.Helper()
+	for _, code := range codes {
+		code := code
+		f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+			return f.admin.AddPurposeCode(ctx, code, "purpose "+code)
+		})
+	}
+}
+
+func TestPurposeCodeList(t *testing.T) {
+	f := newFixture(t)
+
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddPurposeCode(ctx, "SUPP", "Supplier payment")
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	err = f.submit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddPurposeCode(ctx, "supp", "Supplier payment")
+	})
+	checkCode(t, err, contracterrors.Validation)
+
+	f.listPurposeCodes("SUPP", "SALA")
+	f.mustSubmit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RemovePurposeCode(ctx, "SALA")
+	})
+	err = f.submit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RemovePurposeCode(ctx, "SALA")
+	})
+	checkCode(t, err, contracterrors.NotFound)
+
+	var codes []*PurposeCode
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		codes, err = f.admin.QueryPurposeCodes(ctx)
+		return err
+	})
+	checkErr(t, err, "")
+	if len(codes) != 1 || codes[0].Code != "SUPP" || codes[0].Description != "purpose SUPP" {
+		t.Fatalf("unexpected purpose codes %+v", codes)
+	}
+}
+
+func TestPaymentRemittance(t *testing.T) {
+	tests := []struct {
+		name       string
+		remittance string
+		wantCode   contracterrors.Code
+		wantErr    string
+	}{
+		{name: "none", remittance: ""},
+		{name: "structured", remittance: `{"remittanceInfo":"March","creditorReference":"RF18539007547034","invoiceNumbers":["INV-17","INV-18"],"purposeCode":"SUPP"}`},
+		{name: "creditor reference", remittance: `{"creditorReference":"RF19539007547034"}`, wantCode: contracterrors.Validation, wantErr: "creditorReference"},
+		{name: "unlisted purpose", remittance: `{"purposeCode":"SALA"}`, wantCode: contracterrors.Validation, wantErr: "purposeCode SALA is not on the purpose code list"},
+		{name: "too long", remittance: `{"remittanceInfo":"` + strings.Repeat("x", 141) + `"}`, wantCode: contracterrors.Validation, wantErr: "at most 140 characters"},
+		{name: "empty invoice number", remittance: `{"invoiceNumbers":[""]}`, wantCode: contracterrors.Validation, wantErr: "invoiceNumbers"},
+		{name: "not JSON", remittance: `SUPP`, wantCode: contracterrors.Validation, wantErr: "invalid remittance JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.listPurposeCodes("SUPP")
+
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.CreatePayment(ctx, "P1", "A1", "A2", "C1", "C2", 100, 0.9, "2024-01-01", "", tt.remittance)
+			})
+			if tt.wantCode != "" {
+				checkCode(t, err, tt.wantCode)
+				checkErr(t, err, tt.wantErr)
+				return
+			}
+			checkErr(t, err, "")
+			if tt.remittance == "" {
+				return
+			}
+			payment, err := getCommittedPayment(f, "P1")
+			checkErr(t, err, "")
+			if payment.RemittanceInfo != "March" || payment.CreditorReference != "RF18539007547034" || payment.PurposeCode != "SUPP" ||
+				!reflect.DeepEqual(payment.InvoiceNumbers, []string{"INV-17", "INV-18"}) {
+				t.Fatalf("unexpected payment %+v", payment)
+			}
+		})
+	}
+}
+
+func TestPurposeCodeRules(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.listPurposeCodes("SUPP", "GDDS", "SALA")
+
+	err := f.submit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.SetPurposeCodeRule(ctx, "BANK2", "US", nil)
+	})
+	checkCode(t, err, contracterrors.Forbidden)
+	err = f.submit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.SetPurposeCodeRule(ctx, "BANK2", "US", []string{"TRAD"})
+	})
+	checkCode(t, err, contracterrors.Validation)
+
+	// BANK2 in Germany wants trade payments from the US classified
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.SetPurposeCodeRule(ctx, "BANK2", "US", []string{"SUPP", "GDDS"})
+	})
+	var rules []*PurposeCodeRule
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		rules, err = f.banks.QueryPurposeCodeRules(ctx, "BANK2")
+		return err
+	})
+	checkErr(t, err, "")
+	if len(rules) != 1 || rules[0].Country != "US" || len(rules[0].PurposeCodes) != 2 {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+
+	tests := []struct {
+		name       string
+		paymentID  string
+		from       string
+		to         string
+		remittance string
+		wantErr    string
+	}{
+		{name: "missing", paymentID: "P1", from: "A1", to: "A2", wantErr: "bank BANK2 requires a purpose code for payments with US"},
+		{name: "not accepted", paymentID: "P1", from: "A1", to: "A2", remittance: `{"purposeCode":"SALA"}`, wantErr: "bank BANK2 only accepts purpose codes SUPP, GDDS for payments with US"},
+		{name: "accepted", paymentID: "P1", from: "A1", to: "A2", remittance: `{"purposeCode":"GDDS"}`},
+		{name: "other direction", paymentID: "P2", from: "A2", to: "A1", wantErr: "requires a purpose code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := f.account(tt.from), f.account(tt.to)
+			err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.CreatePayment(ctx, tt.paymentID, tt.from, tt.to, from.CustomerID, to.CustomerID, 10, 1, "2024-01-01", "", tt.remittance)
+			})
+			if tt.wantErr != "" {
+				checkCode(t, err, contracterrors.Validation)
+				checkErr(t, err, tt.wantErr)
+				return
+			}
+			checkErr(t, err, "")
+		})
+	}
+
+	instructions := batchJSON(t,
+		PaymentInstruction{PaymentID: "P3", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 10, ExchangeRate: 0.9},
+		PaymentInstruction{PaymentID: "P4", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2", Amount: 10, ExchangeRate: 0.9, PurposeCode: "SUPP"},
+	)
+	var batch *PaymentBatch
+	err = f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		batch, err = f.payments.CreatePaymentBatch(ctx, "B1", instructions, BatchModeBestEffort)
+		return err
+	})
+	checkErr(t, err, "")
+	if batch.Settled != 1 || batch.Results[0].Code != string(contracterrors.Validation) {
+		t.Fatalf("unexpected batch %+v", batch)
+	}
+
+	f.mustSubmit(f.bank2Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.RemovePurposeCodeRule(ctx, "BANK2", "US")
+	})
+	checkErr(t, f.pay("P5", "A1", "A2", 10, 0.9), "")
+}
+
+func TestPacs008Remittance(t *testing.T) {
+	f := newFixture(t)
+	f.seed()
+	f.listPurposeCodes("SUPP")
+	message := strings.Replace(pacs008Message, "<RmtInf><Ustrd>Invoice 2024-17</Ustrd><Ustrd>Thank you</Ustrd></RmtInf>", `<Purp><Cd>SUPP</Cd></Purp>
+      <RmtInf>
+        <Ustrd>Thank you</Ustrd>
+        <Strd>
+          <RfrdDocInf><Tp><CdOrPrtry><Cd>CINV</Cd></CdOrPrtry></Tp><Nb>INV-17</Nb></RfrdDocInf>
+          <RfrdDocInf><Tp><CdOrPrtry><Cd>CREN</Cd></CdOrPrtry></Tp><Nb>CN-3</Nb></RfrdDocInf>
+          <CdtrRefInf><Tp><CdOrPrtry><Cd>SCOR</Cd></CdOrPrtry></Tp><Ref>RF18539007547034</Ref></CdtrRefInf>
+        </Strd>
+      </RmtInf>`, 1)
+
+	var payment *Payment
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		payment, err = f.payments.CreatePaymentFromPacs008(ctx, message)
+		return err
+	})
+	checkErr(t, err, "")
+	if payment.PurposeCode != "SUPP" || payment.CreditorReference != "RF18539007547034" || payment.RemittanceInfo != "Thank you" ||
+		!reflect.DeepEqual(payment.InvoiceNumbers, []string{"INV-17"}) {
+		t.Fatalf("remittance was not kept: %+v", payment)
+	}
+
+	var exported string
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		exported, err = f.payments.ExportPaymentPacs008(ctx, "P1")
+		return err
+	})
+	checkErr(t, err, "")
+	doc, err := iso20022.ParsePacs008([]byte(exported))
+	if err != nil {
+		t.Fatalf("exported message is invalid: %v\n%s", err, exported)
+	}
+	tx := doc.CreditTransfer.Transactions[0]
+	if got := pacs008Remittance(&tx); !reflect.DeepEqual(got, Remittance{RemittanceInfo: "Thank you", CreditorReference: "RF18539007547034", InvoiceNumbers: []string{"INV-17"}, PurposeCode: "SUPP"}) {
+		t.Fatalf("exported remittance %+v:\n%s", got, exported)
+	}
+}
@@ -0,0 +1,355 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// SchemaVersion is the version of the JSON written for banks, customers,
+// accounts and payments. Objects stored before versioning have no version
+// field and count as version 0.
+//
+// To change a shape, increment SchemaVersion and add a step to the upgrade
+// function of the type that converts objects from the previous version.
+// Objects are upgraded whenever they are read, and MigrateState rewrites the
+// ones that are never read again.
+const SchemaVersion = 2
+
+// Object types stored under plain keys
+const (
+	bankObjectType     = "bank"
+	customerObjectType = "customer"
+	accountObjectType  = "account"
+	paymentObjectType  = "payment"
+)
+
+const migrationObjectType = "SchemaMigration"
+
+// maxMigrationPageSize bounds the keys MigrateState reads in one transaction,
+// so that a migration page stays well inside the peer's execution timeout
+const maxMigrationPageSize = 1000
+
+// MigrationProgress records how far MigrateState has rewritten the world state
+// to TargetVersion. Keys up to and including LastKey have been migrated.
+type MigrationProgress struct {
+	TargetVersion int    `json:"targetVersion"`
+	LastKey       string `json:"lastKey"`
+	Scanned       int    `json:"scanned"`
+	Migrated      int    `json:"migrated"`
+	Complete      bool   `json:"complete"`
+	UpdatedAt     string `json:"updatedAt"`
+}
+
+// MigrateState rewrites up to pageSize objects stored under plain keys to the
+// current SchemaVersion, continuing after the last key of the previous call.
+// Call it repeatedly until the returned progress is complete. Only operators
+// may run it.
+func (s *AdminContract) MigrateState(ctx contractapi.TransactionContextInterface, pageSize int) (*MigrationProgress, error) {
+	err := checkArgs(validation.IntRange("pageSize", pageSize, 1, maxMigrationPageSize))
+	if err != nil {
+		return nil, err
+	}
+	err = requireRole(ctx, RoleOperator)
+	if err != nil {
+		return nil, err
+	}
+
+	progress, err := getMigrationProgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if progress.TargetVersion != SchemaVersion {
+		progress = &MigrationProgress{TargetVersion: SchemaVersion}
+	}
+	if progress.Complete {
+		return progress, nil
+	}
+
+	// Range queries include their start key, and no key sorts between
+	// LastKey and LastKey followed by the lowest byte
+	startKey := ""
+	if progress.LastKey != "" {
+		startKey = progress.LastKey + "\x00"
+	}
+	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read world state: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for scanned := 0; scanned < pageSize && resultsIterator.HasNext(); scanned++ {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		migrated, err := migrateObject(ctx, queryResponse.Key, queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		progress.LastKey = queryResponse.Key
+		progress.Scanned++
+		if migrated {
+			progress.Migrated++
+		}
+	}
+	progress.Complete = !resultsIterator.HasNext()
+
+	progress.UpdatedAt, err = getTxDate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = putMigrationProgress(ctx, progress)
+	if err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// GetMigrationProgress returns the progress of the current or last state
+// migration. A ledger that was never migrated reports no progress towards the
+// current SchemaVersion.
+func (s *AdminContract) GetMigrationProgress(ctx contractapi.TransactionContextInterface) (*MigrationProgress, error) {
+	return getMigrationProgress(ctx)
+}
+
+// migrateObject rewrites the object stored under key if it has an older schema
+// version. It reports whether the object was rewritten.
+func migrateObject(ctx contractapi.TransactionContextInterface, key string, value []byte) (bool, error) {
+	objectType := storedObjectType(value)
+	if objectType == "" {
+		return false, nil
+	}
+	var stored struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	err := json.Unmarshal(value, &stored)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal %s %s: %v", objectType, key, err)
+	}
+	if stored.SchemaVersion == SchemaVersion {
+		return false, nil
+	}
+
+	var object interface{}
+	switch objectType {
+	case bankObjectType:
+		bank := &Bank{}
+		err = unmarshalBank(value, bank)
+		object = bank
+	case customerObjectType:
+		customer := &Customer{}
+		err = unmarshalCustomer(value, customer)
+		object = customer
+	case accountObjectType:
+		account := &Account{}
+		err = unmarshalAccount(value, account)
+		object = account
+	case paymentObjectType:
+		payment := &Payment{}
+		err = unmarshalPayment(value, payment)
+		object = payment
+	}
+	if err != nil {
+		return false, contracterrors.Wrap(err, "failed to migrate %s %s", objectType, key)
+	}
+
+	objectJSON, err := json.Marshal(object)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal %s %s: %v", objectType, key, err)
+	}
+	err = ctx.GetStub().PutState(key, objectJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to put %s %s: %v", objectType, key, err)
+	}
+	// Members are indexed, and banks from before governance join the index
+	// when they are migrated
+	if bank, ok := object.(*Bank); ok {
+		err = putMemberIndex(ctx, bank)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// storedObjectType tells banks, customers, accounts and payments apart by the
+// fields only their JSON has, as they are stored under plain keys. It returns
+// the empty string for any other value.
+func storedObjectType(value []byte) string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(value, &fields) != nil {
+		return ""
+	}
+	switch {
+	case fields["bankAdminID"] != nil:
+		return bankObjectType
+	case fields["paymentID"] != nil:
+		return paymentObjectType
+	case fields["id"] != nil && fields["balance"] != nil:
+		return accountObjectType
+	case fields["customerID"] != nil && fields["surname"] != nil:
+		return customerObjectType
+	default:
+		return ""
+	}
+}
+
+func getMigrationProgress(ctx contractapi.TransactionContextInterface) (*MigrationProgress, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(migrationObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration key: %v", err)
+	}
+	progressJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration progress: %v", err)
+	}
+	progress := &MigrationProgress{TargetVersion: SchemaVersion}
+	if progressJSON == nil {
+		return progress, nil
+	}
+	err = json.Unmarshal(progressJSON, progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal migration progress: %v", err)
+	}
+	return progress, nil
+}
+
+func putMigrationProgress(ctx contractapi.TransactionContextInterface, progress *MigrationProgress) error {
+	key, err := ctx.GetStub().CreateCompositeKey(migrationObjectType, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to create migration key: %v", err)
+	}
+	progressJSON, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration progress: %v", err)
+	}
+	return ctx.GetStub().PutState(key, progressJSON)
+}
+
+// checkSchemaVersion rejects objects written by a newer version of the
+// chaincode, which this version cannot interpret safely
+func checkSchemaVersion(objectType string, version int) error {
+	if version > SchemaVersion {
+		return contracterrors.New(contracterrors.InvalidState, "%s has schema version %d, newer than the supported version %d", objectType, version, SchemaVersion)
+	}
+	return nil
+}
+
+func unmarshalBank(data []byte, bank *Bank) error {
+	err := json.Unmarshal(data, bank)
+	if err != nil {
+		return err
+	}
+	return upgradeBank(bank)
+}
+
+// upgradeBank converts a bank to the current schema version. Banks stored
+// before consortium governance were all members.
+func upgradeBank(bank *Bank) error {
+	err := checkSchemaVersion(bankObjectType, bank.SchemaVersion)
+	if err != nil {
+		return err
+	}
+	if bank.SchemaVersion < 1 {
+		if bank.AccountIDs == nil {
+			bank.AccountIDs = []string{}
+		}
+	}
+	if bank.SchemaVersion < 2 {
+		if bank.Status == "" {
+			bank.Status = BankActive
+		}
+	}
+	bank.SchemaVersion = SchemaVersion
+	return nil
+}
+
+func unmarshalCustomer(data []byte, customer *Customer) error {
+	err := json.Unmarshal(data, customer)
+	if err != nil {
+		return err
+	}
+	return upgradeCustomer(customer)
+}
+
+// upgradeCustomer converts a customer to the current schema version.
+// Customers stored before KYC had never been verified, so they start pending.
+func upgradeCustomer(customer *Customer) error {
+	err := checkSchemaVersion(customerObjectType, customer.SchemaVersion)
+	if err != nil {
+		return err
+	}
+	if customer.SchemaVersion < 1 {
+		if customer.AccountIDs == nil {
+			customer.AccountIDs = []string{}
+		}
+		if customer.KYCStatus == "" {
+			customer.KYCStatus = KYCPending
+		}
+	}
+	// Document hashes are omitted from the JSON while there are none
+	if customer.DocumentHashes == nil {
+		customer.DocumentHashes = []string{}
+	}
+	customer.SchemaVersion = SchemaVersion
+	return nil
+}
+
+func unmarshalAccount(data []byte, account *Account) error {
+	err := json.Unmarshal(data, account)
+	if err != nil {
+		return err
+	}
+	return upgradeAccount(account)
+}
+
+// upgradeAccount converts an account to the current schema version. Accounts
+// stored before statuses existed are active.
+func upgradeAccount(account *Account) error {
+	err := checkSchemaVersion(accountObjectType, account.SchemaVersion)
+	if err != nil {
+		return err
+	}
+	if account.SchemaVersion < 1 {
+		if account.PaymentIDs == nil {
+			account.PaymentIDs = []string{}
+		}
+		if account.Status == "" {
+			account.Status = AccountActive
+		}
+	}
+	account.SchemaVersion = SchemaVersion
+	return nil
+}
+
+func unmarshalPayment(data []byte, payment *Payment) error {
+	err := json.Unmarshal(data, payment)
+	if err != nil {
+		return err
+	}
+	return upgradePayment(payment)
+}
+
+// upgradePayment converts a payment to the current schema version. Payments
+// stored before screening was introduced were all settled.
+func upgradePayment(payment *Payment) error {
+	err := checkSchemaVersion(paymentObjectType, payment.SchemaVersion)
+	if err != nil {
+		return err
+	}
+	if payment.SchemaVersion < 1 {
+		if payment.Status == "" {
+			payment.Status = PaymentStatusSettled
+		}
+	}
+	payment.SchemaVersion = SchemaVersion
+	return nil
+}
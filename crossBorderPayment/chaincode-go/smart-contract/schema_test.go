@@ -0,0 +1,170 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/chaincodetest"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// seedLegacyLedger stores a bank, a customer, two accounts and a payment as
+// they were written before schema versions existed
+func seedLegacyLedger(f *fixture) {
+	f.stub.Seed("BANKL", []byte(`{"name":"Legacy Bank","bankID":"BANKL","bankAdminID":"admin","password":"pw","country":"US","currency":"USD","reserves":100,"accountIDs":["AL1","AL2"],"exchangeRate":1}`))
+	f.stub.Seed("CL", []byte(`{"name":"Old","surname":"Timer","customerID":"CL","password":"pw","accountIDs":["AL1","AL2"]}`))
+	f.stub.Seed("AL1", []byte(`{"id":"AL1","customerID":"CL","bankID":"BANKL","balance":40,"currency":"USD","paymentIDs":["PL"]}`))
+	f.stub.Seed("AL2", []byte(`{"id":"AL2","customerID":"CL","bankID":"BANKL","balance":60,"currency":"USD","paymentIDs":null}`))
+	f.stub.Seed("PL", []byte(`{"paymentID":"PL","senderCustomerID":"CL","receiverCustomerID":"CL","senderAccountID":"AL1","receiverAccountID":"AL2","amount":10,"exchangeRate":1,"date":"2023-01-01"}`))
+}
+
+func storedSchemaVersion(t *testing.T, f *fixture, key string) int {
+	t.Helper()
+	var stored struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	err := json.Unmarshal(f.stub.Committed(key), &stored)
+	if err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", key, err)
+	}
+	return stored.SchemaVersion
+}
+
+func TestReadsUpgradeLegacyObjects(t *testing.T) {
+	f := newFixture(t)
+	seedLegacyLedger(f)
+
+	account := f.account("AL2")
+	if account.SchemaVersion != SchemaVersion || account.Status != AccountActive || account.PaymentIDs == nil {
+		t.Fatalf("legacy account was not upgraded: %+v", account)
+	}
+	customer := f.customer("CL")
+	if customer.SchemaVersion != SchemaVersion || customer.KYCStatus != KYCPending || customer.DocumentHashes == nil {
+		t.Fatalf("legacy customer was not upgraded: %+v", customer)
+	}
+	if bank := f.bank("BANKL"); bank.SchemaVersion != SchemaVersion {
+		t.Fatalf("legacy bank was not upgraded: %+v", bank)
+	}
+
+	// Reads do not write, so the stored objects keep their old version
+	if version := storedSchemaVersion(t, f, "AL2"); version != 0 {
+		t.Fatalf("stored schema version = %d after a read, want 0", version)
+	}
+
+	// A write of an upgraded object stores the current version
+	f.mustSubmit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.customers.UpdateProfile(ctx, "CL", "Old", "Timer", "new")
+	})
+	if version := storedSchemaVersion(t, f, "CL"); version != SchemaVersion {
+		t.Fatalf("stored schema version = %d after a write, want %d", version, SchemaVersion)
+	}
+}
+
+func TestReadRejectsNewerSchemaVersion(t *testing.T) {
+	f := newFixture(t)
+	f.stub.Seed("AFUTURE", []byte(`{"id":"AFUTURE","customerID":"C","bankID":"B","balance":1,"currency":"USD","paymentIDs":[],"schemaVersion":99}`))
+
+	err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.accounts.GetAccount(ctx, "AFUTURE")
+		return err
+	})
+	checkErr(t, err, "newer than the supported version")
+
+	f.stub.Seed("CFUTURE", []byte(`{"customerID":"CFUTURE","name":"N","surname":"S","password":"pw","accountIDs":[],"schemaVersion":99}`))
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := f.customers.QueryCustomer(ctx, "CFUTURE")
+		return err
+	})
+	checkErr(t, err, "newer than the supported version")
+}
+
+func TestMigrateState(t *testing.T) {
+	f := newFixture(t)
+	seedLegacyLedger(f)
+	f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+		return f.banks.CreateBank(ctx, "BANK1", "", "First Bank", "pw", "US", "USD", 10000, 1, "")
+	})
+	admitFounding := func() error {
+		return f.submit(f.operator, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := f.admin.AdmitFoundingBank(ctx, "BANK1")
+			return err
+		})
+	}
+	// BANKL is a member missing from the index until it is migrated, so
+	// governance waits for the migration
+	err := admitFounding()
+	checkCode(t, err, contracterrors.InvalidState)
+	checkErr(t, err, "wait until MigrateState has completed")
+
+	migrate := func(identity *chaincodetest.Identity, pageSize int) (*MigrationProgress, error) {
+		var progress *MigrationProgress
+		err := f.submit(identity, func(ctx contractapi.TransactionContextInterface) (err error) {
+			progress, err = f.admin.MigrateState(ctx, pageSize)
+			return err
+		})
+		return progress, err
+	}
+
+	_, err = migrate(f.anyone, 10)
+	checkCode(t, err, contracterrors.Forbidden)
+	_, err = migrate(f.operator, 0)
+	checkCode(t, err, contracterrors.Validation)
+
+	keys := 0
+	for _, key := range f.stub.Keys() {
+		if storedObjectType(f.stub.Committed(key)) != "" {
+			keys++
+		}
+	}
+
+	var progress *MigrationProgress
+	for calls := 1; progress == nil || !progress.Complete; calls++ {
+		if calls > keys {
+			t.Fatalf("migration did not complete after %d calls: %+v", calls, progress)
+		}
+		progress, err = migrate(f.operator, 2)
+		checkErr(t, err, "")
+	}
+	if progress.Scanned != keys || progress.Migrated != 5 || progress.TargetVersion != SchemaVersion {
+		t.Fatalf("unexpected progress %+v, want %d keys scanned and the 5 legacy objects migrated", progress, keys)
+	}
+
+	for _, key := range []string{"BANKL", "CL", "AL1", "AL2", "PL"} {
+		if version := storedSchemaVersion(t, f, key); version != SchemaVersion {
+			t.Fatalf("%s has schema version %d after migration, want %d", key, version, SchemaVersion)
+		}
+	}
+	var payment Payment
+	_ = json.Unmarshal(f.stub.Committed("PL"), &payment)
+	if payment.Status != PaymentStatusSettled {
+		t.Fatalf("legacy payment status = %q, want %s", payment.Status, PaymentStatusSettled)
+	}
+	// Banks stored before governance were members, and vote on the banks
+	// after them
+	if bank := f.bank("BANKL"); bank.Status != BankActive || !contains(f.memberBankIDs(), "BANKL") {
+		t.Fatalf("legacy bank is %q and not a member after migration", bank.Status)
+	}
+	checkErr(t, admitFounding(), "the consortium has 1 members")
+
+	// A complete migration is not repeated
+	again, err := migrate(f.operator, 2)
+	checkErr(t, err, "")
+	if again.Scanned != progress.Scanned || again.LastKey != progress.LastKey {
+		t.Fatalf("completed migration ran again: %+v", again)
+	}
+
+	var stored *MigrationProgress
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		stored, err = f.admin.GetMigrationProgress(ctx)
+		return err
+	})
+	checkErr(t, err, "")
+	if !stored.Complete || stored.Migrated != 5 {
+		t.Fatalf("unexpected stored progress %+v", stored)
+	}
+}
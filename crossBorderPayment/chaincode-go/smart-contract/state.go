@@ -0,0 +1,118 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+// requireUnusedID returns an AlreadyExists error if any object is stored under
+// id. Banks, customers, accounts and payments share one key space.
+func requireUnusedID(ctx contractapi.TransactionContextInterface, objectType string, id string) error {
+	existing, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return fmt.Errorf("failed to read %s state: %v", objectType, err)
+	}
+	if existing != nil {
+		return contracterrors.New(contracterrors.AlreadyExists, "%s ID %s is already in use", objectType, id)
+	}
+	return nil
+}
+
+func getBank(ctx contractapi.TransactionContextInterface, bankID string) (*Bank, error) {
+	bankJSON, err := ctx.GetStub().GetState(bankID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bank state: %v", err)
+	}
+	if bankJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "bank %s does not exist", bankID)
+	}
+
+	var bank Bank
+	err = unmarshalBank(bankJSON, &bank)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bank JSON: %v", err)
+	}
+	return &bank, nil
+}
+
+func putBank(ctx contractapi.TransactionContextInterface, bank *Bank) error {
+	bankJSON, err := json.Marshal(bank)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bank JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(bank.BankID, bankJSON)
+}
+
+func getCustomer(ctx contractapi.TransactionContextInterface, customerID string) (*Customer, error) {
+	customerJSON, err := ctx.GetStub().GetState(customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read customer state: %v", err)
+	}
+	if customerJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "customer %s does not exist", customerID)
+	}
+
+	var customer Customer
+	err = unmarshalCustomer(customerJSON, &customer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal customer JSON: %v", err)
+	}
+	return &customer, nil
+}
+
+func putCustomer(ctx contractapi.TransactionContextInterface, customer *Customer) error {
+	customerJSON, err := json.Marshal(customer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal customer JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(customer.CustomerID, customerJSON)
+}
+
+func getAccount(ctx contractapi.TransactionContextInterface, accountID string) (*Account, error) {
+	accountJSON, err := ctx.GetStub().GetState(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read account state: %v", err)
+	}
+	if accountJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "account %s does not exist", accountID)
+	}
+
+	var account Account
+	err = unmarshalAccount(accountJSON, &account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal account JSON: %v", err)
+	}
+	return &account, nil
+}
+
+func putAccount(ctx contractapi.TransactionContextInterface, account *Account) error {
+	accountJSON, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account JSON: %v", err)
+	}
+	return ctx.GetStub().PutState(account.AccountID, accountJSON)
+}
+
+func getPayment(ctx contractapi.TransactionContextInterface, paymentID string) (*Payment, error) {
+	paymentJSON, err := ctx.GetStub().GetState(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment state: %v", err)
+	}
+	if paymentJSON == nil {
+		return nil, contracterrors.New(contracterrors.NotFound, "payment %s does not exist", paymentID)
+	}
+
+	var payment Payment
+	err = unmarshalPayment(paymentJSON, &payment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payment JSON: %v", err)
+	}
+	return &payment, nil
+}
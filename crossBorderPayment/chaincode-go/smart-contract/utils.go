@@ -3,13 +3,16 @@ package bank
 import (
 	"encoding/base64"
 	"fmt"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
 )
 
-func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
+func getSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
 
 	b64ID, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
@@ -97,7 +100,7 @@ func verifyClientOrgMatchesPeerOrg(ctx contractapi.TransactionContextInterface)
 	}
 
 	if clientMSPID != peerMSPID {
-		return fmt.Errorf("client from org %v is not authorized to read or write private data from an org %v peer", clientMSPID, peerMSPID)
+		return contracterrors.New(contracterrors.Forbidden, "client from org %v is not authorized to read or write private data from an org %v peer", clientMSPID, peerMSPID)
 	}
 
 	return nil
@@ -111,3 +114,89 @@ func contains(sli []string, str string) bool {
 	}
 	return false
 }
+
+// getTxTime returns the transaction timestamp. It is the same on every
+// endorser, unlike the local clock.
+func getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return timestamp.AsTime().UTC(), nil
+}
+
+// getTxDate returns the transaction timestamp in RFC 3339 form
+func getTxDate(ctx contractapi.TransactionContextInterface) (string, error) {
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	return now.Format(time.RFC3339), nil
+}
+
+// checkArgs runs validation rules over transaction arguments and returns the
+// failures as a Validation error
+func checkArgs(results ...error) error {
+	return contracterrors.From(validation.Check(results...))
+}
+
+// RoleAttribute is the Fabric CA attribute that carries a client's role in the
+// payment network.
+const RoleAttribute = "role"
+
+// Roles recognised in the role attribute
+const (
+	RoleCompliance = "compliance"
+	RoleOperator   = "operator"
+)
+
+// requireRole returns an error unless the submitting client holds one of roles
+// and, if the config lists admin MSPs, belongs to one of them.
+func requireRole(ctx contractapi.TransactionContextInterface, roles ...string) error {
+	role, found, err := ctx.GetClientIdentity().GetAttributeValue(RoleAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to read client role: %v", err)
+	}
+	if !found || !contains(roles, role) {
+		return contracterrors.New(contracterrors.Forbidden, "client is not authorized for this operation, required role: %v", roles)
+	}
+
+	config, err := getConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if len(config.AdminMSPs) == 0 {
+		return nil
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSPID: %v", err)
+	}
+	if !contains(config.AdminMSPs, clientMSPID) {
+		return contracterrors.New(contracterrors.Forbidden, "the %s role is not accepted from clients of org %s", role, clientMSPID)
+	}
+	return nil
+}
+
+// requireBankAdmin returns an error unless the submitting client is the
+// administrator recorded on the bank when it was created.
+func requireBankAdmin(ctx contractapi.TransactionContextInterface, bank *Bank) error {
+	clientID, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	if clientID != bank.BankAdminID {
+		return contracterrors.New(contracterrors.Forbidden, "client is not the administrator of bank %s", bank.BankID)
+	}
+	return nil
+}
+
+// requireBankAdminOrOperator returns an error unless the submitting client is
+// the administrator of bank or acts in the operator role.
+func requireBankAdminOrOperator(ctx contractapi.TransactionContextInterface, bank *Bank) error {
+	err := requireBankAdmin(ctx, bank)
+	if contracterrors.CodeOf(err) != contracterrors.Forbidden {
+		return err
+	}
+	return requireRole(ctx, RoleOperator)
+}
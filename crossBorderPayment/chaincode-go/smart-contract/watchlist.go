@@ -0,0 +1,358 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+const (
+	watchlistObjectType   = "WatchlistEntry"
+	heldPaymentObjectType = "HeldPayment"
+)
+
+// Watchlist entry types
+const (
+	WatchlistName       = "name"
+	WatchlistCustomerID = "customerID"
+	WatchlistCountry    = "country"
+)
+
+// Payment statuses
+const (
+	PaymentStatusSettled         = "settled"
+	PaymentStatusHeld            = "held"
+	PaymentStatusRejected        = "rejected"
+	PaymentStatusPendingApproval = "pending_approval"
+)
+
+// WatchlistEntry is a sanctioned name, customer ID or country code
+type WatchlistEntry struct {
+	EntryType string `json:"entryType"`
+	Value     string `json:"value"`
+	Reason    string `json:"reason"`
+	AddedBy   string `json:"addedBy"`
+	Date      string `json:"date"`
+}
+
+// AddWatchlistEntry adds a name, customer ID or country code to the watchlist.
+// Only clients with the compliance role can maintain the watchlist.
+func (s *AdminContract) AddWatchlistEntry(ctx contractapi.TransactionContextInterface, entryType string, value string, reason string) error {
+	err := requireRole(ctx, RoleCompliance)
+	if err != nil {
+		return err
+	}
+
+	key, err := watchlistKey(ctx, entryType, value)
+	if err != nil {
+		return err
+	}
+
+	addedBy, err := getSubmittingClientIdentity(ctx)
+	if err != nil {
+		return err
+	}
+	date, err := getTxDate(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry := WatchlistEntry{
+		EntryType: entryType,
+		Value:     normalizeWatchlistValue(value),
+		Reason:    reason,
+		AddedBy:   addedBy,
+		Date:      date,
+	}
+	entryJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal watchlist entry: %v", err)
+	}
+
+	return ctx.GetStub().PutState(key, entryJSON)
+}
+
+// RemoveWatchlistEntry deletes an entry from the watchlist
+func (s *AdminContract) RemoveWatchlistEntry(ctx contractapi.TransactionContextInterface, entryType string, value string) error {
+	err := requireRole(ctx, RoleCompliance)
+	if err != nil {
+		return err
+	}
+
+	key, err := watchlistKey(ctx, entryType, value)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read watchlist entry: %v", err)
+	}
+	if entryJSON == nil {
+		return contracterrors.New(contracterrors.NotFound, "watchlist entry %s %s does not exist", entryType, value)
+	}
+
+	return ctx.GetStub().DelState(key)
+}
+
+// QueryWatchlist returns every watchlist entry
+func (s *AdminContract) QueryWatchlist(ctx contractapi.TransactionContextInterface) ([]*WatchlistEntry, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(watchlistObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read watchlist: %v", err)
+	}
+	defer iterator.Close()
+
+	entries := []*WatchlistEntry{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over watchlist: %v", err)
+		}
+
+		var entry WatchlistEntry
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal watchlist entry: %v", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// QueryHeldPayments returns the payments waiting for compliance review
+func (s *PaymentContract) QueryHeldPayments(ctx contractapi.TransactionContextInterface) ([]*Payment, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(heldPaymentObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read held payments: %v", err)
+	}
+	defer iterator.Close()
+
+	payments := []*Payment{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate over held payments: %v", err)
+		}
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split composite key: %v", err)
+		}
+
+		payment, err := getPayment(ctx, keyParts[0])
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// ReleaseHeldPayment settles a payment that was held by screening after a
+// compliance officer has cleared it. The checks screening skipped run now,
+// against the state at release: the rates must still be fresh and the payment
+// must fit the sender's limits and funds.
+func (s *PaymentContract) ReleaseHeldPayment(ctx contractapi.TransactionContextInterface, paymentID string) error {
+	payment, err := getHeldPayment(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+
+	ledger := newPaymentLedger(ctx)
+	err = ledger.checkAccountStatus(payment)
+	if err != nil {
+		return err
+	}
+	err = ledger.checkRates(payment)
+	if err != nil {
+		return err
+	}
+	err = ledger.clear(payment)
+	if breach, ok := err.(*LimitBreach); ok {
+		err = limitExceeded(breach)
+	}
+	if err != nil {
+		return err
+	}
+	err = ledger.flush()
+	if err != nil {
+		return err
+	}
+	err = finishPaymentRequest(ctx, payment)
+	if err != nil {
+		return err
+	}
+
+	return deleteHeldPaymentIndex(ctx, paymentID)
+}
+
+// RejectHeldPayment closes a held payment without moving any funds
+func (s *PaymentContract) RejectHeldPayment(ctx contractapi.TransactionContextInterface, paymentID string) error {
+	payment, err := getHeldPayment(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+
+	payment.Status = PaymentStatusRejected
+	paymentJSON, err := json.Marshal(payment)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment JSON: %v", err)
+	}
+	err = ctx.GetStub().PutState(paymentID, paymentJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put payment state: %v", err)
+	}
+	err = finishPaymentRequest(ctx, payment)
+	if err != nil {
+		return err
+	}
+
+	return deleteHeldPaymentIndex(ctx, paymentID)
+}
+
+// screenPayment checks the sender and receiver customers and the countries of
+// both banks against the watchlist and returns a description of every hit.
+func screenPayment(ctx contractapi.TransactionContextInterface, ledger *paymentLedger, payment *Payment) ([]string, error) {
+	hits := []string{}
+
+	for _, customerID := range []string{payment.SenderCustomerID, payment.ReceiverCustomerID} {
+		hit, err := watchlistHit(ctx, WatchlistCustomerID, customerID)
+		if err != nil {
+			return nil, err
+		}
+		if hit != "" {
+			hits = append(hits, hit)
+		}
+
+		customerJSON, err := ctx.GetStub().GetState(customerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read customer state: %v", err)
+		}
+		if customerJSON == nil {
+			continue
+		}
+		var customer Customer
+		err = unmarshalCustomer(customerJSON, &customer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal customer JSON: %v", err)
+		}
+		hit, err = watchlistHit(ctx, WatchlistName, customer.Name+" "+customer.Surname)
+		if err != nil {
+			return nil, err
+		}
+		if hit != "" {
+			hits = append(hits, hit)
+		}
+	}
+
+	for _, accountID := range []string{payment.SenderAccountID, payment.ReceiverAccountID} {
+		account, err := ledger.account(accountID)
+		if err != nil {
+			return nil, err
+		}
+		bank, err := ledger.bank(account.BankID)
+		if err != nil {
+			return nil, err
+		}
+		hit, err := watchlistHit(ctx, WatchlistCountry, bank.Country)
+		if err != nil {
+			return nil, err
+		}
+		if hit != "" && !contains(hits, hit) {
+			hits = append(hits, hit)
+		}
+	}
+
+	return hits, nil
+}
+
+func watchlistHit(ctx contractapi.TransactionContextInterface, entryType string, value string) (string, error) {
+	if normalizeWatchlistValue(value) == "" {
+		return "", nil
+	}
+	key, err := watchlistKey(ctx, entryType, value)
+	if err != nil {
+		return "", err
+	}
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to read watchlist entry: %v", err)
+	}
+	if entryJSON == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("%s %s", entryType, normalizeWatchlistValue(value)), nil
+}
+
+func watchlistKey(ctx contractapi.TransactionContextInterface, entryType string, value string) (string, error) {
+	value = normalizeWatchlistValue(value)
+	err := checkArgs(
+		validation.OneOf("entryType", entryType, WatchlistName, WatchlistCustomerID, WatchlistCountry),
+		validation.Required("value", value),
+	)
+	if err != nil {
+		return "", err
+	}
+	if entryType == WatchlistCountry {
+		err = checkArgs(validation.Country("value", value))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(watchlistObjectType, []string{entryType, value})
+	if err != nil {
+		return "", fmt.Errorf("failed to create watchlist key: %v", err)
+	}
+	return key, nil
+}
+
+// normalizeWatchlistValue makes matching insensitive to case and spacing
+func normalizeWatchlistValue(value string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(value), " "))
+}
+
+func getHeldPayment(ctx contractapi.TransactionContextInterface, paymentID string) (*Payment, error) {
+	err := checkArgs(validation.ID("paymentID", paymentID))
+	if err != nil {
+		return nil, err
+	}
+	err = requireRole(ctx, RoleCompliance)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := getPayment(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Status != PaymentStatusHeld {
+		return nil, contracterrors.New(contracterrors.InvalidState, "payment %s is not held for review", paymentID)
+	}
+	return payment, nil
+}
+
+func heldPaymentKey(ctx contractapi.TransactionContextInterface, paymentID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(heldPaymentObjectType, []string{paymentID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create held payment key: %v", err)
+	}
+	return key, nil
+}
+
+func deleteHeldPaymentIndex(ctx contractapi.TransactionContextInterface, paymentID string) error {
+	key, err := heldPaymentKey(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
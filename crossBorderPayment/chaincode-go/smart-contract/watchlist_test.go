@@ -0,0 +1,226 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/contracterrors"
+)
+
+func TestWatchlistMaintenance(t *testing.T) {
+	f := newFixture(t)
+
+	err := f.submit(f.anyone, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddWatchlistEntry(ctx, WatchlistCountry, "KP", "sanctioned")
+	})
+	checkErr(t, err, "not authorized")
+
+	err = f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddWatchlistEntry(ctx, "ship", "X", "sanctioned")
+	})
+	checkErr(t, err, `invalid entryType "ship"`)
+
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.AddWatchlistEntry(ctx, WatchlistName, "  john   doe ", "sanctioned")
+	})
+
+	var entries []*WatchlistEntry
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		entries, err = f.admin.QueryWatchlist(ctx)
+		return err
+	})
+	checkErr(t, err, "")
+	if len(entries) != 1 || entries[0].Value != "JOHN DOE" || entries[0].AddedBy != f.compliance.ID() {
+		t.Fatalf("unexpected watchlist %+v", entries)
+	}
+
+	f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RemoveWatchlistEntry(ctx, WatchlistName, "John Doe")
+	})
+	err = f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+		return f.admin.RemoveWatchlistEntry(ctx, WatchlistName, "John Doe")
+	})
+	checkErr(t, err, "does not exist")
+}
+
+func TestScreeningHoldsPayment(t *testing.T) {
+	tests := []struct {
+		name      string
+		entryType string
+		value     string
+		wantHit   string
+	}{
+		{name: "sender customer ID", entryType: WatchlistCustomerID, value: "c1", wantHit: "customerID C1"},
+		{name: "receiver name", entryType: WatchlistName, value: "bob jones", wantHit: "name BOB JONES"},
+		{name: "receiver bank country", entryType: WatchlistCountry, value: "de", wantHit: "country DE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.admin.AddWatchlistEntry(ctx, tt.entryType, tt.value, "test")
+			})
+
+			checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+			assertFloat(t, "A1 balance", f.account("A1").Balance, 1000)
+
+			var held []*Payment
+			err := f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				held, err = f.payments.QueryHeldPayments(ctx)
+				return err
+			})
+			checkErr(t, err, "")
+			if len(held) != 1 || held[0].Status != PaymentStatusHeld || !contains(held[0].ScreeningHits, tt.wantHit) {
+				t.Fatalf("unexpected held payments %+v", held)
+			}
+		})
+	}
+}
+
+func TestReviewHeldPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		review      func(f *fixture) txFunc
+		wantStatus  string
+		wantBalance float64
+		wantErr     string
+	}{
+		{
+			name: "release",
+			review: func(f *fixture) txFunc {
+				return func(ctx contractapi.TransactionContextInterface) error {
+					return f.payments.ReleaseHeldPayment(ctx, "P1")
+				}
+			},
+			wantStatus: PaymentStatusSettled, wantBalance: 900,
+		},
+		{
+			name: "reject",
+			review: func(f *fixture) txFunc {
+				return func(ctx contractapi.TransactionContextInterface) error {
+					return f.payments.RejectHeldPayment(ctx, "P1")
+				}
+			},
+			wantStatus: PaymentStatusRejected, wantBalance: 1000,
+		},
+		{
+			name: "release unknown payment",
+			review: func(f *fixture) txFunc {
+				return func(ctx contractapi.TransactionContextInterface) error {
+					return f.payments.ReleaseHeldPayment(ctx, "NOPAYMENT")
+				}
+			},
+			wantErr: "payment NOPAYMENT does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.admin.AddWatchlistEntry(ctx, WatchlistCountry, "DE", "test")
+			})
+			checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+
+			checkErr(t, f.submit(f.anyone, tt.review(f)), "not authorized")
+			err := f.submit(f.compliance, tt.review(f))
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+
+			var payments []*Payment
+			err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+				payments, err = f.payments.QueryHeldPayments(ctx)
+				return err
+			})
+			checkErr(t, err, "")
+			if len(payments) != 0 {
+				t.Fatalf("payment still held after review")
+			}
+			payment, err := getCommittedPayment(f, "P1")
+			checkErr(t, err, "")
+			if payment.Status != tt.wantStatus {
+				t.Fatalf("payment status = %s, want %s", payment.Status, tt.wantStatus)
+			}
+			assertFloat(t, "A1 balance", f.account("A1").Balance, tt.wantBalance)
+
+			err = f.submit(f.compliance, tt.review(f))
+			checkErr(t, err, "is not held for review")
+		})
+	}
+}
+
+func TestReleaseHeldPaymentChecksAgain(t *testing.T) {
+	tests := []struct {
+		name     string
+		prepare  func(f *fixture)
+		wantCode contracterrors.Code
+	}{
+		{
+			name: "over the tier limit",
+			prepare: func(f *fixture) {
+				f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+					return f.admin.SetTierLimit(ctx, DefaultTier, "USD", 50, 0, 0, 0, 0)
+				})
+			},
+			wantCode: contracterrors.LimitExceeded,
+		},
+		{
+			name: "funds spent while held",
+			prepare: func(f *fixture) {
+				checkErr(f.t, f.pay("P2", "A1", "A3", 950, 1), "")
+			},
+			wantCode: contracterrors.InsufficientFunds,
+		},
+		{
+			name: "rate stale by release",
+			prepare: func(f *fixture) {
+				f.configure(ChaincodeConfig{RateMaxAgeSeconds: 3600})
+				f.stub.Now = f.stub.Now.Add(2 * time.Hour)
+			},
+			wantCode: contracterrors.RateStale,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			f.seed()
+			f.mustSubmit(f.bank1Admin, func(ctx contractapi.TransactionContextInterface) error {
+				return f.accounts.CreateAccount(ctx, "A3", "C1", "BANK1", 0, "", "", "", "")
+			})
+			f.mustSubmit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.admin.AddWatchlistEntry(ctx, WatchlistCountry, "DE", "test")
+			})
+			checkErr(t, f.pay("P1", "A1", "A2", 100, 0.9), "")
+			tt.prepare(f)
+
+			err := f.submit(f.compliance, func(ctx contractapi.TransactionContextInterface) error {
+				return f.payments.ReleaseHeldPayment(ctx, "P1")
+			})
+			checkCode(t, err, tt.wantCode)
+			payment, err := getCommittedPayment(f, "P1")
+			checkErr(t, err, "")
+			if payment.Status != PaymentStatusHeld {
+				t.Fatalf("payment is %s after a failed release", payment.Status)
+			}
+		})
+	}
+}
+
+func getCommittedPayment(f *fixture, paymentID string) (payment *Payment, err error) {
+	err = f.evaluate(f.anyone, func(ctx contractapi.TransactionContextInterface) (err error) {
+		payment, err = getPayment(ctx, paymentID)
+		return err
+	})
+	return payment, err
+}
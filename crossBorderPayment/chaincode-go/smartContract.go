@@ -5,19 +5,34 @@ SPDX-License-Identifier: Apache-2.0
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/ccaas"
 	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
 )
 
+// main runs the chaincode as an external chaincode server if
+// CHAINCODE_SERVER_ADDRESS is set, and otherwise in peer-launched mode. See
+// package ccaas for the other settings.
 func main() {
-	bankSmartContract, err := contractapi.NewChaincode(&bank.SmartContract{})
+	bankChaincode, err := bank.NewChaincode()
 	if err != nil {
 		log.Panicf("Error creating bank chaincode: %v", err)
 	}
 
-	if err := bankSmartContract.Start(); err != nil {
+	config, err := ccaas.ConfigFromEnv(os.Getenv)
+	if err != nil {
+		log.Panicf("Error reading chaincode server configuration: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := ccaas.Run(ctx, config, bankChaincode); err != nil {
 		log.Panicf("Error starting bank chaincode: %v", err)
 	}
 }
@@ -0,0 +1,368 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package swift reads and writes the text block of SWIFT MT103 single
+// customer credit transfers for correspondents that do not take ISO 20022
+// messages yet. The MT103 type covers the fields a payment maps onto; other
+// fields of an inbound message, such as :13C: or :72:, are skipped when it is
+// read.
+//
+// ParseMT103 checks every field against its SWIFT format and the network
+// rules that tie the fields together, and reports every failure as a
+// validation.Errors value whose fields are the tags, such as ":32A:".
+package swift
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// Bank operation codes of field 23B
+const (
+	OperationCredit = "CRED"
+)
+
+// Details of charges of field 71A
+const (
+	ChargesOurs        = "OUR"
+	ChargesShared      = "SHA"
+	ChargesBeneficiary = "BEN"
+)
+
+// Lengths of the SWIFT formats 35x, 4*35x, 16x, 15d, 12d and 34x
+const (
+	lineLength          = 35
+	maxNameLines        = 4
+	maxRemittanceLines  = 4
+	referenceLength     = 16
+	amountLength        = 15
+	exchangeRateLength  = 12
+	partyIdentifierSize = 34
+)
+
+// MT103 is the text block of an MT103 message. Amounts, rates and dates are
+// kept as written in the message, for example "1234,5" and "240301".
+type MT103 struct {
+	SenderReference        string   // :20:
+	BankOperationCode      string   // :23B:
+	ValueDate              string   // :32A: as YYMMDD
+	Currency               string   // :32A:
+	Amount                 string   // :32A:
+	InstructedCurrency     string   // :33B:
+	InstructedAmount       string   // :33B:
+	ExchangeRate           string   // :36:
+	OrderingCustomer       Party    // :50A:, :50F: or :50K:
+	OrderingInstitution    *Party   // :52A: or :52D:
+	AccountWithInstitution *Party   // :57A: or :57D:
+	Beneficiary            Party    // :59:, :59A: or :59F:
+	RemittanceInfo         []string // :70:
+	DetailsOfCharges       string   // :71A:
+	SendersCharges         []string // :71F:, each a currency and an amount such as "USD0,"
+}
+
+// Party is a party or institution field. Identifier is the line starting
+// with a slash that holds an account, or the party identifier of option F,
+// and Lines are the name and address lines or the BIC of option A.
+type Party struct {
+	Option     string
+	Identifier string
+	Lines      []string
+}
+
+// Account returns the account of the party's identifier line, or "" if it
+// has none
+func (p *Party) Account() string {
+	if strings.HasPrefix(p.Identifier, "/") && !strings.HasPrefix(p.Identifier, "//") {
+		return p.Identifier[1:]
+	}
+	return ""
+}
+
+// field is one tag and its value lines
+type field struct {
+	tag   string
+	lines []string
+}
+
+// sequence is the order of the MT103 fields, with the option letters of party
+// fields written as "a". Fields that the MT103 type does not hold are read and
+// skipped.
+var sequence = []string{"20", "13C", "23B", "23E", "26T", "32A", "33B", "36", "50a", "51A", "52a", "53a", "54a", "55a", "56a", "57a", "59a", "70", "71A", "71F", "71G", "72", "77B", "77T"}
+
+// repeatable are the fields that may occur more than once
+var repeatable = map[string]bool{"13C": true, "23E": true, "71F": true}
+
+// partyTags are the fields whose letter is an option
+var partyTags = map[string]bool{"50": true, "52": true, "53": true, "54": true, "55": true, "56": true, "57": true, "59": true}
+
+// ParseMT103 reads an MT103 and checks it against the field formats and
+// network rules. text is the text block, from "{4:" to "-}", or a whole
+// message holding one. Lines may end in CRLF or LF.
+func ParseMT103(text string) (*MT103, error) {
+	fields, err := splitFields(strings.ReplaceAll(text, "\r\n", "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MT103{}
+	c := &checker{}
+	position := 0
+	seen := map[string]bool{}
+	for _, f := range fields {
+		key := sequenceKey(f.tag)
+		next := indexOf(sequence[position:], key)
+		switch {
+		case indexOf(sequence, key) < 0:
+			c.fail(tagField(f.tag), "", "is not an MT103 field")
+			continue
+		case seen[key] && !repeatable[key]:
+			c.fail(tagField(f.tag), "", "may occur only once")
+			continue
+		case next < 0:
+			c.fail(tagField(f.tag), "", "is out of order")
+			continue
+		}
+		position += next
+		seen[key] = true
+
+		value := strings.Join(f.lines, "\n")
+		switch key {
+		case "20":
+			m.SenderReference = value
+		case "23B":
+			m.BankOperationCode = value
+		case "32A":
+			if len(value) < 9 {
+				c.fail(":32A:", value, "must be a date, a currency and an amount")
+				continue
+			}
+			m.ValueDate, m.Currency, m.Amount = value[:6], value[6:9], value[9:]
+		case "33B":
+			if len(value) < 3 {
+				c.fail(":33B:", value, "must be a currency and an amount")
+				continue
+			}
+			m.InstructedCurrency, m.InstructedAmount = value[:3], value[3:]
+		case "36":
+			m.ExchangeRate = value
+		case "50a":
+			m.OrderingCustomer = readParty(f)
+		case "52a":
+			m.OrderingInstitution = partyRef(readParty(f))
+		case "57a":
+			m.AccountWithInstitution = partyRef(readParty(f))
+		case "59a":
+			m.Beneficiary = readParty(f)
+		case "70":
+			m.RemittanceInfo = f.lines
+		case "71A":
+			m.DetailsOfCharges = value
+		case "71F":
+			m.SendersCharges = append(m.SendersCharges, value)
+		}
+	}
+
+	err = m.validate(c)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// splitFields returns the fields of the text block of a message
+func splitFields(text string) ([]field, error) {
+	start := strings.Index(text, "{4:")
+	if start < 0 {
+		return nil, validation.Errors{formatError("{4:", "", "the message has no text block")}
+	}
+	body := text[start+len("{4:"):]
+	end := strings.Index(body, "\n-}")
+	if end < 0 {
+		return nil, validation.Errors{formatError("{4:", "", "the text block must end with a line holding -}")}
+	}
+	body = body[:end]
+	if !strings.HasPrefix(body, "\n") {
+		return nil, validation.Errors{formatError("{4:", "", "the text block must start on a new line")}
+	}
+
+	var fields []field
+	for _, line := range strings.Split(body[1:], "\n") {
+		if tag, value, ok := cutTag(line); ok {
+			fields = append(fields, field{tag: tag, lines: []string{value}})
+			continue
+		}
+		if len(fields) == 0 {
+			return nil, validation.Errors{formatError("{4:", line, "the text block must start with a field tag")}
+		}
+		last := &fields[len(fields)-1]
+		last.lines = append(last.lines, line)
+	}
+	return fields, nil
+}
+
+// cutTag splits a line such as ":32A:240301EUR90," into its tag and value
+func cutTag(line string) (string, string, bool) {
+	if len(line) < 4 || line[0] != ':' {
+		return "", "", false
+	}
+	end := strings.Index(line[1:], ":")
+	if end < 2 || end > 3 {
+		return "", "", false
+	}
+	tag := line[1 : end+1]
+	if !isDigit(tag[0]) || !isDigit(tag[1]) || (len(tag) == 3 && !(tag[2] >= 'A' && tag[2] <= 'Z')) {
+		return "", "", false
+	}
+	return tag, line[end+2:], true
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// sequenceKey returns the entry of sequence for a tag
+func sequenceKey(tag string) string {
+	if partyTags[tag[:2]] {
+		return tag[:2] + "a"
+	}
+	return tag
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func readParty(f field) Party {
+	party := Party{Option: f.tag[2:], Lines: f.lines}
+	if (party.Option == "F" && f.tag[:2] == "50") || strings.HasPrefix(f.lines[0], "/") {
+		party.Identifier, party.Lines = f.lines[0], f.lines[1:]
+	}
+	return party
+}
+
+func partyRef(party Party) *Party {
+	return &party
+}
+
+// Format writes the text block of the message, from "{4:" to "-}", with CRLF
+// line ends
+func (m *MT103) Format() string {
+	var b strings.Builder
+	write := func(tag string, lines ...string) {
+		b.WriteString(":" + tag + ":" + strings.Join(lines, "\r\n") + "\r\n")
+	}
+	writeParty := func(tag string, party *Party) {
+		var lines []string
+		if party.Identifier != "" {
+			lines = append(lines, party.Identifier)
+		}
+		write(tag+party.Option, append(lines, party.Lines...)...)
+	}
+
+	b.WriteString("{4:\r\n")
+	write("20", m.SenderReference)
+	write("23B", m.BankOperationCode)
+	write("32A", m.ValueDate+m.Currency+m.Amount)
+	if m.InstructedCurrency != "" || m.InstructedAmount != "" {
+		write("33B", m.InstructedCurrency+m.InstructedAmount)
+	}
+	if m.ExchangeRate != "" {
+		write("36", m.ExchangeRate)
+	}
+	writeParty("50", &m.OrderingCustomer)
+	if m.OrderingInstitution != nil {
+		writeParty("52", m.OrderingInstitution)
+	}
+	if m.AccountWithInstitution != nil {
+		writeParty("57", m.AccountWithInstitution)
+	}
+	writeParty("59", &m.Beneficiary)
+	if len(m.RemittanceInfo) > 0 {
+		write("70", m.RemittanceInfo...)
+	}
+	write("71A", m.DetailsOfCharges)
+	for _, charges := range m.SendersCharges {
+		write("71F", charges)
+	}
+	b.WriteString("-}")
+	return b.String()
+}
+
+// Validate checks the message against the field formats and network rules
+func (m *MT103) Validate() error {
+	return m.validate(&checker{})
+}
+
+func (m *MT103) validate(c *checker) error {
+	c.reference(":20:", m.SenderReference)
+	if m.BankOperationCode == "" {
+		c.fail(":23B:", "", "is required")
+	} else {
+		c.code(":23B:", m.BankOperationCode, OperationCredit, "CRTS", "SPAY", "SPRI", "SSTD")
+	}
+
+	if m.ValueDate == "" && m.Currency == "" && m.Amount == "" {
+		c.fail(":32A:", "", "is required")
+	} else {
+		c.date(":32A:", m.ValueDate)
+		c.amount(":32A:", m.Currency, m.Amount, true)
+	}
+	instructed := m.InstructedCurrency != "" || m.InstructedAmount != ""
+	if instructed {
+		c.amount(":33B:", m.InstructedCurrency, m.InstructedAmount, true)
+	}
+	// Network rule C1: the exchange rate is given if and only if the
+	// instructed amount is in another currency
+	converted := instructed && m.InstructedCurrency != m.Currency
+	switch {
+	case converted && m.ExchangeRate == "":
+		c.fail(":36:", "", "is required when :33B: is in another currency than :32A:")
+	case !converted && m.ExchangeRate != "":
+		c.fail(":36:", m.ExchangeRate, "is only allowed when :33B: is in another currency than :32A:")
+	case m.ExchangeRate != "":
+		c.decimal(":36:", m.ExchangeRate, exchangeRateLength, -1, true)
+	}
+
+	c.party(":50a:", &m.OrderingCustomer, "A", "F", "K")
+	if m.OrderingInstitution != nil {
+		c.party(":52a:", m.OrderingInstitution, "A", "D")
+	}
+	if m.AccountWithInstitution != nil {
+		c.party(":57a:", m.AccountWithInstitution, "A", "D")
+	}
+	c.party(":59a:", &m.Beneficiary, "", "A", "F")
+	c.lines(":70:", m.RemittanceInfo, maxRemittanceLines)
+
+	c.code(":71A:", m.DetailsOfCharges, ChargesOurs, ChargesShared, ChargesBeneficiary)
+	for i, charges := range m.SendersCharges {
+		field := fmt.Sprintf(":71F:[%d]", i)
+		if len(charges) < 3 {
+			c.fail(field, charges, "must be a currency and an amount")
+			continue
+		}
+		c.amount(field, charges[:3], charges[3:], false)
+	}
+	// Network rules E13, D50 and D75: who bears the charges decides whether
+	// the sender's charges are given, and charges need the instructed amount
+	switch {
+	case m.DetailsOfCharges == ChargesOurs && len(m.SendersCharges) > 0:
+		c.fail(":71F:", "", "is not allowed when :71A: is OUR")
+	case m.DetailsOfCharges == ChargesBeneficiary && len(m.SendersCharges) == 0:
+		c.fail(":71F:", "", "is required when :71A: is BEN")
+	case len(m.SendersCharges) > 0 && !instructed:
+		c.fail(":33B:", "", "is required when :71F: is present")
+	}
+
+	if len(c.failed) > 0 {
+		return c.failed
+	}
+	return nil
+}
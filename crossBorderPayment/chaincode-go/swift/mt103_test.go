@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package swift
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/iso20022"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// inbound is an MT103 from a correspondent, with fields the ledger does not
+// use and LF line ends
+const inbound = `{1:F01COBADEFFAXXX0000000000}{2:O1031200240301BNPAFRPPAXXX00000000002403011200N}{3:{121:eb6305c9-1f7f-49de-aed0-16487c27b42d}}{4:
+:20:REF-2024-17
+:13C:/SNDTIME/1200+0100
+:23B:CRED
+:32A:240301EUR1234,5
+:50K:/A1
+ALICE SMITH
+MAIN STREET 1
+:52A:COBADEFFXXX
+:57A:BNPAFRPP
+:59:/A2
+BOB JONES
+:70:INVOICE 17
+:71A:OUR
+:72:/INS/COBADEFF
+-}{5:{CHK:123456789ABC}}`
+
+func TestParseMT103(t *testing.T) {
+	m, err := ParseMT103(inbound)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &MT103{
+		SenderReference:        "REF-2024-17",
+		BankOperationCode:      OperationCredit,
+		ValueDate:              "240301",
+		Currency:               "EUR",
+		Amount:                 "1234,5",
+		OrderingCustomer:       Party{Option: "K", Identifier: "/A1", Lines: []string{"ALICE SMITH", "MAIN STREET 1"}},
+		OrderingInstitution:    &Party{Option: "A", Lines: []string{"COBADEFFXXX"}},
+		AccountWithInstitution: &Party{Option: "A", Lines: []string{"BNPAFRPP"}},
+		Beneficiary:            Party{Identifier: "/A2", Lines: []string{"BOB JONES"}},
+		RemittanceInfo:         []string{"INVOICE 17"},
+		DetailsOfCharges:       ChargesOurs,
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got %+v, want %+v", m, want)
+	}
+
+	instruction, err := m.PaymentInstruction()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantInstruction := &bank.PaymentInstruction{PaymentID: "REF-2024-17", SenderAccountID: "A1", ReceiverAccountID: "A2", Amount: 1234.5, ExchangeRate: 1, Date: "2024-03-01"}
+	if !reflect.DeepEqual(instruction, wantInstruction) {
+		t.Fatalf("got %+v, want %+v", instruction, wantInstruction)
+	}
+}
+
+func TestFieldValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		replace    []string
+		wantFields []string
+	}{
+		{name: "reference too long", replace: []string{":20:REF-2024-17", ":20:REF-2024-17-ABCDEF"}, wantFields: []string{":20:"}},
+		{name: "reference with slashes", replace: []string{":20:REF-2024-17", ":20:REF//17"}, wantFields: []string{":20:"}},
+		{name: "operation code", replace: []string{":23B:CRED", ":23B:CREDIT"}, wantFields: []string{":23B:"}},
+		{name: "value date", replace: []string{"240301EUR", "241301EUR"}, wantFields: []string{":32A:"}},
+		{name: "decimal point", replace: []string{"EUR1234,5", "EUR1234.5"}, wantFields: []string{":32A:"}},
+		{name: "fraction digits", replace: []string{"EUR1234,5", "JPY1234,5"}, wantFields: []string{":32A:"}},
+		{name: "currency", replace: []string{"EUR1234,5", "EUX1234,5"}, wantFields: []string{":32A:"}},
+		{name: "rate without instructed amount", replace: []string{":50K:", ":36:0,9\n:50K:"}, wantFields: []string{":36:"}},
+		{name: "instructed amount without rate", replace: []string{":50K:", ":33B:USD1371,67\n:50K:"}, wantFields: []string{":36:"}},
+		{name: "character set", replace: []string{"ALICE SMITH", "ALICE SMITH & CO"}, wantFields: []string{":50a:"}},
+		{name: "BIC", replace: []string{":57A:BNPAFRPP", ":57A:BNPA"}, wantFields: []string{":57a:"}},
+		{name: "option", replace: []string{":57A:BNPAFRPP", ":57C:/BNPAFRPP"}, wantFields: []string{":57a:"}},
+		{name: "remittance lines", replace: []string{":70:INVOICE 17", ":70:1\n2\n3\n4\n5"}, wantFields: []string{":70:"}},
+		{name: "charges", replace: []string{":71A:OUR", ":71A:BEN"}, wantFields: []string{":71F:"}},
+		{name: "missing field", replace: []string{":23B:CRED\n", ""}, wantFields: []string{":23B:"}},
+		{name: "order", replace: []string{":23B:CRED\n", "", ":71A:OUR", ":71A:OUR\n:23B:CRED"}, wantFields: []string{":23B:", ":23B:"}},
+		{name: "repeated", replace: []string{":70:INVOICE 17", ":70:INVOICE 17\n:70:AGAIN"}, wantFields: []string{":70:"}},
+		{name: "unknown field", replace: []string{":72:", ":99:"}, wantFields: []string{":99:"}},
+		{name: "no text block", replace: []string{"{4:", "{9:"}, wantFields: []string{"{4:"}},
+		{
+			name:       "option F",
+			replace:    []string{":50K:/A1\nALICE SMITH\nMAIN STREET 1", ":50F:/A1\n2/MAIN STREET 1\n1/ALICE SMITH"},
+			wantFields: []string{":50a:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMT103(strings.NewReplacer(tt.replace...).Replace(inbound))
+			failed, ok := err.(validation.Errors)
+			if !ok {
+				t.Fatalf("err = %v, want validation errors", err)
+			}
+			var fields []string
+			for _, e := range failed {
+				fields = append(fields, e.Field)
+				if e.Rule != RuleFormat {
+					t.Fatalf("rule = %s", e.Rule)
+				}
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("failed fields %v (%v), want %v", fields, err, tt.wantFields)
+			}
+		})
+	}
+}
+
+func parties() *Parties {
+	return &Parties{
+		SenderCustomer:   &bank.Customer{CustomerID: "C1", Name: "Alice", Surname: "Smith"},
+		ReceiverCustomer: &bank.Customer{CustomerID: "C2", Name: "Bob", Surname: "Jones"},
+		SenderBank:       &bank.Bank{BankID: "BANK1", Name: "First Bank", Country: "US", Currency: "USD"},
+		ReceiverBank:     &bank.Bank{BankID: "BANK2", Name: "Second Bank", Country: "DE", Currency: "EUR"},
+	}
+}
+
+func TestRenderRoundTrip(t *testing.T) {
+	payment := &bank.Payment{
+		PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", ReceiverCustomerID: "C2",
+		Amount: 100, ExchangeRate: 0.9, Date: "2024-01-02", Status: bank.PaymentStatusSettled,
+		EndToEndID: "INV-2024-17", RemittanceInfo: "Invoice 2024-17\nThank you", ChargeBearer: iso20022.ChargeBearerDebtor,
+	}
+	text, err := RenderMT103(payment, parties())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := strings.Join([]string{
+		"{4:",
+		":20:P1",
+		":23B:CRED",
+		":32A:240102EUR90,",
+		":33B:USD100,",
+		":36:0,9",
+		":50F:/A1",
+		"1/Alice Smith",
+		"6/US/BANK1/C1",
+		":52D:First Bank",
+		":57D:Second Bank",
+		":59:/A2",
+		"Bob Jones",
+		":70:/ROC/INV-2024-17",
+		"Invoice 2024-17",
+		"Thank you",
+		":71A:OUR",
+		"-}",
+	}, "\r\n")
+	if text != want {
+		t.Fatalf("got\n%s\nwant\n%s", text, want)
+	}
+
+	m, err := ParseMT103(text)
+	if err != nil {
+		t.Fatalf("rendered message is invalid: %v", err)
+	}
+	if m.Format() != text {
+		t.Fatalf("formatting the parsed message gave\n%s", m.Format())
+	}
+	instruction, err := m.PaymentInstruction()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantInstruction := &bank.PaymentInstruction{PaymentID: "P1", SenderAccountID: "A1", ReceiverAccountID: "A2", SenderCustomerID: "C1", Amount: 100, ExchangeRate: 0.9, Date: "2024-01-02"}
+	if !reflect.DeepEqual(instruction, wantInstruction) {
+		t.Fatalf("got %+v, want %+v", instruction, wantInstruction)
+	}
+}
+
+func TestRenderMT103(t *testing.T) {
+	sameCurrency := parties()
+	sameCurrency.ReceiverBank = sameCurrency.SenderBank
+
+	tests := []struct {
+		name    string
+		payment bank.Payment
+		parties *Parties
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "charges on the beneficiary",
+			payment: bank.Payment{PaymentID: "P2", Amount: 25.5, ExchangeRate: 1, Date: "2024-01-02T10:00:00Z", ChargeBearer: iso20022.ChargeBearerCreditor},
+			parties: sameCurrency,
+			want:    []string{":32A:240102USD25,5", ":33B:USD25,5", ":71A:BEN", ":71F:USD0,"},
+		},
+		{
+			name:    "long name",
+			payment: bank.Payment{PaymentID: "P3", Amount: 1, ExchangeRate: 1, Date: "2024-01-02"},
+			parties: &Parties{
+				SenderCustomer:   &bank.Customer{Name: "Alexandra Maria Theodora", Surname: "Smith-Montgomery"},
+				ReceiverCustomer: sameCurrency.ReceiverCustomer, SenderBank: sameCurrency.SenderBank, ReceiverBank: sameCurrency.ReceiverBank,
+			},
+			want: []string{"1/Alexandra Maria Theodora Smith-Mo", "1/ntgomery", ":71A:SHA"},
+		},
+		{
+			name: "structured remittance",
+			payment: bank.Payment{PaymentID: "P6", Amount: 1, ExchangeRate: 1, Date: "2024-01-02", RemittanceInfo: "March",
+				CreditorReference: "RF18539007547034", InvoiceNumbers: []string{"INV-17"}, PurposeCode: "SUPP"},
+			parties: sameCurrency,
+			want:    []string{":70:/RFB/RF18539007547034", "/INV/INV-17", "March"},
+		},
+		{
+			name:    "held payment",
+			payment: bank.Payment{PaymentID: "P4", Amount: 1, ExchangeRate: 1, Date: "2024-01-02", Status: bank.PaymentStatusHeld},
+			parties: sameCurrency, wantErr: "only settled payments",
+		},
+		{
+			name:    "reference too long for :20:",
+			payment: bank.Payment{PaymentID: "PAYMENT-2024-000017", Amount: 1, ExchangeRate: 1, Date: "2024-01-02"},
+			parties: sameCurrency, wantErr: ":20:",
+		},
+		{
+			name:    "remittance information too long for :70:",
+			payment: bank.Payment{PaymentID: "P5", Amount: 1, ExchangeRate: 1, Date: "2024-01-02", RemittanceInfo: strings.Repeat("x", 141)},
+			parties: sameCurrency, wantErr: ":70:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.payment.SenderAccountID, tt.payment.ReceiverAccountID = "A1", "A2"
+			text, err := RenderMT103(&tt.payment, tt.parties)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(text, line+"\r\n") {
+					t.Fatalf("message has no line %q:\n%s", line, text)
+				}
+			}
+			if _, err := ParseMT103(text); err != nil {
+				t.Fatalf("rendered message is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{got: FormatAmount(90.00000000001, "EUR"), want: "90,"},
+		{got: FormatAmount(12.345, "USD"), want: "12,35"},
+		{got: FormatAmount(1234.5, "JPY"), want: "1235,"},
+		{got: FormatAmount(1.2345, "KWD"), want: "1,235"},
+		{got: FormatRate(0.8888888888888), want: "0,8888888889"},
+		{got: FormatRate(1234.5), want: "1234,5"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Fatalf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
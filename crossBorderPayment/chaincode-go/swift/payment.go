@@ -0,0 +1,248 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package swift
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/iso20022"
+	bank "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+)
+
+// Codewords that start the lines of :70: carrying the end-to-end reference,
+// the creditor reference and an invoice number
+const (
+	endToEndPrefix  = "/ROC/"
+	referencePrefix = "/RFB/"
+	invoicePrefix   = "/INV/"
+)
+
+// Parties are the ledger objects an MT103 describes besides the payment itself
+type Parties struct {
+	SenderCustomer, ReceiverCustomer *bank.Customer
+	SenderBank, ReceiverBank         *bank.Bank
+}
+
+// RenderMT103 writes a settled payment as the text block of an MT103. :32A:
+// holds the amount credited to the receiver and, for payments between
+// currencies, :33B: and :36: the amount debited from the sender and the
+// rate. The ordering customer is written in option F with the sender's
+// customer ID as its customer identification number, and the banks by name
+// in option D. The end-to-end ID, creditor reference, invoice numbers and
+// remittance information go into :70:, each reference on a line of its own.
+// MT103 has no field for the purpose code, which is left out.
+func RenderMT103(payment *bank.Payment, parties *Parties) (string, error) {
+	if payment.Status != "" && payment.Status != bank.PaymentStatusSettled {
+		return "", fmt.Errorf("payment %s is %s; only settled payments can be sent as MT103", payment.PaymentID, payment.Status)
+	}
+	date, err := parseDate(payment.Date)
+	if err != nil {
+		return "", fmt.Errorf("payment %s has no valid date: %v", payment.PaymentID, err)
+	}
+	senderCurrency, receiverCurrency := parties.SenderBank.Currency, parties.ReceiverBank.Currency
+
+	m := &MT103{
+		SenderReference:   payment.PaymentID,
+		BankOperationCode: OperationCredit,
+		ValueDate:         date.Format("060102"),
+		Currency:          receiverCurrency,
+		Amount:            FormatAmount(payment.Amount*payment.ExchangeRate, receiverCurrency),
+		OrderingCustomer: Party{
+			Option:     "F",
+			Identifier: "/" + payment.SenderAccountID,
+			Lines: append(numbered("1/", customerName(parties.SenderCustomer)),
+				fmt.Sprintf("6/%s/%s/%s", parties.SenderBank.Country, parties.SenderBank.BankID, payment.SenderCustomerID)),
+		},
+		OrderingInstitution:    &Party{Option: "D", Lines: wrap(parties.SenderBank.Name, lineLength, maxNameLines)},
+		AccountWithInstitution: &Party{Option: "D", Lines: wrap(parties.ReceiverBank.Name, lineLength, maxNameLines)},
+		Beneficiary: Party{
+			Identifier: "/" + payment.ReceiverAccountID,
+			Lines:      wrap(customerName(parties.ReceiverCustomer), lineLength, maxNameLines),
+		},
+		DetailsOfCharges: ChargesShared,
+	}
+	if senderCurrency != receiverCurrency {
+		m.InstructedCurrency, m.InstructedAmount = senderCurrency, FormatAmount(payment.Amount, senderCurrency)
+		m.ExchangeRate = FormatRate(payment.ExchangeRate)
+	}
+	if payment.EndToEndID != "" && payment.EndToEndID != iso20022.NotProvided {
+		m.RemittanceInfo = append(m.RemittanceInfo, endToEndPrefix+payment.EndToEndID)
+	}
+	if payment.CreditorReference != "" {
+		m.RemittanceInfo = append(m.RemittanceInfo, referencePrefix+payment.CreditorReference)
+	}
+	for _, number := range payment.InvoiceNumbers {
+		m.RemittanceInfo = append(m.RemittanceInfo, wrap(invoicePrefix+number, lineLength, -1)...)
+	}
+	if payment.RemittanceInfo != "" {
+		m.RemittanceInfo = append(m.RemittanceInfo, wrap(payment.RemittanceInfo, lineLength, -1)...)
+	}
+	switch payment.ChargeBearer {
+	case iso20022.ChargeBearerDebtor:
+		m.DetailsOfCharges = ChargesOurs
+	case iso20022.ChargeBearerCreditor:
+		// BEN needs the sender's charges, and the ledger takes none
+		m.DetailsOfCharges = ChargesBeneficiary
+		m.SendersCharges = []string{senderCurrency + FormatAmount(0, senderCurrency)}
+		if m.InstructedCurrency == "" {
+			m.InstructedCurrency, m.InstructedAmount = senderCurrency, FormatAmount(payment.Amount, senderCurrency)
+		}
+	}
+
+	err = m.Validate()
+	if err != nil {
+		return "", err
+	}
+	return m.Format(), nil
+}
+
+func customerName(customer *bank.Customer) string {
+	return strings.TrimSpace(customer.Name + " " + customer.Surname)
+}
+
+// numbered writes text on option F lines that all start with prefix, which
+// repeats when the text is too long for one line. One line is left for the
+// customer identification number.
+func numbered(prefix string, text string) []string {
+	lines := wrap(text, lineLength-len(prefix), maxNameLines-1)
+	for i := range lines {
+		lines[i] = prefix + lines[i]
+	}
+	return lines
+}
+
+// wrap splits text into lines of at most width characters, breaking at line
+// ends first. The result is cut to max lines unless max is negative, so that
+// Validate reports text that does not fit.
+func wrap(text string, width int, max int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		for len(line) > width {
+			lines = append(lines, line[:width])
+			line = line[width:]
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if max >= 0 && len(lines) > max {
+		lines = lines[:max]
+	}
+	return lines
+}
+
+// parseDate reads a payment date, which is an ISO date or an RFC 3339 time
+func parseDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an ISO date", value)
+	}
+	return date.UTC(), nil
+}
+
+// FormatAmount writes an amount with a decimal comma and the number of
+// fraction digits of its currency, for example "1234,5" or "100,"
+func FormatAmount(amount float64, currency string) string {
+	return formatDecimal(amount, fractionDigits(currency))
+}
+
+// FormatRate writes an exchange rate in the 12 characters :36: allows
+func FormatRate(rate float64) string {
+	intDigits := len(strconv.FormatFloat(math.Trunc(math.Abs(rate)), 'f', 0, 64))
+	return formatDecimal(rate, exchangeRateLength-1-intDigits)
+}
+
+func formatDecimal(f float64, fraction int) string {
+	if fraction < 0 {
+		fraction = 0
+	}
+	scale := math.Pow10(fraction)
+	s := strconv.FormatFloat(math.Round(f*scale)/scale, 'f', fraction, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+	} else {
+		s += "."
+	}
+	return strings.Replace(s, ".", ",", 1)
+}
+
+// ParseDecimal reads a number with a decimal comma such as "1234,56"
+func ParseDecimal(value string) (float64, error) {
+	if !decimalPattern.MatchString(value) {
+		return 0, fmt.Errorf("%q is not a number with a decimal comma", value)
+	}
+	return strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+}
+
+// PaymentInstruction returns the CreatePayment arguments of a valid MT103.
+// The amount is the instructed amount of :33B:, or the amount of :32A: if the
+// message has none. The accounts are those of :50a: and :59a:, and the sender
+// customer ID is the last part of a 6/ line of :50F:. :59a: cannot carry a
+// customer ID, so ReceiverCustomerID is left empty for the caller to fill in
+// from the owner of the receiver account.
+func (m *MT103) PaymentInstruction() (*bank.PaymentInstruction, error) {
+	c := &checker{}
+	instruction := &bank.PaymentInstruction{
+		PaymentID:         m.SenderReference,
+		SenderAccountID:   m.OrderingCustomer.Account(),
+		ReceiverAccountID: m.Beneficiary.Account(),
+		ExchangeRate:      1,
+	}
+	if instruction.SenderAccountID == "" {
+		c.fail(":50a:", m.OrderingCustomer.Identifier, "must start with /account to name the sender account")
+	}
+	if instruction.ReceiverAccountID == "" {
+		c.fail(":59a:", m.Beneficiary.Identifier, "must start with /account to name the receiver account")
+	}
+	if m.OrderingCustomer.Option == "F" {
+		for _, line := range m.OrderingCustomer.Lines {
+			parts := strings.SplitN(line, "/", 4)
+			if parts[0] == "6" && len(parts) == 4 {
+				instruction.SenderCustomerID = parts[3]
+			}
+		}
+	}
+
+	date, _ := time.Parse("060102", m.ValueDate)
+	instruction.Date = date.Format("2006-01-02")
+	var err error
+	instruction.Amount, err = ParseDecimal(m.Amount)
+	if err != nil {
+		c.fail(":32A:", m.Amount, "%v", err)
+	}
+	if m.InstructedAmount != "" {
+		instruction.Amount, err = ParseDecimal(m.InstructedAmount)
+		if err != nil {
+			c.fail(":33B:", m.InstructedAmount, "%v", err)
+		}
+	}
+	if m.ExchangeRate != "" {
+		instruction.ExchangeRate, err = ParseDecimal(m.ExchangeRate)
+		if err != nil {
+			c.fail(":36:", m.ExchangeRate, "%v", err)
+		}
+	}
+
+	if len(c.failed) > 0 {
+		return nil, c.failed
+	}
+	return instruction, nil
+}
+
+// ParsePaymentInstruction reads an inbound MT103 into CreatePayment arguments
+func ParsePaymentInstruction(text string) (*bank.PaymentInstruction, error) {
+	m, err := ParseMT103(text)
+	if err != nil {
+		return nil, err
+	}
+	return m.PaymentInstruction()
+}
@@ -0,0 +1,204 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package swift
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/auction/chaincode-go/validation"
+)
+
+// RuleFormat is the validation rule reported for messages that break the
+// MT103 field formats or network rules
+const RuleFormat = "mt103_format"
+
+// Patterns of the SWIFT formats
+var (
+	// xCharacters is the SWIFT x character set
+	xCharacters      = regexp.MustCompile(`^[a-zA-Z0-9/\-?:().,'+ ]*$`)
+	bicPattern       = regexp.MustCompile(`^[A-Z0-9]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	currencyPattern  = regexp.MustCompile(`^[A-Z]{3}$`)
+	decimalPattern   = regexp.MustCompile(`^[0-9]+,[0-9]*$`)
+	partyCodePattern = regexp.MustCompile(`^[A-Z]{4}/[A-Z]{2}/.+$`)
+	numberedLine     = regexp.MustCompile(`^[1-8]/.+$`)
+)
+
+// minorUnits are the ISO 4217 currencies whose amounts do not have two
+// digits after the decimal comma
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// fractionDigits returns how many digits amounts in a currency may have after
+// the decimal comma
+func fractionDigits(currency string) int {
+	if digits, ok := minorUnits[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+func formatError(field string, value string, format string, args ...interface{}) *validation.Error {
+	return &validation.Error{Field: field, Rule: RuleFormat, Value: value, Message: fmt.Sprintf(format, args...)}
+}
+
+// tagField names a field in errors, with the option letter of party fields
+// written as "a"
+func tagField(tag string) string {
+	return ":" + sequenceKey(tag) + ":"
+}
+
+// checker gathers the format failures of one message
+type checker struct {
+	failed validation.Errors
+}
+
+func (c *checker) fail(field string, value string, format string, args ...interface{}) {
+	c.failed = append(c.failed, formatError(field, value, format, args...))
+}
+
+// text checks one line of the x character set of at most max characters
+func (c *checker) text(field string, value string, max int) bool {
+	switch {
+	case len(value) > max:
+		c.fail(field, value, "must be at most %d characters", max)
+	case !xCharacters.MatchString(value):
+		c.fail(field, value, "may only contain letters, digits, spaces and / - ? : ( ) . , ' +")
+	default:
+		return true
+	}
+	return false
+}
+
+// reference checks a 16x reference, which must not start or end with a slash
+// or hold two slashes in a row
+func (c *checker) reference(field string, value string) {
+	if value == "" {
+		c.fail(field, value, "is required")
+		return
+	}
+	if c.text(field, value, referenceLength) && (strings.HasPrefix(value, "/") || strings.HasSuffix(value, "/") || strings.Contains(value, "//")) {
+		c.fail(field, value, "must not start or end with / or contain //")
+	}
+}
+
+func (c *checker) code(field string, value string, codes ...string) {
+	for _, code := range codes {
+		if value == code {
+			return
+		}
+	}
+	c.fail(field, value, "must be one of %s", strings.Join(codes, ", "))
+}
+
+func (c *checker) date(field string, value string) {
+	if _, err := time.Parse("060102", value); err != nil {
+		c.fail(field, value, "must start with the value date as YYMMDD")
+	}
+}
+
+// decimal checks a number with a decimal comma of at most length characters
+// and at most fraction digits after the comma, or any number of them if
+// fraction is negative
+func (c *checker) decimal(field string, value string, length int, fraction int, positive bool) {
+	switch {
+	case !decimalPattern.MatchString(value):
+		c.fail(field, value, "must be digits with a decimal comma, such as 1234,56")
+	case len(value) > length:
+		c.fail(field, value, "must be at most %d characters", length)
+	case fraction >= 0 && len(value)-strings.Index(value, ",")-1 > fraction:
+		c.fail(field, value, "must have at most %d digits after the decimal comma", fraction)
+	case positive && strings.Trim(value, "0,") == "":
+		c.fail(field, value, "must be greater than zero")
+	}
+}
+
+func (c *checker) amount(field string, currency string, amount string, positive bool) {
+	if !currencyPattern.MatchString(currency) || validation.Currency(field, currency) != nil {
+		c.fail(field, currency, "does not hold an ISO 4217 currency code")
+		return
+	}
+	c.decimal(field, amount, amountLength, fractionDigits(currency), positive)
+}
+
+// lines checks up to max lines of 35x
+func (c *checker) lines(field string, lines []string, max int) {
+	if len(lines) > max {
+		c.fail(field, strings.Join(lines, "\n"), "must be at most %d lines", max)
+	}
+	for _, line := range lines {
+		c.text(field, line, lineLength)
+	}
+}
+
+// party checks a party field written in one of options
+func (c *checker) party(field string, party *Party, options ...string) {
+	if party.Option == "" && party.Identifier == "" && len(party.Lines) == 0 {
+		c.fail(field, "", "is required")
+		return
+	}
+	if indexOf(options, party.Option) < 0 {
+		c.fail(field, party.Option, "must use option %s", strings.Join(options, ", "))
+		return
+	}
+
+	if party.Identifier != "" {
+		c.text(field, party.Identifier, partyIdentifierSize+1)
+	}
+	switch party.Option {
+	case "A":
+		if len(party.Lines) != 1 || !bicPattern.MatchString(party.Lines[0]) {
+			c.fail(field, strings.Join(party.Lines, "\n"), "must hold a BIC")
+		}
+		return
+	case "F":
+		c.numberedLines(field, party)
+		return
+	}
+	if len(party.Lines) == 0 {
+		c.fail(field, "", "must hold a name")
+	}
+	c.lines(field, party.Lines, maxNameLines)
+}
+
+// numberedLines checks option F, whose lines start with a number saying what
+// they hold, such as 1/ for the name and 6/ for a customer identification
+// number
+func (c *checker) numberedLines(field string, party *Party) {
+	if field == ":50a:" && party.Identifier == "" {
+		c.fail(field, "", "must start with a party identifier")
+	} else if party.Identifier != "" && !strings.HasPrefix(party.Identifier, "/") && !partyCodePattern.MatchString(party.Identifier) {
+		c.fail(field, party.Identifier, "must start with /account or a party identifier code such as CUST/US/...")
+	}
+	c.lines(field, party.Lines, maxNameLines)
+
+	max := byte('8')
+	if field == ":59a:" {
+		max = '3'
+	}
+	last := byte('1')
+	for i, line := range party.Lines {
+		switch {
+		case !numberedLine.MatchString(line) || line[0] > max:
+			c.fail(field, line, "lines must start with a number from 1 to %c and a slash", max)
+			return
+		case i == 0 && line[0] != '1':
+			c.fail(field, line, "must start with the name on a line 1/")
+			return
+		case line[0] < last:
+			c.fail(field, line, "numbered lines must be in order")
+			return
+		}
+		last = line[0]
+	}
+	if len(party.Lines) == 0 {
+		c.fail(field, "", "must hold a name")
+	}
+}
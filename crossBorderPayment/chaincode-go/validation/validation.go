@@ -0,0 +1,344 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package validation checks transaction arguments before the contract touches
+// the world state. Each rule returns nil or an *Error naming the offending
+// argument, and Check gathers the failures of several rules into one Errors
+// value so a client sees every problem with its request at once.
+package validation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// MaxIDLength is the longest ID accepted for banks, customers, accounts,
+// payments and other ledger objects
+const MaxIDLength = 64
+
+// Rules reported in Error.Rule
+const (
+	RuleRequired      = "required"
+	RuleIDFormat      = "id_format"
+	RuleCurrency      = "iso4217"
+	RuleCountry       = "iso3166"
+	RulePositive      = "positive"
+	RuleNonNegative   = "non_negative"
+	RuleNonZero       = "non_zero"
+	RuleSelfTransfer  = "self_transfer"
+	RuleOneOf         = "one_of"
+	RuleRange         = "range"
+	RuleIBAN          = "iban"
+	RuleBIC           = "bic"
+	RuleRoutingNumber = "routing_number"
+	RuleAccountNumber = "account_number"
+	RuleLength        = "length"
+	RuleReference     = "creditor_reference"
+	RulePurposeCode   = "purpose_code"
+)
+
+// Routing schemes of local account numbers, each with the country whose
+// clearing system issues its routing numbers
+const (
+	RoutingABA      = "ABA"      // US routing transit number
+	RoutingSortCode = "SORTCODE" // UK sort code
+	RoutingBSB      = "BSB"      // Australian bank-state-branch number
+	RoutingIFSC     = "IFSC"     // Indian financial system code
+)
+
+// RoutingSchemeCountries maps each routing scheme to its country
+var RoutingSchemeCountries = map[string]string{
+	RoutingABA:      "US",
+	RoutingSortCode: "GB",
+	RoutingBSB:      "AU",
+	RoutingIFSC:     "IN",
+}
+
+var (
+	bicPattern           = regexp.MustCompile(`^[A-Z0-9]{4}([A-Z]{2})[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	ibanPattern          = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{1,30}$`)
+	accountNumberPattern = regexp.MustCompile(`^[A-Z0-9]{1,34}$`)
+	referencePattern     = regexp.MustCompile(`^RF[0-9]{2}[A-Z0-9]{1,21}$`)
+	purposeCodePattern   = regexp.MustCompile(`^[A-Z]{4}$`)
+	routingPatterns      = map[string]*regexp.Regexp{
+		RoutingABA:      regexp.MustCompile(`^[0-9]{9}$`),
+		RoutingSortCode: regexp.MustCompile(`^[0-9]{6}$`),
+		RoutingBSB:      regexp.MustCompile(`^[0-9]{6}$`),
+		RoutingIFSC:     regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`),
+	}
+)
+
+// Error is a single failed rule
+type Error struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Message)
+}
+
+// Errors is every rule that failed for one transaction
+type Errors []*Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Check runs rules in order and returns nil if all of them passed, or the
+// failures as Errors
+func Check(results ...error) error {
+	var failed Errors
+	for _, err := range results {
+		if err == nil {
+			continue
+		}
+		if e, ok := err.(*Error); ok {
+			failed = append(failed, e)
+		} else if errs, ok := err.(Errors); ok {
+			failed = append(failed, errs...)
+		} else {
+			failed = append(failed, &Error{Message: err.Error()})
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
+
+func newError(field string, rule string, value interface{}, format string, args ...interface{}) error {
+	return &Error{Field: field, Rule: rule, Value: fmt.Sprint(value), Message: fmt.Sprintf(format, args...)}
+}
+
+// Required rejects empty and whitespace-only values
+func Required(field string, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return newError(field, RuleRequired, value, "must not be empty")
+	}
+	return nil
+}
+
+// ID accepts 1 to MaxIDLength letters, digits and the characters . _ - @ :
+// which covers UUIDs and wallet labels. Anything else, in particular the null
+// character that separates composite key parts, is rejected.
+func ID(field string, id string) error {
+	if id == "" {
+		return newError(field, RuleRequired, id, "must not be empty")
+	}
+	if len(id) > MaxIDLength {
+		return newError(field, RuleIDFormat, id, "must be at most %d characters", MaxIDLength)
+	}
+	for _, r := range id {
+		if !isIDRune(r) {
+			return newError(field, RuleIDFormat, id, "may only contain letters, digits and . _ - @ :")
+		}
+	}
+	return nil
+}
+
+// OptionalID is ID for arguments that may be left empty
+func OptionalID(field string, id string) error {
+	if id == "" {
+		return nil
+	}
+	return ID(field, id)
+}
+
+func isIDRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("._-@:", r)
+	}
+}
+
+// Currency accepts an active ISO 4217 alphabetic code such as USD
+func Currency(field string, code string) error {
+	if !currencies[code] {
+		return newError(field, RuleCurrency, code, "is not an ISO 4217 currency code")
+	}
+	return nil
+}
+
+// Country accepts an ISO 3166-1 alpha-2 code such as DE
+func Country(field string, code string) error {
+	if !countries[code] {
+		return newError(field, RuleCountry, code, "is not an ISO 3166-1 alpha-2 country code")
+	}
+	return nil
+}
+
+// IBAN accepts an ISO 13616 IBAN in electronic format, without spaces and in
+// upper case, whose length is right for its country and whose check digits
+// pass the mod-97 test
+func IBAN(field string, iban string) error {
+	if !ibanPattern.MatchString(iban) {
+		return newError(field, RuleIBAN, iban, "must be a country code, two check digits and up to 30 upper case letters and digits")
+	}
+	length, ok := ibanLengths[iban[:2]]
+	if !ok {
+		return newError(field, RuleIBAN, iban, "%s does not issue IBANs", iban[:2])
+	}
+	if len(iban) != length {
+		return newError(field, RuleIBAN, iban, "must be %d characters for %s", length, iban[:2])
+	}
+	if ibanRemainder(iban) != 1 {
+		return newError(field, RuleIBAN, iban, "has wrong check digits")
+	}
+	return nil
+}
+
+// ibanRemainder returns the ISO 7064 mod 97-10 remainder of an IBAN: the first
+// four characters move to the end, letters become 10 to 35, and the result is
+// read as one number
+func ibanRemainder(iban string) int {
+	remainder := 0
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' {
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(r-'0')) % 97
+		}
+	}
+	return remainder
+}
+
+// BIC accepts an ISO 9362 business identifier code of 8 or 11 characters
+// with a valid country code
+func BIC(field string, bic string) error {
+	match := bicPattern.FindStringSubmatch(bic)
+	if match == nil {
+		return newError(field, RuleBIC, bic, "must be 8 or 11 upper case letters and digits")
+	}
+	if !countries[match[1]] {
+		return newError(field, RuleBIC, bic, "has an unknown country code %s", match[1])
+	}
+	return nil
+}
+
+// RoutingNumber accepts a routing number in the format of its scheme, one of
+// the keys of RoutingSchemeCountries, and checks the check digit of ABA numbers
+func RoutingNumber(field string, scheme string, number string) error {
+	pattern, ok := routingPatterns[scheme]
+	if !ok {
+		return newError(field, RuleRoutingNumber, number, "has no known routing scheme")
+	}
+	if !pattern.MatchString(number) {
+		return newError(field, RuleRoutingNumber, number, "is not a %s routing number", scheme)
+	}
+	if scheme == RoutingABA {
+		sum := 0
+		for i, weight := range []int{3, 7, 1, 3, 7, 1, 3, 7, 1} {
+			sum += int(number[i]-'0') * weight
+		}
+		if sum%10 != 0 {
+			return newError(field, RuleRoutingNumber, number, "has a wrong check digit")
+		}
+	}
+	return nil
+}
+
+// AccountNumber accepts a local account number of 1 to 34 upper case letters
+// and digits
+func AccountNumber(field string, number string) error {
+	if !accountNumberPattern.MatchString(number) {
+		return newError(field, RuleAccountNumber, number, "must be 1 to 34 upper case letters and digits")
+	}
+	return nil
+}
+
+// MaxLength rejects text of more than max characters
+func MaxLength(field string, value string, max int) error {
+	if len([]rune(value)) > max {
+		return newError(field, RuleLength, value, "must be at most %d characters", max)
+	}
+	return nil
+}
+
+// CreditorReference accepts an ISO 11649 structured creditor reference in
+// electronic format, RF and two check digits followed by up to 21 upper case
+// letters and digits, whose check digits pass the same mod-97 test as IBANs
+func CreditorReference(field string, reference string) error {
+	if !referencePattern.MatchString(reference) {
+		return newError(field, RuleReference, reference, "must be RF, two check digits and up to 21 upper case letters and digits")
+	}
+	if ibanRemainder(reference) != 1 {
+		return newError(field, RuleReference, reference, "has wrong check digits")
+	}
+	return nil
+}
+
+// PurposeCode accepts a code in the format of the ISO 20022 external purpose
+// code list, four upper case letters such as SUPP. Whether the code is on the
+// list is up to the caller.
+func PurposeCode(field string, code string) error {
+	if !purposeCodePattern.MatchString(code) {
+		return newError(field, RulePurposeCode, code, "must be four upper case letters")
+	}
+	return nil
+}
+
+// PositiveAmount accepts finite amounts greater than zero
+func PositiveAmount(field string, amount float64) error {
+	if !isFinite(amount) || amount <= 0 {
+		return newError(field, RulePositive, amount, "must be greater than zero")
+	}
+	return nil
+}
+
+// NonNegativeAmount accepts zero and finite amounts greater than zero
+func NonNegativeAmount(field string, amount float64) error {
+	if !isFinite(amount) || amount < 0 {
+		return newError(field, RuleNonNegative, amount, "must not be negative")
+	}
+	return nil
+}
+
+// NonZeroAmount accepts finite adjustments in either direction, but not zero
+func NonZeroAmount(field string, amount float64) error {
+	if !isFinite(amount) || amount == 0 {
+		return newError(field, RuleNonZero, amount, "must be a non-zero number")
+	}
+	return nil
+}
+
+// DistinctAccounts rejects payments from an account to itself
+func DistinctAccounts(field string, senderAccountID string, receiverAccountID string) error {
+	if senderAccountID != "" && senderAccountID == receiverAccountID {
+		return newError(field, RuleSelfTransfer, receiverAccountID, "must differ from the sender account")
+	}
+	return nil
+}
+
+// OneOf accepts value if it is one of allowed
+func OneOf(field string, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return newError(field, RuleOneOf, value, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// IntRange accepts integers from min to max inclusive
+func IntRange(field string, value int, min int, max int) error {
+	if value < min || value > max {
+		return newError(field, RuleRange, value, "must be between %d and %d", min, max)
+	}
+	return nil
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
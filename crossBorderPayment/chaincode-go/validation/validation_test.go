@@ -0,0 +1,103 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package validation
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantRule string
+	}{
+		{name: "id", err: ID("id", "ACC-1_x.y@org1:2")},
+		{name: "empty id", err: ID("id", ""), wantRule: RuleRequired},
+		{name: "id with space", err: ID("id", "A 1"), wantRule: RuleIDFormat},
+		{name: "id with null", err: ID("id", "A\x001"), wantRule: RuleIDFormat},
+		{name: "long id", err: ID("id", strings.Repeat("a", MaxIDLength+1)), wantRule: RuleIDFormat},
+		{name: "empty optional id", err: OptionalID("id", "")},
+		{name: "bad optional id", err: OptionalID("id", "a/b"), wantRule: RuleIDFormat},
+		{name: "blank", err: Required("name", "  "), wantRule: RuleRequired},
+		{name: "currency", err: Currency("currency", "EUR")},
+		{name: "lower case currency", err: Currency("currency", "eur"), wantRule: RuleCurrency},
+		{name: "metal currency", err: Currency("currency", "XAU"), wantRule: RuleCurrency},
+		{name: "country", err: Country("country", "TR")},
+		{name: "unknown country", err: Country("country", "XX"), wantRule: RuleCountry},
+		{name: "positive", err: PositiveAmount("amount", 0.01)},
+		{name: "zero", err: PositiveAmount("amount", 0), wantRule: RulePositive},
+		{name: "infinite", err: PositiveAmount("amount", math.Inf(1)), wantRule: RulePositive},
+		{name: "non-negative zero", err: NonNegativeAmount("amount", 0)},
+		{name: "negative", err: NonNegativeAmount("amount", -1), wantRule: RuleNonNegative},
+		{name: "withdrawal", err: NonZeroAmount("amount", -5)},
+		{name: "not a number", err: NonZeroAmount("amount", math.NaN()), wantRule: RuleNonZero},
+		{name: "transfer", err: DistinctAccounts("receiver", "A1", "A2")},
+		{name: "self transfer", err: DistinctAccounts("receiver", "A1", "A1"), wantRule: RuleSelfTransfer},
+		{name: "one of", err: OneOf("mode", "b", "a", "b")},
+		{name: "none of", err: OneOf("mode", "c", "a", "b"), wantRule: RuleOneOf},
+		{name: "in range", err: IntRange("pageSize", 10, 1, 10)},
+		{name: "out of range", err: IntRange("pageSize", 0, 1, 10), wantRule: RuleRange},
+		{name: "IBAN", err: IBAN("iban", "DE89370400440532013000")},
+		{name: "IBAN check digits", err: IBAN("iban", "DE88370400440532013000"), wantRule: RuleIBAN},
+		{name: "IBAN length", err: IBAN("iban", "GB82WEST1234569876543"), wantRule: RuleIBAN},
+		{name: "IBAN with spaces", err: IBAN("iban", "DE89 3704 0044 0532 0130 00"), wantRule: RuleIBAN},
+		{name: "IBAN country", err: IBAN("iban", "US12345678901234"), wantRule: RuleIBAN},
+		{name: "creditor reference", err: CreditorReference("reference", "RF18539007547034")},
+		{name: "creditor reference check digits", err: CreditorReference("reference", "RF19539007547034"), wantRule: RuleReference},
+		{name: "creditor reference prefix", err: CreditorReference("reference", "XX18539007547034"), wantRule: RuleReference},
+		{name: "purpose code", err: PurposeCode("purposeCode", "SUPP")},
+		{name: "purpose code format", err: PurposeCode("purposeCode", "supp"), wantRule: RulePurposeCode},
+		{name: "max length", err: MaxLength("text", "äöü", 3)},
+		{name: "too long", err: MaxLength("text", "abcd", 3), wantRule: RuleLength},
+		{name: "BIC", err: BIC("bic", "DEUTDEFF500")},
+		{name: "BIC country", err: BIC("bic", "DEUTXXFF"), wantRule: RuleBIC},
+		{name: "BIC length", err: BIC("bic", "DEUTDEFF5"), wantRule: RuleBIC},
+		{name: "ABA", err: RoutingNumber("routingNumber", RoutingABA, "021000021")},
+		{name: "ABA check digit", err: RoutingNumber("routingNumber", RoutingABA, "021000022"), wantRule: RuleRoutingNumber},
+		{name: "sort code", err: RoutingNumber("routingNumber", RoutingSortCode, "12-34-56"), wantRule: RuleRoutingNumber},
+		{name: "IFSC", err: RoutingNumber("routingNumber", RoutingIFSC, "SBIN0001234")},
+		{name: "unknown scheme", err: RoutingNumber("routingNumber", "SWIFT", "021000021"), wantRule: RuleRoutingNumber},
+		{name: "account number", err: AccountNumber("accountNumber", "31926819")},
+		{name: "account number with dashes", err: AccountNumber("accountNumber", "3192-6819"), wantRule: RuleAccountNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantRule == "" {
+				if tt.err != nil {
+					t.Fatalf("unexpected error %v", tt.err)
+				}
+				return
+			}
+			e, ok := tt.err.(*Error)
+			if !ok || e.Rule != tt.wantRule {
+				t.Fatalf("error = %#v, want rule %s", tt.err, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(ID("id", "A1"), nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err := Check(ID("bankID", ""), Currency("currency", "USD"), PositiveAmount("amount", -2))
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("error = %#v, want two failures", err)
+	}
+	want := `validation failed: invalid bankID "": must not be empty; invalid amount "-2": must be greater than zero`
+	if err.Error() != want {
+		t.Fatalf("message = %q, want %q", err.Error(), want)
+	}
+
+	if nested := Check(err, Required("name", "")); len(nested.(Errors)) != 3 {
+		t.Fatalf("Check did not flatten %v", nested)
+	}
+}